@@ -0,0 +1,222 @@
+// Package colors provides helpers for working with gruid.Color values: building
+// colors from RGB components or xterm 256-color palette indices, converting
+// them to standard library colors, interpolating between colors, and mapping
+// application-specific logical colors through a palette.
+//
+// As gruid.Color values are interpreted by drivers, this package defines the
+// following conventions, followed by the ansi driver and the tiles package
+// default colors:
+//
+//   - gruid.ColorDefault is the driver's default color.
+//   - Values 1 to 256 are xterm 256-color palette indices plus one.
+//   - Values with the RGBFlag bit set hold 24-bit RGB components in their
+//     lower bits.
+//
+// Other values are free for application-specific logical colors, and can be
+// mapped to concrete colors with a Palette.
+package colors
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/anaseto/gruid"
+)
+
+// RGBFlag is the bit that marks a color as holding RGB components.
+const RGBFlag gruid.Color = 1 << 24
+
+// RGB returns a color from its red, green and blue components.
+func RGB(r, g, b uint8) gruid.Color {
+	return RGBFlag | gruid.Color(r)<<16 | gruid.Color(g)<<8 | gruid.Color(b)
+}
+
+// FromRGBA returns a RGB color from a standard library color, ignoring
+// transparency.
+func FromRGBA(c color.Color) gruid.Color {
+	r, g, b, _ := c.RGBA()
+	return RGB(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+}
+
+// Xterm returns the color with the given index, between 0 and 255, in the
+// xterm 256-color palette.
+func Xterm(n int) gruid.Color {
+	return gruid.Color(n + 1)
+}
+
+// IsRGB reports whether a color holds RGB components.
+func IsRGB(c gruid.Color) bool {
+	return c&^0xffffff == RGBFlag
+}
+
+// IsXterm reports whether a color is a xterm 256-color palette index.
+func IsXterm(c gruid.Color) bool {
+	return c >= 1 && c <= 256
+}
+
+// ToRGBA returns the standard library color for a RGB or xterm color. It
+// reports false for the default color and other values.
+func ToRGBA(c gruid.Color) (color.RGBA, bool) {
+	switch {
+	case IsRGB(c):
+		return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 255}, true
+	case IsXterm(c):
+		return xterm256(int(c - 1)), true
+	default:
+		return color.RGBA{}, false
+	}
+}
+
+// xterm256 returns the color in the xterm 256-color palette with the given
+// number, between 0 and 255.
+func xterm256(n int) color.RGBA {
+	basic := [16]color.RGBA{
+		{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+		{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+		{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+		{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+	}
+	switch {
+	case n < 16:
+		return basic[n]
+	case n < 232:
+		n -= 16
+		return color.RGBA{cubeLevel(n / 36), cubeLevel((n / 6) % 6), cubeLevel(n % 6), 255}
+	default:
+		g := uint8(8 + 10*(n-232))
+		return color.RGBA{g, g, g, 255}
+	}
+}
+
+// cubeLevel returns the component value of the i-th level of the xterm 6x6x6
+// color cube.
+func cubeLevel(i int) uint8 {
+	if i == 0 {
+		return 0
+	}
+	return uint8(55 + 40*i)
+}
+
+// NearestXterm returns the closest xterm 256-color palette color for a given
+// RGB color, ignoring the 16 basic colors, whose values vary among terminals.
+// Other colors are returned unchanged.
+func NearestXterm(c gruid.Color) gruid.Color {
+	if !IsRGB(c) {
+		return c
+	}
+	rgb, _ := ToRGBA(c)
+	level := func(v uint8) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+	r, g, b := level(rgb.R), level(rgb.G), level(rgb.B)
+	best := 16 + 36*r + 6*g + b
+	bestd := dist(rgb, xterm256(best))
+	// gray ramp
+	avg := (int(rgb.R) + int(rgb.G) + int(rgb.B)) / 3
+	gi := (avg - 3) / 10
+	if gi < 0 {
+		gi = 0
+	} else if gi > 23 {
+		gi = 23
+	}
+	if d := dist(rgb, xterm256(232+gi)); d < bestd {
+		best = 232 + gi
+	}
+	return Xterm(best)
+}
+
+// dist returns the squared euclidean distance between two colors.
+func dist(c1, c2 color.RGBA) int {
+	dr := int(c1.R) - int(c2.R)
+	dg := int(c1.G) - int(c2.G)
+	db := int(c1.B) - int(c2.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// Interpolate returns the RGB color at ratio t between c1 (t = 0) and c2 (t =
+// 1), for RGB or xterm colors, such as for light falloff or damage flashes.
+// If one of the colors has no RGB components, such as the default color, it
+// returns c1 if t < 0.5, and c2 otherwise.
+func Interpolate(c1, c2 gruid.Color, t float64) gruid.Color {
+	if t <= 0 {
+		return c1
+	}
+	if t >= 1 {
+		return c2
+	}
+	rgb1, ok1 := ToRGBA(c1)
+	rgb2, ok2 := ToRGBA(c2)
+	if !ok1 || !ok2 {
+		if t < 0.5 {
+			return c1
+		}
+		return c2
+	}
+	lerp := func(a, b uint8) uint8 {
+		return uint8(float64(a) + t*(float64(b)-float64(a)) + 0.5)
+	}
+	return RGB(lerp(rgb1.R, rgb2.R), lerp(rgb1.G, rgb2.G), lerp(rgb1.B, rgb2.B))
+}
+
+// Palette maps application-specific logical colors to concrete RGB or xterm
+// colors. It allows using the same logical colors for all drivers, and
+// switching color themes.
+type Palette map[gruid.Color]gruid.Color
+
+// Map returns the concrete color for c. Colors that are not in the palette
+// are returned unchanged.
+func (p Palette) Map(c gruid.Color) gruid.Color {
+	if pc, ok := p[c]; ok {
+		return pc
+	}
+	return c
+}
+
+// RGBA returns the standard library color for c after mapping, or def if it
+// has no RGB components. It can be used by tile managers.
+func (p Palette) RGBA(c gruid.Color, def color.Color) color.Color {
+	if rgb, ok := ToRGBA(p.Map(c)); ok {
+		return rgb
+	}
+	return def
+}
+
+// sgrAttrs associates standard attributes to SGR parameters.
+var sgrAttrs = []struct {
+	attr  gruid.AttrMask
+	param string
+}{
+	{gruid.AttrBold, "1"},
+	{gruid.AttrItalic, "3"},
+	{gruid.AttrUnderline, "4"},
+	{gruid.AttrBlink, "5"},
+	{gruid.AttrReverse, "7"},
+	{gruid.AttrStrikethrough, "9"},
+}
+
+// SGR returns SGR parameters for a style, with colors mapped using the xterm
+// 256-color palette, and standard attributes, such as gruid.AttrBold, mapped to
+// the corresponding SGR attributes. Other attributes are ignored. It implements
+// the ansi.StyleManager interface.
+func (p Palette) SGR(st gruid.Style) string {
+	var params []string
+	for _, a := range sgrAttrs {
+		if st.Attrs&a.attr != 0 {
+			params = append(params, a.param)
+		}
+	}
+	if c := NearestXterm(p.Map(st.Fg)); IsXterm(c) {
+		params = append(params, fmt.Sprintf("38;5;%d", c-1))
+	}
+	if c := NearestXterm(p.Map(st.Bg)); IsXterm(c) {
+		params = append(params, fmt.Sprintf("48;5;%d", c-1))
+	}
+	return strings.Join(params, ";")
+}
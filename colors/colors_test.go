@@ -0,0 +1,88 @@
+package colors
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestRGB(t *testing.T) {
+	c := RGB(255, 128, 1)
+	if !IsRGB(c) || IsXterm(c) {
+		t.Errorf("bad RGB color kind: %x", c)
+	}
+	if rgb, ok := ToRGBA(c); !ok || rgb != (color.RGBA{255, 128, 1, 255}) {
+		t.Errorf("bad RGB components: %v", rgb)
+	}
+	if FromRGBA(color.RGBA{255, 128, 1, 255}) != c {
+		t.Errorf("bad FromRGBA")
+	}
+	if IsRGB(RGB(0, 0, 0)) == false || RGB(0, 0, 0) == gruid.ColorDefault {
+		t.Errorf("black is not a RGB color")
+	}
+	if _, ok := ToRGBA(gruid.ColorDefault); ok {
+		t.Errorf("default color with RGB components")
+	}
+}
+
+func TestXterm(t *testing.T) {
+	tests := []struct {
+		n   int
+		rgb color.RGBA
+	}{
+		{1, color.RGBA{205, 0, 0, 255}},
+		{196, color.RGBA{255, 0, 0, 255}},
+		{16, color.RGBA{0, 0, 0, 255}},
+		{244, color.RGBA{128, 128, 128, 255}},
+	}
+	for _, test := range tests {
+		if rgb, ok := ToRGBA(Xterm(test.n)); !ok || rgb != test.rgb {
+			t.Errorf("bad xterm color %d: %v", test.n, rgb)
+		}
+	}
+	if c := NearestXterm(RGB(250, 10, 5)); c != Xterm(196) {
+		t.Errorf("bad nearest red: %d", c-1)
+	}
+	if c := NearestXterm(RGB(128, 130, 127)); c != Xterm(244) {
+		t.Errorf("bad nearest gray: %d", c-1)
+	}
+	if c := NearestXterm(Xterm(3)); c != Xterm(3) {
+		t.Errorf("xterm color changed: %d", c-1)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	c := Interpolate(RGB(0, 0, 0), RGB(200, 100, 50), 0.5)
+	if c != RGB(100, 50, 25) {
+		rgb, _ := ToRGBA(c)
+		t.Errorf("bad interpolation: %v", rgb)
+	}
+	if c := Interpolate(Xterm(196), RGB(0, 0, 255), 0.25); c != RGB(191, 0, 64) {
+		rgb, _ := ToRGBA(c)
+		t.Errorf("bad xterm interpolation: %v", rgb)
+	}
+	if c := Interpolate(gruid.ColorDefault, RGB(1, 2, 3), 0.4); c != gruid.ColorDefault {
+		t.Errorf("bad default interpolation: %x", c)
+	}
+}
+
+func TestPalette(t *testing.T) {
+	const (
+		ColorPlayer gruid.Color = 1000 + iota
+		ColorMonster
+	)
+	p := Palette{ColorPlayer: RGB(0, 0, 255), ColorMonster: Xterm(1)}
+	if p.Map(ColorMonster) != Xterm(1) || p.Map(Xterm(5)) != Xterm(5) {
+		t.Errorf("bad palette mapping")
+	}
+	if c := p.RGBA(gruid.ColorDefault, color.White); c != color.White {
+		t.Errorf("bad default: %v", c)
+	}
+	if s := p.SGR(gruid.Style{Fg: ColorPlayer, Bg: ColorMonster}); s != "38;5;21;48;5;1" {
+		t.Errorf("bad SGR: %q", s)
+	}
+	if s := p.SGR(gruid.Style{Fg: ColorMonster, Attrs: gruid.AttrBold | gruid.AttrReverse}); s != "1;7;38;5;1" {
+		t.Errorf("bad SGR with attributes: %q", s)
+	}
+}
@@ -0,0 +1,381 @@
+// Package ansi provides a minimal gruid driver that renders frames as ANSI
+// escape sequences to an io.Writer, and reads key input from an io.Reader.
+//
+// Unlike the gruid-tcell driver, it does not take over a terminal and has no
+// dependencies, so it can be used over an SSH session channel, on a serial
+// console, inside another terminal application, or in tests. The output
+// assumes a VT100-compatible terminal with 256 colors support. Setting the
+// terminal in raw mode, if needed, is the responsibility of the caller.
+package ansi
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/colors"
+)
+
+// StyleManager allows for retrieving the SGR (Select Graphic Rendition)
+// parameters of an escape sequence for a given gruid.Style.
+type StyleManager interface {
+	// SGR returns the semicolon-separated SGR parameters corresponding to
+	// a style, such as "1;38;5;3" for bold yellow. The parameters are
+	// applied after a reset, so an empty string represents the default
+	// style.
+	SGR(gruid.Style) string
+}
+
+// Config contains configurations options for the driver.
+type Config struct {
+	Output io.Writer // output for frames (required)
+	Input  io.Reader // optional input for key messages
+	Width  int       // screen width in cells (default: 80)
+	Height int       // screen height in cells (default: 24)
+
+	// StyleManager maps styles to SGR parameters. If nil, an empty
+	// colors.Palette is used: colors are interpreted as 256 colors
+	// palette indices plus one, so that gruid.ColorDefault maps to the
+	// default color, and RGB colors from the colors package are mapped
+	// to the nearest palette color. Standard attributes, such as
+	// gruid.AttrBold, are mapped to the corresponding SGR attributes, and
+	// other attributes are ignored.
+	StyleManager StyleManager
+
+	// Escape is the delay after which a lone escape byte is reported as
+	// the Escape key, instead of being interpreted as the start of an
+	// escape sequence (default: 50ms).
+	Escape time.Duration
+}
+
+// Driver implements gruid.Driver using ANSI escape sequences.
+type Driver struct {
+	out    *bufio.Writer
+	in     io.Reader
+	w, h   int
+	sm     StyleManager
+	escape time.Duration
+	style  gruid.Style // current output style
+	sgr    bool        // whether style is valid
+	p      gruid.Point // current output cursor position
+	cursor bool        // whether cursor position is known
+}
+
+// NewDriver returns a new driver with given configuration options.
+func NewDriver(cfg Config) *Driver {
+	dr := &Driver{
+		out:    bufio.NewWriter(cfg.Output),
+		in:     cfg.Input,
+		w:      cfg.Width,
+		h:      cfg.Height,
+		sm:     cfg.StyleManager,
+		escape: cfg.Escape,
+	}
+	if dr.w <= 0 {
+		dr.w = 80
+	}
+	if dr.h <= 0 {
+		dr.h = 24
+	}
+	if dr.sm == nil {
+		dr.sm = colors.Palette(nil)
+	}
+	if dr.escape <= 0 {
+		dr.escape = 50 * time.Millisecond
+	}
+	return dr
+}
+
+// Size returns the screen size in cells.
+func (dr *Driver) Size() gruid.Point {
+	return gruid.Point{dr.w, dr.h}
+}
+
+// Capabilities implements gruid.DriverInfo.
+func (dr *Driver) Capabilities() gruid.DriverCapabilities {
+	return gruid.DriverCapabilities{Colors: 256}
+}
+
+// Init implements gruid.Driver.Init. It hides the cursor and clears the
+// screen.
+func (dr *Driver) Init() error {
+	dr.out.WriteString("\x1b[?25l\x1b[0m\x1b[2J")
+	dr.sgr = false
+	dr.cursor = false
+	return dr.out.Flush()
+}
+
+// PollMsgs implements gruid.Driver.PollMsgs. It reports key messages read
+// from the input, if any.
+func (dr *Driver) PollMsgs(ctx context.Context, msgs chan<- gruid.Msg) error {
+	if dr.in == nil {
+		<-ctx.Done()
+		return nil
+	}
+	chunks := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			buf := make([]byte, 256)
+			n, err := dr.in.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- buf[:n]:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	var pending []byte
+	var timeout <-chan time.Time
+	send := func(msg gruid.Msg) bool {
+		select {
+		case msgs <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			if err == io.EOF {
+				<-ctx.Done()
+				return nil
+			}
+			return err
+		case <-timeout:
+			timeout = nil
+			for _, msg := range parseKeys(pending) {
+				if !send(msg) {
+					return nil
+				}
+			}
+			pending = nil
+		case bs := <-chunks:
+			var keys []gruid.MsgKeyDown
+			pending = append(pending, bs...)
+			keys, pending = parseInput(pending)
+			now := time.Now()
+			for _, msg := range keys {
+				msg.Time = now
+				if !send(msg) {
+					return nil
+				}
+			}
+			if len(pending) > 0 {
+				timeout = time.After(dr.escape)
+			}
+		}
+	}
+}
+
+// Flush implements gruid.Driver.Flush.
+func (dr *Driver) Flush(frame gruid.Frame) {
+	for _, fc := range frame.Cells {
+		p := fc.P
+		if p.X >= dr.w || p.Y >= dr.h || p.X < 0 || p.Y < 0 {
+			continue
+		}
+		if !dr.cursor || p != dr.p {
+			fmt.Fprintf(dr.out, "\x1b[%d;%dH", p.Y+1, p.X+1)
+		}
+		c := fc.Cell
+		st := c.Style
+		st.Attrs &^= gruid.AttrWide | gruid.AttrRedraw
+		if !dr.sgr || st != dr.style {
+			dr.out.WriteString("\x1b[0")
+			if sgr := dr.sm.SGR(st); sgr != "" {
+				dr.out.WriteByte(';')
+				dr.out.WriteString(sgr)
+			}
+			dr.out.WriteByte('m')
+			dr.style = st
+			dr.sgr = true
+		}
+		dr.out.WriteString(c.Content())
+		dr.p = p.Shift(1, 0)
+		if c.Wide() {
+			dr.p = dr.p.Shift(1, 0)
+		}
+		dr.cursor = dr.p.X < dr.w
+	}
+	dr.out.Flush()
+}
+
+// Close implements gruid.Driver.Close. It resets the style, clears the
+// screen, and shows the cursor again.
+func (dr *Driver) Close() {
+	dr.out.WriteString("\x1b[0m\x1b[2J\x1b[H\x1b[?25h")
+	dr.out.Flush()
+}
+
+// csiKeys maps the final byte of CSI sequences without parameters, and the
+// final byte of SS3 sequences, to keys.
+var csiKeys = map[byte]gruid.Key{
+	'A': gruid.KeyArrowUp,
+	'B': gruid.KeyArrowDown,
+	'C': gruid.KeyArrowRight,
+	'D': gruid.KeyArrowLeft,
+	'F': gruid.KeyEnd,
+	'H': gruid.KeyHome,
+	'Z': gruid.KeyTab, // with shift
+}
+
+// tildeKeys maps the parameter of CSI sequences ending with a tilde to keys.
+var tildeKeys = map[string]gruid.Key{
+	"1": gruid.KeyHome,
+	"2": gruid.KeyInsert,
+	"3": gruid.KeyDelete,
+	"4": gruid.KeyEnd,
+	"5": gruid.KeyPageUp,
+	"6": gruid.KeyPageDown,
+	"7": gruid.KeyHome,
+	"8": gruid.KeyEnd,
+}
+
+// parseInput parses key messages from input bytes. It returns the remaining
+// bytes that may form an incomplete escape sequence or UTF-8 encoding.
+func parseInput(bs []byte) ([]gruid.MsgKeyDown, []byte) {
+	var msgs []gruid.MsgKeyDown
+	for len(bs) > 0 {
+		msg, n := parseKey(bs)
+		if n == 0 {
+			break
+		}
+		if msg.Key != "" {
+			msgs = append(msgs, msg)
+		}
+		bs = bs[n:]
+	}
+	return msgs, bs
+}
+
+// parseKeys parses remaining input bytes after a timeout, interpreting any
+// incomplete escape sequence as the Escape key followed by other keys.
+func parseKeys(bs []byte) []gruid.Msg {
+	var msgs []gruid.Msg
+	for len(bs) > 0 {
+		msg, n := parseKey(bs)
+		if n == 0 {
+			if bs[0] == 0x1b {
+				msg, n = gruid.MsgKeyDown{Key: gruid.KeyEscape}, 1
+			} else {
+				// invalid or truncated UTF-8
+				n = 1
+			}
+		}
+		if msg.Key != "" {
+			msg.Time = time.Now()
+			msgs = append(msgs, msg)
+		}
+		bs = bs[n:]
+	}
+	return msgs
+}
+
+// parseKey parses a key message at the start of bs, and returns the number
+// of bytes used. It returns zero if more bytes are needed. Unknown escape
+// sequences are consumed, and reported as a message with an empty key.
+func parseKey(bs []byte) (gruid.MsgKeyDown, int) {
+	b := bs[0]
+	switch {
+	case b == 0x1b:
+		return parseEscape(bs)
+	case b == '\r' || b == '\n':
+		return gruid.MsgKeyDown{Key: gruid.KeyEnter}, 1
+	case b == '\t':
+		return gruid.MsgKeyDown{Key: gruid.KeyTab}, 1
+	case b == 0x7f || b == 0x08:
+		return gruid.MsgKeyDown{Key: gruid.KeyBackspace}, 1
+	case b >= 0x1c && b < 0x20:
+		return gruid.MsgKeyDown{}, 1
+	case b == 0:
+		return gruid.MsgKeyDown{Key: gruid.KeySpace, Mod: gruid.ModCtrl}, 1
+	case b <= 0x1a:
+		return gruid.MsgKeyDown{Key: gruid.Key(rune('a' + b - 1)), Mod: gruid.ModCtrl}, 1
+	}
+	if !utf8.FullRune(bs) {
+		return gruid.MsgKeyDown{}, 0
+	}
+	r, n := utf8.DecodeRune(bs)
+	if r == utf8.RuneError {
+		return gruid.MsgKeyDown{}, n
+	}
+	return gruid.MsgKeyDown{Key: gruid.Key(string(r))}, n
+}
+
+// parseEscape parses a key starting with an escape byte.
+func parseEscape(bs []byte) (gruid.MsgKeyDown, int) {
+	if len(bs) < 2 {
+		return gruid.MsgKeyDown{}, 0
+	}
+	switch bs[1] {
+	case '[':
+		return parseCSI(bs)
+	case 'O':
+		if len(bs) < 3 {
+			return gruid.MsgKeyDown{}, 0
+		}
+		return gruid.MsgKeyDown{Key: csiKeys[bs[2]]}, 3
+	case 0x1b:
+		return gruid.MsgKeyDown{Key: gruid.KeyEscape}, 1
+	}
+	msg, n := parseKey(bs[1:])
+	if n == 0 {
+		return msg, 0
+	}
+	msg.Mod |= gruid.ModAlt
+	return msg, n + 1
+}
+
+// parseCSI parses a Control Sequence Introducer escape sequence.
+func parseCSI(bs []byte) (gruid.MsgKeyDown, int) {
+	i := 2
+	for i < len(bs) && bs[i] >= 0x30 && bs[i] <= 0x3f {
+		// parameter bytes
+		i++
+	}
+	if i >= len(bs) {
+		return gruid.MsgKeyDown{}, 0
+	}
+	params := strings.Split(string(bs[2:i]), ";")
+	final := bs[i]
+	var msg gruid.MsgKeyDown
+	if final == '~' {
+		msg.Key = tildeKeys[params[0]]
+	} else {
+		msg.Key = csiKeys[final]
+		if final == 'Z' {
+			msg.Mod = gruid.ModShift
+		}
+	}
+	if len(params) > 1 {
+		// xterm modifier parameter
+		var m int
+		fmt.Sscanf(params[1], "%d", &m)
+		m--
+		if m&1 != 0 {
+			msg.Mod |= gruid.ModShift
+		}
+		if m&2 != 0 {
+			msg.Mod |= gruid.ModAlt
+		}
+		if m&4 != 0 {
+			msg.Mod |= gruid.ModCtrl
+		}
+	}
+	return msg, i + 1
+}
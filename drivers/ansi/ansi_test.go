@@ -0,0 +1,123 @@
+package ansi
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		in   string
+		keys []gruid.MsgKeyDown
+		rest string
+	}{
+		{"aé\r", []gruid.MsgKeyDown{{Key: "a"}, {Key: "é"}, {Key: gruid.KeyEnter}}, ""},
+		{"\x1b[A\x1bOB\x1b[5~", []gruid.MsgKeyDown{{Key: gruid.KeyArrowUp}, {Key: gruid.KeyArrowDown}, {Key: gruid.KeyPageUp}}, ""},
+		{"\x1b[1;5C\x1b[Z", []gruid.MsgKeyDown{{Key: gruid.KeyArrowRight, Mod: gruid.ModCtrl}, {Key: gruid.KeyTab, Mod: gruid.ModShift}}, ""},
+		{"\x1bx\x01\x7f", []gruid.MsgKeyDown{{Key: "x", Mod: gruid.ModAlt}, {Key: "a", Mod: gruid.ModCtrl}, {Key: gruid.KeyBackspace}}, ""},
+		{"\x1b\x1b[3", []gruid.MsgKeyDown{{Key: gruid.KeyEscape}}, "\x1b[3"},
+		{"a\x1b", []gruid.MsgKeyDown{{Key: "a"}}, "\x1b"},
+		{"\xc3", nil, "\xc3"},
+	}
+	for _, test := range tests {
+		keys, rest := parseInput([]byte(test.in))
+		if len(keys) != len(test.keys) {
+			t.Errorf("%q: bad keys: %v", test.in, keys)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != test.keys[i] {
+				t.Errorf("%q: bad key %d: %v (expected %v)", test.in, i, keys[i], test.keys[i])
+			}
+		}
+		if string(rest) != test.rest {
+			t.Errorf("%q: bad rest: %q", test.in, rest)
+		}
+	}
+	msgs := parseKeys([]byte("\x1b"))
+	if len(msgs) != 1 || msgs[0].(gruid.MsgKeyDown).Key != gruid.KeyEscape {
+		t.Errorf("bad keys after timeout: %v", msgs)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	dr := NewDriver(Config{Output: buf, Width: 10, Height: 2})
+	if err := dr.Init(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	st := gruid.Style{Fg: 2}
+	dr.Flush(gruid.Frame{Cells: []gruid.FrameCell{
+		{Cell: gruid.Cell{Rune: 'a', Style: st}, P: gruid.Point{1, 0}},
+		{Cell: gruid.Cell{Rune: 'b', Style: st}, P: gruid.Point{2, 0}},
+		{Cell: gruid.Cell{Rune: 'c'}, P: gruid.Point{0, 1}},
+		{Cell: gruid.Cell{Rune: 'd'}, P: gruid.Point{10, 1}},
+	}})
+	want := "\x1b[1;2H\x1b[0;38;5;1mab\x1b[2;1H\x1b[0mc"
+	if buf.String() != want {
+		t.Errorf("bad output: %q (expected %q)", buf.String(), want)
+	}
+}
+
+func TestFlushAttrs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	dr := NewDriver(Config{Output: buf, Width: 10, Height: 2})
+	if err := dr.Init(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	dr.Flush(gruid.Frame{Cells: []gruid.FrameCell{
+		{Cell: gruid.Cell{Rune: 'a', Style: gruid.Style{Fg: 2, Attrs: gruid.AttrBold | gruid.AttrReverse}}, P: gruid.Point{0, 0}},
+		{Cell: gruid.Cell{Rune: 'b', Style: gruid.Style{Attrs: gruid.AttrUnderline | 1<<10}}, P: gruid.Point{1, 0}},
+	}})
+	want := "\x1b[1;1H\x1b[0;1;7;38;5;1ma\x1b[0;4mb"
+	if buf.String() != want {
+		t.Errorf("bad output: %q (expected %q)", buf.String(), want)
+	}
+}
+
+type testModel struct {
+	gd   gruid.Grid
+	keys []gruid.Key
+}
+
+func (m *testModel) Update(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgInit:
+		m.gd.Fill(gruid.Cell{Rune: '.'})
+	case gruid.MsgKeyDown:
+		m.keys = append(m.keys, msg.Key)
+		if msg.Key == "q" {
+			return gruid.End()
+		}
+	}
+	return nil
+}
+
+func (m *testModel) Draw() gruid.Grid {
+	return m.gd
+}
+
+func TestApp(t *testing.T) {
+	out := &bytes.Buffer{}
+	dr := NewDriver(Config{Output: out, Input: strings.NewReader("h\x1b[Bq"), Width: 4, Height: 2})
+	m := &testModel{gd: gruid.NewGrid(4, 2)}
+	app := gruid.NewApp(gruid.AppConfig{Driver: dr, Model: m})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.Start(ctx); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if len(m.keys) != 3 || m.keys[1] != gruid.KeyArrowDown {
+		t.Errorf("bad keys: %v", m.keys)
+	}
+	if strings.Count(out.String(), ".") != 8 {
+		t.Errorf("bad output: %q", out.String())
+	}
+}
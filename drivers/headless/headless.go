@@ -0,0 +1,192 @@
+// Package headless provides a gruid driver that renders frames to an
+// in-memory grid and reports scripted input messages. It allows writing
+// end-to-end tests of a model's Update and Draw logic, without a terminal or
+// display.
+//
+// A typical test starts the application in a goroutine, sends input messages
+// with Send, and waits with Wait until the screen contents satisfy some
+// condition.
+package headless
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// Step represents a scripted input message.
+type Step struct {
+	Msg   gruid.Msg     // input message
+	Delay time.Duration // delay before sending the message, after previous one
+}
+
+// Config contains configurations options for the driver.
+type Config struct {
+	Width  int // initial screen width in cells (default: 80)
+	Height int // initial screen height in cells (default: 24)
+
+	// Script is an optional list of input messages sent in order after
+	// initialization, before any messages from Send.
+	Script []Step
+
+	// Capabilities are the capabilities reported by the driver.
+	Capabilities gruid.DriverCapabilities
+}
+
+// Driver implements gruid.Driver, rendering frames to an in-memory grid.
+// Its methods can be used concurrently with the running application.
+type Driver struct {
+	mu     sync.Mutex
+	grid   gruid.Grid
+	frames int
+	closed bool
+	flush  chan struct{} // closed and replaced on each flush
+	inputs chan gruid.Msg
+	script []Step
+	caps   gruid.DriverCapabilities
+}
+
+// NewDriver returns a new headless driver with given configuration options.
+func NewDriver(cfg Config) *Driver {
+	w, h := cfg.Width, cfg.Height
+	if w <= 0 {
+		w = 80
+	}
+	if h <= 0 {
+		h = 24
+	}
+	return &Driver{
+		grid:   gruid.NewGrid(w, h),
+		flush:  make(chan struct{}),
+		inputs: make(chan gruid.Msg, 64),
+		script: cfg.Script,
+		caps:   cfg.Capabilities,
+	}
+}
+
+// Init implements gruid.Driver.Init.
+func (dr *Driver) Init() error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.closed = false
+	return nil
+}
+
+// Capabilities implements gruid.DriverInfo.
+func (dr *Driver) Capabilities() gruid.DriverCapabilities {
+	return dr.caps
+}
+
+// PollMsgs implements gruid.Driver.PollMsgs. It sends the scripted messages,
+// and then the messages provided with Send.
+func (dr *Driver) PollMsgs(ctx context.Context, msgs chan<- gruid.Msg) error {
+	send := func(msg gruid.Msg) bool {
+		select {
+		case msgs <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for _, st := range dr.script {
+		if st.Delay > 0 {
+			t := time.NewTimer(st.Delay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return nil
+			}
+		}
+		if !send(st.Msg) {
+			return nil
+		}
+	}
+	for {
+		select {
+		case msg := <-dr.inputs:
+			if !send(msg) {
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Send queues an input message, to be reported after the scripted ones. It
+// blocks if too many messages are queued. Screen resizes can be simulated
+// by sending a MsgScreen message: the screen grid then follows the size of
+// the frames flushed by the application.
+func (dr *Driver) Send(msg gruid.Msg) {
+	dr.inputs <- msg
+}
+
+// Flush implements gruid.Driver.Flush.
+func (dr *Driver) Flush(frame gruid.Frame) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if max := dr.grid.Size(); frame.Width != max.X || frame.Height != max.Y {
+		dr.grid = dr.grid.Resize(frame.Width, frame.Height)
+	}
+	for _, fc := range frame.Cells {
+		dr.grid.Set(fc.P, fc.Cell)
+	}
+	dr.frames++
+	close(dr.flush)
+	dr.flush = make(chan struct{})
+}
+
+// Close implements gruid.Driver.Close.
+func (dr *Driver) Close() {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.closed = true
+}
+
+// Closed reports whether the driver has been closed, typically after the
+// application's end.
+func (dr *Driver) Closed() bool {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	return dr.closed
+}
+
+// Frames returns the number of frames flushed so far.
+func (dr *Driver) Frames() int {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	return dr.frames
+}
+
+// Grid returns a copy of the current screen contents.
+func (dr *Driver) Grid() gruid.Grid {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	max := dr.grid.Size()
+	gd := gruid.NewGrid(max.X, max.Y)
+	gd.Copy(dr.grid)
+	return gd
+}
+
+// Wait waits until a condition on the screen contents is satisfied, checking
+// it now and after every flushed frame. It returns the context's error if the
+// context is done before.
+func (dr *Driver) Wait(ctx context.Context, cond func(gruid.Grid) bool) error {
+	for {
+		dr.mu.Lock()
+		ok := cond(dr.grid)
+		flush := dr.flush
+		dr.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-flush:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package headless
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/ui"
+)
+
+type model struct {
+	gd gruid.Grid
+	ti *ui.TextInput
+}
+
+func (m *model) Update(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgScreen:
+		m.gd = m.gd.Resize(msg.Width, msg.Height)
+		return nil
+	case gruid.MsgKeyDown:
+		if msg.Key == gruid.KeyEscape {
+			return gruid.End()
+		}
+	}
+	m.ti.Update(msg)
+	return nil
+}
+
+func (m *model) Draw() gruid.Grid {
+	m.gd.Copy(m.ti.Draw())
+	return m.gd
+}
+
+func TestDriver(t *testing.T) {
+	dr := NewDriver(Config{
+		Width:  10,
+		Height: 2,
+		Script: []Step{{Msg: gruid.MsgKeyDown{Key: "a"}}, {Msg: gruid.MsgKeyDown{Key: "b"}, Delay: time.Millisecond}},
+	})
+	gd := gruid.NewGrid(10, 2)
+	m := &model{gd: gd, ti: ui.NewTextInput(ui.TextInputConfig{Grid: gd.Slice(gd.Range().Line(0))})}
+	app := gruid.NewApp(gruid.AppConfig{Driver: dr, Model: m})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- app.Start(ctx)
+	}()
+	err := dr.Wait(ctx, func(gd gruid.Grid) bool {
+		return gd.At(gruid.Point{0, 0}).Rune == 'a' && gd.At(gruid.Point{1, 0}).Rune == 'b'
+	})
+	if err != nil {
+		t.Fatalf("scripted input not drawn: %v", err)
+	}
+	dr.Send(gruid.MsgKeyDown{Key: "c"})
+	err = dr.Wait(ctx, func(gd gruid.Grid) bool {
+		return gd.At(gruid.Point{2, 0}).Rune == 'c'
+	})
+	if err != nil {
+		t.Fatalf("sent input not drawn: %v", err)
+	}
+	if dr.Grid().At(gruid.Point{1, 0}).Rune != 'b' {
+		t.Errorf("bad grid copy")
+	}
+	dr.Send(gruid.MsgScreen{Width: 12, Height: 3})
+	err = dr.Wait(ctx, func(gd gruid.Grid) bool {
+		return gd.Size() == gruid.Point{12, 3}
+	})
+	if err != nil {
+		t.Fatalf("screen not resized: %v", err)
+	}
+	dr.Send(gruid.MsgKeyDown{Key: gruid.KeyEscape})
+	if err := <-done; err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if !dr.Closed() || dr.Frames() == 0 {
+		t.Errorf("bad driver state: %v %d", dr.Closed(), dr.Frames())
+	}
+}
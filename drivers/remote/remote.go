@@ -0,0 +1,380 @@
+// Package remote provides a gruid driver that runs the application on a
+// server, and displays it in a browser: frames are sent over a WebSocket
+// connection to a small JavaScript client rendering them into a canvas, and
+// the client sends back key and mouse input.
+//
+// This allows playing in a browser without compiling the application to
+// WebAssembly. Only one client is connected at a time: a new connection
+// replaces the previous one, and receives the whole current screen.
+//
+// The driver is an http.Handler serving the client page for any path, and the
+// WebSocket endpoint at the “ws” path relative to the page. It can be used in
+// an existing server, or serve on its own with the Addr configuration field.
+package remote
+
+import (
+	"context"
+	_ "embed" // client page
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/colors"
+)
+
+//go:embed client.html
+var clientPage []byte
+
+// Resize describes how the client adapts to the size of the browser window.
+type Resize int
+
+// These constants represent the available client resize behaviors.
+const (
+	// ResizeNone keeps a canvas with one pixel per tile pixel, whatever
+	// the window size.
+	ResizeNone Resize = iota
+
+	// ResizeScale scales the canvas so that it fits the window,
+	// preserving its aspect ratio, with empty borders in the direction
+	// that does not fill the window (letterboxing).
+	ResizeScale
+
+	// ResizeReflow changes the size of the screen grid so that it fills
+	// the window. The driver reports a gruid.MsgScreen with the new size
+	// in cells when it changes, and the application is expected to resize
+	// its grid accordingly.
+	ResizeReflow
+)
+
+// ColorManager maps gruid colors to CSS colors.
+type ColorManager interface {
+	// CSS returns the CSS color to be used for a gruid color, either as
+	// foreground or background color.
+	CSS(c gruid.Color, fg bool) string
+}
+
+// Config contains configurations options for the driver.
+type Config struct {
+	// Addr is an optional TCP address, such as ":8080", on which the
+	// driver serves the client page and WebSocket endpoint after Init. If
+	// empty, the driver should be registered as a handler in some
+	// http.Server.
+	Addr string
+
+	Width  int    // initial screen width in cells (default: 80)
+	Height int    // initial screen height in cells (default: 24)
+	Font   string // CSS font for the client canvas (default: "18px monospace")
+
+	// ColorManager maps colors to CSS colors. If nil, colors are
+	// interpreted as RGB or xterm 256-color palette colors, as defined by
+	// the colors package, and gruid.ColorDefault maps to light gray on
+	// black.
+	ColorManager ColorManager
+
+	// Resize is the client behavior when the window size changes
+	// (default: ResizeNone).
+	Resize Resize
+
+	// CheckOrigin optionally reports whether a WebSocket connection
+	// request should be accepted, depending on its Origin header. If nil,
+	// only requests without Origin header, or whose origin matches the
+	// request Host, are accepted, so that other web pages visited by the
+	// player cannot take over the session.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Driver implements gruid.Driver, and http.Handler for the client page and
+// WebSocket connection.
+type Driver struct {
+	addr   string
+	font   string
+	cm     ColorManager
+	resize Resize
+	origin func(*http.Request) bool
+	mu     sync.Mutex
+	grid   gruid.Grid // current screen contents
+	ws     *wsConn    // connected client, if any
+	srv    *http.Server
+	inputs chan gruid.Msg
+}
+
+// NewDriver returns a new driver with given configuration options.
+func NewDriver(cfg Config) *Driver {
+	w, h := cfg.Width, cfg.Height
+	if w <= 0 {
+		w = 80
+	}
+	if h <= 0 {
+		h = 24
+	}
+	dr := &Driver{
+		addr:   cfg.Addr,
+		font:   cfg.Font,
+		cm:     cfg.ColorManager,
+		resize: cfg.Resize,
+		origin: cfg.CheckOrigin,
+		grid:   gruid.NewGrid(w, h),
+		inputs: make(chan gruid.Msg, 64),
+	}
+	if dr.font == "" {
+		dr.font = "18px monospace"
+	}
+	if dr.cm == nil {
+		dr.cm = xtermColors{}
+	}
+	if dr.origin == nil {
+		dr.origin = sameOrigin
+	}
+	return dr
+}
+
+// Capabilities implements gruid.DriverInfo.
+func (dr *Driver) Capabilities() gruid.DriverCapabilities {
+	return gruid.DriverCapabilities{Colors: 256, Mouse: true}
+}
+
+// Init implements gruid.Driver.Init. It starts serving on the configured
+// address, if any.
+func (dr *Driver) Init() error {
+	if dr.addr == "" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", dr.addr)
+	if err != nil {
+		return fmt.Errorf("remote: %v", err)
+	}
+	dr.srv = &http.Server{Handler: dr}
+	go dr.srv.Serve(ln)
+	return nil
+}
+
+// PollMsgs implements gruid.Driver.PollMsgs. It reports input messages from
+// the connected client.
+func (dr *Driver) PollMsgs(ctx context.Context, msgs chan<- gruid.Msg) error {
+	for {
+		select {
+		case msg := <-dr.inputs:
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Flush implements gruid.Driver.Flush. Frames are sent to the connected
+// client, if any.
+func (dr *Driver) Flush(frame gruid.Frame) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if max := dr.grid.Size(); frame.Width != max.X || frame.Height != max.Y {
+		dr.grid = dr.grid.Resize(frame.Width, frame.Height)
+	}
+	for _, fc := range frame.Cells {
+		dr.grid.Set(fc.P, fc.Cell)
+	}
+	if dr.ws == nil {
+		return
+	}
+	dr.send(frame)
+}
+
+// send sends a frame to the connected client. It drops the client on error.
+func (dr *Driver) send(frame gruid.Frame) {
+	msg := wireFrame{Type: "frame", Width: frame.Width, Height: frame.Height, Cells: make([][]interface{}, 0, len(frame.Cells))}
+	for _, fc := range frame.Cells {
+		c := fc.Cell
+		fg, bg := c.Style.Fg, c.Style.Bg
+		wide := 0
+		if c.Wide() {
+			wide = 1
+		}
+		attrs := c.Style.Attrs & stdAttrs
+		msg.Cells = append(msg.Cells, []interface{}{fc.P.X, fc.P.Y, c.Content(), dr.cm.CSS(fg, true), dr.cm.CSS(bg, false), wide, attrs})
+	}
+	bs, err := json.Marshal(msg)
+	if err == nil {
+		err = dr.ws.WriteMessage(bs)
+	}
+	if err != nil {
+		dr.ws.Close()
+		dr.ws = nil
+	}
+}
+
+// Close implements gruid.Driver.Close. It closes the client connection and
+// the server started by Init, if any.
+func (dr *Driver) Close() {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if dr.ws != nil {
+		dr.ws.writeFrame(wsClose, nil)
+		dr.ws.Close()
+		dr.ws = nil
+	}
+	if dr.srv != nil {
+		dr.srv.Close()
+		dr.srv = nil
+	}
+}
+
+// ServeHTTP implements http.Handler. It serves the WebSocket endpoint for
+// paths ending in “/ws”, and the client page otherwise.
+func (dr *Driver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/ws") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(clientPage)
+		return
+	}
+	ws, err := wsUpgrade(w, r, dr.origin)
+	if err != nil {
+		return
+	}
+	dr.connect(ws)
+	defer dr.disconnect(ws)
+	for {
+		bs, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := parseInput(bs)
+		if msg == nil {
+			continue
+		}
+		if _, ok := msg.(gruid.MsgScreen); ok && dr.resize != ResizeReflow {
+			continue
+		}
+		select {
+		case dr.inputs <- msg:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// connect replaces the connected client, and sends it the initialization
+// message and the whole screen.
+func (dr *Driver) connect(ws *wsConn) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if dr.ws != nil {
+		dr.ws.writeFrame(wsClose, nil)
+		dr.ws.Close()
+	}
+	dr.ws = ws
+	bs, _ := json.Marshal(wireInit{Type: "init", Font: dr.font, Resize: int(dr.resize)})
+	if err := ws.WriteMessage(bs); err != nil {
+		ws.Close()
+		dr.ws = nil
+		return
+	}
+	max := dr.grid.Size()
+	frame := gruid.Frame{Width: max.X, Height: max.Y}
+	wide := false
+	dr.grid.Iter(func(p gruid.Point, c gruid.Cell) {
+		if wide && p.X > 0 {
+			// continuation of a wide cell
+			wide = false
+			return
+		}
+		wide = c.Wide()
+		frame.Cells = append(frame.Cells, gruid.FrameCell{Cell: c, P: p})
+	})
+	dr.send(frame)
+}
+
+func (dr *Driver) disconnect(ws *wsConn) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	ws.Close()
+	if dr.ws == ws {
+		dr.ws = nil
+	}
+}
+
+// wireInit is the first message sent to a client.
+type wireInit struct {
+	Type   string `json:"t"`
+	Font   string `json:"font"`
+	Resize int    `json:"resize"`
+}
+
+// stdAttrs are the standard attributes honored by the client.
+const stdAttrs = gruid.AttrBold | gruid.AttrItalic | gruid.AttrUnderline | gruid.AttrReverse | gruid.AttrStrikethrough
+
+// wireFrame is a frame message sent to a client. Cells are encoded as arrays
+// [x, y, content, fg, bg, wide, attrs], where attrs are the standard
+// attributes of the cell.
+type wireFrame struct {
+	Type   string          `json:"t"`
+	Width  int             `json:"w"`
+	Height int             `json:"h"`
+	Cells  [][]interface{} `json:"cells"`
+}
+
+// wireInput is an input message sent by a client.
+type wireInput struct {
+	Type   string  `json:"t"` // "key", "mouse" or "screen"
+	Key    string  `json:"key"`
+	Action int     `json:"a"`
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+	Mod    int     `json:"mod"`
+	TW     int     `json:"tw"`  // tile width (screen)
+	TH     int     `json:"th"`  // tile height (screen)
+	Ratio  float64 `json:"dpr"` // device pixel ratio (screen)
+}
+
+// maxScreen is the maximum screen size in cells accepted from a client.
+const maxScreen = 1000
+
+// parseInput returns the message corresponding to a client input, or nil if
+// it is invalid.
+func parseInput(bs []byte) gruid.Msg {
+	var in wireInput
+	if err := json.Unmarshal(bs, &in); err != nil {
+		return nil
+	}
+	mod := gruid.ModMask(in.Mod)
+	switch in.Type {
+	case "key":
+		if in.Key == "" {
+			return nil
+		}
+		return gruid.MsgKeyDown{Key: gruid.Key(in.Key), Mod: mod, Time: time.Now()}
+	case "mouse":
+		if in.Action < int(gruid.MouseMain) || in.Action > int(gruid.MouseMove) {
+			return nil
+		}
+		return gruid.MsgMouse{Action: gruid.MouseAction(in.Action), P: gruid.Point{X: in.X, Y: in.Y}, Mod: mod, Time: time.Now()}
+	case "screen":
+		if in.X <= 0 || in.Y <= 0 || in.X > maxScreen || in.Y > maxScreen {
+			return nil
+		}
+		return gruid.MsgScreen{Width: in.X, Height: in.Y, Time: time.Now(),
+			TileWidth: in.TW, TileHeight: in.TH, PixelRatio: in.Ratio}
+	}
+	return nil
+}
+
+// xtermColors is the default color manager.
+type xtermColors struct{}
+
+func (xtermColors) CSS(c gruid.Color, fg bool) string {
+	rgb, ok := colors.ToRGBA(c)
+	if !ok {
+		if fg {
+			return "#d0d0d0"
+		}
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
+}
@@ -0,0 +1,223 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/colors"
+)
+
+// testClient is a minimal WebSocket client.
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// handshake sends a WebSocket opening handshake request, with an optional
+// Origin header.
+func handshake(t *testing.T, url, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := "GET /game/ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		req += "Origin: " + origin + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+func dial(t *testing.T, url string) *testClient {
+	conn, br, resp := handshake(t, url, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("bad status: %v", resp.Status)
+	}
+	if acc := resp.Header.Get("Sec-WebSocket-Accept"); acc != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("bad accept key: %q", acc)
+	}
+	return &testClient{conn: conn, br: br}
+}
+
+func (tc *testClient) write(t *testing.T, msg string) {
+	mask := [4]byte{1, 2, 3, 4}
+	buf := []byte{0x81, 0x80 | byte(len(msg))}
+	buf = append(buf, mask[:]...)
+	for i := 0; i < len(msg); i++ {
+		buf = append(buf, msg[i]^mask[i%4])
+	}
+	if _, err := tc.conn.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (tc *testClient) read(t *testing.T) map[string]interface{} {
+	tc.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var hd [2]byte
+	if _, err := io.ReadFull(tc.br, hd[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(hd[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(tc.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(tc.br, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(tc.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	msg := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("bad message %q: %v", payload, err)
+	}
+	return msg
+}
+
+func TestDriver(t *testing.T) {
+	dr := NewDriver(Config{Width: 20, Height: 10})
+	srv := httptest.NewServer(dr)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/game/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), "WebSocket") {
+		t.Errorf("bad client page")
+	}
+	tc := dial(t, srv.URL)
+	defer tc.conn.Close()
+	if msg := tc.read(t); msg["t"] != "init" {
+		t.Errorf("bad init message: %v", msg)
+	}
+	msg := tc.read(t)
+	if msg["t"] != "frame" || msg["w"] != 20.0 || len(msg["cells"].([]interface{})) != 20*10 {
+		t.Errorf("bad initial frame: %v %v", msg["t"], msg["w"])
+	}
+	dr.Flush(gruid.Frame{Width: 20, Height: 10, Cells: []gruid.FrameCell{
+		{Cell: gruid.Cell{Rune: '@', Style: gruid.Style{Fg: 2, Attrs: gruid.AttrBold | 1<<10}}, P: gruid.Point{3, 4}},
+	}})
+	msg = tc.read(t)
+	cells := msg["cells"].([]interface{})
+	if len(cells) != 1 {
+		t.Fatalf("bad frame: %v", msg)
+	}
+	cell := cells[0].([]interface{})
+	if cell[0] != 3.0 || cell[1] != 4.0 || cell[2] != "@" || cell[3] != "#cd0000" || cell[6] != float64(gruid.AttrBold) {
+		t.Errorf("bad frame cell: %v", cell)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msgs := make(chan gruid.Msg)
+	go dr.PollMsgs(ctx, msgs)
+	tc.write(t, `{"t":"key","key":"ArrowUp","mod":2}`)
+	tc.write(t, `{"t":"mouse","a":5,"x":2,"y":1}`)
+	select {
+	case m := <-msgs:
+		if m, ok := m.(gruid.MsgKeyDown); !ok || m.Key != gruid.KeyArrowUp || m.Mod != gruid.ModCtrl {
+			t.Errorf("bad key message: %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("no key message")
+	}
+	select {
+	case m := <-msgs:
+		if m, ok := m.(gruid.MsgMouse); !ok || m.Action != gruid.MouseRelease || m.P != (gruid.Point{2, 1}) {
+			t.Errorf("bad mouse message: %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("no mouse message")
+	}
+	dr.Close()
+}
+
+func TestResizeReflow(t *testing.T) {
+	dr := NewDriver(Config{Width: 20, Height: 10, Resize: ResizeReflow})
+	srv := httptest.NewServer(dr)
+	defer srv.Close()
+	tc := dial(t, srv.URL)
+	defer tc.conn.Close()
+	if msg := tc.read(t); msg["t"] != "init" || msg["resize"] != float64(ResizeReflow) {
+		t.Errorf("bad init message: %v", msg)
+	}
+	tc.read(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msgs := make(chan gruid.Msg)
+	go dr.PollMsgs(ctx, msgs)
+	tc.write(t, `{"t":"screen","x":0,"y":3}`)
+	tc.write(t, `{"t":"screen","x":50,"y":30,"tw":10,"th":20,"dpr":2}`)
+	select {
+	case m := <-msgs:
+		if m, ok := m.(gruid.MsgScreen); !ok || m.Width != 50 || m.Height != 30 || m.TileWidth != 10 || m.PixelRatio != 2 {
+			t.Errorf("bad screen message: %v", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("no screen message")
+	}
+	dr.Close()
+}
+
+func TestCheckOrigin(t *testing.T) {
+	dr := NewDriver(Config{Width: 20, Height: 10})
+	srv := httptest.NewServer(dr)
+	defer srv.Close()
+	conn, _, resp := handshake(t, srv.URL, "http://evil.example")
+	conn.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign origin not refused: %v", resp.Status)
+	}
+	conn, _, resp = handshake(t, srv.URL, "http://localhost")
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("same origin refused: %v", resp.Status)
+	}
+	dr = NewDriver(Config{CheckOrigin: func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "http://evil.example"
+	}})
+	srv2 := httptest.NewServer(dr)
+	defer srv2.Close()
+	conn, _, resp = handshake(t, srv2.URL, "http://evil.example")
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("allowed origin refused: %v", resp.Status)
+	}
+}
+
+func TestColors(t *testing.T) {
+	cm := xtermColors{}
+	if s := cm.CSS(colors.RGB(0x12, 0x34, 0xab), true); s != "#1234ab" {
+		t.Errorf("bad RGB color: %s", s)
+	}
+	if s := cm.CSS(colors.Xterm(196), false); s != "#ff0000" {
+		t.Errorf("bad xterm color: %s", s)
+	}
+	if s := cm.CSS(gruid.ColorDefault, true); s != "#d0d0d0" {
+		t.Errorf("bad default color: %s", s)
+	}
+}
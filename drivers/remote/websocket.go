@@ -0,0 +1,223 @@
+// This file implements the minimal subset of the WebSocket protocol (RFC
+// 6455) needed by the driver: server handshake, and text messages.
+
+package remote
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsMaxMessage is the maximum size of a message read from a client. Input
+// messages are small.
+const wsMaxMessage = 1 << 16
+
+// wsWriteTimeout is the maximum duration of a write, so that a slow or
+// unresponsive client does not block the application.
+const wsWriteTimeout = 5 * time.Second
+
+// wsConn represents a server-side WebSocket connection.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	mu   sync.Mutex // protects writes
+}
+
+// wsAccept returns the Sec-WebSocket-Accept header value for a given key.
+func wsAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether the Origin header of a request, if any, matches
+// the request Host. Browsers always send this header for WebSocket
+// connections, so this prevents other web pages from connecting to the
+// driver.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// wsUpgrade performs the server side of the WebSocket opening handshake. The
+// checkOrigin function reports whether the request origin is allowed.
+func wsUpgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(*http.Request) bool) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket connection expected", http.StatusBadRequest)
+		return nil, errors.New("websocket: bad handshake")
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, errors.New("websocket: origin not allowed")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: connection cannot be hijacked")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	_, err = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n\r\n")
+	if err == nil {
+		err = brw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: brw.Reader}, nil
+}
+
+// ReadMessage reads the next text message, answering pings in the process.
+// It returns io.EOF when the client closes the connection.
+func (ws *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case wsClose:
+			ws.writeFrame(wsClose, nil)
+			return nil, io.EOF
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		}
+		msg = append(msg, payload...)
+		if len(msg) > wsMaxMessage {
+			return nil, errors.New("websocket: message too long")
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+func (ws *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var hd [2]byte
+	if _, err = io.ReadFull(ws.br, hd[:]); err != nil {
+		return
+	}
+	fin = hd[0]&0x80 != 0
+	op = hd[0] & 0x0f
+	masked := hd[1]&0x80 != 0
+	n := uint64(hd[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessage {
+		err = errors.New("websocket: frame too long")
+		return
+	}
+	if !masked {
+		err = errors.New("websocket: unmasked client frame")
+		return
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// WriteMessage writes a text message.
+func (ws *wsConn) WriteMessage(msg []byte) error {
+	return ws.writeFrame(wsText, msg)
+}
+
+func (ws *wsConn) writeFrame(op byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	buf := make([]byte, 10, 10+len(payload))
+	buf[0] = 0x80 | op
+	n := len(payload)
+	switch {
+	case n < 126:
+		buf[1] = byte(n)
+		buf = buf[:2]
+	case n <= 0xffff:
+		buf[1] = 126
+		binary.BigEndian.PutUint16(buf[2:], uint16(n))
+		buf = buf[:4]
+	default:
+		buf[1] = 127
+		binary.BigEndian.PutUint64(buf[2:], uint64(n))
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err := ws.conn.Write(append(buf, payload...))
+	return err
+}
+
+// Close closes the underlying connection.
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
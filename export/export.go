@@ -0,0 +1,234 @@
+// Package export converts frame recordings, as produced by an application
+// with an AppConfig.FrameWriter, into formats that can be shared outside the
+// application: asciinema casts, for terminal-styled output, and animated GIF
+// images, for tile-based output.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/drivers/ansi"
+	"github.com/anaseto/gruid/tiles"
+)
+
+// CastConfig contains configuration options for Cast.
+type CastConfig struct {
+	// StyleManager maps styles to SGR parameters, as in the ansi
+	// driver. If nil, colors are interpreted as 256 colors palette
+	// indices plus one.
+	StyleManager ansi.StyleManager
+
+	Title string // optional title of the cast
+
+	// MaxIdle is an optional limit for the time between two frames when
+	// playing the cast.
+	MaxIdle time.Duration
+}
+
+// castHeader is the first line of an asciinema v2 cast file.
+type castHeader struct {
+	Version   int     `json:"version"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+	Timestamp int64   `json:"timestamp,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	IdleLimit float64 `json:"idle_time_limit,omitempty"`
+}
+
+// Cast writes to w an asciinema v2 cast file for the frames decoded by dec.
+// Frames are rendered as ANSI escape sequences, and changes in frame size are
+// recorded as resize events.
+func Cast(dec *gruid.FrameDecoder, w io.Writer, cfg CastConfig) error {
+	var frame gruid.Frame
+	err := dec.Decode(&frame)
+	if err == io.EOF {
+		return errors.New("cast export: no frames")
+	}
+	if err != nil {
+		return fmt.Errorf("cast export: %v", err)
+	}
+	hdr := castHeader{Version: 2, Width: frame.Width, Height: frame.Height, Title: cfg.Title}
+	if dh := dec.Header(); dh.Name != "" && hdr.Title == "" {
+		hdr.Title = dh.Name
+	}
+	if !frame.Time.IsZero() {
+		hdr.Timestamp = frame.Time.Unix()
+	}
+	if cfg.MaxIdle > 0 {
+		hdr.IdleLimit = cfg.MaxIdle.Seconds()
+	}
+	enc := json.NewEncoder(w)
+	err = enc.Encode(hdr)
+	if err != nil {
+		return fmt.Errorf("cast export: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	newDriver := func(w, h int) *ansi.Driver {
+		return ansi.NewDriver(ansi.Config{Output: buf, Width: w, Height: h, StyleManager: cfg.StyleManager})
+	}
+	dr := newDriver(frame.Width, frame.Height)
+	dr.Init()
+	t0 := frame.Time
+	size := gruid.Point{frame.Width, frame.Height}
+	for {
+		t := frame.Time.Sub(t0).Seconds()
+		if fs := (gruid.Point{frame.Width, frame.Height}); fs != size {
+			size = fs
+			err = enc.Encode([]interface{}{t, "r", fmt.Sprintf("%dx%d", fs.X, fs.Y)})
+			if err != nil {
+				return fmt.Errorf("cast export: %v", err)
+			}
+			dr = newDriver(fs.X, fs.Y)
+			dr.Init()
+		}
+		dr.Flush(frame)
+		if buf.Len() > 0 {
+			err = enc.Encode([]interface{}{t, "o", buf.String()})
+			if err != nil {
+				return fmt.Errorf("cast export: %v", err)
+			}
+			buf.Reset()
+		}
+		err = dec.Decode(&frame)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cast export: %v", err)
+		}
+	}
+}
+
+// TileManager manages the tiles used for GIF export. It is the interface
+// implemented by tiles.Manager, and by tile managers of tile-based drivers.
+type TileManager interface {
+	// GetImage returns the image to be used for a given cell style and
+	// content.
+	GetImage(gruid.Cell) image.Image
+
+	// TileSize returns the (width, height) in pixels of the tiles.
+	TileSize() gruid.Point
+}
+
+// GIFConfig contains configuration options for GIF.
+type GIFConfig struct {
+	TileManager TileManager // tile manager (required)
+
+	// MaxIdle is an optional limit for the time between two frames.
+	MaxIdle time.Duration
+
+	// LastDelay is the time during which the last frame is shown before
+	// looping (default: 1s).
+	LastDelay time.Duration
+}
+
+// GIF writes to w an animated GIF image for the frames decoded by dec. The
+// image has the size of the first frame: cells outside of it in later frames
+// are ignored.
+//
+// Each image frame uses an exact palette when it contains no more than 256
+// colors, and the Plan 9 palette otherwise. Only the changed area of each
+// frame is encoded, but the whole animation is kept in memory until written,
+// so long recordings may be better exported in parts, for example using
+// FrameDecoder.SeekFrame.
+func GIF(dec *gruid.FrameDecoder, w io.Writer, cfg GIFConfig) error {
+	if cfg.TileManager == nil {
+		return errors.New("gif export: no tile manager")
+	}
+	if cfg.LastDelay <= 0 {
+		cfg.LastDelay = time.Second
+	}
+	var frame gruid.Frame
+	err := dec.Decode(&frame)
+	if err == io.EOF {
+		return errors.New("gif export: no frames")
+	}
+	if err != nil {
+		return fmt.Errorf("gif export: %v", err)
+	}
+	buf := tiles.NewBuffer(gruid.Point{frame.Width, frame.Height}, cfg.TileManager.TileSize())
+	bounds := buf.Image().Bounds()
+	if bounds.Empty() {
+		return errors.New("gif export: empty first frame")
+	}
+	anim := &gif.GIF{Config: image.Config{Width: bounds.Dx(), Height: bounds.Dy()}}
+	var last time.Time // time of the last image frame
+	for {
+		buf.DrawFrame(frame, cfg.TileManager.GetImage)
+		r := buf.Present()
+		if !r.Empty() {
+			if n := len(anim.Delay); n > 0 {
+				anim.Delay[n-1] = gifDelay(frame.Time.Sub(last), cfg.MaxIdle)
+			}
+			anim.Image = append(anim.Image, paletted(buf.Image(), r))
+			anim.Delay = append(anim.Delay, gifDelay(cfg.LastDelay, 0))
+			anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+			last = frame.Time
+		}
+		err = dec.Decode(&frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("gif export: %v", err)
+		}
+	}
+	err = gif.EncodeAll(w, anim)
+	if err != nil {
+		return fmt.Errorf("gif export: %v", err)
+	}
+	return nil
+}
+
+// gifDelay returns a GIF frame delay, in hundredths of a second, for a given
+// duration, limited by max if positive.
+func gifDelay(d, max time.Duration) int {
+	if max > 0 && d > max {
+		d = max
+	}
+	delay := int(d / (10 * time.Millisecond))
+	if delay < 2 {
+		// smaller delays are not honored by most viewers
+		delay = 2
+	}
+	return delay
+}
+
+// paletted returns a paletted copy of the given area of an image.
+func paletted(img *image.RGBA, r image.Rectangle) *image.Paletted {
+	pimg := image.NewPaletted(r, exactPalette(img, r))
+	draw.Draw(pimg, r, img, r.Min, draw.Src)
+	return pimg
+}
+
+// exactPalette returns the palette of the colors used in the given area of an
+// image, or the Plan 9 palette if there are more than 256 colors.
+func exactPalette(img *image.RGBA, r image.Rectangle) color.Palette {
+	var pal color.Palette
+	seen := map[color.RGBA]bool{}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if seen[c] {
+				continue
+			}
+			if len(pal) == 256 {
+				return palette.Plan9
+			}
+			seen[c] = true
+			pal = append(pal, c)
+		}
+	}
+	return pal
+}
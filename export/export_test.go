@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/drivers/headless"
+)
+
+type model struct {
+	gd gruid.Grid
+	p  gruid.Point
+}
+
+func (m *model) Update(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		if msg.Key == gruid.KeyEscape {
+			return gruid.End()
+		}
+		m.p = m.p.Shift(1, 0)
+	}
+	return nil
+}
+
+func (m *model) Draw() gruid.Grid {
+	m.gd.Fill(gruid.Cell{Rune: '.'})
+	m.gd.Set(m.p, gruid.Cell{Rune: '@', Style: gruid.Style{Fg: 2}})
+	return m.gd
+}
+
+// record returns a frame recording of a short session.
+func record(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	dr := headless.NewDriver(headless.Config{Width: 6, Height: 2, Script: []headless.Step{
+		{Msg: gruid.MsgKeyDown{Key: "l"}, Delay: 20 * time.Millisecond},
+		{Msg: gruid.MsgKeyDown{Key: "l"}, Delay: 20 * time.Millisecond},
+		{Msg: gruid.MsgKeyDown{Key: gruid.KeyEscape}},
+	}})
+	app := gruid.NewApp(gruid.AppConfig{
+		Driver:      dr,
+		Model:       &model{gd: gruid.NewGrid(6, 2)},
+		FrameWriter: buf,
+		FrameHeader: gruid.FrameHeader{Name: "test"},
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCast(t *testing.T) {
+	dec, err := gruid.NewFrameDecoder(bytes.NewReader(record(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := Cast(dec, out, CastConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	sc := bufio.NewScanner(out)
+	sc.Scan()
+	hdr := castHeader{}
+	if err := json.Unmarshal(sc.Bytes(), &hdr); err != nil {
+		t.Fatalf("bad header: %v", err)
+	}
+	if hdr.Version != 2 || hdr.Width != 6 || hdr.Height != 2 || hdr.Title != "test" {
+		t.Errorf("bad header: %+v", hdr)
+	}
+	events := 0
+	last := -1.0
+	for sc.Scan() {
+		var ev []interface{}
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil || len(ev) != 3 {
+			t.Fatalf("bad event %q: %v", sc.Text(), err)
+		}
+		if ev[0].(float64) < last || ev[1] != "o" {
+			t.Errorf("bad event: %v", ev)
+		}
+		last = ev[0].(float64)
+		if events == 0 && !strings.Contains(ev[2].(string), "\x1b[0;38;5;1m@") {
+			t.Errorf("bad first event: %q", ev[2])
+		}
+		events++
+	}
+	if events != 3 {
+		t.Errorf("bad event count: %d", events)
+	}
+}
+
+type tileManager struct{}
+
+func (tileManager) GetImage(c gruid.Cell) image.Image {
+	col := color.RGBA{0, 0, 0, 255}
+	if c.Rune == '@' {
+		col = color.RGBA{255, 0, 0, 255}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 8))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: col}, image.Point{}, draw.Src)
+	return img
+}
+
+func (tileManager) TileSize() gruid.Point {
+	return gruid.Point{4, 8}
+}
+
+func TestGIF(t *testing.T) {
+	dec, err := gruid.NewFrameDecoder(bytes.NewReader(record(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := GIF(dec, out, GIFConfig{}); err == nil {
+		t.Errorf("no error without tile manager")
+	}
+	if err := GIF(dec, out, GIFConfig{TileManager: tileManager{}}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anim.Config.Width != 24 || anim.Config.Height != 16 || len(anim.Image) != 3 {
+		t.Errorf("bad animation: %dx%d, %d images", anim.Config.Width, anim.Config.Height, len(anim.Image))
+	}
+	if r := anim.Image[1].Bounds(); r != image.Rect(0, 0, 8, 8) {
+		t.Errorf("bad changed area: %v", r)
+	}
+	if anim.Delay[0] < 2 || anim.Delay[2] != 100 {
+		t.Errorf("bad delays: %v", anim.Delay)
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/anaseto/gruid
 
-go 1.17
+go 1.18
 
 require golang.org/x/image v0.0.0-20201208152932-35266b937fa6
@@ -0,0 +1,104 @@
+package gruid
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// Runes in the Supplementary Private Use Area-B are used to represent
+// registered grapheme clusters.
+const (
+	graphemeMin rune = 0x100000
+	graphemeMax rune = 0x10FFFD
+)
+
+var graphemes struct {
+	mu    sync.RWMutex
+	runes map[string]rune
+	strs  []string
+}
+
+// Grapheme returns a rune representing the given grapheme cluster, such as a
+// letter followed by combining diacritics, or an emoji ZWJ sequence. The rune
+// can then be used as the content of a Cell, and drivers retrieve the cluster
+// with RuneContent. It is assumed that the cluster is displayed in a single
+// cell.
+//
+// The first call for a given cluster registers it in a package-level table,
+// and next calls return the same rune. If the string is a single rune, it is
+// returned unchanged. Runes from the Supplementary Private Use Area-B
+// (U+100000 to U+10FFFD) are used for multi-rune clusters, so they should not
+// be used otherwise as cell content. It panics if the table is full, or if
+// the string is empty.
+func Grapheme(s string) rune {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == len(s) {
+		if size == 0 {
+			panic("empty grapheme cluster")
+		}
+		return r
+	}
+	graphemes.mu.RLock()
+	r, ok := graphemes.runes[s]
+	graphemes.mu.RUnlock()
+	if ok {
+		return r
+	}
+	graphemes.mu.Lock()
+	defer graphemes.mu.Unlock()
+	if r, ok := graphemes.runes[s]; ok {
+		return r
+	}
+	r = graphemeMin + rune(len(graphemes.strs))
+	if r > graphemeMax {
+		panic(fmt.Sprintf("too many grapheme clusters: %q", s))
+	}
+	if graphemes.runes == nil {
+		graphemes.runes = map[string]rune{}
+	}
+	graphemes.runes[s] = r
+	graphemes.strs = append(graphemes.strs, s)
+	return r
+}
+
+// RuneContent returns the text represented by a cell rune: the registered
+// grapheme cluster for runes returned by Grapheme, or the rune itself
+// otherwise.
+func RuneContent(r rune) string {
+	if r < graphemeMin {
+		return string(r)
+	}
+	s, ok := graphemeString(r)
+	if !ok {
+		return string(r)
+	}
+	return s
+}
+
+// graphemeString returns the grapheme cluster registered for a rune, if any.
+func graphemeString(r rune) (string, bool) {
+	if r < graphemeMin || r > graphemeMax {
+		return "", false
+	}
+	i := int(r - graphemeMin)
+	graphemes.mu.RLock()
+	defer graphemes.mu.RUnlock()
+	if i >= len(graphemes.strs) {
+		return "", false
+	}
+	return graphemes.strs[i], true
+}
+
+// WithGrapheme returns a derived Cell whose content is the given grapheme
+// cluster. See Grapheme.
+func (c Cell) WithGrapheme(s string) Cell {
+	c.Rune = Grapheme(s)
+	return c
+}
+
+// Content returns the text content of the cell. It is the same as
+// string(c.Rune), except for grapheme clusters registered with Grapheme.
+func (c Cell) Content() string {
+	return RuneContent(c.Rune)
+}
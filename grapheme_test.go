@@ -0,0 +1,76 @@
+package gruid
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGrapheme(t *testing.T) {
+	if r := Grapheme("a"); r != 'a' {
+		t.Errorf("bad single rune grapheme: %c", r)
+	}
+	r := Grapheme("e\u0301")
+	if r < graphemeMin || r > graphemeMax {
+		t.Errorf("bad grapheme rune: %U", r)
+	}
+	if Grapheme("e\u0301") != r {
+		t.Errorf("grapheme not reused")
+	}
+	if Grapheme("\U0001F469\u200D\U0001F680") == r {
+		t.Errorf("grapheme reused for distinct cluster")
+	}
+	c := Cell{}.WithGrapheme("e\u0301")
+	if c.Rune != r || c.Content() != "e\u0301" {
+		t.Errorf("bad cell content: %q", c.Content())
+	}
+	if s := RuneContent('x'); s != "x" {
+		t.Errorf("bad rune content: %q", s)
+	}
+	if s := RuneContent(graphemeMax); s != string(graphemeMax) {
+		t.Errorf("bad unregistered rune content: %q", s)
+	}
+}
+
+func TestGraphemeRecording(t *testing.T) {
+	framebuf := &bytes.Buffer{}
+	idxbuf := &bytes.Buffer{}
+	enc := newFrameEncoder(framebuf, idxbuf, FrameHeader{})
+	r := Grapheme("a\u0308")
+	const nframes = frameIndexInterval + 2
+	for i := 0; i < nframes; i++ {
+		fr := Frame{Width: 8, Height: 4}
+		fr.Cells = []FrameCell{{P: Point{i % 8, i % 4}, Cell: Cell{Rune: r}}}
+		if err := enc.encode(fr); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	enc.close()
+	dec, err := NewFrameDecoder(bytes.NewReader(framebuf.Bytes()))
+	if err != nil {
+		t.Fatalf("frame decoding %v", err)
+	}
+	if err := dec.LoadIndex(idxbuf); err != nil {
+		t.Fatalf("index decoding %v", err)
+	}
+	if _, err := dec.SeekFrame(frameIndexInterval + 1); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	frame := Frame{}
+	for dec.Decode(&frame) == nil {
+		if frame.Graphemes != nil {
+			t.Errorf("grapheme definitions not consumed")
+		}
+		if s := frame.Cells[0].Cell.Content(); s != "a\u0308" {
+			t.Errorf("bad decoded content: %q", s)
+		}
+	}
+	// recorded runes may differ from local ones
+	fr := Frame{
+		Cells:     []FrameCell{{Cell: Cell{Rune: graphemeMax}}},
+		Graphemes: map[rune]string{graphemeMax: "o\u0303"},
+	}
+	dec.mapGraphemes(&fr)
+	if s := fr.Cells[0].Cell.Content(); s != "o\u0303" {
+		t.Errorf("bad mapped content: %q", s)
+	}
+}
@@ -2,6 +2,8 @@ package gruid
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,6 +19,40 @@ type AttrMask uint32
 // AttrsDefault represents the default styling attributes.
 const AttrsDefault AttrMask = 0
 
+// These constants represent standard styling attributes, using the lower bits
+// of the mask, so that projects and drivers can share the same
+// representation. Drivers honor them by default when possible: for example,
+// the ansi driver maps them to the corresponding SGR attributes, and the tile
+// manager of the tiles package draws bold, underlined, reversed and
+// strikethrough cells. Custom attributes should use other bits.
+const (
+	AttrBold AttrMask = 1 << iota
+	AttrItalic
+	AttrUnderline
+	AttrReverse
+	AttrBlink
+	AttrStrikethrough
+)
+
+// AttrWide is a special attribute, reserved by gruid, that marks a cell as
+// double-width, such as a CJK character or a large tile. The next cell in the
+// same line of the whole grid is then a continuation of the wide cell: its
+// content is ignored and it is never sent to the driver, which is expected
+// to render the wide cell over two columns.
+//
+// Other attributes should not use the corresponding bit.
+const AttrWide AttrMask = 1 << 31
+
+// AttrRedraw is a special attribute, reserved by gruid, that marks a cell to
+// be sent to the driver in every frame, even if it did not change, and even
+// if it is out of the grid slice returned by Draw. It can be used for cells
+// whose rendering changes over time on the driver side, such as animated
+// tiles or emulated blinking, so that the driver gets a chance to update
+// them regularly.
+//
+// Other attributes should not use the corresponding bit.
+const AttrRedraw AttrMask = 1 << 30
+
 // Color is a generic value for representing colors. Those have to be mapped to
 // concrete foreground and background colors for each driver, as appropriate.
 type Color uint32
@@ -38,6 +74,11 @@ func (c Cell) WithRune(r rune) Cell {
 	return c
 }
 
+// Wide reports whether the cell is a double-width cell. See AttrWide.
+func (c Cell) Wide() bool {
+	return c.Style.Attrs&AttrWide != 0
+}
+
 // WithStyle returns a derived Cell with a new Style.
 func (c Cell) WithStyle(st Style) Cell {
 	c.Style = st
@@ -82,6 +123,57 @@ func (p Point) String() string {
 	return fmt.Sprintf("(%d,%d)", p.X, p.Y)
 }
 
+// Text returns a compact representation of the form "x,y", as used in JSON.
+func (p Point) Text() string {
+	return strconv.Itoa(p.X) + "," + strconv.Itoa(p.Y)
+}
+
+// MarshalJSON implements json.Marshaler. Points are represented as strings of
+// the form "x,y".
+//
+// Note that Point does not implement encoding.TextMarshaler, because it would
+// change the gob encoding of points, and break existing frame recordings.
+func (p Point) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(p.Text())), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts the same forms as
+// ParsePoint.
+func (p *Point) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid point %s: not a string", data)
+	}
+	q, err := ParsePoint(s)
+	if err != nil {
+		return err
+	}
+	*p = q
+	return nil
+}
+
+// ParsePoint parses a point from a string of the form "x,y" or "(x,y)", as
+// produced by Text and String respectively.
+func ParsePoint(s string) (Point, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	i := strings.IndexByte(s, ',')
+	if i < 0 {
+		return Point{}, fmt.Errorf("invalid point %q: missing comma", s)
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(s[:i]))
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid point %q: %v", s, err)
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(s[i+1:]))
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid point %q: %v", s, err)
+	}
+	return Point{X: x, Y: y}, nil
+}
+
 // Shift returns a new point with coordinates shifted by (x,y). It's a
 // shorthand for p.Add(Point{x,y}).
 func (p Point) Shift(x, y int) Point {
@@ -139,6 +231,67 @@ func (rg Range) String() string {
 	return fmt.Sprintf("%s-%s", rg.Min, rg.Max)
 }
 
+// Text returns a compact representation of the form "x0,y0-x1,y1", as used
+// in JSON.
+func (rg Range) Text() string {
+	return rg.Min.Text() + "-" + rg.Max.Text()
+}
+
+// MarshalJSON implements json.Marshaler. Ranges are represented as strings of
+// the form "x0,y0-x1,y1".
+func (rg Range) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(rg.Text())), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts the same forms as
+// ParseRange.
+func (rg *Range) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid range %s: not a string", data)
+	}
+	nrg, err := ParseRange(s)
+	if err != nil {
+		return err
+	}
+	*rg = nrg
+	return nil
+}
+
+// ParseRange parses a range from a string of the form "x0,y0-x1,y1" or
+// "(x0,y0)-(x1,y1)", as produced by Text and String respectively. The range
+// is not normalized.
+func ParseRange(s string) (Range, error) {
+	// The separator is the first dash following a digit or a closing
+	// parenthesis, as coordinates may be negative.
+	sep := -1
+	for i := 1; i < len(s) && sep < 0; i++ {
+		if s[i] != '-' {
+			continue
+		}
+		prev := strings.TrimRight(s[:i], " ")
+		if prev == "" {
+			continue
+		}
+		c := prev[len(prev)-1]
+		if c >= '0' && c <= '9' || c == ')' {
+			sep = i
+		}
+	}
+	if sep < 0 {
+		return Range{}, fmt.Errorf("invalid range %q: missing separator", s)
+	}
+	min, err := ParsePoint(s[:sep])
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range %q: %v", s, err)
+	}
+	max, err := ParsePoint(s[sep+1:])
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range %q: %v", s, err)
+	}
+	return Range{Min: min, Max: max}, nil
+}
+
 // Size returns the (width, height) of the range in cells.
 func (rg Range) Size() Point {
 	return rg.Max.Sub(rg.Min)
@@ -329,6 +482,25 @@ type Frame struct {
 	Cells  []FrameCell // cells that changed from previous frame
 	Width  int         // width of the whole grid when the frame was issued
 	Height int         // height of the whole grid when the frame was issued
+
+	// Graphemes maps grapheme cluster runes (see Grapheme) used in Cells
+	// to their content, for runes not yet defined previously in the same
+	// chunk of recorded frames. It is only used in frame recordings, and
+	// is handled transparently by FrameDecoder: drivers should use
+	// RuneContent instead.
+	Graphemes map[rune]string
+
+	// Inputs contains the input messages handled by the application since
+	// the previous frame. It is only used in frame recordings, when
+	// AppConfig.RecordInputs is true: drivers never receive inputs.
+	Inputs []FrameInput
+}
+
+// FrameInput represents a recorded input message. Exactly one of its fields
+// is non-nil.
+type FrameInput struct {
+	Key   *MsgKeyDown
+	Mouse *MsgMouse
 }
 
 // FrameCell represents a cell drawing instruction at a specific absolute
@@ -341,12 +513,21 @@ type FrameCell struct {
 // NewGrid returns a new grid with given width and height in cells. The width
 // and height should be positive or null. The new grid contains all positions
 // (X,Y) with 0 <= X < w and 0 <= Y < h. The grid is filled with Cell{Rune: ' '}.
+// It panics if the number of cells w*h overflows an int.
+//
+// Grids are not limited to screen sizes: large grids, such as 1024x1024 world
+// maps, can be used off-screen, and the visible part copied into the screen
+// grid with Copy. Note that the grid returned by Draw should not be a slice of
+// such a large grid, as frames cover the whole underlying grid.
 func NewGrid(w, h int) Grid {
 	gd := Grid{}
 	gd.Ug = &grid{}
 	if w < 0 || h < 0 {
 		panic(fmt.Sprintf("negative dimensions: NewGrid(%d,%d)", w, h))
 	}
+	if h > 0 && w > math.MaxInt/h {
+		panic(fmt.Sprintf("too large dimensions: NewGrid(%d,%d)", w, h))
+	}
 	gd.Rg.Max = Point{w, h}
 	gd.Ug.Width = w
 	gd.Ug.Height = h
@@ -564,6 +745,107 @@ func (gd Grid) Map(fn func(Point, Cell) Cell) {
 	}
 }
 
+// DrawLine draws a line segment between positions p and q (both included)
+// using the given cell. It uses Bresenham's line algorithm. Positions out of
+// the grid's range are ignored.
+func (gd Grid) DrawLine(p, q Point, c Cell) {
+	bresenham(p, q, func(r Point) {
+		gd.Set(r, c)
+	})
+}
+
+// DrawRect draws the outline of a rectangle corresponding to the given range,
+// relative to the grid, using the given cell. It does not draw anything in
+// the interior region.
+func (gd Grid) DrawRect(rg Range, c Cell) {
+	if rg.Empty() {
+		return
+	}
+	max := rg.Max.Shift(-1, -1)
+	for x := rg.Min.X; x <= max.X; x++ {
+		gd.Set(Point{X: x, Y: rg.Min.Y}, c)
+		gd.Set(Point{X: x, Y: max.Y}, c)
+	}
+	for y := rg.Min.Y + 1; y < max.Y; y++ {
+		gd.Set(Point{X: rg.Min.X, Y: y}, c)
+		gd.Set(Point{X: max.X, Y: y}, c)
+	}
+}
+
+// DrawCircle draws the outline of a circle with the given center and radius
+// using the given cell. It uses the midpoint circle algorithm. A null radius
+// draws only the center, and a negative one draws nothing.
+func (gd Grid) DrawCircle(center Point, radius int, c Cell) {
+	circle(center, radius, func(p Point) {
+		gd.Set(p, c)
+	})
+}
+
+// bresenham calls fn on the positions of the line segment between p and q,
+// in order, according to Bresenham's line algorithm.
+func bresenham(p, q Point, fn func(Point)) {
+	dx := absInt(q.X - p.X)
+	dy := -absInt(q.Y - p.Y)
+	sx, sy := 1, 1
+	if p.X > q.X {
+		sx = -1
+	}
+	if p.Y > q.Y {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		fn(p)
+		if p == q {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			p.Y += sy
+		}
+	}
+}
+
+// circle calls fn on the positions of the circle outline with given center
+// and radius, according to the midpoint circle algorithm. Some positions may
+// be visited more than once.
+func circle(center Point, radius int, fn func(Point)) {
+	if radius < 0 {
+		return
+	}
+	x, y := radius, 0
+	e := 1 - radius
+	for x >= y {
+		fn(center.Shift(x, y))
+		fn(center.Shift(y, x))
+		fn(center.Shift(-y, x))
+		fn(center.Shift(-x, y))
+		fn(center.Shift(-x, -y))
+		fn(center.Shift(-y, -x))
+		fn(center.Shift(y, -x))
+		fn(center.Shift(x, -y))
+		y++
+		if e < 0 {
+			e += 2*y + 1
+		} else {
+			x--
+			e += 2*(y-x) + 1
+		}
+	}
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 // Copy copies elements from a source grid src into the destination grid gd,
 // and returns the copied grid-slice size, which is the minimum of both grids
 // for each dimension. The result is independent of whether the two grids
@@ -592,7 +874,7 @@ func (gd Grid) cp(src Grid) Point {
 	wsrc := src.Ug.Width
 	max := gd.Range().Intersect(src.Range()).Size()
 	idxmin := gd.Rg.Min.Y*w + gd.Rg.Min.X
-	idxsrcmin := src.Rg.Min.Y*w + src.Rg.Min.X
+	idxsrcmin := src.Rg.Min.Y*wsrc + src.Rg.Min.X
 	idxmax := (gd.Rg.Min.Y + max.Y) * w
 	for idx, idxsrc := idxmin, idxsrcmin; idx < idxmax; idx, idxsrc = idx+w, idxsrc+wsrc {
 		copy(gd.Ug.Cells[idx:idx+max.X], src.Ug.Cells[idxsrc:idxsrc+max.X])
@@ -629,6 +911,44 @@ func (gd Grid) cprev(src Grid) Point {
 	return max
 }
 
+// CopyOver is like Copy, but it only copies the cells from src whose rune is
+// not the transparent one, or whose style is not the zero value. It can be used
+// to stamp prefabricated fragments, such as sprites or map overlays, over the
+// destination grid. Like Copy, it returns the size of the processed
+// grid-slice.
+func (gd Grid) CopyOver(src Grid, transparent rune) Point {
+	if gd.Ug == nil {
+		return Point{}
+	}
+	w := gd.Ug.Width
+	wsrc := src.Ug.Width
+	max := gd.Range().Intersect(src.Range()).Size()
+	cells := gd.Ug.Cells
+	srccells := src.Ug.Cells
+	cpcell := func(x, y int) {
+		c := srccells[(src.Rg.Min.Y+y)*wsrc+src.Rg.Min.X+x]
+		if c.Rune != transparent || c.Style != (Style{}) {
+			cells[(gd.Rg.Min.Y+y)*w+gd.Rg.Min.X+x] = c
+		}
+	}
+	if gd.Ug == src.Ug && (gd.Rg.Min.Y > src.Rg.Min.Y ||
+		gd.Rg.Min.Y == src.Rg.Min.Y && gd.Rg.Min.X > src.Rg.Min.X) {
+		// reverse order, in case of overlapping
+		for y := max.Y - 1; y >= 0; y-- {
+			for x := max.X - 1; x >= 0; x-- {
+				cpcell(x, y)
+			}
+		}
+		return max
+	}
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			cpcell(x, y)
+		}
+	}
+	return max
+}
+
 // GridIterator represents a stateful iterator for a grid. They are created
 // with the Iterator method.
 type GridIterator struct {
@@ -714,7 +1034,7 @@ func (it *GridIterator) SetCell(c Cell) {
 
 // computeFrame computes next frame minimal changes and returns them.
 func (app *App) computeFrame(gd Grid, exposed bool) Frame {
-	if gd.Ug == nil || gd.Rg.Empty() && !exposed {
+	if gd.Ug == nil || gd.Rg.Empty() && !exposed && len(app.redraw) == 0 {
 		return Frame{}
 	}
 	if app.grid.Ug == nil {
@@ -722,44 +1042,121 @@ func (app *App) computeFrame(gd Grid, exposed bool) Frame {
 		app.frame.Width = gd.Ug.Width
 		app.frame.Height = gd.Ug.Height
 	} else if app.grid.Ug.Width != gd.Ug.Width || app.grid.Ug.Height != gd.Ug.Height {
-		app.grid = app.grid.Resize(gd.Ug.Width, gd.Ug.Height)
+		// The previous cells are indexed like the new grid's cells, so
+		// the underlying grids should have the same dimensions: Resize
+		// is not enough, as it never shrinks the underlying grid.
+		ngd := NewGrid(gd.Ug.Width, gd.Ug.Height)
+		ngd.Copy(app.grid)
+		app.grid = ngd
 		app.frame.Width = gd.Ug.Width
 		app.frame.Height = gd.Ug.Height
+		app.rdirty = true
 	}
 	app.frame.Time = time.Now()
 	app.frame.Cells = app.frame.Cells[:0]
 	if exposed {
-		return app.refresh(gd)
+		app.rdirty = true
+		frame := app.refresh(gd)
+		app.computeRedraw()
+		return frame
 	}
 	w := gd.Ug.Width
 	cells := gd.Ug.Cells
 	pcells := app.grid.Ug.Cells // previous cells
 	yimax := gd.Rg.Max.Y * w
-	for y, yi := 0, gd.Rg.Min.Y*w; yi < yimax; y, yi = y+1, yi+w {
+	for y, yi := gd.Rg.Min.Y, gd.Rg.Min.Y*w; yi < yimax; y, yi = y+1, yi+w {
 		ximax := yi + gd.Rg.Max.X
-		for x, xi := 0, yi+gd.Rg.Min.X; xi < ximax; x, xi = x+1, xi+1 {
+		x, xi := gd.Rg.Min.X, yi+gd.Rg.Min.X
+		if gd.Rg.Min.X > 0 && cells[xi-1].Wide() {
+			// continuation of a wide cell out of range
+			pcells[xi] = wideCont
+			x, xi = x+1, xi+1
+		}
+		for ; xi < ximax; x, xi = x+1, xi+1 {
 			c := cells[xi]
-			if c == pcells[xi] {
-				continue
+			if c != pcells[xi] || c.Style.Attrs&AttrRedraw != 0 {
+				if (c.Style.Attrs|pcells[xi].Style.Attrs)&AttrRedraw != 0 {
+					app.rdirty = true
+				}
+				pcells[xi] = c
+				p := Point{X: x, Y: y}
+				cdraw := FrameCell{Cell: c, P: p}
+				app.frame.Cells = append(app.frame.Cells, cdraw)
 			}
-			pcells[xi] = c
-			p := Point{X: x, Y: y}
-			cdraw := FrameCell{Cell: c, P: p}
-			app.frame.Cells = append(app.frame.Cells, cdraw)
+			if c.Wide() && xi+1 < yi+w {
+				// skip continuation cell
+				pcells[xi+1] = wideCont
+				x, xi = x+1, xi+1
+			}
+		}
+	}
+	app.computeRedraw()
+	for _, p := range app.redraw {
+		if p.In(gd.Rg) {
+			// already sent
+			continue
 		}
+		app.frame.Cells = append(app.frame.Cells, FrameCell{Cell: app.grid.At(p), P: p})
 	}
 	return app.frame
 }
 
+// computeRedraw updates, if needed, the positions of cells with the
+// AttrRedraw attribute.
+func (app *App) computeRedraw() {
+	if !app.rdirty {
+		return
+	}
+	app.rdirty = false
+	app.redraw = app.redraw[:0]
+	app.grid.Iter(func(p Point, c Cell) {
+		if c != wideCont && c.Style.Attrs&AttrRedraw != 0 {
+			app.redraw = append(app.redraw, p)
+		}
+	})
+}
+
+// wideCont is the cell stored in the previous frame's grid for continuation
+// cells of wide cells. It never matches actual content, so that continuation
+// cells are sent again when they become normal cells.
+var wideCont = Cell{Rune: -1}
+
+// keyFrame returns a frame with the same time as the given one, but containing
+// all the cells of the current grid state.
+func (app *App) keyFrame(frame Frame) Frame {
+	kf := Frame{
+		Time:   frame.Time,
+		Width:  frame.Width,
+		Height: frame.Height,
+		Cells:  make([]FrameCell, 0, frame.Width*frame.Height),
+	}
+	app.grid.Iter(func(p Point, c Cell) {
+		if c == wideCont {
+			return
+		}
+		kf.Cells = append(kf.Cells, FrameCell{Cell: c, P: p})
+	})
+	return kf
+}
+
 // refresh forces a complete redraw of the screen, even for cells that did not
 // change.
 func (app *App) refresh(gd Grid) Frame {
 	gd.Rg.Min = Point{0, 0}
 	gd.Rg.Max = gd.Rg.Min.Add(Point{gd.Ug.Width, gd.Ug.Height})
 	app.grid.Copy(gd)
-	it := gd.Iterator()
+	it := app.grid.Iterator()
+	wide := false
 	for it.Next() {
-		cdraw := FrameCell{Cell: it.Cell(), P: it.P()}
+		p, c := it.P(), it.Cell()
+		if wide && p.X > 0 {
+			// continuation of a wide cell
+			it.SetCell(wideCont)
+			wide = false
+			continue
+		}
+		wide = c.Wide()
+		cdraw := FrameCell{Cell: c, P: p}
 		app.frame.Cells = append(app.frame.Cells, cdraw)
 	}
 	return app.frame
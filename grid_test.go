@@ -1,6 +1,7 @@
 package gruid
 
 import (
+	"encoding/json"
 	//"log"
 	"math/rand"
 	"testing"
@@ -240,6 +241,43 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestCopyOver(t *testing.T) {
+	gd := NewGrid(20, 10)
+	gd.Fill(Cell{Rune: '.'})
+	sprite := NewGrid(3, 3)
+	sprite.Fill(Cell{Rune: ' '})
+	sprite.Set(Point{1, 1}, Cell{Rune: '@'})
+	sprite.Set(Point{0, 0}, Cell{Rune: ' ', Style: Style{Bg: 1}})
+	slice := gd.Slice(NewRange(5, 5, 8, 8))
+	if max := slice.CopyOver(sprite, ' '); max != (Point{3, 3}) {
+		t.Errorf("bad copied size: %v", max)
+	}
+	gd.Iter(func(p Point, c Cell) {
+		switch p {
+		case Point{6, 6}:
+			if c.Rune != '@' {
+				t.Errorf("bad sprite cell: %c at %v", c.Rune, p)
+			}
+		case Point{5, 5}:
+			if c.Rune != ' ' || c.Style.Bg != 1 {
+				t.Errorf("bad styled cell: %c at %v", c.Rune, p)
+			}
+		default:
+			if c.Rune != '.' {
+				t.Errorf("bad transparent cell: %c at %v", c.Rune, p)
+			}
+		}
+	})
+	// overlapping
+	gd.Slice(NewRange(6, 6, 9, 9)).CopyOver(slice, '.')
+	if c := gd.At(Point{7, 7}); c.Rune != '@' {
+		t.Errorf("bad overlapping copy: %c", c.Rune)
+	}
+	if c := gd.At(Point{6, 6}); c.Rune != ' ' {
+		t.Errorf("bad overlapping copy: %c", c.Rune)
+	}
+}
+
 func TestCopy2(t *testing.T) {
 	gd := NewGrid(80, 10)
 	max := gd.Size()
@@ -621,6 +659,57 @@ func TestCell(t *testing.T) {
 	}
 }
 
+func TestDraw(t *testing.T) {
+	gd := NewGrid(10, 10)
+	c := Cell{Rune: '#'}
+	count := func() int {
+		n := 0
+		gd.Iter(func(p Point, cc Cell) {
+			if cc == c {
+				n++
+			}
+		})
+		return n
+	}
+	gd.DrawLine(Point{0, 0}, Point{9, 9}, c)
+	if count() != 10 {
+		t.Errorf("bad diagonal line count: %d", count())
+	}
+	gd.Fill(Cell{Rune: ' '})
+	gd.DrawLine(Point{8, 2}, Point{1, 2}, c)
+	if count() != 8 {
+		t.Errorf("bad horizontal line count: %d", count())
+	}
+	gd.Fill(Cell{Rune: ' '})
+	gd.DrawLine(Point{0, 0}, Point{9, 3}, c)
+	if gd.At(Point{0, 0}) != c || gd.At(Point{9, 3}) != c || count() != 10 {
+		t.Errorf("bad line:\n%v", gd)
+	}
+	gd.Fill(Cell{Rune: ' '})
+	gd.DrawRect(NewRange(1, 1, 5, 4), c)
+	if count() != 10 {
+		t.Errorf("bad rect count: %d", count())
+	}
+	if gd.At(Point{2, 2}) == c {
+		t.Errorf("rect interior drawn")
+	}
+	gd.Fill(Cell{Rune: ' '})
+	gd.DrawCircle(Point{5, 5}, 3, c)
+	for _, p := range []Point{{8, 5}, {2, 5}, {5, 8}, {5, 2}} {
+		if gd.At(p) != c {
+			t.Errorf("bad circle at %v:\n%v", p, gd)
+		}
+	}
+	if gd.At(Point{5, 5}) == c {
+		t.Errorf("circle center drawn")
+	}
+	gd.Fill(Cell{Rune: ' '})
+	gd.DrawCircle(Point{0, 0}, 20, c)
+	if count() != 0 {
+		t.Errorf("bad out of range circle count: %d", count())
+	}
+}
+
 func TestPoint(t *testing.T) {
 	p := Point{2, 3}
 	if p.Mul(3).X != 6 {
@@ -631,6 +720,46 @@ func TestPoint(t *testing.T) {
 	}
 }
 
+func TestPointRangeJSON(t *testing.T) {
+	p := Point{3, -4}
+	bs, err := json.Marshal(p)
+	if err != nil || string(bs) != `"3,-4"` {
+		t.Errorf("bad json point: %s (%v)", bs, err)
+	}
+	var q Point
+	if err := json.Unmarshal(bs, &q); err != nil || q != p {
+		t.Errorf("bad unmarshaled point: %v (%v)", q, err)
+	}
+	if q, err := ParsePoint(p.String()); err != nil || q != p {
+		t.Errorf("bad point from String: %v (%v)", q, err)
+	}
+	rg := NewRange(-2, 0, 80, 24)
+	bs, err = json.Marshal(map[string]Range{"map": rg})
+	if err != nil || string(bs) != `{"map":"-2,0-80,24"}` {
+		t.Errorf("bad json range: %s (%v)", bs, err)
+	}
+	var rgs map[string]Range
+	if err := json.Unmarshal(bs, &rgs); err != nil || rgs["map"] != rg {
+		t.Errorf("bad unmarshaled range: %v (%v)", rgs, err)
+	}
+	for _, s := range []string{rg.String(), "-2,0 - 80,24", "(-2, 0)-(80, 24)"} {
+		if nrg, err := ParseRange(s); err != nil || nrg != rg {
+			t.Errorf("bad range from %q: %v (%v)", s, nrg, err)
+		}
+	}
+	for _, s := range []string{"", "3", "a,b", "1,2", "1,2-3", "1,2-3,x"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("no error for %q", s)
+		}
+	}
+	if err := json.Unmarshal([]byte(`"1;2"`), &q); err == nil {
+		t.Errorf("no error for bad point")
+	}
+	if err := json.Unmarshal([]byte(`{"X":1}`), &q); err == nil {
+		t.Errorf("no error for non-string point")
+	}
+}
+
 func TestRangeShift(t *testing.T) {
 	rg := NewRange(1, 2, 3, 4)
 	nrg := NewRange(2, 3, 4, 5)
@@ -781,3 +910,164 @@ func BenchmarkGridFillVertical8(b *testing.B) {
 		gd.Fill(Cell{}.WithRune('x'))
 	}
 }
+
+func BenchmarkGridCopyLarge(b *testing.B) {
+	world := NewGrid(1024, 1024)
+	gd := NewGrid(1024, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gd.Copy(world)
+	}
+}
+
+func BenchmarkGridCopyLargeSlice(b *testing.B) {
+	world := NewGrid(1024, 1024)
+	gd := NewGrid(80, 24)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gd.Copy(world.Slice(NewRange(500, 500, 580, 524)))
+	}
+}
+
+func BenchmarkGridFillLarge(b *testing.B) {
+	gd := NewGrid(1024, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gd.Fill(Cell{}.WithRune('x'))
+	}
+}
+
+func BenchmarkComputeFrameLarge(b *testing.B) {
+	app := NewApp(AppConfig{})
+	gd := NewGrid(1024, 1024)
+	app.computeFrame(gd, false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gd.Set(Point{i % 1024, (i / 1024) % 1024}, Cell{Rune: rune('a' + i%26)})
+		app.computeFrame(gd, false)
+	}
+}
+
+func TestLargeGrid(t *testing.T) {
+	const size = 1024
+	world := NewGrid(size, size)
+	world.Slice(NewRange(size-10, size-10, size, size)).Fill(Cell{Rune: 'x'})
+	if c := world.At(Point{size - 1, size - 1}); c.Rune != 'x' {
+		t.Errorf("bad cell at bottom-right corner: %v", c)
+	}
+	n := 0
+	world.Iter(func(p Point, c Cell) {
+		if c.Rune == 'x' {
+			n++
+		}
+	})
+	if n != 100 {
+		t.Errorf("bad count: %d", n)
+	}
+	gd := NewGrid(20, 20)
+	if max := gd.Copy(world.Slice(NewRange(size-15, size-15, size, size))); max != (Point{15, 15}) {
+		t.Errorf("bad copy size: %v", max)
+	}
+	if c := gd.At(Point{14, 14}); c.Rune != 'x' {
+		t.Errorf("bad copied cell: %v", c)
+	}
+	app := NewApp(AppConfig{})
+	app.computeFrame(world, false)
+	world.Set(Point{size - 1, size - 1}, Cell{Rune: 'y'})
+	fr := app.computeFrame(world, false)
+	if len(fr.Cells) != 1 || fr.Cells[0].P != (Point{size - 1, size - 1}) || fr.Width != size {
+		t.Errorf("bad frame: %+v", fr)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for overflowing dimensions")
+		}
+	}()
+	NewGrid(1<<40, 1<<40)
+}
+
+func TestComputeFrameNewGrid(t *testing.T) {
+	app := NewApp(AppConfig{})
+	app.computeFrame(NewGrid(10, 5), false)
+	// smaller underlying grid
+	gd := NewGrid(8, 5)
+	gd.Set(Point{2, 3}, Cell{Rune: 'x'})
+	fr := app.computeFrame(gd, false)
+	if len(fr.Cells) != 1 || fr.Width != 8 {
+		t.Errorf("bad frame: %+v", fr)
+	}
+	kf := app.keyFrame(fr)
+	if len(kf.Cells) != 8*5 {
+		t.Fatalf("bad key frame length: %d", len(kf.Cells))
+	}
+	for _, fc := range kf.Cells {
+		if fc.Cell.Rune == 'x' && fc.P != (Point{2, 3}) {
+			t.Errorf("bad key frame cell position: %v", fc.P)
+		}
+	}
+}
+
+func TestWideCellsFrame(t *testing.T) {
+	app := NewApp(AppConfig{})
+	gd := NewGrid(4, 2)
+	wide := Cell{Rune: '字'}.WithStyle(Style{Attrs: AttrWide})
+	if !wide.Wide() {
+		t.Fatalf("cell not wide")
+	}
+	app.computeFrame(gd, false)
+	gd.Set(Point{1, 0}, wide)
+	gd.Set(Point{2, 0}, Cell{Rune: 'x'})
+	gd.Set(Point{3, 1}, wide)
+	fr := app.computeFrame(gd, false)
+	if len(fr.Cells) != 2 || fr.Cells[0].P != (Point{1, 0}) || fr.Cells[1].P != (Point{3, 1}) {
+		t.Errorf("bad frame with wide cells: %+v", fr.Cells)
+	}
+	gd.Set(Point{1, 0}, Cell{Rune: 'a'})
+	fr = app.computeFrame(gd, false)
+	if len(fr.Cells) != 2 || fr.Cells[1].P != (Point{2, 0}) || fr.Cells[1].Cell.Rune != 'x' {
+		t.Errorf("continuation cell not redrawn: %+v", fr.Cells)
+	}
+	gd.Set(Point{1, 0}, wide)
+	fr = app.computeFrame(gd.Slice(NewRange(2, 0, 4, 1)), false)
+	if len(fr.Cells) != 0 {
+		t.Errorf("continuation cell out of range drawn: %+v", fr.Cells)
+	}
+	fr = app.computeFrame(gd, true)
+	if len(fr.Cells) != 4*2-1 {
+		t.Errorf("bad refresh frame length: %d", len(fr.Cells))
+	}
+	if kf := app.keyFrame(fr); len(kf.Cells) != 4*2-1 {
+		t.Errorf("bad key frame length: %d", len(kf.Cells))
+	}
+}
+
+func TestRedrawCellsFrame(t *testing.T) {
+	app := NewApp(AppConfig{})
+	gd := NewGrid(4, 3)
+	app.computeFrame(gd, false)
+	blink := Cell{Rune: '*'}.WithStyle(Style{Attrs: AttrRedraw})
+	gd.Set(Point{3, 2}, blink)
+	fr := app.computeFrame(gd, false)
+	if len(fr.Cells) != 1 || fr.Cells[0].P != (Point{3, 2}) {
+		t.Errorf("bad frame with redraw cell: %+v", fr.Cells)
+	}
+	fr = app.computeFrame(gd, false)
+	if len(fr.Cells) != 1 || fr.Cells[0].Cell != blink {
+		t.Errorf("redraw cell not sent again: %+v", fr.Cells)
+	}
+	gd.Set(Point{1, 1}, Cell{Rune: 'x'})
+	fr = app.computeFrame(gd.Slice(NewRange(1, 1, 2, 2)), false)
+	if len(fr.Cells) != 2 || fr.Cells[0].P != (Point{1, 1}) || fr.Cells[1].P != (Point{3, 2}) {
+		t.Errorf("bad frame with redraw cell out of range: %+v", fr.Cells)
+	}
+	fr = app.computeFrame(gd.Slice(Range{}), false)
+	if len(fr.Cells) != 1 {
+		t.Errorf("redraw cell not sent with empty range: %+v", fr.Cells)
+	}
+	gd.Set(Point{3, 2}, Cell{Rune: ' '})
+	app.computeFrame(gd, false)
+	fr = app.computeFrame(gd, false)
+	if len(fr.Cells) != 0 {
+		t.Errorf("former redraw cell sent: %+v", fr.Cells)
+	}
+}
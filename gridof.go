@@ -0,0 +1,473 @@
+package gruid
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math"
+)
+
+// GridOf is a generic version of Grid, with cells of arbitrary type T. It
+// shares the same slicing semantics: it represents a rectangular range within
+// an underlying original grid, and slices share memory with their parent. It
+// can be used to represent map data, such as terrain, light levels or entity
+// identifiers, using the same positions as the grid used for drawing.
+//
+// Grid itself is not defined in terms of GridOf, so that drawing and frame
+// computations keep the same efficient non-generic code.
+//
+// GridOf elements must be created with NewGridOf.
+//
+// GridOf implements gob.Decoder and gob.Encoder for easy serialization.
+type GridOf[T any] struct {
+	innerGridOf[T]
+}
+
+type innerGridOf[T any] struct {
+	Ug *gridOf[T] // underlying whole grid
+	Rg Range      // range within the whole grid
+}
+
+type gridOf[T any] struct {
+	Cells  []T
+	Width  int
+	Height int
+}
+
+// NewGridOf returns a new grid with given width and height in cells. The
+// width and height should be positive or null. The new grid contains all
+// positions (X,Y) with 0 <= X < w and 0 <= Y < h. The grid is filled with the
+// zero value for cells. It panics if the number of cells w*h overflows an int.
+func NewGridOf[T any](w, h int) GridOf[T] {
+	if w < 0 || h < 0 {
+		panic(fmt.Sprintf("negative dimensions: NewGridOf(%d,%d)", w, h))
+	}
+	if h > 0 && w > math.MaxInt/h {
+		panic(fmt.Sprintf("too large dimensions: NewGridOf(%d,%d)", w, h))
+	}
+	gd := GridOf[T]{}
+	gd.Ug = &gridOf[T]{Cells: make([]T, w*h), Width: w, Height: h}
+	gd.Rg.Max = Point{w, h}
+	return gd
+}
+
+// GobDecode implements gob.GobDecoder.
+func (gd *GridOf[T]) GobDecode(bs []byte) error {
+	r := bytes.NewReader(bs)
+	gdec := gob.NewDecoder(r)
+	igd := &innerGridOf[T]{}
+	err := gdec.Decode(igd)
+	if err != nil {
+		return err
+	}
+	gd.innerGridOf = *igd
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder.
+func (gd *GridOf[T]) GobEncode() ([]byte, error) {
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	err := ge.Encode(&gd.innerGridOf)
+	return buf.Bytes(), err
+}
+
+// Bounds returns the range that is covered by this grid slice within the
+// underlying original grid.
+func (gd GridOf[T]) Bounds() Range {
+	return gd.Rg
+}
+
+// Range returns the range with Min set to (0,0) and Max set to gd.Size(). It
+// may be convenient when using Slice with a range Shift.
+func (gd GridOf[T]) Range() Range {
+	return gd.Rg.Sub(gd.Rg.Min)
+}
+
+// Slice returns a rectangular slice of the grid given by a range relative to
+// the grid. If the range is out of bounds of the parent grid, it will be
+// reduced to fit to the available space. The returned grid shares memory with
+// the parent.
+func (gd GridOf[T]) Slice(rg Range) GridOf[T] {
+	if rg.Min.X < 0 {
+		rg.Min.X = 0
+	}
+	if rg.Min.Y < 0 {
+		rg.Min.Y = 0
+	}
+	max := gd.Rg.Size()
+	if rg.Max.X > max.X {
+		rg.Max.X = max.X
+	}
+	if rg.Max.Y > max.Y {
+		rg.Max.Y = max.Y
+	}
+	min := gd.Rg.Min
+	rg.Min = rg.Min.Add(min)
+	rg.Max = rg.Max.Add(min)
+	return GridOf[T]{innerGridOf[T]{Ug: gd.Ug, Rg: rg}}
+}
+
+// Size returns the grid (width, height) in cells, and is a shorthand for
+// gd.Range().Size().
+func (gd GridOf[T]) Size() Point {
+	return gd.Rg.Size()
+}
+
+// Resize is similar to Slice, but it only specifies new dimensions, and if the
+// range goes beyond the underlying original grid range, it will grow the
+// underlying grid. It preserves the content, and any new cells get the zero
+// value.
+func (gd GridOf[T]) Resize(w, h int) GridOf[T] {
+	max := gd.Size()
+	ow, oh := max.X, max.Y
+	if ow == w && oh == h {
+		return gd
+	}
+	if w <= 0 || h <= 0 {
+		gd.Rg.Max = gd.Rg.Min
+		return gd
+	}
+	if gd.Ug == nil {
+		gd.Ug = &gridOf[T]{}
+	}
+	gd.Rg.Max = gd.Rg.Min.Shift(w, h)
+	uh := gd.Ug.Height
+	nw := gd.Ug.Width
+	if w+gd.Rg.Min.X > gd.Ug.Width {
+		nw = w + gd.Rg.Min.X
+	}
+	nh := uh
+	if h+gd.Rg.Min.Y > uh {
+		nh = h + gd.Rg.Min.Y
+	}
+	if nw > gd.Ug.Width || nh > uh {
+		ngd := NewGridOf[T](nw, nh)
+		ngd.Copy(GridOf[T]{innerGridOf[T]{Ug: gd.Ug, Rg: NewRange(0, 0, gd.Ug.Width, uh)}})
+		*gd.Ug = *ngd.Ug
+	}
+	return gd
+}
+
+// Contains returns true if the given relative position is within the grid.
+func (gd GridOf[T]) Contains(p Point) bool {
+	return p.Add(gd.Rg.Min).In(gd.Rg)
+}
+
+// Set sets a cell at a given position in the grid. If the position is out of
+// range, the function does nothing.
+func (gd GridOf[T]) Set(p Point, c T) {
+	q := p.Add(gd.Rg.Min)
+	if !q.In(gd.Rg) {
+		return
+	}
+	i := q.Y*gd.Ug.Width + q.X
+	gd.Ug.Cells[i] = c
+}
+
+// At returns the cell at a given position. If the position is out of range, it
+// returns the zero value.
+func (gd GridOf[T]) At(p Point) T {
+	q := p.Add(gd.Rg.Min)
+	if !q.In(gd.Rg) {
+		var zero T
+		return zero
+	}
+	i := q.Y*gd.Ug.Width + q.X
+	return gd.Ug.Cells[i]
+}
+
+// AtU returns the cell at a given position without checking the grid slice
+// bounds. If the position is out of bounds, it returns a value corresponding
+// to the position in the underlying grid, or the zero value if also out of
+// the underlying grid's range.
+//
+// It may be somewhat faster than At in tight loops, but most of the time you
+// can get the same performance using GridOfIterator or iteration functions,
+// which are less error-prone.
+func (gd GridOf[T]) AtU(p Point) T {
+	p = p.Add(gd.Rg.Min)
+	i := p.Y*gd.Ug.Width + p.X
+	if i < 0 || i >= len(gd.Ug.Cells) {
+		var zero T
+		return zero
+	}
+	return gd.Ug.Cells[i]
+}
+
+// Fill sets the given cell as content for all the grid positions.
+func (gd GridOf[T]) Fill(c T) {
+	if gd.Ug == nil {
+		return
+	}
+	w := gd.Rg.Max.X - gd.Rg.Min.X
+	switch {
+	case w > 8:
+		gd.fillcp(c)
+	case w == 1:
+		gd.fillv(c)
+	default:
+		gd.fill(c)
+	}
+}
+
+func (gd GridOf[T]) fillcp(c T) {
+	w := gd.Ug.Width
+	ymin := gd.Rg.Min.Y * w
+	gdw := gd.Rg.Max.X - gd.Rg.Min.X
+	cells := gd.Ug.Cells
+	for xi := ymin + gd.Rg.Min.X; xi < ymin+gd.Rg.Max.X; xi++ {
+		cells[xi] = c
+	}
+	idxmax := (gd.Rg.Max.Y-1)*w + gd.Rg.Max.X
+	for idx := ymin + w + gd.Rg.Min.X; idx < idxmax; idx += w {
+		copy(cells[idx:idx+gdw], cells[ymin+gd.Rg.Min.X:ymin+gd.Rg.Max.X])
+	}
+}
+
+func (gd GridOf[T]) fill(c T) {
+	w := gd.Ug.Width
+	cells := gd.Ug.Cells
+	yimax := gd.Rg.Max.Y * w
+	for yi := gd.Rg.Min.Y * w; yi < yimax; yi += w {
+		ximax := yi + gd.Rg.Max.X
+		for xi := yi + gd.Rg.Min.X; xi < ximax; xi++ {
+			cells[xi] = c
+		}
+	}
+}
+
+func (gd GridOf[T]) fillv(c T) {
+	w := gd.Ug.Width
+	cells := gd.Ug.Cells
+	ximax := gd.Rg.Max.Y*w + gd.Rg.Min.X
+	for xi := gd.Rg.Min.Y*w + gd.Rg.Min.X; xi < ximax; xi += w {
+		cells[xi] = c
+	}
+}
+
+// FillFunc updates the content for all the grid positions in order using the
+// given function return value.
+func (gd GridOf[T]) FillFunc(fn func() T) {
+	if gd.Ug == nil {
+		return
+	}
+	w := gd.Ug.Width
+	yimax := gd.Rg.Max.Y * w
+	cells := gd.Ug.Cells
+	for yi := gd.Rg.Min.Y * w; yi < yimax; yi += w {
+		ximax := yi + gd.Rg.Max.X
+		for xi := yi + gd.Rg.Min.X; xi < ximax; xi++ {
+			cells[xi] = fn()
+		}
+	}
+}
+
+// Iter iterates a function on all the grid positions and cells.
+func (gd GridOf[T]) Iter(fn func(Point, T)) {
+	if gd.Ug == nil {
+		return
+	}
+	w := gd.Ug.Width
+	yimax := gd.Rg.Max.Y * w
+	cells := gd.Ug.Cells
+	for y, yi := 0, gd.Rg.Min.Y*w; yi < yimax; y, yi = y+1, yi+w {
+		ximax := yi + gd.Rg.Max.X
+		for x, xi := 0, yi+gd.Rg.Min.X; xi < ximax; x, xi = x+1, xi+1 {
+			fn(Point{X: x, Y: y}, cells[xi])
+		}
+	}
+}
+
+// Map updates the grid content using the given mapping function.
+func (gd GridOf[T]) Map(fn func(Point, T) T) {
+	if gd.Ug == nil {
+		return
+	}
+	w := gd.Ug.Width
+	cells := gd.Ug.Cells
+	yimax := gd.Rg.Max.Y * w
+	for y, yi := 0, gd.Rg.Min.Y*w; yi < yimax; y, yi = y+1, yi+w {
+		ximax := yi + gd.Rg.Max.X
+		for x, xi := 0, yi+gd.Rg.Min.X; xi < ximax; x, xi = x+1, xi+1 {
+			cells[xi] = fn(Point{X: x, Y: y}, cells[xi])
+		}
+	}
+}
+
+// DrawLine draws a line segment between positions p and q (both included)
+// using the given cell. It uses Bresenham's line algorithm. Positions out of
+// the grid's range are ignored.
+func (gd GridOf[T]) DrawLine(p, q Point, c T) {
+	bresenham(p, q, func(r Point) {
+		gd.Set(r, c)
+	})
+}
+
+// DrawRect draws the outline of a rectangle corresponding to the given range,
+// relative to the grid, using the given cell. It does not draw anything in
+// the interior region.
+func (gd GridOf[T]) DrawRect(rg Range, c T) {
+	if rg.Empty() {
+		return
+	}
+	max := rg.Max.Shift(-1, -1)
+	for x := rg.Min.X; x <= max.X; x++ {
+		gd.Set(Point{X: x, Y: rg.Min.Y}, c)
+		gd.Set(Point{X: x, Y: max.Y}, c)
+	}
+	for y := rg.Min.Y + 1; y < max.Y; y++ {
+		gd.Set(Point{X: rg.Min.X, Y: y}, c)
+		gd.Set(Point{X: max.X, Y: y}, c)
+	}
+}
+
+// DrawCircle draws the outline of a circle with the given center and radius
+// using the given cell. It uses the midpoint circle algorithm. A null radius
+// draws only the center, and a negative one draws nothing.
+func (gd GridOf[T]) DrawCircle(center Point, radius int, c T) {
+	circle(center, radius, func(p Point) {
+		gd.Set(p, c)
+	})
+}
+
+// Copy copies elements from a source grid src into the destination grid gd,
+// and returns the copied grid-slice size, which is the minimum of both grids
+// for each dimension. The result is independent of whether the two grids
+// referenced memory overlaps or not.
+func (gd GridOf[T]) Copy(src GridOf[T]) Point {
+	if gd.Ug == nil || src.Ug == nil {
+		return Point{}
+	}
+	w := gd.Ug.Width
+	wsrc := src.Ug.Width
+	max := gd.Range().Intersect(src.Range()).Size()
+	if max.X <= 0 || max.Y <= 0 {
+		return max
+	}
+	if gd.Ug == src.Ug && gd.Rg == src.Rg {
+		return max
+	}
+	cells := gd.Ug.Cells
+	srccells := src.Ug.Cells
+	if gd.Ug == src.Ug && gd.Rg.Overlaps(src.Rg) && gd.Rg.Min.Y > src.Rg.Min.Y {
+		// reverse line order, in case of overlapping
+		for y := max.Y - 1; y >= 0; y-- {
+			idx := (gd.Rg.Min.Y+y)*w + gd.Rg.Min.X
+			idxsrc := (src.Rg.Min.Y+y)*wsrc + src.Rg.Min.X
+			copy(cells[idx:idx+max.X], srccells[idxsrc:idxsrc+max.X])
+		}
+		return max
+	}
+	for y := 0; y < max.Y; y++ {
+		idx := (gd.Rg.Min.Y+y)*w + gd.Rg.Min.X
+		idxsrc := (src.Rg.Min.Y+y)*wsrc + src.Rg.Min.X
+		copy(cells[idx:idx+max.X], srccells[idxsrc:idxsrc+max.X])
+	}
+	return max
+}
+
+// CountOf returns the number of cells in the grid which are equal to the given
+// one.
+func CountOf[T comparable](gd GridOf[T], c T) int {
+	if gd.Ug == nil {
+		return 0
+	}
+	w := gd.Ug.Width
+	count := 0
+	yimax := gd.Rg.Max.Y * w
+	cells := gd.Ug.Cells
+	for yi := gd.Rg.Min.Y * w; yi < yimax; yi += w {
+		ximax := yi + gd.Rg.Max.X
+		for xi := yi + gd.Rg.Min.X; xi < ximax; xi++ {
+			if cells[xi] == c {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// GridOfIterator represents a stateful iterator for a generic grid. They are
+// created with the Iterator method.
+type GridOfIterator[T any] struct {
+	cells  []T   // grid cells
+	p      Point // iterator's current position
+	max    Point // last position
+	i      int   // current position's index
+	w      int   // underlying grid's width
+	nlstep int   // newline step
+	rg     Range // grid range
+}
+
+// Iterator returns an iterator that can be used to iterate on the grid. It may
+// be convenient when more flexibility than the provided by the other iteration
+// functions is needed. It is used as follows:
+//
+//	it := gd.Iterator()
+//	for it.Next() {
+//		// call it.P() or it.Cell() or it.SetCell() as appropriate
+//	}
+func (gd GridOf[T]) Iterator() GridOfIterator[T] {
+	if gd.Ug == nil || gd.Rg.Empty() {
+		return GridOfIterator[T]{}
+	}
+	w := gd.Ug.Width
+	it := GridOfIterator[T]{
+		w:      w,
+		cells:  gd.Ug.Cells,
+		max:    gd.Size().Shift(-1, -1),
+		rg:     gd.Rg,
+		nlstep: gd.Rg.Min.X + (w - gd.Rg.Max.X + 1),
+	}
+	it.Reset()
+	return it
+}
+
+// Reset resets the iterator's state so that it can be used again.
+func (it *GridOfIterator[T]) Reset() {
+	it.p = Point{-1, 0}
+	it.i = it.rg.Min.Y*it.w + it.rg.Min.X - 1
+}
+
+// Next advances the iterator the next position in the grid.
+func (it *GridOfIterator[T]) Next() bool {
+	if it.p.X < it.max.X {
+		it.p.X++
+		it.i++
+		return true
+	}
+	if it.p.Y < it.max.Y {
+		it.p.Y++
+		it.p.X = 0
+		it.i += it.nlstep
+		return true
+	}
+	return false
+}
+
+// P returns the iterator's current position.
+func (it *GridOfIterator[T]) P() Point {
+	return it.p
+}
+
+// SetP sets the iterator's current position.
+func (it *GridOfIterator[T]) SetP(p Point) {
+	q := p.Add(it.rg.Min)
+	if !q.In(it.rg) {
+		return
+	}
+	it.p = p
+	it.i = q.Y*it.w + q.X
+}
+
+// Cell returns the cell in the grid at the iterator's current position.
+func (it *GridOfIterator[T]) Cell() T {
+	return it.cells[it.i]
+}
+
+// SetCell updates the grid cell at the iterator's current position. It's
+// faster than calling Set on the grid.
+func (it *GridOfIterator[T]) SetCell(c T) {
+	it.cells[it.i] = c
+}
@@ -0,0 +1,67 @@
+package gruid
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestGridOf(t *testing.T) {
+	gd := NewGridOf[float64](10, 6)
+	if gd.Size() != (Point{10, 6}) {
+		t.Errorf("bad size: %v", gd.Size())
+	}
+	sl := gd.Slice(NewRange(2, 1, 6, 4))
+	sl.Fill(0.5)
+	if v := gd.At(Point{3, 2}); v != 0.5 {
+		t.Errorf("bad value after slice fill: %v", v)
+	}
+	if v := gd.At(Point{-1, 2}); v != 0 {
+		t.Errorf("bad out of range value: %v", v)
+	}
+	if n := CountOf(gd, 0.5); n != 12 {
+		t.Errorf("bad count: %d", n)
+	}
+	sl.Map(func(p Point, v float64) float64 { return v + float64(p.X) })
+	if v := gd.At(Point{5, 1}); v != 3.5 {
+		t.Errorf("bad mapped value: %v", v)
+	}
+	// overlapping copy
+	gd.Slice(NewRange(3, 2, 10, 6)).Copy(gd)
+	if v := gd.At(Point{8, 4}); v != 3.5 {
+		t.Errorf("bad copied value: %v", v)
+	}
+	it := sl.Iterator()
+	n := 0
+	for it.Next() {
+		it.SetCell(1)
+		n++
+	}
+	if n != 12 || CountOf(sl, 1) != 12 {
+		t.Errorf("bad iteration: %d", n)
+	}
+	rs := sl.Resize(10, 10)
+	if rs.Size() != (Point{10, 10}) || gd.At(Point{2, 1}) != 1 {
+		t.Errorf("bad resize: %v", rs.Size())
+	}
+	if n := CountOf(rs, 1); n != 12 || rs.Bounds().Max != (Point{12, 11}) {
+		t.Errorf("bad content after resize: %d %v", n, rs.Bounds())
+	}
+}
+
+func TestGridOfGob(t *testing.T) {
+	gd := NewGridOf[string](4, 3)
+	gd.Set(Point{1, 1}, "x")
+	sl := gd.Slice(NewRange(1, 1, 3, 3))
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(&sl); err != nil {
+		t.Fatal(err)
+	}
+	var ngd GridOf[string]
+	if err := gob.NewDecoder(&buf).Decode(&ngd); err != nil {
+		t.Fatal(err)
+	}
+	if ngd.Bounds() != sl.Bounds() || ngd.At(Point{0, 0}) != "x" {
+		t.Errorf("bad decoded grid: %v %q", ngd.Bounds(), ngd.At(Point{0, 0}))
+	}
+}
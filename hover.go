@@ -0,0 +1,137 @@
+package gruid
+
+// HoverAction represents a change in the hovering state of a region.
+type HoverAction int
+
+// These constants represent the possible hover actions.
+const (
+	HoverEnter HoverAction = iota // mouse entered the region
+	HoverLeave                    // mouse left the region
+	HoverMove                     // mouse moved within the region
+)
+
+func (ha HoverAction) String() string {
+	var s string
+	switch ha {
+	case HoverEnter:
+		s = "HoverEnter"
+	case HoverLeave:
+		s = "HoverLeave"
+	case HoverMove:
+		s = "HoverMove"
+	}
+	return s
+}
+
+// HoverEvent represents a change in the hovering state of a named region, as
+// reported by Hover.Update.
+type HoverEvent struct {
+	Name   string      // region name
+	Action HoverAction // enter, leave or move
+	P      Point       // mouse position, relative to the region's range
+}
+
+// Hover tracks the mouse position against a set of named regions, and
+// reports when the mouse enters, leaves or moves within them. It can be used
+// for tooltips, button highlighting or cursor changes, without having to
+// compare mouse positions manually in the model.
+//
+// Regions may overlap: the hovering state of each region is tracked
+// independently. The zero value is ready to use.
+type Hover struct {
+	regions []hoverRegion
+	p       Point
+	known   bool // p is a known mouse position
+}
+
+type hoverRegion struct {
+	name   string
+	rg     Range
+	inside bool
+}
+
+// Set adds a region with a given name and range, or updates the range of the
+// region with that name. The hovering state of the region is updated on the
+// next mouse message.
+func (h *Hover) Set(name string, rg Range) {
+	for i := range h.regions {
+		if h.regions[i].name == name {
+			h.regions[i].rg = rg
+			return
+		}
+	}
+	h.regions = append(h.regions, hoverRegion{name: name, rg: rg})
+}
+
+// Remove removes the region with the given name, if any. No leave event is
+// reported for it.
+func (h *Hover) Remove(name string) {
+	for i := range h.regions {
+		if h.regions[i].name == name {
+			h.regions = append(h.regions[:i], h.regions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clear removes all the regions.
+func (h *Hover) Clear() {
+	h.regions = h.regions[:0]
+}
+
+// Hovered reports whether the mouse is currently within the region with the
+// given name, according to the last mouse message.
+func (h *Hover) Hovered(name string) bool {
+	for _, r := range h.regions {
+		if r.name == name {
+			return r.inside
+		}
+	}
+	return false
+}
+
+// Top returns the name of the last added region that is currently hovered,
+// which is usually the one drawn on top. It returns false if no region is
+// hovered.
+func (h *Hover) Top() (string, bool) {
+	for i := len(h.regions) - 1; i >= 0; i-- {
+		if h.regions[i].inside {
+			return h.regions[i].name, true
+		}
+	}
+	return "", false
+}
+
+// Update updates the hovering state of the regions from a MsgMouse message,
+// and returns the resulting events, if any: leave events come first, followed
+// by enter events, and then move events for the regions that were already
+// hovered. Within each kind, events follow the order in which regions were
+// added. Other messages are ignored.
+func (h *Hover) Update(msg Msg) []HoverEvent {
+	mmsg, ok := msg.(MsgMouse)
+	if !ok {
+		return nil
+	}
+	moved := !h.known || mmsg.P != h.p
+	h.p = mmsg.P
+	h.known = true
+	var leave, enter, move []HoverEvent
+	for i := range h.regions {
+		r := &h.regions[i]
+		in := h.p.In(r.rg)
+		ev := HoverEvent{Name: r.name, P: h.p.Sub(r.rg.Min)}
+		switch {
+		case in && !r.inside:
+			ev.Action = HoverEnter
+			enter = append(enter, ev)
+		case !in && r.inside:
+			ev.Action = HoverLeave
+			leave = append(leave, ev)
+		case in && moved:
+			ev.Action = HoverMove
+			move = append(move, ev)
+		}
+		r.inside = in
+	}
+	return append(append(leave, enter...), move...)
+}
@@ -0,0 +1,47 @@
+package gruid
+
+import "testing"
+
+func TestHover(t *testing.T) {
+	h := &Hover{}
+	h.Set("button", NewRange(0, 0, 5, 1))
+	h.Set("map", NewRange(0, 1, 20, 10))
+	h.Set("tooltip", NewRange(3, 0, 8, 3))
+	move := func(x, y int) []HoverEvent {
+		return h.Update(MsgMouse{Action: MouseMove, P: Point{x, y}})
+	}
+	evs := move(1, 0)
+	if len(evs) != 1 || evs[0] != (HoverEvent{Name: "button", Action: HoverEnter, P: Point{1, 0}}) {
+		t.Errorf("bad enter events: %v", evs)
+	}
+	evs = move(2, 0)
+	if len(evs) != 1 || evs[0].Action != HoverMove || evs[0].P != (Point{2, 0}) {
+		t.Errorf("bad move events: %v", evs)
+	}
+	if evs := h.Update(MsgMouse{Action: MouseMain, P: Point{2, 0}}); len(evs) != 0 {
+		t.Errorf("events without motion: %v", evs)
+	}
+	evs = move(4, 1)
+	if len(evs) != 3 || evs[0].Name != "button" || evs[0].Action != HoverLeave ||
+		evs[1].Name != "map" || evs[1].Action != HoverEnter ||
+		evs[2].Name != "tooltip" || evs[2].Action != HoverEnter || evs[2].P != (Point{1, 1}) {
+		t.Errorf("bad leave and enter events: %v", evs)
+	}
+	if !h.Hovered("map") || h.Hovered("button") {
+		t.Errorf("bad hovered state")
+	}
+	if name, ok := h.Top(); !ok || name != "tooltip" {
+		t.Errorf("bad top region: %s", name)
+	}
+	h.Remove("tooltip")
+	if name, ok := h.Top(); !ok || name != "map" {
+		t.Errorf("bad top region after removal: %s", name)
+	}
+	if evs := h.Update(MsgKeyDown{Key: "a"}); evs != nil {
+		t.Errorf("events for key message: %v", evs)
+	}
+	h.Clear()
+	if evs := move(5, 5); len(evs) != 0 {
+		t.Errorf("events after clear: %v", evs)
+	}
+}
@@ -47,13 +47,13 @@ const (
 // ModMask is a bit mask of modifier keys.
 type ModMask int16
 
-// These values represent modifier keys for a MsgKeyDown message. Those are not
-// supported equally well across all platforms and drivers, for both technical
-// and simplicity reasons. In particular, terminal drivers may not report shift
-// for key presses corresponding to upper case letters. Modifiers may conflict
-// in some cases with browser or system shortcuts too.  If you want portability
-// across platforms and drivers, your application should not depend on them
-// for its core functionality.
+// These values represent modifier keys for a MsgKeyDown or MsgKeyUp message.
+// Those are not supported equally well across all platforms and drivers, for
+// both technical and simplicity reasons. In particular, terminal drivers may
+// not report shift for key presses corresponding to upper case letters.
+// Modifiers may conflict in some cases with browser or system shortcuts too.
+// If you want portability across platforms and drivers, your application
+// should not depend on them for its core functionality.
 const (
 	ModShift ModMask = 1 << iota
 	ModCtrl
@@ -97,6 +97,24 @@ type MsgKeyDown struct {
 	// functionality in portable applications.
 	Mod ModMask
 
+	// Repeat reports whether the key press was generated automatically
+	// by the system because the key was held down. Drivers that cannot
+	// distinguish repeated key presses always report false.
+	Repeat bool
+
+	Time time.Time // time when the event was generated
+}
+
+// MsgKeyUp represents a key release. It is not supported by all drivers: in
+// particular, terminal drivers usually cannot report key releases. It can be
+// used together with MsgKeyDown for press-and-hold actions with custom repeat
+// logic.
+type MsgKeyUp struct {
+	Key Key // name of the key in MsgKeyUp event
+
+	// Mod represents modifier keys, as in MsgKeyDown.
+	Mod ModMask
+
 	Time time.Time // time when the event was generated
 }
 
@@ -144,6 +162,112 @@ type MsgMouse struct {
 	P      Point       // mouse position in the grid
 	Mod    ModMask     // modifier keys (unequal driver support)
 	Time   time.Time   // time when the event was generated
+
+	// Clicks is the number of successive main button presses at the same
+	// position for a MouseMain action, such as 2 for a double click, as
+	// reported by a MouseFilter with MultiClick. It is zero otherwise.
+	Clicks int
+}
+
+// GamepadButton represents a gamepad button.
+type GamepadButton int
+
+// This is the list of supported gamepad buttons, following a common Xbox-like
+// layout. Drivers map the controller's buttons to those positions.
+const (
+	GamepadNone          GamepadButton = iota // no button (axis motion)
+	GamepadA                                  // bottom face button
+	GamepadB                                  // right face button
+	GamepadX                                  // left face button
+	GamepadY                                  // top face button
+	GamepadBack                               // back or select button
+	GamepadGuide                              // guide or home button
+	GamepadStart                              // start button
+	GamepadLeftStick                          // left stick press
+	GamepadRightStick                         // right stick press
+	GamepadLeftShoulder                       // left shoulder button
+	GamepadRightShoulder                      // right shoulder button
+	GamepadDPadUp                             // directional pad up
+	GamepadDPadDown                           // directional pad down
+	GamepadDPadLeft                           // directional pad left
+	GamepadDPadRight                          // directional pad right
+)
+
+func (gb GamepadButton) String() string {
+	var s string
+	switch gb {
+	case GamepadNone:
+		s = "GamepadNone"
+	case GamepadA:
+		s = "GamepadA"
+	case GamepadB:
+		s = "GamepadB"
+	case GamepadX:
+		s = "GamepadX"
+	case GamepadY:
+		s = "GamepadY"
+	case GamepadBack:
+		s = "GamepadBack"
+	case GamepadGuide:
+		s = "GamepadGuide"
+	case GamepadStart:
+		s = "GamepadStart"
+	case GamepadLeftStick:
+		s = "GamepadLeftStick"
+	case GamepadRightStick:
+		s = "GamepadRightStick"
+	case GamepadLeftShoulder:
+		s = "GamepadLeftShoulder"
+	case GamepadRightShoulder:
+		s = "GamepadRightShoulder"
+	case GamepadDPadUp:
+		s = "GamepadDPadUp"
+	case GamepadDPadDown:
+		s = "GamepadDPadDown"
+	case GamepadDPadLeft:
+		s = "GamepadDPadLeft"
+	case GamepadDPadRight:
+		s = "GamepadDPadRight"
+	}
+	return s
+}
+
+// GamepadAxis represents a gamepad analog axis.
+type GamepadAxis int
+
+// This is the list of supported gamepad axes.
+const (
+	GamepadAxisNone         GamepadAxis = iota // no axis (button event)
+	GamepadAxisLeftX                           // left stick horizontal axis
+	GamepadAxisLeftY                           // left stick vertical axis
+	GamepadAxisRightX                          // right stick horizontal axis
+	GamepadAxisRightY                          // right stick vertical axis
+	GamepadAxisTriggerLeft                     // left trigger
+	GamepadAxisTriggerRight                    // right trigger
+)
+
+// MsgGamepad represents a gamepad user input event: either a button press or
+// release, or an axis motion. It is only reported by drivers with controller
+// support.
+type MsgGamepad struct {
+	ID     int           // gamepad identifier, for multiple controllers
+	Button GamepadButton // button, or GamepadNone for axis motion
+	Axis   GamepadAxis   // axis, or GamepadAxisNone for button events
+
+	// Value is 1 for button presses and 0 for releases. For axis motions,
+	// it is in the range [-32768, 32767] for sticks (negative values are
+	// left or up), and [0, 32767] for triggers.
+	Value int
+
+	Time time.Time // time when the event was generated
+}
+
+// MsgPaste represents text pasted by the user, for example with bracketed
+// paste in a terminal, or as a result of a clipboard request with
+// RequestClipboard. It is only reported by drivers with clipboard support.
+type MsgPaste struct {
+	Text string    // pasted text
+	Time time.Time // time when the event was generated
 }
 
 // MsgScreen is reported by some drivers when the screen has been exposed in
@@ -152,10 +276,20 @@ type MsgMouse struct {
 // Note that the application takes care of the redraw, so you may not need to
 // handle it in most cases, unless you want to adapt grid size and layout
 // in response to a potential screen resize.
+//
+// Graphical drivers may report in addition the size of tiles in pixels and
+// the device pixel ratio, so that the model can take layout decisions, such
+// as switching to a compact interface when tiles are large. Those fields are
+// zero when the information is not available, as is the case with terminal
+// drivers.
 type MsgScreen struct {
 	Width  int       // screen width in cells
 	Height int       // screen height in cells
 	Time   time.Time // time when the event was generated
+
+	TileWidth  int     // tile width in pixels, if known
+	TileHeight int     // tile height in pixels, if known
+	PixelRatio float64 // device pixel ratio (physical pixels per logical pixel), if known
 }
 
 // MsgInit is a special message that is always sent first to Update after
@@ -171,6 +305,36 @@ type MsgQuit time.Time
 // is manually produced by the End() command.
 type msgEnd struct{}
 
+// msgEndAfterDraw is an internal message used to end the application's Start
+// loop after a final Draw. It is produced by the EndAfterDraw command.
+type msgEndAfterDraw struct {
+	fn func()
+}
+
 // msgBatch is an internal message used to perform a bunch of effects. You can
 // send a msgBatch with Batch.
 type msgBatch []Effect
+
+// msgSetClipboard is an internal message used to set the clipboard contents.
+// It is produced by the SetClipboard command.
+type msgSetClipboard string
+
+// msgRequestClipboard is an internal message used to request the clipboard
+// contents. It is produced by the RequestClipboard command.
+type msgRequestClipboard struct{}
+
+// msgShowCursor is an internal message used to show the hardware cursor. It
+// is produced by the ShowCursor command.
+type msgShowCursor struct{ p Point }
+
+// msgHideCursor is an internal message used to hide the hardware cursor. It
+// is produced by the HideCursor command.
+type msgHideCursor struct{}
+
+// msgAttachMirror is an internal message used to attach a mirror driver. It
+// is produced by the AttachMirror command.
+type msgAttachMirror struct{ dr Driver }
+
+// msgDetachMirror is an internal message used to detach a mirror driver. It
+// is produced by the DetachMirror command.
+type msgDetachMirror struct{ dr Driver }
@@ -0,0 +1,83 @@
+package gruid
+
+import "time"
+
+// MouseFilterConfig contains configuration options for a MouseFilter.
+type MouseFilterConfig struct {
+	// NoDrag drops mouse motion events while a button is pressed, so that
+	// models do not have to distinguish clicks from drags.
+	NoDrag bool
+
+	// MultiClick is the maximum delay between two main button presses at
+	// the same position for them to be reported as a double or triple
+	// click. If zero, the Clicks field of messages is not filled.
+	MultiClick time.Duration
+
+	// MotionInterval is the minimum interval between two reported mouse
+	// motion events. Intermediate motion events are dropped. If zero, all
+	// motion events are reported.
+	MotionInterval time.Duration
+}
+
+// MouseFilter transforms the stream of mouse events reported by a driver,
+// according to the options in its configuration. It is intended to be used
+// by drivers, so that every model does not have to reconstruct double clicks
+// or filter drag motion, but it can be used in models too.
+//
+// With MultiClick, the Clicks field of messages counts successive main button
+// presses within the given delay at the same position: 1 for a single click, 2
+// for a double click and 3 for a triple click. There are no separate double or
+// triple click actions: every press is still reported as MouseMain, so that
+// enabling MultiClick does not break models and ui widgets that only handle
+// MouseMain, which would otherwise silently miss fast repeated clicks.
+//
+// MouseFilter uses the Time field of messages, so drivers should fill it.
+type MouseFilter struct {
+	cfg      MouseFilterConfig
+	pressed  bool      // a button is pressed
+	clicks   int       // number of successive main button presses
+	lastP    Point     // position of the last main button press
+	lastT    time.Time // time of the last main button press
+	lastMove time.Time // time of the last reported motion
+}
+
+// NewMouseFilter returns a new mouse filter with the given configuration.
+func NewMouseFilter(cfg MouseFilterConfig) *MouseFilter {
+	return &MouseFilter{cfg: cfg}
+}
+
+// Filter returns the mouse message to be reported for a given one, and
+// whether it should be reported at all.
+func (mf *MouseFilter) Filter(msg MsgMouse) (MsgMouse, bool) {
+	switch msg.Action {
+	case MouseMain:
+		mf.pressed = true
+		if mf.cfg.MultiClick <= 0 {
+			break
+		}
+		if mf.clicks > 0 && mf.clicks < 3 && msg.P == mf.lastP && msg.Time.Sub(mf.lastT) <= mf.cfg.MultiClick {
+			mf.clicks++
+		} else {
+			mf.clicks = 1
+		}
+		mf.lastP = msg.P
+		mf.lastT = msg.Time
+		msg.Clicks = mf.clicks
+	case MouseAuxiliary, MouseSecondary:
+		mf.pressed = true
+		mf.clicks = 0
+	case MouseRelease:
+		mf.pressed = false
+	case MouseMove:
+		if mf.cfg.NoDrag && mf.pressed {
+			return msg, false
+		}
+		if mf.cfg.MotionInterval > 0 {
+			if !mf.lastMove.IsZero() && msg.Time.Sub(mf.lastMove) < mf.cfg.MotionInterval {
+				return msg, false
+			}
+			mf.lastMove = msg.Time
+		}
+	}
+	return msg, true
+}
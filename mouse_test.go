@@ -0,0 +1,60 @@
+package gruid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMouseFilterMultiClick(t *testing.T) {
+	mf := NewMouseFilter(MouseFilterConfig{MultiClick: 300 * time.Millisecond})
+	t0 := time.Now()
+	at := func(a MouseAction, p Point, ms int) int {
+		msg, ok := mf.Filter(MsgMouse{Action: a, P: p, Time: t0.Add(time.Duration(ms) * time.Millisecond)})
+		if !ok {
+			t.Errorf("dropped message %v", a)
+		}
+		if msg.Action != a {
+			t.Errorf("bad action: %v (expected %v)", msg.Action, a)
+		}
+		return msg.Clicks
+	}
+	want := []int{1, 0, 2, 0, 3, 0, 1}
+	got := []int{
+		at(MouseMain, Point{1, 1}, 0),
+		at(MouseRelease, Point{1, 1}, 50),
+		at(MouseMain, Point{1, 1}, 200),
+		at(MouseRelease, Point{1, 1}, 250),
+		at(MouseMain, Point{1, 1}, 400),
+		at(MouseRelease, Point{1, 1}, 450),
+		at(MouseMain, Point{1, 1}, 600),
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bad clicks %d: %d (expected %d)", i, got[i], want[i])
+		}
+	}
+	if n := at(MouseMain, Point{2, 1}, 700); n != 1 {
+		t.Errorf("bad clicks at other position: %d", n)
+	}
+	if n := at(MouseMain, Point{2, 1}, 1100); n != 1 {
+		t.Errorf("bad clicks after delay: %d", n)
+	}
+}
+
+func TestMouseFilterMotion(t *testing.T) {
+	mf := NewMouseFilter(MouseFilterConfig{NoDrag: true, MotionInterval: 100 * time.Millisecond})
+	t0 := time.Now()
+	ok := func(a MouseAction, ms int) bool {
+		_, ok := mf.Filter(MsgMouse{Action: a, Time: t0.Add(time.Duration(ms) * time.Millisecond)})
+		return ok
+	}
+	if !ok(MouseMove, 0) || ok(MouseMove, 50) || !ok(MouseMove, 100) {
+		t.Errorf("bad motion throttling")
+	}
+	if !ok(MouseSecondary, 300) || ok(MouseMove, 400) {
+		t.Errorf("drag motion reported")
+	}
+	if !ok(MouseRelease, 500) || !ok(MouseMove, 600) {
+		t.Errorf("motion not reported after release")
+	}
+}
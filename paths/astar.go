@@ -39,7 +39,9 @@ type Astar interface {
 }
 
 // AstarPath returns a path from a position to another, including thoses
-// positions, in the path order. It returns nil if no path was found.
+// positions, in the path order. It returns nil if no path was found. Ties
+// between paths of equal cost are broken randomly if a random number generator
+// was provided with SetRand or SetSeed.
 func (pr *PathRange) AstarPath(ast Astar, from, to gruid.Point) []gruid.Point {
 	if !from.In(pr.Rg) || !to.In(pr.Rg) {
 		return nil
@@ -66,27 +68,14 @@ func (pr *PathRange) AstarPath(ast Astar, from, to gruid.Point) []gruid.Point {
 
 		if n.P == to {
 			// Found a path to the goal.
-			path := []gruid.Point{}
-			pn := n
-			path = append(path, pn.P)
-			for {
-				if pn.P == from {
-					break
-				}
-				pn = nm.at(pr, pn.Parent)
-				path = append(path, pn.P)
-			}
-			for i := range path[:len(path)/2] {
-				path[i], path[len(path)-i-1] = path[len(path)-i-1], path[i]
-			}
-			return path
+			return pr.astarBuildPath(n, from)
 		}
 
 		for _, q := range ast.Neighbors(n.P) {
 			if !q.In(pr.Rg) {
 				continue
 			}
-			cost := n.Cost + ast.Cost(n.P, q)
+			cost := n.Cost + pr.cost(ast, n.P, q)
 			nbNode := nm.get(pr, q)
 			if cost < nbNode.Cost {
 				if nbNode.Open {
@@ -101,10 +90,341 @@ func (pr *PathRange) AstarPath(ast Astar, from, to gruid.Point) []gruid.Point {
 				nbNode.Estimation = ast.Estimation(q, to)
 				nbNode.Rank = cost + nbNode.Estimation
 				nbNode.Parent = n.P
+				if pr.rand != nil {
+					nbNode.Tie = pr.rand.Int()
+				}
+				pqPush(nq, nbNode)
+			}
+		}
+	}
+}
+
+// AstarPathFunc returns a path from a position to the first position found
+// that satisfies the goal predicate, including those positions, in the path
+// order. As there is no fixed destination, no estimation is used, and the
+// returned path is a path of lowest cost to the nearest matching position.
+// It returns nil if no path was found.
+//
+// The optional visit function is called on every position, along with its
+// path cost from the starting position, just before checking it against the
+// goal predicate. If it returns false, the search is aborted and nil is
+// returned. This can be used, for example, to bound the search by cost.
+//
+// It is useful for queries like “path to the nearest item of some kind”,
+// without computing a full Dijkstra map or calling AstarPath for each
+// candidate.
+func (pr *PathRange) AstarPathFunc(dij Dijkstra, from gruid.Point, goal func(gruid.Point) bool, visit func(gruid.Point, int) bool) []gruid.Point {
+	if !from.In(pr.Rg) {
+		return nil
+	}
+	pr.initAstar()
+	nm := pr.AstarNodes
+	nm.Idx++
+	defer checkNodesIdx(nm)
+	nqs := pr.AstarQueue[:0]
+	nq := &nqs
+	pqInit(nq)
+	fromNode := nm.get(pr, from)
+	fromNode.Open = true
+	pqPush(nq, fromNode)
+	for nq.Len() > 0 {
+		n := pqPop(nq)
+		n.Open = false
+		n.Closed = true
+		if visit != nil && !visit(n.P, n.Cost) {
+			return nil
+		}
+		if goal(n.P) {
+			return pr.astarBuildPath(n, from)
+		}
+		for _, q := range dij.Neighbors(n.P) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			cost := n.Cost + pr.cost(dij, n.P, q)
+			nbNode := nm.get(pr, q)
+			if cost < nbNode.Cost {
+				if nbNode.Open {
+					pqRemove(nq, nbNode.Idx)
+				}
+				nbNode.Open = false
+				nbNode.Closed = false
+			}
+			if !nbNode.Open && !nbNode.Closed {
+				nbNode.Cost = cost
+				nbNode.Open = true
+				nbNode.Rank = cost
+				nbNode.Parent = n.P
+				if pr.rand != nil {
+					nbNode.Tie = pr.rand.Int()
+				}
+				pqPush(nq, nbNode)
+			}
+		}
+	}
+	// There's no path.
+	return nil
+}
+
+// AstarPaths returns paths from several positions to a same destination,
+// including those positions, in the path order. The i-th returned path
+// corresponds to the i-th starting position, and is nil if no path was found.
+//
+// Paths are computed with a single reverse Dijkstra search from the
+// destination, stopped as soon as all the starting positions have been
+// reached, which is faster than calling AstarPath for each starting position,
+// as in the typical case of several monsters moving toward the player. As
+// there are several targets, no estimation is used, so any Astar value can be
+// passed. This requires symmetric neighbors and costs: in particular, starting
+// positions should be returned by Neighbors, even if they are occupied.
+func (pr *PathRange) AstarPaths(dij Dijkstra, froms []gruid.Point, to gruid.Point) [][]gruid.Point {
+	paths := make([][]gruid.Point, len(froms))
+	if !to.In(pr.Rg) {
+		return paths
+	}
+	pr.initAstar()
+	nm := pr.AstarNodes
+	nm.Idx++
+	defer checkNodesIdx(nm)
+	remaining := pr.markTargets(froms)
+	if remaining == 0 {
+		return paths
+	}
+	nqs := pr.AstarQueue[:0]
+	nq := &nqs
+	pqInit(nq)
+	toNode := nm.get(pr, to)
+	toNode.Open = true
+	pqPush(nq, toNode)
+	for nq.Len() > 0 && remaining > 0 {
+		n := pqPop(nq)
+		n.Open = false
+		n.Closed = true
+		if n.Target {
+			remaining--
+		}
+		for _, q := range dij.Neighbors(n.P) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			cost := n.Cost + pr.cost(dij, q, n.P)
+			nbNode := nm.get(pr, q)
+			if cost < nbNode.Cost {
+				if nbNode.Open {
+					pqRemove(nq, nbNode.Idx)
+				}
+				nbNode.Open = false
+				nbNode.Closed = false
+			}
+			if !nbNode.Open && !nbNode.Closed {
+				nbNode.Cost = cost
+				nbNode.Open = true
+				nbNode.Rank = cost
+				nbNode.Parent = n.P
+				if pr.rand != nil {
+					nbNode.Tie = pr.rand.Int()
+				}
 				pqPush(nq, nbNode)
 			}
 		}
 	}
+	pr.reversePaths(paths, froms, to)
+	return paths
+}
+
+// markTargets marks the nodes of the given in-range positions as targets. It
+// returns the number of distinct targets.
+func (pr *PathRange) markTargets(ps []gruid.Point) int {
+	nm := pr.AstarNodes
+	count := 0
+	for _, p := range ps {
+		if !p.In(pr.Rg) {
+			continue
+		}
+		n := nm.get(pr, p)
+		if !n.Target {
+			n.Target = true
+			count++
+		}
+	}
+	return count
+}
+
+// reversePaths fills the paths from the given starting positions to the
+// destination of a reverse search, following parents.
+func (pr *PathRange) reversePaths(paths [][]gruid.Point, froms []gruid.Point, to gruid.Point) {
+	nm := pr.AstarNodes
+	for i, from := range froms {
+		if !from.In(pr.Rg) {
+			continue
+		}
+		n := nm.at(pr, from)
+		if n == nil || !n.Closed {
+			continue
+		}
+		path := []gruid.Point{from}
+		for n.P != to {
+			n = nm.at(pr, n.Parent)
+			path = append(path, n.P)
+		}
+		paths[i] = path
+	}
+}
+
+// fromSearch is the state of an incremental search started with BeginFrom.
+type fromSearch struct {
+	dij   Dijkstra
+	src   gruid.Point
+	nodes *nodeMap
+	queue priorityQueue
+}
+
+// BeginFrom starts an incremental search from a source position, such as the
+// player's position, so that several destinations can then be queried with
+// PathTo, reusing the already expanded search tree between calls. This is
+// more efficient than calling AstarPath many times from the same source, for
+// example for mouse hover paths or many AI checks during the same turn.
+//
+// The search is a lazy Dijkstra search: each PathTo call only expands the
+// search as much as needed. The state becomes invalid when the map changes:
+// BeginFrom should then be called again. It is independent from the state of
+// other searches, like AstarPath or DijkstraMap.
+func (pr *PathRange) BeginFrom(dij Dijkstra, src gruid.Point) {
+	fs := pr.from
+	if fs == nil {
+		max := pr.Rg.Size()
+		fs = &fromSearch{
+			nodes: &nodeMap{Nodes: make([]node, max.X*max.Y)},
+			queue: make(priorityQueue, 0, max.X*max.Y),
+		}
+		pr.from = fs
+	}
+	fs.dij = dij
+	fs.src = src
+	nm := fs.nodes
+	nm.Idx++
+	checkNodesIdx(nm)
+	fs.queue = fs.queue[:0]
+	pqInit(&fs.queue)
+	if !src.In(pr.Rg) {
+		return
+	}
+	n := nm.get(pr, src)
+	n.Open = true
+	pqPush(&fs.queue, n)
+}
+
+// PathTo returns a path of lowest cost from the source of the last BeginFrom
+// call to the given position, including those positions, in the path order.
+// It returns nil if no path was found, or if BeginFrom was not called.
+// Ties between paths of equal cost are broken randomly if a random number
+// generator was provided with SetRand or SetSeed.
+func (pr *PathRange) PathTo(to gruid.Point) []gruid.Point {
+	fs := pr.from
+	if fs == nil || !to.In(pr.Rg) {
+		return nil
+	}
+	nm := fs.nodes
+	if n := nm.at(pr, to); n != nil && n.Closed {
+		return pr.buildPath(nm, n, fs.src)
+	}
+	nq := &fs.queue
+	for nq.Len() > 0 {
+		n := pqPop(nq)
+		n.Open = false
+		n.Closed = true
+		for _, q := range fs.dij.Neighbors(n.P) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			nbNode := nm.get(pr, q)
+			if nbNode.Closed {
+				continue
+			}
+			cost := n.Cost + pr.cost(fs.dij, n.P, q)
+			if nbNode.Open {
+				if cost >= nbNode.Cost {
+					continue
+				}
+				pqRemove(nq, nbNode.Idx)
+			}
+			nbNode.Cost = cost
+			nbNode.Open = true
+			nbNode.Rank = cost
+			nbNode.Parent = n.P
+			if pr.rand != nil {
+				nbNode.Tie = pr.rand.Int()
+			}
+			pqPush(nq, nbNode)
+		}
+		if n.P == to {
+			// node neighbors have been expanded, so that the
+			// search can be resumed later
+			return pr.buildPath(nm, n, fs.src)
+		}
+	}
+	return nil
+}
+
+// astarBuildPath returns the path from a position to the given node,
+// following parents.
+func (pr *PathRange) astarBuildPath(n *node, from gruid.Point) []gruid.Point {
+	return pr.buildPath(pr.AstarNodes, n, from)
+}
+
+// buildPath returns the path from a position to the given node, following
+// parents in the given node map.
+func (pr *PathRange) buildPath(nm *nodeMap, n *node, from gruid.Point) []gruid.Point {
+	path := []gruid.Point{}
+	pn := n
+	path = append(path, pn.P)
+	for {
+		if pn.P == from {
+			break
+		}
+		pn = nm.at(pr, pn.Parent)
+		path = append(path, pn.P)
+	}
+	for i := range path[:len(path)/2] {
+		path[i], path[len(path)-i-1] = path[len(path)-i-1], path[i]
+	}
+	return path
+}
+
+// ValidatePath checks whether all the positions of a path are within the
+// range and passable according to the given function. It returns true and -1
+// if the path is valid, or false and the index of the first blocked position
+// otherwise. It can be used to check whether a previously computed path is
+// still valid after some changes in the map, such as a door closing.
+func (pr *PathRange) ValidatePath(path []gruid.Point, passable func(gruid.Point) bool) (bool, int) {
+	for i, p := range path {
+		if !p.In(pr.Rg) || !passable(p) {
+			return false, i
+		}
+	}
+	return true, -1
+}
+
+// RepairPath returns the given path if it is still valid according to
+// ValidatePath. Otherwise, it returns a new path with the same destination,
+// that preserves the prefix of the path up to the position before the first
+// blocked one, and re-plans the rest with AstarPath. It returns nil if the
+// start or the destination are blocked, or if no path was found.
+func (pr *PathRange) RepairPath(ast Astar, path []gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+	ok, i := pr.ValidatePath(path, passable)
+	if ok {
+		return path
+	}
+	if i == 0 || i == len(path)-1 {
+		return nil
+	}
+	tail := pr.AstarPath(ast, path[i-1], path[len(path)-1])
+	if tail == nil {
+		return nil
+	}
+	npath := make([]gruid.Point, 0, i-1+len(tail))
+	npath = append(npath, path[:i-1]...)
+	return append(npath, tail...)
 }
 
 func (pr *PathRange) initAstar() {
@@ -15,7 +15,8 @@ type Dijkstra interface {
 }
 
 // DijkstraMapAt returns the cost associated to a position in the last computed
-// Dijkstra map. It returns maxCost + 1 if the position is out of range.
+// Dijkstra map. It returns maxCost + 1 if the position is out of range or
+// unreachable from the sources.
 func (pr *PathRange) DijkstraMapAt(p gruid.Point) int {
 	n := pr.DijkstraNodes.at(pr, p)
 	if n == nil {
@@ -28,6 +29,12 @@ func (pr *PathRange) DijkstraMapAt(p gruid.Point) int {
 // maximal cost from those sources. It returns a slice with the nodes of the
 // map, in cost increasing order. The resulting slice is cached for efficiency,
 // so future calls to DijkstraMap will invalidate its contents.
+//
+// Contrary to BreadthFirstMap, it honors the costs between adjacent positions
+// given by the Dijkstra interface. It can be used to implement the classic
+// roguelike "Dijkstra maps" technique: for example, a monster can approach
+// the closest source by moving to the neighbor with lowest DijkstraMapAt
+// cost, or flee by moving to the neighbor with highest one.
 func (pr *PathRange) DijkstraMap(dij Dijkstra, sources []gruid.Point, maxCost int) []Node {
 	if pr.DijkstraNodes == nil {
 		pr.DijkstraNodes = &nodeMap{}
@@ -65,7 +72,7 @@ func (pr *PathRange) DijkstraMap(dij Dijkstra, sources []gruid.Point, maxCost in
 			if !q.In(pr.Rg) {
 				continue
 			}
-			cost := n.Cost + dij.Cost(n.P, q)
+			cost := n.Cost + pr.cost(dij, n.P, q)
 			if cost > maxCost {
 				continue
 			}
@@ -93,3 +100,11 @@ type Node struct {
 	P    gruid.Point
 	Cost int
 }
+
+// DijkstraMapIter iterates a function on the nodes of the last computed
+// Dijkstra map, in cost increasing order.
+func (pr *PathRange) DijkstraMapIter(fn func(Node)) {
+	for _, n := range pr.DijkstraIterNodes {
+		fn(n)
+	}
+}
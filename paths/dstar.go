@@ -0,0 +1,293 @@
+// This file implements incremental path planning with the D* Lite algorithm,
+// as described in “D* Lite” by Sven Koenig and Maxim Likhachev (2002).
+
+package paths
+
+import (
+	"container/heap"
+
+	"github.com/anaseto/gruid"
+)
+
+// IncrementalPath is an incremental path planner for an agent that moves
+// toward a fixed destination, using the D* Lite algorithm. After small
+// changes in the map, such as a door opening or a temporary obstacle, the
+// path is updated by only reconsidering the affected positions, instead of
+// being computed again from scratch. This is much faster than calling
+// AstarPath each turn for agents that frequently have to repath.
+//
+// Neighbors are assumed to be adjacent positions, as returned by the methods
+// of Neighbors, and the neighbor relation is assumed to be symmetric: a
+// position p is a neighbor of q if and only if q is a neighbor of p. Costs
+// may be asymmetric.
+type IncrementalPath struct {
+	ast   Astar
+	rg    gruid.Range
+	w     int
+	nodes []dnode
+	queue dqueue
+	start gruid.Point
+	last  gruid.Point // start position at last key modifier update
+	goal  gruid.Point
+	km    int  // key modifier
+	ok    bool // start and goal in range
+	nbs   Neighbors
+}
+
+type dnode struct {
+	g, rhs int
+	key    dkey
+	qidx   int // index in the queue, or -1
+}
+
+type dkey struct {
+	k1, k2 int
+}
+
+func (k dkey) less(l dkey) bool {
+	return k.k1 < l.k1 || k.k1 == l.k1 && k.k2 < l.k2
+}
+
+// dinf represents an infinite cost.
+const dinf = int(^uint(0)>>1) / 4
+
+// NewIncrementalPath returns a new incremental path planner for paths within
+// the path range, from a position to another, using the given A* interface.
+// Its state is independent from the path range's cached structures, so that
+// there can be one planner per agent.
+func (pr *PathRange) NewIncrementalPath(ast Astar, from, to gruid.Point) *IncrementalPath {
+	max := pr.Rg.Size()
+	ip := &IncrementalPath{
+		ast:   ast,
+		rg:    pr.Rg,
+		w:     max.X,
+		nodes: make([]dnode, max.X*max.Y),
+		start: from,
+		last:  from,
+		goal:  to,
+		ok:    from.In(pr.Rg) && to.In(pr.Rg),
+	}
+	for i := range ip.nodes {
+		ip.nodes[i] = dnode{g: dinf, rhs: dinf, qidx: -1}
+	}
+	ip.queue.ip = ip
+	if !ip.ok {
+		return ip
+	}
+	gn := ip.node(to)
+	gn.rhs = 0
+	ip.push(ip.idx(to))
+	return ip
+}
+
+// Start returns the current starting position.
+func (ip *IncrementalPath) Start() gruid.Point {
+	return ip.start
+}
+
+// Goal returns the destination.
+func (ip *IncrementalPath) Goal() gruid.Point {
+	return ip.goal
+}
+
+// SetStart updates the starting position, usually after the agent moved along
+// the path. The search is not recomputed from scratch.
+func (ip *IncrementalPath) SetStart(p gruid.Point) {
+	if !p.In(ip.rg) {
+		ip.ok = false
+		ip.start = p
+		return
+	}
+	if ip.ok {
+		ip.km += ip.ast.Estimation(ip.last, p)
+	}
+	ip.start = p
+	ip.last = p
+	ip.ok = ip.goal.In(ip.rg)
+}
+
+// Changed notifies the planner that the costs of moving from or into the
+// given positions changed, for example because a door was opened or closed
+// there, or because a monster now blocks the way. Only positions that changed
+// should be notified.
+func (ip *IncrementalPath) Changed(ps ...gruid.Point) {
+	for _, p := range ps {
+		if !p.In(ip.rg) {
+			continue
+		}
+		ip.update(p)
+		for _, q := range ip.preds(p) {
+			ip.update(q)
+		}
+	}
+}
+
+// Path returns a path of lowest cost from the starting position to the
+// destination, including those positions, in the path order. It returns nil
+// if there is no path.
+func (ip *IncrementalPath) Path() []gruid.Point {
+	if !ip.ok {
+		return nil
+	}
+	ip.computePath()
+	if ip.node(ip.start).g >= dinf {
+		return nil
+	}
+	path := []gruid.Point{ip.start}
+	p := ip.start
+	for p != ip.goal {
+		if len(path) > len(ip.nodes) {
+			// should not happen with non-negative costs
+			return nil
+		}
+		best, bestc := p, dinf
+		for _, q := range ip.ast.Neighbors(p) {
+			if !q.In(ip.rg) {
+				continue
+			}
+			g := ip.node(q).g
+			if g >= dinf {
+				continue
+			}
+			c := ip.ast.Cost(p, q) + g
+			if c < bestc {
+				best, bestc = q, c
+			}
+		}
+		if best == p {
+			return nil
+		}
+		p = best
+		path = append(path, p)
+	}
+	return path
+}
+
+func (ip *IncrementalPath) idx(p gruid.Point) int {
+	p = p.Sub(ip.rg.Min)
+	return p.Y*ip.w + p.X
+}
+
+func (ip *IncrementalPath) point(i int) gruid.Point {
+	return gruid.Point{X: i % ip.w, Y: i / ip.w}.Add(ip.rg.Min)
+}
+
+func (ip *IncrementalPath) node(p gruid.Point) *dnode {
+	return &ip.nodes[ip.idx(p)]
+}
+
+// preds returns the possible predecessors of a position: the adjacent
+// positions in range.
+func (ip *IncrementalPath) preds(p gruid.Point) []gruid.Point {
+	return ip.nbs.All(p, func(q gruid.Point) bool { return q.In(ip.rg) })
+}
+
+func (ip *IncrementalPath) key(p gruid.Point) dkey {
+	n := ip.node(p)
+	m := n.g
+	if n.rhs < m {
+		m = n.rhs
+	}
+	if m >= dinf {
+		return dkey{k1: dinf, k2: dinf}
+	}
+	return dkey{k1: m + ip.ast.Estimation(ip.start, p) + ip.km, k2: m}
+}
+
+func (ip *IncrementalPath) push(i int) {
+	ip.nodes[i].key = ip.key(ip.point(i))
+	heap.Push(&ip.queue, i)
+}
+
+// update recomputes the right-hand side value of a position from its
+// successors, and updates its state in the queue.
+func (ip *IncrementalPath) update(p gruid.Point) {
+	i := ip.idx(p)
+	n := &ip.nodes[i]
+	if p != ip.goal {
+		rhs := dinf
+		for _, q := range ip.ast.Neighbors(p) {
+			if !q.In(ip.rg) {
+				continue
+			}
+			g := ip.node(q).g
+			if g >= dinf {
+				continue
+			}
+			if c := ip.ast.Cost(p, q) + g; c < rhs {
+				rhs = c
+			}
+		}
+		n.rhs = rhs
+	}
+	if n.qidx >= 0 {
+		heap.Remove(&ip.queue, n.qidx)
+	}
+	if n.g != n.rhs {
+		ip.push(i)
+	}
+}
+
+func (ip *IncrementalPath) computePath() {
+	sn := ip.node(ip.start)
+	for ip.queue.Len() > 0 {
+		i := ip.queue.idxs[0]
+		kold := ip.nodes[i].key
+		if !kold.less(ip.key(ip.start)) && sn.rhs == sn.g {
+			break
+		}
+		heap.Pop(&ip.queue)
+		p := ip.point(i)
+		n := &ip.nodes[i]
+		if knew := ip.key(p); kold.less(knew) {
+			ip.push(i)
+			continue
+		}
+		if n.g > n.rhs {
+			n.g = n.rhs
+			for _, q := range ip.preds(p) {
+				ip.update(q)
+			}
+			continue
+		}
+		n.g = dinf
+		ip.update(p)
+		for _, q := range ip.preds(p) {
+			ip.update(q)
+		}
+	}
+}
+
+// dqueue is a priority queue of node indices, ordered by key.
+type dqueue struct {
+	ip   *IncrementalPath
+	idxs []int
+}
+
+func (dq *dqueue) Len() int {
+	return len(dq.idxs)
+}
+
+func (dq *dqueue) Less(i, j int) bool {
+	return dq.ip.nodes[dq.idxs[i]].key.less(dq.ip.nodes[dq.idxs[j]].key)
+}
+
+func (dq *dqueue) Swap(i, j int) {
+	dq.idxs[i], dq.idxs[j] = dq.idxs[j], dq.idxs[i]
+	dq.ip.nodes[dq.idxs[i]].qidx = i
+	dq.ip.nodes[dq.idxs[j]].qidx = j
+}
+
+func (dq *dqueue) Push(x any) {
+	i := x.(int)
+	dq.ip.nodes[i].qidx = len(dq.idxs)
+	dq.idxs = append(dq.idxs, i)
+}
+
+func (dq *dqueue) Pop() any {
+	n := len(dq.idxs) - 1
+	i := dq.idxs[n]
+	dq.idxs = dq.idxs[:n]
+	dq.ip.nodes[i].qidx = -1
+	return i
+}
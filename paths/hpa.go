@@ -0,0 +1,370 @@
+// This file implements a hierarchical pathfinding algorithm (HPA*). For more
+// information, see the paper “Near Optimal Hierarchical Path-Finding”, by A.
+// Botea, M. Müller and J. Schaeffer (2004).
+
+package paths
+
+import (
+	"container/heap"
+
+	"github.com/anaseto/gruid"
+)
+
+// hpaGraph represents the abstract graph used by HierarchicalPath. Its nodes
+// are portals between adjacent chunks.
+type hpaGraph struct {
+	size   int                 // chunk size
+	nodes  []hpaNode           // abstract nodes
+	chunks [][]int             // abstract nodes indices for each chunk
+	nx     int                 // number of chunks per line
+	local  *PathRange          // chunk-restricted path range
+	pq     hpaQueue            // abstract search queue
+	dist   []int               // abstract search costs
+	parent []int               // abstract search parents
+	closed []bool              // abstract search closed nodes
+	idx    map[gruid.Point]int // portal position to node index
+}
+
+type hpaNode struct {
+	p     gruid.Point
+	edges []hpaEdge
+}
+
+type hpaEdge struct {
+	to   int
+	cost int
+}
+
+// HierarchicalPath returns a path from a position to another, including those
+// positions, in the path order, or nil if no path was found. It uses a
+// hierarchical path-finding algorithm (HPA*): the range is divided in square
+// chunks of a given size, and an abstract graph of portals between adjacent
+// chunks is built and cached, with precomputed costs between the portals of
+// each chunk. Paths are first searched in that small abstract graph, and then
+// refined using chunk-restricted A* searches. Portals are placed on straight
+// crossings between chunks, as well as on diagonal crossings, including those
+// at chunk corners, when they are allowed by the neighbors function and there
+// is no equivalent pair of straight steps.
+//
+// The resulting paths are near optimal, but not optimal in general. The
+// algorithm makes the assumption that paths are bidirectional with symmetric
+// costs. It is mainly useful for repeated queries on big maps (such as
+// 300x300), for which AstarPath can be slow. If chunk size is not positive, a
+// default size of 16 is used.
+//
+// The cached abstract graph has to be invalidated with HierarchyReset after
+// any change in the map passability or costs.
+func (pr *PathRange) HierarchicalPath(ast Astar, from, to gruid.Point, chunk int) []gruid.Point {
+	if !from.In(pr.Rg) || !to.In(pr.Rg) {
+		return nil
+	}
+	if chunk <= 0 {
+		chunk = 16
+	}
+	if pr.hpa == nil || pr.hpa.size != chunk {
+		pr.hpa = &hpaGraph{size: chunk}
+		pr.buildHierarchy(ast)
+	}
+	return pr.hierarchicalPath(ast, from, to)
+}
+
+// HierarchyReset invalidates the abstract graph cached by HierarchicalPath.
+// It should be called after any change in the map that may affect paths.
+func (pr *PathRange) HierarchyReset() {
+	pr.hpa = nil
+}
+
+func (pr *PathRange) chunkIdx(p gruid.Point) int {
+	p = p.Sub(pr.Rg.Min)
+	size := pr.hpa.size
+	return (p.Y/size)*pr.hpa.nx + p.X/size
+}
+
+func (pr *PathRange) chunkRange(i int) gruid.Range {
+	size := pr.hpa.size
+	x, y := (i%pr.hpa.nx)*size, (i/pr.hpa.nx)*size
+	rg := gruid.NewRange(x, y, x+size, y+size).Add(pr.Rg.Min)
+	return rg.Intersect(pr.Rg)
+}
+
+func (pr *PathRange) buildHierarchy(ast Astar) {
+	g := pr.hpa
+	max := pr.Rg.Size()
+	g.nx = (max.X + g.size - 1) / g.size
+	ny := (max.Y + g.size - 1) / g.size
+	g.chunks = make([][]int, g.nx*ny)
+	g.idx = map[gruid.Point]int{}
+	g.local = NewPathRange(pr.chunkRange(0))
+	for i := range g.chunks {
+		rg := pr.chunkRange(i)
+		if rg.Max.X < pr.Rg.Max.X {
+			// border with right chunks, including the diagonal ones
+			col := rg.Column(rg.Size().X - 1)
+			pr.addEntrances(ast, col, gruid.Point{X: 1})
+			pr.addEntrances(ast, col, gruid.Point{X: 1, Y: -1})
+			pr.addEntrances(ast, col, gruid.Point{X: 1, Y: 1})
+		}
+		if rg.Max.Y < pr.Rg.Max.Y {
+			// border with bottom chunk: diagonal crossings at corners
+			// are handled from right borders
+			line := rg.Line(rg.Size().Y - 1)
+			pr.addEntrances(ast, line, gruid.Point{Y: 1})
+			pr.addEntrances(ast, line.Shift(1, 0, 0, 0), gruid.Point{X: -1, Y: 1})
+			pr.addEntrances(ast, line.Shift(0, 0, -1, 0), gruid.Point{X: 1, Y: 1})
+		}
+	}
+	for i, ns := range g.chunks {
+		g.local.SetRange(pr.chunkRange(i))
+		for j, n := range ns {
+			for _, m := range ns[j+1:] {
+				path := g.local.AstarPath(ast, g.nodes[n].p, g.nodes[m].p)
+				if path == nil {
+					continue
+				}
+				pr.addEdge(n, m, pathCost(ast, path))
+				pr.addEdge(m, n, pathCostReverse(ast, path))
+			}
+		}
+	}
+	n := len(g.nodes) + 2
+	g.dist = make([]int, n)
+	g.parent = make([]int, n)
+	g.closed = make([]bool, n)
+}
+
+// addEntrances adds portals for the maximal runs of crossings from the given
+// border range in direction dir towards a same chunk.
+func (pr *PathRange) addEntrances(ast Astar, border gruid.Range, dir gruid.Point) {
+	var ps []gruid.Point
+	start, chunk := -1, -1
+	border.Iter(func(p gruid.Point) {
+		c := -1
+		if q := p.Add(dir); pr.crossing(ast, p, q) {
+			c = pr.chunkIdx(q)
+		}
+		if start >= 0 && c != chunk {
+			pr.addPortal(ast, ps[start:], dir)
+			start = -1
+		}
+		if c >= 0 && start < 0 {
+			start = len(ps)
+			chunk = c
+		}
+		ps = append(ps, p)
+	})
+	if start >= 0 {
+		pr.addPortal(ast, ps[start:], dir)
+	}
+}
+
+// crossing reports whether a step from p to q is a crossing that should be
+// considered for portals. Diagonal crossings are ignored if there is an
+// equivalent pair of straight steps.
+func (pr *PathRange) crossing(ast Astar, p, q gruid.Point) bool {
+	if !q.In(pr.Rg) || !crossable(ast, p, q) {
+		return false
+	}
+	if p.X == q.X || p.Y == q.Y {
+		return true
+	}
+	for _, r := range [2]gruid.Point{{q.X, p.Y}, {p.X, q.Y}} {
+		if crossable(ast, p, r) && crossable(ast, r, q) {
+			return false
+		}
+	}
+	return true
+}
+
+// addPortal adds a portal in the middle of a run of border positions ps, with
+// crossings in direction dir.
+func (pr *PathRange) addPortal(ast Astar, ps []gruid.Point, dir gruid.Point) {
+	p := ps[(len(ps)-1)/2]
+	q := p.Add(dir)
+	n := pr.addNode(p)
+	m := pr.addNode(q)
+	pr.addEdge(n, m, ast.Cost(p, q))
+	pr.addEdge(m, n, ast.Cost(q, p))
+}
+
+func (pr *PathRange) addNode(p gruid.Point) int {
+	g := pr.hpa
+	if i, ok := g.idx[p]; ok {
+		return i
+	}
+	i := len(g.nodes)
+	chunk := pr.chunkIdx(p)
+	g.nodes = append(g.nodes, hpaNode{p: p})
+	g.chunks[chunk] = append(g.chunks[chunk], i)
+	g.idx[p] = i
+	return i
+}
+
+func (pr *PathRange) addEdge(from, to, cost int) {
+	n := &pr.hpa.nodes[from]
+	n.edges = append(n.edges, hpaEdge{to: to, cost: cost})
+}
+
+// crossable reports whether there is a bidirectional step between p and q.
+func crossable(ast Astar, p, q gruid.Point) bool {
+	return containsPoint(ast.Neighbors(p), q) && containsPoint(ast.Neighbors(q), p)
+}
+
+func containsPoint(ps []gruid.Point, p gruid.Point) bool {
+	for _, q := range ps {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+func pathCost(ast Astar, path []gruid.Point) int {
+	cost := 0
+	for i := 0; i < len(path)-1; i++ {
+		cost += ast.Cost(path[i], path[i+1])
+	}
+	return cost
+}
+
+func pathCostReverse(ast Astar, path []gruid.Point) int {
+	cost := 0
+	for i := len(path) - 1; i > 0; i-- {
+		cost += ast.Cost(path[i], path[i-1])
+	}
+	return cost
+}
+
+func (pr *PathRange) hierarchicalPath(ast Astar, from, to gruid.Point) []gruid.Point {
+	g := pr.hpa
+	start, goal := len(g.nodes), len(g.nodes)+1
+	fchunk, tchunk := pr.chunkIdx(from), pr.chunkIdx(to)
+	// temporary edges from start, and towards goal
+	var sedges []hpaEdge
+	gcosts := map[int]int{}
+	g.local.SetRange(pr.chunkRange(fchunk))
+	for _, n := range g.chunks[fchunk] {
+		if path := g.local.AstarPath(ast, from, g.nodes[n].p); path != nil {
+			sedges = append(sedges, hpaEdge{to: n, cost: pathCost(ast, path)})
+		}
+	}
+	if fchunk == tchunk {
+		if path := g.local.AstarPath(ast, from, to); path != nil {
+			sedges = append(sedges, hpaEdge{to: goal, cost: pathCost(ast, path)})
+		}
+	}
+	g.local.SetRange(pr.chunkRange(tchunk))
+	for _, n := range g.chunks[tchunk] {
+		if path := g.local.AstarPath(ast, g.nodes[n].p, to); path != nil {
+			gcosts[n] = pathCost(ast, path)
+		}
+	}
+	position := func(i int) gruid.Point {
+		switch i {
+		case start:
+			return from
+		case goal:
+			return to
+		}
+		return g.nodes[i].p
+	}
+	for i := range g.dist {
+		g.dist[i] = -1
+		g.closed[i] = false
+	}
+	g.pq = g.pq[:0]
+	g.dist[start] = 0
+	heap.Push(&g.pq, hpaItem{i: start, rank: ast.Estimation(from, to)})
+	relax := func(n, m, cost int) {
+		if g.closed[m] {
+			return
+		}
+		c := g.dist[n] + cost
+		if g.dist[m] >= 0 && g.dist[m] <= c {
+			return
+		}
+		g.dist[m] = c
+		g.parent[m] = n
+		heap.Push(&g.pq, hpaItem{i: m, rank: c + ast.Estimation(position(m), to)})
+	}
+	found := false
+	for g.pq.Len() > 0 {
+		n := heap.Pop(&g.pq).(hpaItem).i
+		if g.closed[n] {
+			continue
+		}
+		g.closed[n] = true
+		if n == goal {
+			found = true
+			break
+		}
+		var edges []hpaEdge
+		if n == start {
+			edges = sedges
+		} else {
+			edges = g.nodes[n].edges
+		}
+		for _, e := range edges {
+			relax(n, e.to, e.cost)
+		}
+		if cost, ok := gcosts[n]; ok {
+			relax(n, goal, cost)
+		}
+	}
+	if !found {
+		return nil
+	}
+	var abstract []int
+	for n := goal; n != start; n = g.parent[n] {
+		abstract = append(abstract, n)
+	}
+	abstract = append(abstract, start)
+	path := []gruid.Point{from}
+	for i := len(abstract) - 1; i > 0; i-- {
+		p, q := position(abstract[i]), position(abstract[i-1])
+		if p == q {
+			continue
+		}
+		pchunk := pr.chunkIdx(p)
+		if pchunk != pr.chunkIdx(q) {
+			// inter-chunk portal step
+			path = append(path, q)
+			continue
+		}
+		g.local.SetRange(pr.chunkRange(pchunk))
+		lpath := g.local.AstarPath(ast, p, q)
+		path = append(path, lpath[1:]...)
+	}
+	return path
+}
+
+type hpaItem struct {
+	i    int // abstract node index
+	rank int // cost plus estimation
+}
+
+// hpaQueue implements heap.Interface.
+type hpaQueue []hpaItem
+
+func (q hpaQueue) Len() int {
+	return len(q)
+}
+
+func (q hpaQueue) Less(i, j int) bool {
+	return q[i].rank < q[j].rank
+}
+
+func (q hpaQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *hpaQueue) Push(x interface{}) {
+	*q = append(*q, x.(hpaItem))
+}
+
+func (q *hpaQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	*q = old[0 : n-1]
+	return it
+}
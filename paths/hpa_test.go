@@ -0,0 +1,86 @@
+package paths
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func checkPath(t *testing.T, ap apath, path []gruid.Point, from, to gruid.Point) {
+	if path[0] != from || path[len(path)-1] != to {
+		t.Errorf("bad path extremities: %v", path)
+	}
+	for i := 0; i < len(path)-1; i++ {
+		if !containsPoint(ap.Neighbors(path[i]), path[i+1]) {
+			t.Errorf("bad path step %v -> %v", path[i], path[i+1])
+		}
+	}
+}
+
+func TestHierarchicalPath(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	for _, diags := range []bool{false, true} {
+		ap := apath{nb: &Neighbors{}, passable: passable2, diags: diags}
+		for _, chunk := range []int{5, 8, 16} {
+			for i := 0; i < 300; i++ {
+				from := gruid.Point{rand.Intn(80), rand.Intn(24)}
+				to := gruid.Point{rand.Intn(80), rand.Intn(24)}
+				if !passable2(from) || !passable2(to) {
+					continue
+				}
+				patha := pr.AstarPath(ap, from, to)
+				path := pr.HierarchicalPath(ap, from, to, chunk)
+				if (path == nil) != (patha == nil) {
+					t.Errorf("bad path existence (chunk %d): %v vs %v", chunk, path, patha)
+					continue
+				}
+				if path == nil {
+					continue
+				}
+				checkPath(t, ap, path, from, to)
+				if len(path) < len(patha) || len(path) > 2*len(patha)+2*chunk {
+					t.Errorf("bad path length (chunk %d): %d vs %d", chunk, len(path), len(patha))
+				}
+			}
+		}
+	}
+	if pr.HierarchicalPath(apath{nb: &Neighbors{}, passable: passable2}, gruid.Point{-1, 0}, gruid.Point{2, 2}, 0) != nil {
+		t.Errorf("path from out of range position")
+	}
+}
+
+func TestHierarchicalPathDiagonal(t *testing.T) {
+	// walls leaving only a diagonal crossing from p to p.Shift(1, 1)
+	wall := func(p gruid.Point) func(gruid.Point) bool {
+		return func(q gruid.Point) bool {
+			return q.X == p.X+1 && q.Y != p.Y+1 || q.X == p.X && q.Y > p.Y
+		}
+	}
+	for _, p := range []gruid.Point{{4, 2}, {4, 4}, {9, 9}} {
+		blocked := wall(p)
+		ap := apath{nb: &Neighbors{}, passable: func(q gruid.Point) bool { return !blocked(q) }, diags: true}
+		for _, chunk := range []int{5, 10} {
+			pr := NewPathRange(gruid.NewRange(0, 0, 20, 20))
+			from, to := gruid.Point{0, 0}, gruid.Point{19, 19}
+			patha := pr.AstarPath(ap, from, to)
+			if patha == nil {
+				t.Fatalf("no A* path (crossing %v)", p)
+			}
+			path := pr.HierarchicalPath(ap, from, to, chunk)
+			if path == nil {
+				t.Errorf("no hierarchical path (crossing %v, chunk %d)", p, chunk)
+				continue
+			}
+			checkPath(t, ap, path, from, to)
+		}
+	}
+}
+
+func BenchmarkHierarchicalPath(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	ap := apath{nb: &Neighbors{}, passable: passable1, diags: true}
+	for i := 0; i < b.N; i++ {
+		pr.HierarchicalPath(ap, gruid.Point{X: 2, Y: 2}, gruid.Point{X: 70, Y: 20}, 0)
+	}
+}
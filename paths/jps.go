@@ -87,6 +87,74 @@ func (pr *PathRange) JPSPath(path []gruid.Point, from, to gruid.Point, passable
 	}
 }
 
+// JPSPaths returns paths from several positions to a same destination, like
+// AstarPaths, but with the same uniform costs and grid geometry as JPSPath.
+// The i-th returned path corresponds to the i-th starting position, and is
+// nil if no path was found. As with JPSPath, starting positions do not need to
+// be passable, but the destination does.
+//
+// Paths are computed with a single reverse breadth first search from the
+// destination, whose cost does not depend much on the number of starting
+// positions. As JPSPath is very fast, calling it for each starting position
+// is usually faster when there are only a few dozens of them or less.
+func (pr *PathRange) JPSPaths(froms []gruid.Point, to gruid.Point, passable func(gruid.Point) bool, diags bool) [][]gruid.Point {
+	paths := make([][]gruid.Point, len(froms))
+	if !to.In(pr.Rg) || !passable(to) {
+		return paths
+	}
+	pr.initAstar()
+	nm := pr.AstarNodes
+	nm.Idx++
+	defer checkNodesIdx(nm)
+	remaining := pr.markTargets(froms)
+	keep := func(p gruid.Point) bool {
+		if passable(p) {
+			return true
+		}
+		if !p.In(pr.Rg) {
+			return false
+		}
+		n := nm.at(pr, p)
+		return n != nil && n.Target
+	}
+	nb := &Neighbors{}
+	queue := pr.AstarQueue[:0]
+	toNode := nm.get(pr, to)
+	toNode.Closed = true
+	queue = append(queue, toNode)
+	for i := 0; i < len(queue) && remaining > 0; i++ {
+		n := queue[i]
+		if n.Target {
+			remaining--
+			if !passable(n.P) {
+				// non-passable starting positions are only path ends
+				continue
+			}
+		}
+		var neighbors []gruid.Point
+		if diags {
+			neighbors = nb.All(n.P, keep)
+		} else {
+			neighbors = nb.Cardinal(n.P, keep)
+		}
+		for _, q := range neighbors {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			nbNode := nm.get(pr, q)
+			if nbNode.Closed {
+				continue
+			}
+			nbNode.Closed = true
+			nbNode.Parent = n.P
+			queue = append(queue, nbNode)
+		}
+	}
+	pr.AstarQueue = queue[:0]
+	pr.reversePaths(paths, froms, to)
+	return paths
+}
+
 func (pr *PathRange) expandOrigin(from, to gruid.Point) {
 	for y := -1; y <= 1; y++ {
 		for x := -1; x <= 1; x++ {
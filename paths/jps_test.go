@@ -155,6 +155,46 @@ func TestPassableBorder(t *testing.T) {
 	//fmt.Printf("%s\n\n", logrid)
 }
 
+func TestJPSPaths(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	for _, diags := range []bool{true, false} {
+		froms := []gruid.Point{}
+		for i := 0; i < 20; i++ {
+			froms = append(froms, gruid.Point{rand.Intn(80), rand.Intn(24)})
+		}
+		froms = append(froms, gruid.Point{-1, 0}, gruid.Point{70, 12})
+		to := gruid.Point{70, 13}
+		paths := pr.JPSPaths(froms, to, passable2, diags)
+		for i, from := range froms {
+			path := pr.JPSPath([]gruid.Point{}, from, to, passable2, diags)
+			if len(path) != len(paths[i]) {
+				t.Errorf("bad path length from %v (diags: %v): %d vs %d", from, diags, len(paths[i]), len(path))
+				continue
+			}
+			if path == nil {
+				continue
+			}
+			if paths[i][0] != from || paths[i][len(path)-1] != to {
+				t.Errorf("bad path ends: %v", paths[i])
+			}
+			if ok, j := pr.ValidatePath(paths[i][1:], passable2); !ok {
+				t.Errorf("invalid path at %d: %v", j, paths[i])
+			}
+		}
+	}
+}
+
+func BenchmarkJPSPaths(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	froms := []gruid.Point{}
+	for i := 0; i < 20; i++ {
+		froms = append(froms, gruid.Point{rand.Intn(80), rand.Intn(24)})
+	}
+	for i := 0; i < b.N; i++ {
+		pr.JPSPaths(froms, gruid.Point{70, 13}, passable2, true)
+	}
+}
+
 func BenchmarkJPS(b *testing.B) {
 	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
 	path := []gruid.Point{}
@@ -208,3 +248,70 @@ func BenchmarkPassable2RandAstar(b *testing.B) {
 	}
 	//fmt.Printf("%s\n\n", logrid)
 }
+
+func TestJPSPlus(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	if pr.JPSPlusPath(nil, gruid.Point{2, 2}, gruid.Point{70, 20}) != nil {
+		t.Errorf("path without precomputation")
+	}
+	rd := rand.New(rand.NewSource(42))
+	walls := map[gruid.Point]bool{}
+	for i := 0; i < 500; i++ {
+		walls[gruid.Point{rd.Intn(80), rd.Intn(24)}] = true
+	}
+	passable3 := func(p gruid.Point) bool { return !walls[p] }
+	for _, passable := range []func(gruid.Point) bool{passable1, passable2, passable3} {
+		pr.JPSPlusBuild(passable)
+		ap := apath{nb: &Neighbors{}, passable: passable, diags: true}
+		for i := 0; i < 200; i++ {
+			from := gruid.Point{rd.Intn(80), rd.Intn(24)}
+			to := gruid.Point{rd.Intn(80), rd.Intn(24)}
+			path := pr.JPSPlusPath(nil, from, to)
+			patha := pr.AstarPath(ap, from, to)
+			if len(path) != len(patha) {
+				t.Errorf("bad path length from %v to %v: %d vs %d", from, to, len(path), len(patha))
+				continue
+			}
+			if path == nil {
+				continue
+			}
+			if path[0] != from || path[len(path)-1] != to {
+				t.Errorf("bad path ends: %v", path)
+			}
+			if ok, j := pr.ValidatePath(path[1:], passable); !ok {
+				t.Errorf("invalid path at %d: %v", j, path)
+			}
+		}
+	}
+	pr.JPSPlusReset()
+	if pr.JPSPlusPath(nil, gruid.Point{2, 2}, gruid.Point{70, 20}) != nil {
+		t.Errorf("path after reset")
+	}
+}
+
+func BenchmarkJPSPlusPassable1(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	pr.JPSPlusBuild(passable1)
+	path := []gruid.Point{}
+	for i := 0; i < b.N; i++ {
+		path = pr.JPSPlusPath(path, gruid.Point{X: 2, Y: 2}, gruid.Point{X: 70, Y: 20})
+	}
+}
+
+func BenchmarkPassable2RandJPSPlus(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	pr.JPSPlusBuild(passable2)
+	path := []gruid.Point{}
+	for i := 0; i < b.N; i++ {
+		from := gruid.Point{rand.Intn(80), rand.Intn(24)}
+		to := gruid.Point{rand.Intn(80), rand.Intn(24)}
+		path = pr.JPSPlusPath(path, from, to)
+	}
+}
+
+func BenchmarkJPSPlusBuild(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	for i := 0; i < b.N; i++ {
+		pr.JPSPlusBuild(passable2)
+	}
+}
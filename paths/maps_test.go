@@ -1,7 +1,10 @@
 package paths
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/anaseto/gruid"
 )
@@ -54,6 +57,21 @@ func TestPathMaps(t *testing.T) {
 			}
 		}
 	}
+	count := 0
+	cost := 0
+	pr.DijkstraMapIter(func(n Node) {
+		if n.Cost < cost {
+			t.Errorf("bad dijkstra iteration order: %d after %d", n.Cost, cost)
+		}
+		if n.Cost != pr.DijkstraMapAt(n.P) {
+			t.Errorf("bad dijkstra iteration cost %d for %+v", n.Cost, n.P)
+		}
+		cost = n.Cost
+		count++
+	})
+	if count != len(pr.DijkstraIterNodes) {
+		t.Errorf("bad dijkstra iteration count: %d", count)
+	}
 	for _, n := range pr.BreadthFirstMap(nb, []gruid.Point{{X: 2, Y: 0}, {X: 2, Y: 2}}, 4) {
 		for _, pc := range poscosts {
 			if pc.p == n.P && pc.cost != n.Cost {
@@ -138,6 +156,135 @@ func (ap apath) Estimation(p, q gruid.Point) int {
 	//return abs(p.X) + abs(p.Y)
 }
 
+func TestAstarSeed(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 10, 10))
+	ap := apath{nb: &Neighbors{}, passable: func(p gruid.Point) bool { return true }}
+	from, to := gruid.Point{1, 1}, gruid.Point{6, 6}
+	paths := map[string]bool{}
+	for seed := int64(0); seed < 20; seed++ {
+		pr.SetSeed(seed)
+		path := fmt.Sprint(pr.AstarPath(ap, from, to))
+		pr.SetSeed(seed)
+		if path2 := fmt.Sprint(pr.AstarPath(ap, from, to)); path != path2 {
+			t.Errorf("not reproducible path: %s vs %s", path, path2)
+		}
+		if n := len(pr.AstarPath(ap, from, to)); n != 11 {
+			t.Errorf("bad path length: %d", n)
+		}
+		paths[path] = true
+	}
+	if len(paths) < 2 {
+		t.Errorf("no path variety")
+	}
+}
+
+func TestRepairPath(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 10, 10))
+	door := gruid.Point{5, 2}
+	open := true
+	passable := func(p gruid.Point) bool { return open || p != door }
+	ap := apath{nb: &Neighbors{}, passable: passable}
+	path := pr.AstarPath(ap, gruid.Point{2, 2}, gruid.Point{8, 2})
+	if ok, i := pr.ValidatePath(path, passable); !ok || i != -1 {
+		t.Errorf("invalid path: %d", i)
+	}
+	if len(path) != 7 {
+		t.Fatalf("bad path length: %d", len(path))
+	}
+	open = false
+	ok, i := pr.ValidatePath(path, passable)
+	if ok || i != 3 {
+		t.Errorf("bad blocked index: %d", i)
+	}
+	npath := pr.RepairPath(ap, path, passable)
+	if npath == nil || npath[0] != path[0] || npath[len(npath)-1] != path[len(path)-1] || len(npath) != 9 {
+		t.Errorf("bad repaired path: %v", npath)
+	}
+	if ok, _ := pr.ValidatePath(npath, passable); !ok {
+		t.Errorf("repaired path not valid: %v", npath)
+	}
+	if fmt.Sprint(npath[:3]) != fmt.Sprint(path[:3]) {
+		t.Errorf("prefix not preserved: %v", npath)
+	}
+}
+
+func TestAstarPathFunc(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 10, 10))
+	wall := gruid.NewRange(4, 0, 5, 8)
+	passable := func(p gruid.Point) bool { return !p.In(wall) }
+	ap := apath{nb: &Neighbors{}, passable: passable}
+	items := map[gruid.Point]bool{{6, 1}: true, {1, 9}: true}
+	goal := func(p gruid.Point) bool { return items[p] }
+	path := pr.AstarPathFunc(ap, gruid.Point{2, 2}, goal, nil)
+	if len(path) != 9 || path[0] != (gruid.Point{2, 2}) || path[len(path)-1] != (gruid.Point{1, 9}) {
+		t.Errorf("bad path: %v", path)
+	}
+	visited := 0
+	visit := func(p gruid.Point, c int) bool {
+		visited++
+		return c <= 5
+	}
+	if path := pr.AstarPathFunc(ap, gruid.Point{2, 2}, goal, visit); path != nil {
+		t.Errorf("path found beyond bound: %v", path)
+	}
+	if visited == 0 {
+		t.Errorf("no visited nodes")
+	}
+	if path := pr.AstarPathFunc(ap, gruid.Point{2, 2}, func(gruid.Point) bool { return false }, nil); path != nil {
+		t.Errorf("unexpected path: %v", path)
+	}
+}
+
+func TestCostOverlay(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 10, 5))
+	ap := apath{nb: &Neighbors{}, passable: func(gruid.Point) bool { return true }}
+	from, to := gruid.Point{0, 2}, gruid.Point{9, 2}
+	if path := pr.AstarPath(ap, from, to); len(path) != 10 {
+		t.Errorf("bad path without overlay: %v", path)
+	}
+	danger := gruid.NewRange(3, 2, 7, 3)
+	pr.SetCostOverlay(func(p gruid.Point) int {
+		if p.In(danger) {
+			return 10
+		}
+		return 0
+	})
+	path := pr.AstarPath(ap, from, to)
+	if len(path) != 12 {
+		t.Errorf("bad path length with overlay: %v", path)
+	}
+	for _, p := range path {
+		if p.In(danger) {
+			t.Errorf("path through dangerous position %v: %v", p, path)
+		}
+	}
+	pr.DijkstraMap(ap, []gruid.Point{from}, 20)
+	if c := pr.DijkstraMapAt(to); c != 11 {
+		t.Errorf("bad dijkstra cost with overlay: %d", c)
+	}
+	if c := pr.DijkstraMapAt(gruid.Point{4, 2}); c != 4+2+10 {
+		t.Errorf("bad dijkstra cost in dangerous position: %d", c)
+	}
+	pr.SetCostOverlay(nil)
+	if path := pr.AstarPath(ap, from, to); len(path) != 10 {
+		t.Errorf("bad path after removing overlay: %v", path)
+	}
+}
+
+func TestAstarPaths(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	ap := apath{nb: &Neighbors{}, passable: passable1, diags: true}
+	froms := []gruid.Point{{0, 23}, {10, 5}, {79, 0}, {45, 12}, {30, 20}}
+	to := gruid.Point{50, 3}
+	paths := pr.AstarPaths(ap, froms, to)
+	for i, from := range froms {
+		path := pr.AstarPath(ap, from, to)
+		if len(path) != len(paths[i]) {
+			t.Errorf("bad path length from %v: %d vs %d", from, len(paths[i]), len(path))
+		}
+	}
+}
+
 func BenchmarkAstarPassable1(b *testing.B) {
 	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
 	ap := apath{nb: &Neighbors{}, passable: passable1, diags: true}
@@ -153,3 +300,119 @@ func BenchmarkAstarPassable1NoDiags(b *testing.B) {
 		pr.AstarPath(ap, gruid.Point{X: 2, Y: 2}, gruid.Point{X: 70, Y: 20})
 	}
 }
+
+func TestPathTo(t *testing.T) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	ap := apath{nb: &Neighbors{}, passable: passable1, diags: true}
+	if pr.PathTo(gruid.Point{1, 1}) != nil {
+		t.Errorf("path without BeginFrom")
+	}
+	from := gruid.Point{50, 3}
+	pr.BeginFrom(ap, from)
+	tos := []gruid.Point{{51, 3}, {0, 23}, {10, 5}, {50, 3}, {79, 0}, {45, 12}, {30, 20}, {10, 5}}
+	for _, to := range tos {
+		path := pr.PathTo(to)
+		apath := pr.AstarPath(ap, from, to)
+		if len(path) != len(apath) {
+			t.Errorf("bad path length to %v: %d vs %d", to, len(path), len(apath))
+		}
+		if len(path) > 0 && (path[0] != from || path[len(path)-1] != to) {
+			t.Errorf("bad path ends to %v: %v", to, path)
+		}
+		if ok, i := pr.ValidatePath(path, passable1); !ok {
+			t.Errorf("invalid path to %v at %d", to, i)
+		}
+	}
+	pr.BeginFrom(ap, gruid.Point{0, 23})
+	if path := pr.PathTo(gruid.Point{50, 3}); len(path) != len(pr.AstarPath(ap, gruid.Point{0, 23}, gruid.Point{50, 3})) {
+		t.Errorf("bad path length after new BeginFrom: %d", len(path))
+	}
+}
+
+func BenchmarkPathToMany(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	ap := apath{nb: &Neighbors{}, passable: passable1, diags: true}
+	for i := 0; i < b.N; i++ {
+		pr.BeginFrom(ap, gruid.Point{2, 2})
+		for x := 0; x < 80; x += 4 {
+			pr.PathTo(gruid.Point{x, 20})
+		}
+	}
+}
+
+func BenchmarkAstarMany(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	ap := apath{nb: &Neighbors{}, passable: passable1, diags: true}
+	for i := 0; i < b.N; i++ {
+		for x := 0; x < 80; x += 4 {
+			pr.AstarPath(ap, gruid.Point{2, 2}, gruid.Point{x, 20})
+		}
+	}
+}
+
+func TestIncrementalPath(t *testing.T) {
+	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rg := gruid.NewRange(0, 0, 30, 20)
+	blocked := map[gruid.Point]bool{}
+	passable := func(p gruid.Point) bool { return !blocked[p] }
+	for _, diags := range []bool{false, true} {
+		for k := range blocked {
+			delete(blocked, k)
+		}
+		pr := NewPathRange(rg)
+		ap := apath{nb: &Neighbors{}, passable: passable, diags: diags}
+		from, to := gruid.Point{0, 0}, gruid.Point{29, 19}
+		ip := pr.NewIncrementalPath(ap, from, to)
+		for i := 0; i < 200; i++ {
+			var changed []gruid.Point
+			for j := 0; j < 5; j++ {
+				p := gruid.Point{rd.Intn(30), rd.Intn(20)}
+				if p == to || p == ip.Start() {
+					continue
+				}
+				blocked[p] = !blocked[p]
+				changed = append(changed, p)
+			}
+			ip.Changed(changed...)
+			path := ip.Path()
+			want := pr.AstarPath(ap, ip.Start(), to)
+			if len(path) != len(want) {
+				t.Fatalf("bad path length (diags: %v): %d vs %d", diags, len(path), len(want))
+			}
+			if ok, _ := pr.ValidatePath(path, passable); !ok {
+				t.Fatalf("invalid path: %v", path)
+			}
+			if len(path) > 2 && rd.Intn(2) == 0 {
+				ip.SetStart(path[1])
+			}
+		}
+	}
+}
+
+func BenchmarkIncrementalPath(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	door := gruid.Point{X: 40, Y: 12}
+	open := false
+	passable := func(p gruid.Point) bool { return passable1(p) && (open || p != door) }
+	ap := apath{nb: &Neighbors{}, passable: passable, diags: true}
+	ip := pr.NewIncrementalPath(ap, gruid.Point{X: 2, Y: 2}, gruid.Point{X: 70, Y: 20})
+	ip.Path()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		open = !open
+		ip.Changed(door)
+		ip.Path()
+	}
+}
+
+func BenchmarkIncrementalPathAstar(b *testing.B) {
+	pr := NewPathRange(gruid.NewRange(0, 0, 80, 24))
+	door := gruid.Point{X: 40, Y: 12}
+	open := false
+	passable := func(p gruid.Point) bool { return passable1(p) && (open || p != door) }
+	ap := apath{nb: &Neighbors{}, passable: passable, diags: true}
+	for i := 0; i < b.N; i++ {
+		open = !open
+		pr.AstarPath(ap, gruid.Point{X: 2, Y: 2}, gruid.Point{X: 70, Y: 20})
+	}
+}
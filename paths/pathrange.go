@@ -5,6 +5,7 @@ package paths
 import (
 	"bytes"
 	"encoding/gob"
+	"math/rand"
 
 	"github.com/anaseto/gruid"
 )
@@ -20,6 +21,11 @@ type PathRange struct {
 type pathRange struct {
 	diags               bool                   // JPS diagonal movement
 	passable            func(gruid.Point) bool // JPS passable function
+	hpa                 *hpaGraph              // HierarchicalPath cache
+	jpsPlus             *jpsPlusTable          // JPSPlusPath jump distances
+	rand                *rand.Rand             // optional A* tie-breaking
+	overlay             func(gruid.Point) int  // optional extra costs
+	from                *fromSearch            // BeginFrom incremental search
 	AstarNodes          *nodeMap
 	DijkstraNodes       *nodeMap // dijkstra map
 	DijkstraIterNodes   []Node
@@ -74,14 +80,65 @@ func NewPathRange(rg gruid.Range) *PathRange {
 // cached structures will be preserved, otherwise they will be reinitialized.
 func (pr *PathRange) SetRange(rg gruid.Range) {
 	pr.Rg = rg
+	pr.hpa = nil
+	pr.jpsPlus = nil
+	pr.from = nil
 	max := rg.Size()
 	if max.X*max.Y <= pr.Capacity {
 		return
 	}
 	npr := NewPathRange(rg)
+	npr.rand = pr.rand
+	npr.overlay = pr.overlay
 	*pr = *npr
 }
 
+// SetRand sets a random number generator used by AstarPath to break ties
+// between nodes of equal rank randomly, instead of always in the same way.
+// This gives some variety among paths of equal cost, for example for
+// patrolling monsters, while keeping results reproducible when using a
+// generator with a fixed seed. A nil value restores deterministic
+// tie-breaking, which is the default.
+//
+// The generator is not serialized with the path range.
+func (pr *PathRange) SetRand(rd *rand.Rand) {
+	pr.rand = rd
+}
+
+// SetSeed is a shorthand for SetRand with a new generator using the given
+// seed.
+func (pr *PathRange) SetSeed(seed int64) {
+	pr.rand = rand.New(rand.NewSource(seed))
+}
+
+// SetCostOverlay sets a function returning an extra cost for moving into a
+// position, that is added to the Cost of the Astar or Dijkstra value passed to
+// the path finding functions. It can be used, for example, to make monsters
+// prefer safe routes according to some danger map computed each turn, without
+// changing their pathing implementation. Negative extra costs are ignored, so
+// that A* estimations remain valid. A nil value removes the overlay.
+//
+// The overlay is used by AstarPath, AstarPathFunc, AstarPaths, BeginFrom and
+// DijkstraMap. It is not used by algorithms that ignore costs, or cache
+// structures depending on them, such as JPSPath, HierarchicalPath or
+// IncrementalPath. As for map changes, BeginFrom should be called again after
+// changing the overlay. The overlay is not serialized with the path range.
+func (pr *PathRange) SetCostOverlay(fn func(gruid.Point) int) {
+	pr.overlay = fn
+}
+
+// cost returns the cost of moving from p to adjacent q, including the
+// overlay's extra cost, if any.
+func (pr *PathRange) cost(dij Dijkstra, p, q gruid.Point) int {
+	c := dij.Cost(p, q)
+	if pr.overlay != nil {
+		if e := pr.overlay(q); e > 0 {
+			c += e
+		}
+	}
+	return c
+}
+
 // Range returns the current PathRange's range of positions.
 func (pr *PathRange) Range() gruid.Range {
 	return pr.Rg
@@ -118,6 +175,8 @@ type node struct {
 	Rank       int
 	Idx        int
 	Estimation int
+	Tie        int  // random tie-breaker
+	Target     bool // target of a multi-target search
 	CacheIndex int
 }
 
@@ -134,7 +193,8 @@ func (pq priorityQueue) Len() int {
 }
 
 func (pq priorityQueue) Less(i, j int) bool {
-	return pq[i].Rank < pq[j].Rank || pq[i].Rank == pq[j].Rank && pq[i].Estimation < pq[j].Estimation
+	return pq[i].Rank < pq[j].Rank || pq[i].Rank == pq[j].Rank &&
+		(pq[i].Estimation < pq[j].Estimation || pq[i].Estimation == pq[j].Estimation && pq[i].Tie < pq[j].Tie)
 }
 
 func (pq priorityQueue) Swap(i, j int) {
@@ -0,0 +1,180 @@
+// Package pathstest provides utilities for testing path finding algorithms,
+// such as custom Pather or Astar implementations, by comparing their results
+// with the ones of a reference A* search on random maps.
+package pathstest
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/paths"
+)
+
+// RandomMap returns a passable function for a random map within a range,
+// where each position is an obstacle with a given probability, between 0 and
+// 1. Positions out of the range are not passable. The map is generated using
+// the given random number generator, so it is reproducible.
+func RandomMap(rd *rand.Rand, rg gruid.Range, density float64) func(gruid.Point) bool {
+	max := rg.Size()
+	walls := make([]bool, max.X*max.Y)
+	for i := range walls {
+		walls[i] = rd.Float64() < density
+	}
+	return func(p gruid.Point) bool {
+		if !p.In(rg) {
+			return false
+		}
+		q := p.Sub(rg.Min)
+		return !walls[q.Y*max.X+q.X]
+	}
+}
+
+// Grid is an Astar implementation for maps with uniform costs, where moves
+// are allowed between adjacent passable positions. It can be used as a
+// reference.
+type Grid struct {
+	Passable func(gruid.Point) bool // passable positions
+	Diags    bool                   // allow diagonal moves
+
+	nbs paths.Neighbors
+}
+
+// Neighbors implements paths.Pather.Neighbors.
+func (gd *Grid) Neighbors(p gruid.Point) []gruid.Point {
+	if gd.Diags {
+		return gd.nbs.All(p, gd.Passable)
+	}
+	return gd.nbs.Cardinal(p, gd.Passable)
+}
+
+// Cost implements paths.Dijkstra.Cost. It returns 1.
+func (gd *Grid) Cost(p, q gruid.Point) int {
+	return 1
+}
+
+// Estimation implements paths.Astar.Estimation. It returns the Chebyshev
+// distance if diagonal moves are allowed, or the Manhattan distance
+// otherwise.
+func (gd *Grid) Estimation(p, q gruid.Point) int {
+	if gd.Diags {
+		return paths.DistanceChebyshev(p, q)
+	}
+	return paths.DistanceManhattan(p, q)
+}
+
+// PathFunc computes a path from a position to another, including both, in a
+// map described by a passable function, using the given path range. It
+// should return nil if there is no path.
+type PathFunc func(pr *paths.PathRange, from, to gruid.Point, passable func(gruid.Point) bool) []gruid.Point
+
+// Config contains configuration options for Compare.
+type Config struct {
+	Range   gruid.Range // map range (default: 80x24)
+	Maps    int         // number of random maps (default: 10)
+	Paths   int         // number of random paths per map (default: 50)
+	Density float64     // obstacle density (default: 0.25)
+	Seed    int64       // random seed for maps and paths
+
+	// Reference returns the reference A* implementation for a given map.
+	// Path costs and moves are checked according to it. If nil, a Grid
+	// with cardinal moves is used.
+	Reference func(passable func(gruid.Point) bool) paths.Astar
+}
+
+// Compare computes paths between random positions on random maps, both with
+// the given path function and with AstarPath, using the reference A*
+// implementation. It returns an error describing the first discrepancy, if
+// any: the path function did not find a path when there is one, or the
+// converse, its path does not start or end at the requested positions,
+// contains a move that is not between reference neighbors, or has a higher
+// cost than the reference path.
+//
+// It is typically used in tests of custom path finding implementations:
+//
+//	err := pathstest.Compare(pathstest.Config{Seed: 42}, fn)
+//	if err != nil {
+//		t.Error(err)
+//	}
+func Compare(cfg Config, fn PathFunc) error {
+	if cfg.Range.Empty() {
+		cfg.Range = gruid.NewRange(0, 0, 80, 24)
+	}
+	if cfg.Maps <= 0 {
+		cfg.Maps = 10
+	}
+	if cfg.Paths <= 0 {
+		cfg.Paths = 50
+	}
+	if cfg.Density <= 0 {
+		cfg.Density = 0.25
+	}
+	if cfg.Reference == nil {
+		cfg.Reference = func(passable func(gruid.Point) bool) paths.Astar {
+			return &Grid{Passable: passable}
+		}
+	}
+	rd := rand.New(rand.NewSource(cfg.Seed))
+	pr := paths.NewPathRange(cfg.Range)
+	rpr := paths.NewPathRange(cfg.Range)
+	max := cfg.Range.Size()
+	randPoint := func() gruid.Point {
+		return cfg.Range.Min.Add(gruid.Point{rd.Intn(max.X), rd.Intn(max.Y)})
+	}
+	for i := 0; i < cfg.Maps; i++ {
+		passable := RandomMap(rd, cfg.Range, cfg.Density)
+		ref := cfg.Reference(passable)
+		for j := 0; j < cfg.Paths; j++ {
+			from, to := randPoint(), randPoint()
+			want := rpr.AstarPath(ref, from, to)
+			got := fn(pr, from, to, passable)
+			if err := check(ref, from, to, got, want); err != nil {
+				return fmt.Errorf("map %d, path %d from %v to %v: %v", i, j, from, to, err)
+			}
+		}
+	}
+	return nil
+}
+
+func check(ref paths.Astar, from, to gruid.Point, got, want []gruid.Point) error {
+	switch {
+	case got == nil && want == nil:
+		return nil
+	case got == nil:
+		return fmt.Errorf("no path found, but reference found %v", want)
+	case want == nil:
+		return fmt.Errorf("reference found no path, but got %v", got)
+	case got[0] != from || got[len(got)-1] != to:
+		return fmt.Errorf("bad path ends: %v", got)
+	}
+	gcost, err := pathCost(ref, got)
+	if err != nil {
+		return err
+	}
+	wcost, _ := pathCost(ref, want)
+	if gcost > wcost {
+		return fmt.Errorf("path cost %d greater than reference cost %d: %v", gcost, wcost, got)
+	}
+	return nil
+}
+
+// pathCost returns the cost of a path according to an Astar implementation,
+// or an error if it contains an invalid move.
+func pathCost(ref paths.Astar, path []gruid.Point) (int, error) {
+	cost := 0
+	for i := 1; i < len(path); i++ {
+		p, q := path[i-1], path[i]
+		ok := false
+		for _, nb := range ref.Neighbors(p) {
+			if nb == q {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("invalid move from %v to %v", p, q)
+		}
+		cost += ref.Cost(p, q)
+	}
+	return cost, nil
+}
@@ -0,0 +1,84 @@
+package pathstest_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/paths"
+	"github.com/anaseto/gruid/paths/pathstest"
+)
+
+func TestRandomMap(t *testing.T) {
+	rg := gruid.NewRange(2, 3, 30, 20)
+	pass1 := pathstest.RandomMap(rand.New(rand.NewSource(1)), rg, 0.3)
+	pass2 := pathstest.RandomMap(rand.New(rand.NewSource(1)), rg, 0.3)
+	walls := 0
+	rg.Iter(func(p gruid.Point) {
+		if pass1(p) != pass2(p) {
+			t.Errorf("non reproducible map at %v", p)
+		}
+		if !pass1(p) {
+			walls++
+		}
+	})
+	if walls == 0 || walls == rg.Size().X*rg.Size().Y {
+		t.Errorf("bad number of walls: %d", walls)
+	}
+	if pass1(gruid.Point{1, 3}) || pass1(gruid.Point{30, 19}) {
+		t.Errorf("out of range position is passable")
+	}
+}
+
+func TestCompareJPS(t *testing.T) {
+	for _, diags := range []bool{false, true} {
+		diags := diags
+		cfg := pathstest.Config{Seed: 42}
+		cfg.Reference = func(passable func(gruid.Point) bool) paths.Astar {
+			return &pathstest.Grid{Passable: passable, Diags: diags}
+		}
+		err := pathstest.Compare(cfg, func(pr *paths.PathRange, from, to gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+			return pr.JPSPath(nil, from, to, passable, diags)
+		})
+		if err != nil {
+			t.Errorf("diags %v: %v", diags, err)
+		}
+	}
+}
+
+func TestCompareJPSPlus(t *testing.T) {
+	cfg := pathstest.Config{Seed: 7, Range: gruid.NewRange(0, 0, 40, 30)}
+	cfg.Reference = func(passable func(gruid.Point) bool) paths.Astar {
+		return &pathstest.Grid{Passable: passable, Diags: true}
+	}
+	err := pathstest.Compare(cfg, func(pr *paths.PathRange, from, to gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+		pr.JPSPlusBuild(passable)
+		return pr.JPSPlusPath(nil, from, to)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompareBad(t *testing.T) {
+	// a path function that ignores obstacles
+	err := pathstest.Compare(pathstest.Config{Seed: 1}, func(pr *paths.PathRange, from, to gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+		path := []gruid.Point{from}
+		for p := from; p != to; path = append(path, p) {
+			switch {
+			case p.X < to.X:
+				p.X++
+			case p.X > to.X:
+				p.X--
+			case p.Y < to.Y:
+				p.Y++
+			default:
+				p.Y--
+			}
+		}
+		return path
+	})
+	if err == nil {
+		t.Error("no error for invalid path function")
+	}
+}
@@ -1,65 +1,404 @@
 package gruid
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"time"
 )
 
+// FrameHeader contains metadata recorded at the start of a frame recording
+// stream. It allows tools to identify recordings and adapt to their format.
+type FrameHeader struct {
+	// Version is the version of the recording format. It is filled
+	// automatically when recording. Newer versions may add fields to the
+	// header, but remain readable by older decoders. Recordings made
+	// before the introduction of the header have version zero.
+	Version int
+
+	Width  int       // grid width at the start of the recording (automatic)
+	Height int       // grid height at the start of the recording (automatic)
+	Time   time.Time // creation time (automatic)
+
+	Name     string            // optional application name
+	Metadata map[string]string // optional free-form metadata
+}
+
+// FrameFormatVersion is the current version of the frame recording format.
+const FrameFormatVersion = 1
+
+// frameMagic identifies the start of a frame recording stream with a header.
+// Older recordings start directly with the gzip magic number.
+const frameMagic = "GRUIDFR\n"
+
+// maxFrameHeader is the maximum size in bytes of an encoded header.
+const maxFrameHeader = 1 << 20
+
 // FrameDecoder manages the decoding of the frame recording stream produced by
 // the running of an application, in case a FrameWriter was provided. It can be
 // used to replay an application session.
 type FrameDecoder struct {
-	gzr *gzip.Reader
-	gbd *gob.Decoder
+	r      io.Reader
+	br     *bufio.Reader
+	header FrameHeader
+	start  int64 // offset of the first frame in the stream
+	gzr    *gzip.Reader
+	gbd    *gob.Decoder
+	index  []frameIndexEntry
+	n      int           // number of the next frame to be decoded
+	last   time.Time     // time of the last decoded frame
+	runes  map[rune]rune // recorded grapheme runes to local ones
+}
+
+// frameIndexEntry describes the start of an independent chunk of frames in
+// the recording stream. The first frame of a chunk is a key frame, that
+// contains all the cells of the grid.
+type frameIndexEntry struct {
+	Offset int64     // offset of the chunk in the stream
+	Frame  int       // number of the first frame in the chunk
+	Time   time.Time // time of the first frame in the chunk
 }
 
+// frameIndexInterval is the number of frames in each independent chunk of
+// frames, when an index is recorded.
+const frameIndexInterval = 128
+
 // NewFrameDecoder returns a FrameDecoder using a given reader as source for
 // frames.
 //
 // It is your responsibility to call Close on the reader when done.
 func NewFrameDecoder(r io.Reader) (*FrameDecoder, error) {
-	fd := &FrameDecoder{}
-	var err error
-	fd.gzr, err = gzip.NewReader(r)
+	fd := &FrameDecoder{r: r}
+	fd.br = bufio.NewReader(r)
+	err := fd.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	fd.gzr, err = gzip.NewReader(fd.br)
 	if err != nil {
 		return nil, fmt.Errorf("frame decoding: gzip: %v", err)
 	}
+	fd.gzr.Multistream(false)
 	fd.gbd = gob.NewDecoder(fd.gzr)
 	return fd, nil
 }
 
+// readHeader reads the stream header, if any. The header is made of a magic
+// string, followed by the big-endian uint32 size of the gob-encoded header.
+func (fd *FrameDecoder) readHeader() error {
+	magic, err := fd.br.Peek(len(frameMagic))
+	if err != nil || string(magic) != frameMagic {
+		// recording without header
+		return nil
+	}
+	fd.br.Discard(len(frameMagic))
+	var size uint32
+	err = binary.Read(fd.br, binary.BigEndian, &size)
+	if err != nil {
+		return fmt.Errorf("frame decoding: header: %v", err)
+	}
+	if size > maxFrameHeader {
+		return errors.New("frame decoding: header too large")
+	}
+	buf := make([]byte, size)
+	_, err = io.ReadFull(fd.br, buf)
+	if err != nil {
+		return fmt.Errorf("frame decoding: header: %v", err)
+	}
+	err = gob.NewDecoder(bytes.NewReader(buf)).Decode(&fd.header)
+	if err != nil {
+		return fmt.Errorf("frame decoding: header: %v", err)
+	}
+	fd.start = int64(len(frameMagic)+4) + int64(size)
+	return nil
+}
+
+// Header returns the header of the recording. For recordings made before the
+// introduction of headers, the returned header is zero.
+func (fd *FrameDecoder) Header() FrameHeader {
+	return fd.header
+}
+
 // Decode retrieves the next frame from the input stream. The frame pointer
 // should be non nil. If the input is at EOF, it returns the error io.EOF.
 func (fd *FrameDecoder) Decode(framep *Frame) error {
 	if framep == nil {
 		return errors.New("frame decoding: attempt to decode into nil pointer")
 	}
-	var err error
-	for err = fd.gbd.Decode(&framep); err != nil && err != io.EOF; {
-		err = fd.gbd.Decode(&framep)
+	err := fd.decode(framep)
+	if err == nil {
+		fd.n++
+		fd.last = framep.Time
 	}
 	return err
 }
 
+func (fd *FrameDecoder) decode(framep *Frame) error {
+	for {
+		// reset fields that may be omitted in the encoding
+		framep.Cells = framep.Cells[:0]
+		framep.Graphemes = nil
+		framep.Inputs = nil
+		err := fd.gbd.Decode(framep)
+		if err == nil {
+			fd.mapGraphemes(framep)
+		}
+		if err != io.EOF {
+			return err
+		}
+		// end of a chunk of frames: continue with next one, if any
+		err = fd.gzr.Reset(fd.br)
+		if err != nil {
+			return err
+		}
+		fd.gzr.Multistream(false)
+		fd.gbd = gob.NewDecoder(fd.gzr)
+	}
+}
+
+// mapGraphemes registers the grapheme clusters defined in a decoded frame,
+// and replaces the recorded grapheme runes with the local ones, which may
+// differ.
+func (fd *FrameDecoder) mapGraphemes(framep *Frame) {
+	for r, s := range framep.Graphemes {
+		if fd.runes == nil {
+			fd.runes = map[rune]rune{}
+		}
+		fd.runes[r] = Grapheme(s)
+	}
+	framep.Graphemes = nil
+	if len(fd.runes) == 0 {
+		return
+	}
+	for i, c := range framep.Cells {
+		if c.Cell.Rune < graphemeMin {
+			continue
+		}
+		if r, ok := fd.runes[c.Cell.Rune]; ok {
+			framep.Cells[i].Cell.Rune = r
+		}
+	}
+}
+
+// LoadIndex reads a frame index recorded during an application session with
+// an AppConfig.FrameIndexWriter. The index allows for efficient seeking with
+// SeekFrame and SeekTo in long recordings, provided the frame source reader
+// implements io.Seeker.
+func (fd *FrameDecoder) LoadIndex(r io.Reader) error {
+	gbd := gob.NewDecoder(r)
+	var index []frameIndexEntry
+	for {
+		e := frameIndexEntry{}
+		err := gbd.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("frame index decoding: %v", err)
+		}
+		index = append(index, e)
+	}
+	fd.index = index
+	return nil
+}
+
+// SeekFrame moves the decoder so that next call to Decode returns the closest
+// key frame whose number is lower or equal to n, and returns its number. Frames
+// normally only contain the changes from the previous frame, but key frames
+// contain all the grid cells, so the state of the screen at frame n can be
+// reconstructed by decoding the frames starting from the returned one.
+//
+// If the next frame to be decoded is already between that key frame and n,
+// the decoder does not move, and the number of the next frame is returned
+// instead, so that decoding can simply continue forward. In particular,
+// without index, the only key frame is the first one, so the decoding only
+// restarts from the beginning when n is before the current frame. Seeking
+// requires the source reader to implement io.Seeker.
+func (fd *FrameDecoder) SeekFrame(n int) (int, error) {
+	i := sort.Search(len(fd.index), func(i int) bool { return fd.index[i].Frame > n }) - 1
+	return fd.seekEntry(i, func() bool { return fd.n <= n })
+}
+
+// SeekTo is similar to SeekFrame, but it searches for the closest key frame
+// whose time is before or equal to the given time. The decoder does not move
+// if the last decoded frame is after that key frame, but not after t.
+func (fd *FrameDecoder) SeekTo(t time.Time) (int, error) {
+	i := sort.Search(len(fd.index), func(i int) bool { return fd.index[i].Time.After(t) }) - 1
+	return fd.seekEntry(i, func() bool { return !fd.last.After(t) })
+}
+
+// seekEntry seeks to the start of the chunk described by the index entry i,
+// or the first chunk if i is negative, unless the current position is after
+// the chunk start and forward reports that the target is not before it.
+func (fd *FrameDecoder) seekEntry(i int, forward func() bool) (int, error) {
+	e := frameIndexEntry{Offset: fd.start}
+	if i >= 0 {
+		e = fd.index[i]
+	}
+	if e.Frame == fd.n || e.Frame < fd.n && forward() {
+		return fd.n, nil
+	}
+	s, ok := fd.r.(io.Seeker)
+	if !ok {
+		return fd.n, errors.New("frame decoding: seeking requires an io.Seeker source")
+	}
+	_, err := s.Seek(e.Offset, io.SeekStart)
+	if err != nil {
+		return fd.n, fmt.Errorf("frame decoding: seek: %v", err)
+	}
+	fd.br.Reset(fd.r)
+	err = fd.gzr.Reset(fd.br)
+	if err != nil {
+		return fd.n, fmt.Errorf("frame decoding: gzip: %v", err)
+	}
+	fd.gzr.Multistream(false)
+	fd.gbd = gob.NewDecoder(fd.gzr)
+	fd.n = e.Frame
+	fd.last = time.Time{}
+	return fd.n, nil
+}
+
 type frameEncoder struct {
+	w   *countWriter
+	hdr *FrameHeader // header to be written before the first frame, if any
 	gzw *gzip.Writer
 	gbe *gob.Encoder
+	idx *gob.Encoder  // optional index encoder
+	n   int           // number of encoded frames
+	gms map[rune]bool // grapheme runes already defined in current chunk
+}
+
+// countWriter is an io.Writer that counts the number of written bytes.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
 }
 
-func newFrameEncoder(w io.Writer) *frameEncoder {
-	fe := &frameEncoder{}
-	fe.gzw = gzip.NewWriter(w)
+func newFrameEncoder(w io.Writer, idxw io.Writer, hdr FrameHeader) *frameEncoder {
+	fe := &frameEncoder{hdr: &hdr}
+	fe.w = &countWriter{w: w}
+	fe.gzw = gzip.NewWriter(fe.w)
 	fe.gbe = gob.NewEncoder(fe.gzw)
+	if idxw != nil {
+		fe.idx = gob.NewEncoder(idxw)
+	}
 	return fe
 }
 
+// keyFrame reports whether the next frame to be encoded should be a key frame
+// containing all the grid cells.
+func (fe *frameEncoder) keyFrame() bool {
+	return fe.idx != nil && fe.n%frameIndexInterval == 0
+}
+
+// writeHeader writes the stream header, if not done already. The grid size
+// and time are taken from the first frame.
+func (fe *frameEncoder) writeHeader(fr Frame) error {
+	if fe.hdr == nil {
+		return nil
+	}
+	hdr := *fe.hdr
+	fe.hdr = nil
+	hdr.Version = FrameFormatVersion
+	hdr.Width = fr.Width
+	hdr.Height = fr.Height
+	hdr.Time = fr.Time
+	if hdr.Time.IsZero() {
+		hdr.Time = time.Now()
+	}
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(hdr)
+	if err != nil {
+		return err
+	}
+	if buf.Len() > maxFrameHeader {
+		return errors.New("header too large")
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(buf.Len()))
+	for _, bs := range [][]byte{[]byte(frameMagic), size[:], buf.Bytes()} {
+		_, err = fe.w.Write(bs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close writes the header, if not done yet, and closes the gzip stream.
+func (fe *frameEncoder) close() error {
+	err := fe.writeHeader(Frame{})
+	if err != nil {
+		return err
+	}
+	return fe.gzw.Close()
+}
+
 func (fe *frameEncoder) encode(fr Frame) error {
-	err := fe.gbe.Encode(fr)
+	err := fe.writeHeader(fr)
+	if err != nil {
+		return err
+	}
+	if fe.keyFrame() {
+		if fe.n > 0 {
+			// start a new independent chunk of frames
+			err := fe.gzw.Close()
+			if err != nil {
+				return err
+			}
+			fe.gzw.Reset(fe.w)
+			fe.gbe = gob.NewEncoder(fe.gzw)
+			for r := range fe.gms {
+				delete(fe.gms, r)
+			}
+		}
+		err := fe.idx.Encode(frameIndexEntry{Offset: fe.w.n, Frame: fe.n, Time: fr.Time})
+		if err != nil {
+			return err
+		}
+	}
+	fr.Graphemes = fe.graphemes(fr)
+	err = fe.gbe.Encode(fr)
 	if err != nil {
 		return err
 	}
+	fe.n++
 	return nil
 }
+
+// graphemes returns the definitions of the grapheme runes used in the frame
+// that were not yet defined in the current chunk, if any.
+func (fe *frameEncoder) graphemes(fr Frame) map[rune]string {
+	var gms map[rune]string
+	for _, c := range fr.Cells {
+		r := c.Cell.Rune
+		if r < graphemeMin || fe.gms[r] {
+			continue
+		}
+		s, ok := graphemeString(r)
+		if !ok {
+			continue
+		}
+		if fe.gms == nil {
+			fe.gms = map[rune]bool{}
+		}
+		fe.gms[r] = true
+		if gms == nil {
+			gms = map[rune]string{}
+		}
+		gms[r] = s
+	}
+	return gms
+}
@@ -0,0 +1,201 @@
+package rl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/bits"
+
+	"github.com/anaseto/gruid"
+)
+
+// BitGrid is a compact boolean layer, using one bit per cell. It is suitable
+// for flags associated with map positions, such as explored or visible
+// positions, or scent trails, using less memory than a Grid or a Layer8. It
+// provides logical operations between bit grids, such as And and Or, which are
+// performed on whole words at once for grids of the same size.
+//
+// Unlike Grid, BitGrid is not a slice type: it always represents the whole
+// range with positions (X,Y) with 0 <= X < w and 0 <= Y < h. BitGrid elements
+// must be created with NewBitGrid.
+//
+// BitGrid implements gob.Decoder and gob.Encoder for easy serialization.
+type BitGrid struct {
+	innerBitGrid
+}
+
+type innerBitGrid struct {
+	Words  []uint64
+	Width  int
+	Height int
+}
+
+// NewBitGrid returns a new bit grid with given width and height in cells,
+// with all cells unset.
+func NewBitGrid(w, h int) *BitGrid {
+	if w < 0 || h < 0 {
+		panic(fmt.Sprintf("negative dimensions: NewBitGrid(%d,%d)", w, h))
+	}
+	bg := &BitGrid{}
+	bg.Width = w
+	bg.Height = h
+	bg.Words = make([]uint64, (w*h+63)/64)
+	return bg
+}
+
+// GobDecode implements gob.GobDecoder.
+func (bg *BitGrid) GobDecode(bs []byte) error {
+	r := bytes.NewReader(bs)
+	gd := gob.NewDecoder(r)
+	ibg := &innerBitGrid{}
+	err := gd.Decode(ibg)
+	if err != nil {
+		return err
+	}
+	bg.innerBitGrid = *ibg
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder.
+func (bg *BitGrid) GobEncode() ([]byte, error) {
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	err := ge.Encode(&bg.innerBitGrid)
+	return buf.Bytes(), err
+}
+
+// Size returns the grid (width, height) in cells.
+func (bg *BitGrid) Size() gruid.Point {
+	return gruid.Point{bg.Width, bg.Height}
+}
+
+// Range returns the range of the grid positions.
+func (bg *BitGrid) Range() gruid.Range {
+	return gruid.Range{Max: bg.Size()}
+}
+
+// Contains returns true if the given position is within the grid range.
+func (bg *BitGrid) Contains(p gruid.Point) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < bg.Width && p.Y < bg.Height
+}
+
+// Set sets the cell at the given position if b is true, and unsets it
+// otherwise. If the position is out of range, the function does nothing.
+func (bg *BitGrid) Set(p gruid.Point, b bool) {
+	if !bg.Contains(p) {
+		return
+	}
+	i := p.Y*bg.Width + p.X
+	if b {
+		bg.Words[i/64] |= 1 << (i % 64)
+	} else {
+		bg.Words[i/64] &^= 1 << (i % 64)
+	}
+}
+
+// At reports whether the cell at the given position is set. It returns false
+// if the position is out of range.
+func (bg *BitGrid) At(p gruid.Point) bool {
+	if !bg.Contains(p) {
+		return false
+	}
+	i := p.Y*bg.Width + p.X
+	return bg.Words[i/64]&(1<<(i%64)) != 0
+}
+
+// Fill sets all the cells if b is true, and unsets them otherwise.
+func (bg *BitGrid) Fill(b bool) {
+	var w uint64
+	if b {
+		w = ^w
+	}
+	for i := range bg.Words {
+		bg.Words[i] = w
+	}
+	bg.clearTail()
+}
+
+// clearTail unsets the unused bits of the last word, so that they do not
+// count as set cells.
+func (bg *BitGrid) clearTail() {
+	if n := bg.Width * bg.Height % 64; n > 0 {
+		bg.Words[len(bg.Words)-1] &= 1<<n - 1
+	}
+}
+
+// Count returns the number of set cells.
+func (bg *BitGrid) Count() int {
+	count := 0
+	for _, w := range bg.Words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// Iter iterates a function on all the grid positions whose cell is set.
+func (bg *BitGrid) Iter(fn func(gruid.Point)) {
+	for i, w := range bg.Words {
+		for w != 0 {
+			j := i*64 + bits.TrailingZeros64(w)
+			fn(gruid.Point{j % bg.Width, j / bg.Width})
+			w &= w - 1
+		}
+	}
+}
+
+// Copy copies the cells of a source bit grid src into bg, and returns the
+// copied size, which is the minimum of both grids for each dimension.
+func (bg *BitGrid) Copy(src *BitGrid) gruid.Point {
+	if bg.Size() == src.Size() {
+		copy(bg.Words, src.Words)
+		return bg.Size()
+	}
+	return bg.combine(src, func(a, b bool) bool { return b })
+}
+
+// And unsets the cells of bg that are not set in src, within the common
+// range of both grids. It returns the size of that range.
+func (bg *BitGrid) And(src *BitGrid) gruid.Point {
+	if bg.Size() == src.Size() {
+		for i, w := range src.Words {
+			bg.Words[i] &= w
+		}
+		return bg.Size()
+	}
+	return bg.combine(src, func(a, b bool) bool { return a && b })
+}
+
+// Or sets the cells of bg that are set in src, within the common range of
+// both grids, as when marking visible positions as explored. It returns the
+// size of that range.
+func (bg *BitGrid) Or(src *BitGrid) gruid.Point {
+	if bg.Size() == src.Size() {
+		for i, w := range src.Words {
+			bg.Words[i] |= w
+		}
+		return bg.Size()
+	}
+	return bg.combine(src, func(a, b bool) bool { return a || b })
+}
+
+// Not inverts all the cells of the grid.
+func (bg *BitGrid) Not() {
+	for i, w := range bg.Words {
+		bg.Words[i] = ^w
+	}
+	bg.clearTail()
+}
+
+// combine updates the cells of bg within the common range of both grids,
+// using the given function of the cells of bg and src. It returns the size of
+// the common range.
+func (bg *BitGrid) combine(src *BitGrid, fn func(a, b bool) bool) gruid.Point {
+	max := bg.Range().Intersect(src.Range()).Size()
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			p := gruid.Point{x, y}
+			bg.Set(p, fn(bg.At(p), src.At(p)))
+		}
+	}
+	return max
+}
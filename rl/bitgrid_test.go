@@ -0,0 +1,93 @@
+package rl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestBitGrid(t *testing.T) {
+	bg := NewBitGrid(13, 7)
+	if bg.Count() != 0 {
+		t.Errorf("bad initial count: %d", bg.Count())
+	}
+	p := gruid.Point{12, 6}
+	bg.Set(p, true)
+	bg.Set(gruid.Point{13, 0}, true)
+	if !bg.At(p) || bg.At(gruid.Point{0, 0}) || bg.At(gruid.Point{13, 0}) {
+		t.Errorf("bad cells")
+	}
+	if bg.Count() != 1 {
+		t.Errorf("bad count: %d", bg.Count())
+	}
+	bg.Not()
+	if bg.Count() != 13*7-1 || bg.At(p) {
+		t.Errorf("bad count after Not: %d", bg.Count())
+	}
+	bg.Fill(true)
+	if bg.Count() != 13*7 {
+		t.Errorf("bad count after Fill: %d", bg.Count())
+	}
+	bg.Set(p, false)
+	n := 0
+	bg.Iter(func(q gruid.Point) {
+		if !bg.At(q) || q == p {
+			t.Errorf("bad iterated position: %v", q)
+		}
+		n++
+	})
+	if n != 13*7-1 {
+		t.Errorf("bad number of iterated positions: %d", n)
+	}
+}
+
+func TestBitGridOps(t *testing.T) {
+	explored := NewBitGrid(10, 10)
+	visible := NewBitGrid(10, 10)
+	visible.Set(gruid.Point{1, 1}, true)
+	visible.Set(gruid.Point{2, 1}, true)
+	explored.Set(gruid.Point{5, 5}, true)
+	explored.Or(visible)
+	if explored.Count() != 3 || !explored.At(gruid.Point{2, 1}) {
+		t.Errorf("bad Or: %d", explored.Count())
+	}
+	explored.And(visible)
+	if explored.Count() != 2 || explored.At(gruid.Point{5, 5}) {
+		t.Errorf("bad And: %d", explored.Count())
+	}
+	small := NewBitGrid(2, 2)
+	small.Fill(true)
+	if max := explored.Copy(small); max != (gruid.Point{2, 2}) {
+		t.Errorf("bad copied size: %v", max)
+	}
+	if explored.Count() != 5 || !explored.At(gruid.Point{0, 0}) || !explored.At(gruid.Point{2, 1}) {
+		t.Errorf("bad Copy: %d", explored.Count())
+	}
+	small.Fill(false)
+	explored.And(small)
+	if explored.Count() != 1 {
+		t.Errorf("bad And with different sizes: %d", explored.Count())
+	}
+}
+
+func TestBitGridGob(t *testing.T) {
+	bg := NewBitGrid(70, 3)
+	bg.Set(gruid.Point{65, 2}, true)
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	err := ge.Encode(bg)
+	if err != nil {
+		t.Error(err)
+	}
+	bg = &BitGrid{}
+	gd := gob.NewDecoder(&buf)
+	err = gd.Decode(bg)
+	if err != nil {
+		t.Error(err)
+	}
+	if bg.Size() != (gruid.Point{70, 3}) || !bg.At(gruid.Point{65, 2}) || bg.Count() != 1 {
+		t.Errorf("bad decoded bit grid: %v %d", bg.Size(), bg.Count())
+	}
+}
@@ -0,0 +1,174 @@
+// This file implements directional movement helpers.
+
+package rl
+
+import "github.com/anaseto/gruid"
+
+// These variables represent the unit directions, in grid coordinates, where Y
+// grows downwards.
+var (
+	North     = gruid.Point{0, -1}
+	NorthEast = gruid.Point{1, -1}
+	East      = gruid.Point{1, 0}
+	SouthEast = gruid.Point{1, 1}
+	South     = gruid.Point{0, 1}
+	SouthWest = gruid.Point{-1, 1}
+	West      = gruid.Point{-1, 0}
+	NorthWest = gruid.Point{-1, -1}
+)
+
+// Dirs8 contains the eight unit directions, in clockwise order starting from
+// North.
+var Dirs8 = [8]gruid.Point{North, NorthEast, East, SouthEast, South, SouthWest, West, NorthWest}
+
+// Dirs4 contains the four cardinal unit directions, in clockwise order
+// starting from North.
+var Dirs4 = [4]gruid.Point{North, East, South, West}
+
+// DirKeys is a bit mask of key sets recognized by KeyToDir.
+type DirKeys int
+
+// These constants represent the available key sets for directions.
+const (
+	// DirKeysArrows represents the arrow keys, as well as Home, PageUp,
+	// End and PageDown for diagonals, as reported for keypad keys with
+	// numlock off.
+	DirKeysArrows DirKeys = 1 << iota
+
+	// DirKeysVi represents the vi-keys: h, j, k and l, as well as y, u, b
+	// and n for diagonals.
+	DirKeysVi
+
+	// DirKeysNumpad represents the digits 1 to 9, except 5, as reported
+	// for keypad keys with numlock on.
+	DirKeysNumpad
+
+	// DirKeysAll represents all the key sets.
+	DirKeysAll = DirKeysArrows | DirKeysVi | DirKeysNumpad
+)
+
+// KeyToDir returns the unit direction corresponding to a key among the given
+// key sets, or a zero point if there is none. For example, it returns West
+// for ArrowLeft, h or 4.
+func KeyToDir(k gruid.Key, keys DirKeys) gruid.Point {
+	if keys&DirKeysArrows != 0 {
+		switch k {
+		case gruid.KeyArrowUp:
+			return North
+		case gruid.KeyPageUp:
+			return NorthEast
+		case gruid.KeyArrowRight:
+			return East
+		case gruid.KeyPageDown:
+			return SouthEast
+		case gruid.KeyArrowDown:
+			return South
+		case gruid.KeyEnd:
+			return SouthWest
+		case gruid.KeyArrowLeft:
+			return West
+		case gruid.KeyHome:
+			return NorthWest
+		}
+	}
+	if keys&DirKeysVi != 0 {
+		switch k {
+		case "k":
+			return North
+		case "u":
+			return NorthEast
+		case "l":
+			return East
+		case "n":
+			return SouthEast
+		case "j":
+			return South
+		case "b":
+			return SouthWest
+		case "h":
+			return West
+		case "y":
+			return NorthWest
+		}
+	}
+	if keys&DirKeysNumpad != 0 {
+		switch k {
+		case "8":
+			return North
+		case "9":
+			return NorthEast
+		case "6":
+			return East
+		case "3":
+			return SouthEast
+		case "2":
+			return South
+		case "1":
+			return SouthWest
+		case "4":
+			return West
+		case "7":
+			return NorthWest
+		}
+	}
+	return gruid.Point{}
+}
+
+// Dir8 returns the unit direction among the eight ones that best approximates
+// a given delta, such as the difference between the mouse position and the
+// player's position. Each coordinate is reduced to its sign, except that a
+// coordinate is ignored if it is less than half of the other in absolute
+// value, so that the direction is not diagonal for nearly straight deltas. It
+// returns a zero point for a zero delta.
+func Dir8(delta gruid.Point) gruid.Point {
+	ax, ay := abs(delta.X), abs(delta.Y)
+	switch {
+	case 2*ax < ay:
+		delta.X = 0
+	case 2*ay < ax:
+		delta.Y = 0
+	}
+	return gruid.Point{sign(delta.X), sign(delta.Y)}
+}
+
+// Dir4 returns the cardinal unit direction that best approximates a given
+// delta, following the coordinate of largest absolute value. Ties are broken
+// in favor of the horizontal direction. It returns a zero point for a zero
+// delta.
+func Dir4(delta gruid.Point) gruid.Point {
+	if abs(delta.X) >= abs(delta.Y) {
+		return gruid.Point{sign(delta.X), 0}
+	}
+	return gruid.Point{0, sign(delta.Y)}
+}
+
+// RotateDir rotates a direction by n eighths of a turn clockwise, or
+// counter-clockwise for negative n. The direction is first approximated with
+// Dir8. It returns a zero point for a zero direction.
+func RotateDir(dir gruid.Point, n int) gruid.Point {
+	dir = Dir8(dir)
+	for i, d := range Dirs8 {
+		if d == dir {
+			n = (i + n) % 8
+			if n < 0 {
+				n += 8
+			}
+			return Dirs8[n]
+		}
+	}
+	return gruid.Point{}
+}
+
+// DirLeft returns the direction at the left of a direction, that is, the
+// direction rotated by an eighth of a turn counter-clockwise, such as
+// NorthWest for North.
+func DirLeft(dir gruid.Point) gruid.Point {
+	return RotateDir(dir, -1)
+}
+
+// DirRight returns the direction at the right of a direction, that is, the
+// direction rotated by an eighth of a turn clockwise, such as NorthEast for
+// North.
+func DirRight(dir gruid.Point) gruid.Point {
+	return RotateDir(dir, 1)
+}
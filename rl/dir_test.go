@@ -0,0 +1,65 @@
+package rl
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestKeyToDir(t *testing.T) {
+	tests := []struct {
+		key  gruid.Key
+		keys DirKeys
+		dir  gruid.Point
+	}{
+		{gruid.KeyArrowLeft, DirKeysArrows, West},
+		{gruid.KeyPageDown, DirKeysArrows, SouthEast},
+		{"y", DirKeysVi, NorthWest},
+		{"y", DirKeysArrows, gruid.Point{}},
+		{"9", DirKeysAll, NorthEast},
+		{"5", DirKeysAll, gruid.Point{}},
+		{"j", DirKeysAll, South},
+	}
+	for _, test := range tests {
+		if dir := KeyToDir(test.key, test.keys); dir != test.dir {
+			t.Errorf("bad direction for %q: %v (expected %v)", test.key, dir, test.dir)
+		}
+	}
+}
+
+func TestDir8(t *testing.T) {
+	tests := []struct {
+		delta, dir8, dir4 gruid.Point
+	}{
+		{gruid.Point{}, gruid.Point{}, gruid.Point{}},
+		{gruid.Point{5, -1}, East, East},
+		{gruid.Point{5, -4}, NorthEast, East},
+		{gruid.Point{-3, 3}, SouthWest, West},
+		{gruid.Point{1, -7}, North, North},
+	}
+	for _, test := range tests {
+		if dir := Dir8(test.delta); dir != test.dir8 {
+			t.Errorf("bad Dir8 for %v: %v", test.delta, dir)
+		}
+		if dir := Dir4(test.delta); dir != test.dir4 {
+			t.Errorf("bad Dir4 for %v: %v", test.delta, dir)
+		}
+	}
+}
+
+func TestRotateDir(t *testing.T) {
+	for i, dir := range Dirs8 {
+		if d := DirRight(dir); d != Dirs8[(i+1)%8] {
+			t.Errorf("bad right of %v: %v", dir, d)
+		}
+		if d := DirLeft(DirRight(dir)); d != dir {
+			t.Errorf("bad left of %v: %v", dir, d)
+		}
+		if d := RotateDir(dir, -10); d != Dirs8[(i+6)%8] {
+			t.Errorf("bad rotation of %v: %v", dir, d)
+		}
+	}
+	if d := RotateDir(gruid.Point{}, 1); d != (gruid.Point{}) {
+		t.Errorf("bad zero rotation: %v", d)
+	}
+}
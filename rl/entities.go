@@ -0,0 +1,280 @@
+// This file implements a lightweight entity store with a position component
+// synchronized with a spatial index.
+
+package rl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+
+	"github.com/anaseto/gruid"
+)
+
+// EntityID identifies an entity in an EntityStore. The zero value is never
+// used for an entity, so it can represent the absence of an entity.
+type EntityID int
+
+// EntityStore manages the lifetime of entities, such as monsters, items or
+// doors, identified by an EntityID, and their position on the map, with a
+// spatial index for fast queries by position. Other data is stored in
+// Components, that are associated with the store and do not need to be
+// updated when an entity is removed.
+//
+// This is intended as a simple starting architecture for grid games, in the
+// spirit of the Entity Component System pattern: the model's Update function
+// typically drives game logic by iterating over entities with given
+// components, while the spatial index is used for collisions, attacks or
+// item pickups. Iteration follows entity creation order, so that game logic
+// is deterministic.
+//
+// EntityStore must be created with NewEntityStore.
+//
+// EntityStore implements gob.Decoder and gob.Encoder for easy serialization.
+// Components are serialized separately.
+type EntityStore struct {
+	entityStore
+	index map[gruid.Point][]EntityID // spatial index
+	comps []componentDeleter         // associated components
+}
+
+type entityStore struct {
+	Next      EntityID                 // next entity identifier
+	IDs       []EntityID               // alive entities, in ascending order
+	Positions map[EntityID]gruid.Point // entity positions
+}
+
+type componentDeleter interface {
+	Delete(EntityID)
+}
+
+// NewEntityStore returns a new empty entity store.
+func NewEntityStore() *EntityStore {
+	return &EntityStore{
+		entityStore: entityStore{
+			Next:      1,
+			Positions: map[EntityID]gruid.Point{},
+		},
+		index: map[gruid.Point][]EntityID{},
+	}
+}
+
+// GobDecode implements gob.GobDecoder. The spatial index is rebuilt from the
+// decoded positions.
+func (es *EntityStore) GobDecode(bs []byte) error {
+	r := bytes.NewReader(bs)
+	gd := gob.NewDecoder(r)
+	ies := &entityStore{}
+	err := gd.Decode(ies)
+	if err != nil {
+		return err
+	}
+	if ies.Positions == nil {
+		ies.Positions = map[EntityID]gruid.Point{}
+	}
+	es.entityStore = *ies
+	es.index = map[gruid.Point][]EntityID{}
+	for _, id := range es.IDs {
+		if p, ok := es.Positions[id]; ok {
+			es.index[p] = append(es.index[p], id)
+		}
+	}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder.
+func (es *EntityStore) GobEncode() ([]byte, error) {
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	err := ge.Encode(&es.entityStore)
+	return buf.Bytes(), err
+}
+
+// New creates a new entity without position and returns its identifier.
+// Identifiers are not reused.
+func (es *EntityStore) New() EntityID {
+	id := es.Next
+	es.Next++
+	es.IDs = append(es.IDs, id)
+	return id
+}
+
+// Remove removes an entity, its position, and its data from all the
+// components associated with the store.
+func (es *EntityStore) Remove(id EntityID) {
+	i := es.search(id)
+	if i < 0 {
+		return
+	}
+	es.RemovePos(id)
+	for _, c := range es.comps {
+		c.Delete(id)
+	}
+	es.IDs = append(es.IDs[:i], es.IDs[i+1:]...)
+}
+
+// search returns the index of an alive entity in IDs, or -1.
+func (es *EntityStore) search(id EntityID) int {
+	i := sort.Search(len(es.IDs), func(i int) bool { return es.IDs[i] >= id })
+	if i < len(es.IDs) && es.IDs[i] == id {
+		return i
+	}
+	return -1
+}
+
+// Alive reports whether an entity exists in the store.
+func (es *EntityStore) Alive(id EntityID) bool {
+	return es.search(id) >= 0
+}
+
+// Len returns the number of entities in the store.
+func (es *EntityStore) Len() int {
+	return len(es.IDs)
+}
+
+// Iter calls a function for each entity, in creation order. The function
+// should not create or remove entities.
+func (es *EntityStore) Iter(fn func(EntityID)) {
+	for _, id := range es.IDs {
+		fn(id)
+	}
+}
+
+// SetPos places an entity at a given position, or moves it if it already had
+// one. It does nothing if the entity does not exist.
+func (es *EntityStore) SetPos(id EntityID, p gruid.Point) {
+	if !es.Alive(id) {
+		return
+	}
+	if q, ok := es.Positions[id]; ok {
+		if q == p {
+			return
+		}
+		es.unindex(id, q)
+	}
+	es.Positions[id] = p
+	es.index[p] = append(es.index[p], id)
+}
+
+// RemovePos removes the position of an entity, for example when an item is
+// picked up. The entity is not removed.
+func (es *EntityStore) RemovePos(id EntityID) {
+	q, ok := es.Positions[id]
+	if !ok {
+		return
+	}
+	es.unindex(id, q)
+	delete(es.Positions, id)
+}
+
+func (es *EntityStore) unindex(id EntityID, p gruid.Point) {
+	ids := es.index[p]
+	for i, j := range ids {
+		if j == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(es.index, p)
+		return
+	}
+	es.index[p] = ids
+}
+
+// Pos returns the position of an entity. It returns false if the entity does
+// not exist or has no position.
+func (es *EntityStore) Pos(id EntityID) (gruid.Point, bool) {
+	p, ok := es.Positions[id]
+	return p, ok
+}
+
+// At returns the entities at a given position, in the order in which they
+// were placed there. The returned slice is only valid until the next
+// position change, and should not be modified.
+func (es *EntityStore) At(p gruid.Point) []EntityID {
+	return es.index[p]
+}
+
+// Components associates values of a given type, such as health points or a
+// name, with entities of a store. It is a thin layer over a map that is kept
+// in sync with the store when entities are removed.
+//
+// Components must be created with NewComponents.
+//
+// Components implements gob.Decoder and gob.Encoder for easy serialization.
+// When loading a game, the components should be created first with
+// NewComponents for the decoded store, and then decoded.
+type Components[T any] struct {
+	es *EntityStore
+	m  map[EntityID]T
+}
+
+// NewComponents returns new empty components associated with a store.
+func NewComponents[T any](es *EntityStore) *Components[T] {
+	c := &Components[T]{es: es, m: map[EntityID]T{}}
+	es.comps = append(es.comps, c)
+	return c
+}
+
+// GobDecode implements gob.GobDecoder.
+func (c *Components[T]) GobDecode(bs []byte) error {
+	r := bytes.NewReader(bs)
+	gd := gob.NewDecoder(r)
+	m := map[EntityID]T{}
+	err := gd.Decode(&m)
+	if err != nil {
+		return err
+	}
+	c.m = m
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder.
+func (c *Components[T]) GobEncode() ([]byte, error) {
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	err := ge.Encode(c.m)
+	return buf.Bytes(), err
+}
+
+// Set associates a value with an entity. It does nothing if the entity does
+// not exist in the store.
+func (c *Components[T]) Set(id EntityID, v T) {
+	if c.es.Alive(id) {
+		c.m[id] = v
+	}
+}
+
+// Get returns the value associated with an entity, if any.
+func (c *Components[T]) Get(id EntityID) (T, bool) {
+	v, ok := c.m[id]
+	return v, ok
+}
+
+// Has reports whether a value is associated with an entity.
+func (c *Components[T]) Has(id EntityID) bool {
+	_, ok := c.m[id]
+	return ok
+}
+
+// Delete removes the value associated with an entity, if any.
+func (c *Components[T]) Delete(id EntityID) {
+	delete(c.m, id)
+}
+
+// Len returns the number of entities with an associated value.
+func (c *Components[T]) Len() int {
+	return len(c.m)
+}
+
+// Iter calls a function for each entity with an associated value, in
+// creation order. The function may modify values with Set, but should not
+// create or remove entities.
+func (c *Components[T]) Iter(fn func(EntityID, T)) {
+	for _, id := range c.es.IDs {
+		if v, ok := c.m[id]; ok {
+			fn(id, v)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestEntityStore(t *testing.T) {
+	es := NewEntityStore()
+	hp := NewComponents[int](es)
+	a, b, c := es.New(), es.New(), es.New()
+	if a == 0 || a == b || es.Len() != 3 {
+		t.Errorf("bad entities: %v %v %v", a, b, c)
+	}
+	es.SetPos(a, gruid.Point{1, 1})
+	es.SetPos(b, gruid.Point{1, 1})
+	es.SetPos(c, gruid.Point{2, 3})
+	if ids := es.At(gruid.Point{1, 1}); len(ids) != 2 || ids[0] != a || ids[1] != b {
+		t.Errorf("bad entities at position: %v", ids)
+	}
+	es.SetPos(a, gruid.Point{2, 3})
+	if ids := es.At(gruid.Point{1, 1}); len(ids) != 1 || ids[0] != b {
+		t.Errorf("bad entities after move: %v", ids)
+	}
+	if p, ok := es.Pos(a); !ok || p != (gruid.Point{2, 3}) {
+		t.Errorf("bad position: %v", p)
+	}
+	hp.Set(a, 10)
+	hp.Set(c, 5)
+	es.Remove(c)
+	if es.Alive(c) || hp.Has(c) || es.Len() != 2 {
+		t.Errorf("entity not removed")
+	}
+	if ids := es.At(gruid.Point{2, 3}); len(ids) != 1 || ids[0] != a {
+		t.Errorf("bad entities after remove: %v", ids)
+	}
+	hp.Set(c, 3)
+	if hp.Has(c) {
+		t.Errorf("value set for removed entity")
+	}
+	es.RemovePos(b)
+	if _, ok := es.Pos(b); ok || len(es.At(gruid.Point{1, 1})) > 0 {
+		t.Errorf("position not removed")
+	}
+	d := es.New()
+	if d <= c {
+		t.Errorf("identifier reused: %v", d)
+	}
+	hp.Set(d, 7)
+	ids := []EntityID{}
+	hp.Iter(func(id EntityID, v int) {
+		ids = append(ids, id)
+	})
+	if len(ids) != 2 || ids[0] != a || ids[1] != d {
+		t.Errorf("bad iteration: %v", ids)
+	}
+}
+
+func TestEntityStoreGob(t *testing.T) {
+	es := NewEntityStore()
+	names := NewComponents[string](es)
+	a, b := es.New(), es.New()
+	es.SetPos(a, gruid.Point{3, 4})
+	names.Set(a, "orc")
+	names.Set(b, "potion")
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	if err := ge.Encode(es); err != nil {
+		t.Fatal(err)
+	}
+	if err := ge.Encode(names); err != nil {
+		t.Fatal(err)
+	}
+	es = &EntityStore{}
+	names = NewComponents[string](es)
+	gd := gob.NewDecoder(&buf)
+	if err := gd.Decode(es); err != nil {
+		t.Fatal(err)
+	}
+	if err := gd.Decode(names); err != nil {
+		t.Fatal(err)
+	}
+	if ids := es.At(gruid.Point{3, 4}); len(ids) != 1 || ids[0] != a {
+		t.Errorf("bad spatial index: %v", ids)
+	}
+	if s, _ := names.Get(b); s != "potion" {
+		t.Errorf("bad component: %q", s)
+	}
+	es.Remove(a)
+	if names.Has(a) {
+		t.Errorf("component not removed")
+	}
+	if c := es.New(); c <= b {
+		t.Errorf("bad identifier after decoding: %v", c)
+	}
+}
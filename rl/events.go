@@ -1,5 +1,5 @@
 // Package rl provides some facilities for common roguelike programming needs:
-// event queue, field of view and map generation.
+// event queue, field of view, map generation and entity management.
 package rl
 
 import (
@@ -97,6 +97,19 @@ func (eq *EventQueue) Empty() bool {
 	return eq.Queue.Len() <= 0
 }
 
+// Len returns the number of events in the queue.
+func (eq *EventQueue) Len() int {
+	return eq.Queue.Len()
+}
+
+// PeekR returns the first element rank-wise (lowest rank) in the event queue,
+// along with its rank, without removing it. It can be used, for example, to
+// know the time of the next turn. The queue should not be empty.
+func (eq *EventQueue) PeekR() (Event, int) {
+	ev := (*eq.Queue)[0]
+	return ev.Event, ev.Rank
+}
+
 // Filter removes events that do not satisfy a given predicate from the event
 // queue.
 func (eq *EventQueue) Filter(fn func(ev Event) bool) {
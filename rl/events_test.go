@@ -106,6 +106,24 @@ func TestEventsQueuePopR(t *testing.T) {
 	}
 }
 
+func TestEventsQueuePeekR(t *testing.T) {
+	eq := NewEventQueue()
+	eq.Push(3, 5)
+	eq.PushFirst(2, 5)
+	eq.Push(1, 7)
+	n, r := eq.PeekR()
+	if n != 2 || r != 5 {
+		t.Errorf("bad peek: %v %d", n, r)
+	}
+	if eq.Len() != 3 {
+		t.Errorf("bad length after peek: %d", eq.Len())
+	}
+	eq.Pop()
+	if n, _ := eq.PeekR(); n != 3 || eq.Len() != 2 {
+		t.Errorf("bad peek after pop: %v", n)
+	}
+}
+
 func TestEventQueueGob(t *testing.T) {
 	eq := NewEventQueue()
 	eq.Push(3, 2)
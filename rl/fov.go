@@ -5,6 +5,7 @@ package rl
 import (
 	"bytes"
 	"encoding/gob"
+	"math"
 
 	"github.com/anaseto/gruid"
 )
@@ -48,6 +49,10 @@ type innerFOV struct {
 	Src           gruid.Point
 	passable      func(gruid.Point) bool
 	tiles         []gruid.Point
+	rows          []row
+	out           []int // outgoing costs for OpaqueLighter
+	vmap          bool  // Costs and Lighted come from a vision map
+	radius        int   // radius of last vision map
 	Capacity      int
 }
 
@@ -74,6 +79,35 @@ func (fov *FOV) SetRange(rg gruid.Range) {
 	*fov = *nfov
 }
 
+// Reserve pre-allocates the internal structures used by the field of view for
+// computations with sources at most at a given radius, so that they do not
+// have to grow progressively. Cached structures are reused across calls, so
+// that computing a field of view in a game loop does not allocate.
+func (fov *FOV) Reserve(radius int) {
+	n := (2*radius + 1) * (2*radius + 1)
+	if n > fov.Capacity {
+		n = fov.Capacity
+	}
+	if fov.Costs == nil {
+		fov.Costs = make([]int, fov.Capacity)
+	}
+	if fov.ShadowCasting == nil {
+		fov.ShadowCasting = make([]bool, fov.Capacity)
+	}
+	if cap(fov.Lighted) < n {
+		fov.Lighted = make([]LightNode, 0, n)
+	}
+	if cap(fov.Visibles) < n {
+		fov.Visibles = make([]gruid.Point, 0, n)
+	}
+	if cap(fov.RayCache) < radius+1 {
+		fov.RayCache = make([]LightNode, 0, radius+1)
+	}
+	if cap(fov.tiles) < 2*radius+1 {
+		fov.tiles = make([]gruid.Point, 0, 2*radius+1)
+	}
+}
+
 // Range returns the current FOV's range of positions.
 func (fov *FOV) Range() gruid.Range {
 	return fov.Rg
@@ -109,7 +143,8 @@ func (fov *FOV) At(p gruid.Point) (int, bool) {
 	}
 	cost := fov.Costs[fov.idx(p)]
 	if cost <= 0 {
-		return cost, false
+		// not reached, or out of the cone of VisionCone
+		return 0, false
 	}
 	return cost - 1, true
 }
@@ -160,7 +195,7 @@ func (fov *FOV) octantParents(ps []LightNode, src, p gruid.Point) []LightNode {
 	q := src.Sub(p)
 	r := gruid.Point{sign(q.X), sign(q.Y)}
 	p0 := p.Add(gruid.Point{r.X, r.Y})
-	c0 := fov.Costs[fov.idx(p0)]
+	c0 := abs(fov.Costs[fov.idx(p0)]) // negative outside cones
 	if c0 > 0 {
 		ps = append(ps, LightNode{P: p0, Cost: c0})
 	}
@@ -168,13 +203,13 @@ func (fov *FOV) octantParents(ps []LightNode, src, p gruid.Point) []LightNode {
 	case q.X == 0 || q.Y == 0 || abs(q.X) == abs(q.Y):
 	case abs(q.X) > abs(q.Y):
 		p1 := p.Add(gruid.Point{r.X, 0})
-		c1 := fov.Costs[fov.idx(p1)]
+		c1 := abs(fov.Costs[fov.idx(p1)])
 		if c1 > 0 {
 			ps = append(ps, LightNode{P: p1, Cost: c1})
 		}
 	default:
 		p1 := p.Add(gruid.Point{0, r.Y})
-		c1 := fov.Costs[fov.idx(p1)]
+		c1 := abs(fov.Costs[fov.idx(p1)])
 		if c1 > 0 {
 			ps = append(ps, LightNode{P: p1, Cost: c1})
 		}
@@ -246,6 +281,29 @@ type Lighter interface {
 	MaxCost(src gruid.Point) int
 }
 
+// OpaqueLighter is a Lighter for the common case of uniform propagation costs
+// with opaque obstacles. VisionMap and LightMap use a faster specialized
+// implementation for *OpaqueLighter values.
+type OpaqueLighter struct {
+	Passable func(gruid.Point) bool // whether light propagates from a position
+	MaxDist  int                     // maximum light distance
+}
+
+// Cost implements Lighter.Cost. It returns 1 for passable positions and the
+// source, and MaxDist otherwise, so that positions behind obstacles get a cost
+// greater than MaxDist.
+func (lt *OpaqueLighter) Cost(src, from, to gruid.Point) int {
+	if src == from || lt.Passable(from) {
+		return 1
+	}
+	return lt.MaxDist
+}
+
+// MaxCost implements Lighter.MaxCost. It returns MaxDist.
+func (lt *OpaqueLighter) MaxCost(src gruid.Point) int {
+	return lt.MaxDist
+}
+
 // VisionMap builds a field of vision map for a viewer at src. It returns a
 // cached slice of lighted nodes. Values can also be consulted individually
 // with At.
@@ -263,7 +321,15 @@ type Lighter interface {
 // directions: a diagonal and an orthogonal one (for example north east and
 // east).
 func (fov *FOV) VisionMap(lt Lighter, src gruid.Point) []LightNode {
+	return fov.VisionMapRadius(lt, src, lt.MaxCost(src))
+}
+
+// VisionMapRadius is like VisionMap, but positions are computed only up to
+// the given radius in Chebyshev distance, instead of lt.MaxCost(src). The
+// radius can then be changed cheaply with ResizeVisionMap.
+func (fov *FOV) VisionMapRadius(lt Lighter, src gruid.Point, radius int) []LightNode {
 	fov.Lighted = fov.Lighted[:0]
+	fov.vmap = false
 	if !src.In(fov.Rg) {
 		return fov.Lighted
 	}
@@ -276,7 +342,64 @@ func (fov *FOV) VisionMap(lt Lighter, src gruid.Point) []LightNode {
 	fov.Src = src
 	fov.Costs[fov.idx(src)] = 1
 	fov.Lighted = append(fov.Lighted, LightNode{P: src, Cost: 0})
-	for d := 1; d <= lt.MaxCost(src); d++ {
+	if _, ok := lt.(*OpaqueLighter); ok {
+		fov.initOut()
+		fov.out[fov.idx(src)] = 2
+	}
+	fov.visionRings(lt, src, 1, radius)
+	fov.vmap = true
+	fov.radius = radius
+	return fov.Lighted
+}
+
+// ResizeVisionMap changes the radius of the field of vision computed by the
+// last VisionMap or VisionMapRadius call, as if VisionMapRadius had been
+// called with the new radius. Only the positions at a distance between the
+// old and new radius are computed or removed, reusing the interior results.
+// This makes it suitable for effects like torch flicker, where the radius
+// changes by one from a turn to another, without doubling the cost of the
+// field of vision. It returns the updated cached slice of lighted nodes.
+//
+// The source, the lighter's costs and the map should not have changed since
+// the last call. In particular, for an OpaqueLighter, MaxDist, which is the
+// cost of obstacles, should not be changed. If the last computation was not a
+// vision map, such as after LightMap or VisionCone, the field of vision is
+// computed again from scratch for the last source.
+func (fov *FOV) ResizeVisionMap(lt Lighter, radius int) []LightNode {
+	if !fov.vmap {
+		return fov.VisionMapRadius(lt, fov.Src, radius)
+	}
+	if radius < 0 {
+		radius = 0
+	}
+	switch {
+	case radius > fov.radius:
+		fov.visionRings(lt, fov.Src, fov.radius+1, radius)
+	case radius < fov.radius:
+		// lighted nodes are sorted by distance to the source
+		n := len(fov.Lighted)
+		for n > 0 {
+			q := fov.Lighted[n-1].P.Sub(fov.Src)
+			if abs(q.X) <= radius && abs(q.Y) <= radius {
+				break
+			}
+			fov.Costs[fov.idx(fov.Lighted[n-1].P)] = 0
+			n--
+		}
+		fov.Lighted = fov.Lighted[:n]
+	}
+	fov.radius = radius
+	return fov.Lighted
+}
+
+// visionRings computes the vision map positions at a distance from d0 to d1
+// from the source, in increasing distance order.
+func (fov *FOV) visionRings(lt Lighter, src gruid.Point, d0, d1 int) {
+	if olt, ok := lt.(*OpaqueLighter); ok {
+		fov.visionMapOpaque(olt, src, false, d0, d1)
+		return
+	}
+	for d := d0; d <= d1; d++ {
 		rg := fov.Rg.Intersect(gruid.NewRange(src.X-d, src.Y-d+1, src.X+d+1, src.Y+d))
 		if src.Y+d < fov.Rg.Max.Y {
 			for x := rg.Min.X; x < rg.Max.X; x++ {
@@ -299,7 +422,6 @@ func (fov *FOV) VisionMap(lt Lighter, src gruid.Point) []LightNode {
 			}
 		}
 	}
-	return fov.Lighted
 }
 
 func (fov *FOV) visionUpdate(lt Lighter, src gruid.Point, to gruid.Point) {
@@ -310,9 +432,234 @@ func (fov *FOV) visionUpdate(lt Lighter, src gruid.Point, to gruid.Point) {
 	}
 }
 
+func (fov *FOV) initOut() {
+	if len(fov.out) != len(fov.Costs) {
+		fov.out = make([]int, len(fov.Costs))
+	}
+}
+
+// visionMapOpaque is the equivalent of the VisionMap and LightMap loops for an
+// OpaqueLighter, updating each position at a distance from d0 to d1 from the
+// source, in increasing distance order.
+func (fov *FOV) visionMapOpaque(lt *OpaqueLighter, src gruid.Point, light bool, d0, d1 int) {
+	update := func(lt *OpaqueLighter, p gruid.Point) {
+		if light {
+			fov.lightUpdateOpaque(lt, p)
+		} else {
+			fov.visionUpdateOpaque(lt, p)
+		}
+	}
+	for d := d0; d <= d1; d++ {
+		rg := fov.Rg.Intersect(gruid.NewRange(src.X-d, src.Y-d+1, src.X+d+1, src.Y+d))
+		if src.Y+d < fov.Rg.Max.Y {
+			for x := rg.Min.X; x < rg.Max.X; x++ {
+				update(lt, gruid.Point{x, src.Y + d})
+			}
+		}
+		if src.Y-d >= fov.Rg.Min.Y {
+			for x := rg.Min.X; x < rg.Max.X; x++ {
+				update(lt, gruid.Point{x, src.Y - d})
+			}
+		}
+		if src.X+d < fov.Rg.Max.X {
+			for y := rg.Min.Y; y < rg.Max.Y; y++ {
+				update(lt, gruid.Point{src.X + d, y})
+			}
+		}
+		if src.X-d >= fov.Rg.Min.X {
+			for y := rg.Min.Y; y < rg.Max.Y; y++ {
+				update(lt, gruid.Point{src.X - d, y})
+			}
+		}
+	}
+}
+
+// fromOpaque is the equivalent of from for an OpaqueLighter. As the cost of a
+// step only depends on the starting position, it uses the outgoing costs
+// precomputed for the parents, instead of calling the Lighter for each ray.
+func (fov *FOV) fromOpaque(to gruid.Point) int {
+	q := fov.Src.Sub(to)
+	r := gruid.Point{sign(q.X), sign(q.Y)}
+	cost := 0
+	i0 := fov.idx(to.Add(r))
+	if fov.Costs[i0] > 0 {
+		cost = fov.out[i0]
+	}
+	var p1 gruid.Point
+	switch {
+	case q.X == 0 || q.Y == 0 || abs(q.X) == abs(q.Y):
+		return cost
+	case abs(q.X) > abs(q.Y):
+		p1 = to.Add(gruid.Point{r.X, 0})
+	default:
+		p1 = to.Add(gruid.Point{0, r.Y})
+	}
+	i1 := fov.idx(p1)
+	if fov.Costs[i1] > 0 && (cost == 0 || fov.out[i1] < cost) {
+		cost = fov.out[i1]
+	}
+	return cost
+}
+
+// outCost returns the cost of a ray going through p for an OpaqueLighter,
+// given the cost to reach p.
+func outCost(lt *OpaqueLighter, p gruid.Point, c int) int {
+	if lt.Passable(p) {
+		return c + 1
+	}
+	return c + lt.MaxDist
+}
+
+func (fov *FOV) visionUpdateOpaque(lt *OpaqueLighter, to gruid.Point) {
+	c := fov.fromOpaque(to)
+	if c > 0 {
+		i := fov.idx(to)
+		fov.Costs[i] = c
+		fov.out[i] = outCost(lt, to, c)
+		fov.Lighted = append(fov.Lighted, LightNode{P: to, Cost: c - 1})
+	}
+}
+
+// cone represents a cone of vision.
+type cone struct {
+	dir  float64 // direction angle (radians)
+	half float64 // half aperture angle (radians)
+}
+
+func newCone(dir gruid.Point, angle int) cone {
+	return cone{
+		dir:  math.Atan2(float64(dir.Y), float64(dir.X)),
+		half: float64(angle) * math.Pi / 360,
+	}
+}
+
+// angDist returns the angular distance between the direction of a relative
+// position q and the cone's direction.
+func (cn cone) angDist(q gruid.Point) float64 {
+	d := math.Abs(math.Atan2(float64(q.Y), float64(q.X)) - cn.dir)
+	if d > math.Pi {
+		d = 2*math.Pi - d
+	}
+	return d
+}
+
+// contains reports whether relative position q is within the cone.
+func (cn cone) contains(q gruid.Point) bool {
+	return q == gruid.Point{} || cn.angDist(q) <= cn.half+1e-9
+}
+
+// VisionCone is like VisionMap, but only positions within a cone of vision are
+// lighted. The cone starts at src, and is centered on a direction dir relative
+// to the source, such as (1, 0) for east, with a total aperture angle given in
+// degrees. Light rays are the same as with VisionMap, but only the octants
+// intersecting the cone are computed.
+func (fov *FOV) VisionCone(lt Lighter, src, dir gruid.Point, angle int) []LightNode {
+	if angle >= 360 || dir == (gruid.Point{}) {
+		return fov.VisionMap(lt, src)
+	}
+	fov.Lighted = fov.Lighted[:0]
+	fov.vmap = false
+	if !src.In(fov.Rg) {
+		return fov.Lighted
+	}
+	if fov.Costs == nil {
+		fov.Costs = make([]int, fov.Capacity)
+	}
+	for i := range fov.Costs {
+		fov.Costs[i] = 0
+	}
+	fov.Src = src
+	fov.Costs[fov.idx(src)] = 1
+	fov.Lighted = append(fov.Lighted, LightNode{P: src, Cost: 0})
+	cn := newCone(dir, angle)
+	for d := 1; d <= lt.MaxCost(src); d++ {
+		rg := fov.Rg.Intersect(gruid.NewRange(src.X-d, src.Y-d+1, src.X+d+1, src.Y+d))
+		if src.Y+d < fov.Rg.Max.Y {
+			for x := rg.Min.X; x < rg.Max.X; x++ {
+				fov.coneUpdate(lt, cn, gruid.Point{x, src.Y + d})
+			}
+		}
+		if src.Y-d >= fov.Rg.Min.Y {
+			for x := rg.Min.X; x < rg.Max.X; x++ {
+				fov.coneUpdate(lt, cn, gruid.Point{x, src.Y - d})
+			}
+		}
+		if src.X+d < fov.Rg.Max.X {
+			for y := rg.Min.Y; y < rg.Max.Y; y++ {
+				fov.coneUpdate(lt, cn, gruid.Point{src.X + d, y})
+			}
+		}
+		if src.X-d >= fov.Rg.Min.X {
+			for y := rg.Min.Y; y < rg.Max.Y; y++ {
+				fov.coneUpdate(lt, cn, gruid.Point{src.X - d, y})
+			}
+		}
+	}
+	return fov.Lighted
+}
+
+func (fov *FOV) coneUpdate(lt Lighter, cn cone, to gruid.Point) {
+	q := to.Sub(fov.Src)
+	if cn.angDist(q) > cn.half+math.Pi/4+1e-9 {
+		// not in an octant intersecting the cone
+		return
+	}
+	n := fov.from(lt, to)
+	if n.Cost <= 0 {
+		return
+	}
+	if !cn.contains(q) {
+		// needed for rays, but not visible
+		fov.Costs[fov.idx(to)] = -n.Cost
+		return
+	}
+	fov.Costs[fov.idx(to)] = n.Cost
+	fov.Lighted = append(fov.Lighted, LightNode{P: to, Cost: n.Cost - 1})
+}
+
+// FacingLighter is a Lighter wrapper that makes vision depend on a facing
+// direction, as for humanoid vision models. Positions within the forward cone
+// have the underlying lighter costs, positions within the peripheral cone
+// have costs multiplied by Multiplier, and positions outside are in a blind
+// spot and get a cost greater than the underlying MaxCost, so that they cannot
+// be seen. It can be used with VisionMap.
+//
+// Like with VisionCone, angles are total aperture angles in degrees, and the
+// cones are centered on Dir, relative to the source, such as (1, 0) for east.
+// A zero Dir means no facing direction, in which case the underlying costs
+// are used everywhere.
+type FacingLighter struct {
+	Lighter                // underlying lighter
+	Dir        gruid.Point // facing direction
+	Forward    int         // aperture of full-cost vision
+	Peripheral int         // aperture of peripheral vision (includes Forward)
+	Multiplier int         // cost multiplier for peripheral vision
+}
+
+// Cost implements Lighter.Cost.
+func (lt *FacingLighter) Cost(src, from, to gruid.Point) int {
+	c := lt.Lighter.Cost(src, from, to)
+	if lt.Dir == (gruid.Point{}) {
+		return c
+	}
+	q := to.Sub(src)
+	if newCone(lt.Dir, lt.Forward).contains(q) {
+		return c
+	}
+	if newCone(lt.Dir, lt.Peripheral).contains(q) {
+		if lt.Multiplier > 1 {
+			c *= lt.Multiplier
+		}
+		return c
+	}
+	// blind spot
+	return lt.Lighter.MaxCost(src) + 1
+}
+
 // LightMap builds a lighting map with given light sources. It returs a cached
 // slice of lighted nodes. Values can also be consulted with At.
 func (fov *FOV) LightMap(lt Lighter, srcs []gruid.Point) []LightNode {
+	fov.vmap = false
 	if fov.Costs == nil {
 		fov.Costs = make([]int, fov.Capacity)
 	}
@@ -325,6 +672,13 @@ func (fov *FOV) LightMap(lt Lighter, srcs []gruid.Point) []LightNode {
 		}
 		fov.Src = src
 		fov.Costs[fov.idx(src)] = 1
+		if olt, ok := lt.(*OpaqueLighter); ok {
+			fov.initOut()
+			fov.out[fov.idx(src)] = 2
+			fov.visionMapOpaque(olt, src, true, 1, olt.MaxDist)
+			fov.out[fov.idx(src)] = outCost(olt, src, 1)
+			continue
+		}
 		for d := 1; d <= lt.MaxCost(src); d++ {
 			rg := fov.Rg.Intersect(gruid.NewRange(src.X-d, src.Y-d+1, src.X+d+1, src.Y+d))
 			if src.Y+d < fov.Rg.Max.Y {
@@ -365,6 +719,19 @@ func (fov *FOV) lightUpdate(lt Lighter, src gruid.Point, to gruid.Point) {
 	*c1p = n.Cost
 }
 
+func (fov *FOV) lightUpdateOpaque(lt *OpaqueLighter, to gruid.Point) {
+	c := fov.fromOpaque(to)
+	if c <= 0 {
+		return
+	}
+	i := fov.idx(to)
+	if fov.Costs[i] > 0 && fov.Costs[i] <= c {
+		return
+	}
+	fov.Costs[i] = c
+	fov.out[i] = outCost(lt, to, c)
+}
+
 func (fov *FOV) computeLighted() {
 	fov.Lighted = fov.Lighted[:0]
 	w := fov.Rg.Max.X - fov.Rg.Min.X
@@ -603,7 +970,8 @@ func (fov *FOV) sscQuadrant(src gruid.Point, maxDepth int, dir quadDir, diags bo
 		slopeStart: gruid.Point{-1, 1},
 		slopeEnd:   gruid.Point{1, 1},
 	}
-	rows := []row{r}
+	rows := append(fov.rows[:0], r)
+	defer func() { fov.rows = rows[:0] }()
 	for len(rows) > 0 {
 		r := rows[len(rows)-1]
 		rows = rows[:len(rows)-1]
@@ -666,6 +1034,51 @@ func (fov *FOV) sscQuadrant(src gruid.Point, maxDepth int, dir quadDir, diags bo
 	}
 }
 
+// SSCVisionCone is like SSCVisionMap, but only positions within a cone of
+// vision are visible. The cone starts at src, and is centered on a direction
+// dir relative to the source, such as (1, 0) for east, with a total aperture
+// angle given in degrees. Only the quadrants intersecting the cone are
+// computed.
+func (fov *FOV) SSCVisionCone(src, dir gruid.Point, angle int, maxDepth int, passable func(p gruid.Point) bool, diags bool) []gruid.Point {
+	if angle >= 360 || dir == (gruid.Point{}) {
+		return fov.SSCVisionMap(src, maxDepth, passable, diags)
+	}
+	if !src.In(fov.Rg) {
+		return nil
+	}
+	if fov.ShadowCasting == nil {
+		fov.ShadowCasting = make([]bool, fov.Capacity)
+	}
+	for i := range fov.ShadowCasting {
+		fov.ShadowCasting[i] = false
+	}
+	fov.passable = passable
+	fov.Visibles = fov.Visibles[:0]
+	fov.ShadowCasting[fov.idx(src)] = true
+	fov.Visibles = append(fov.Visibles, src)
+	cn := newCone(dir, angle)
+	for i := 0; i < 4; i++ {
+		qt := quadrant{dir: quadDir(i)}
+		// quadrant's central direction
+		if cn.angDist(qt.transform(gruid.Point{1, 0})) > cn.half+math.Pi/4+1e-9 {
+			continue
+		}
+		fov.sscQuadrant(src, maxDepth, quadDir(i), diags)
+	}
+	// keep only positions within the cone
+	j := 0
+	for _, p := range fov.Visibles {
+		if cn.contains(p.Sub(src)) {
+			fov.Visibles[j] = p
+			j++
+		} else {
+			fov.ShadowCasting[fov.idx(p)] = false
+		}
+	}
+	fov.Visibles = fov.Visibles[:j]
+	return fov.Visibles
+}
+
 // SSCLightMap is the equivalent of SSCVisionMap with several sources.
 func (fov *FOV) SSCLightMap(srcs []gruid.Point, maxDepth int, passable func(p gruid.Point) bool, diags bool) []gruid.Point {
 	if fov.ShadowCasting == nil {
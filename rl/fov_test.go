@@ -3,6 +3,7 @@ package rl
 import (
 	"bytes"
 	"encoding/gob"
+	"math/rand"
 	"testing"
 
 	"github.com/anaseto/gruid"
@@ -145,6 +146,174 @@ func (lt *lighter) diagonalStep(from, to gruid.Point) bool {
 	return step.X != 0 && step.Y != 0
 }
 
+// genericLighter hides the OpaqueLighter type from the FOV fast path.
+type genericLighter struct {
+	olt *OpaqueLighter
+}
+
+func (lt genericLighter) Cost(src, from, to gruid.Point) int {
+	return lt.olt.Cost(src, from, to)
+}
+
+func (lt genericLighter) MaxCost(src gruid.Point) int {
+	return lt.olt.MaxCost(src)
+}
+
+func TestFOVOpaqueLighter(t *testing.T) {
+	rg := gruid.NewRange(0, 0, 40, 30)
+	gd := NewGrid(40, 30)
+	rand := rand.New(rand.NewSource(42))
+	gd.FillFunc(func() Cell {
+		if rand.Intn(4) == 0 {
+			return wall
+		}
+		return ground
+	})
+	olt := &OpaqueLighter{
+		Passable: func(p gruid.Point) bool { return gd.At(p) == ground },
+		MaxDist:  maxLOS,
+	}
+	glt := genericLighter{olt}
+	fov := NewFOV(rg)
+	fov.Reserve(maxLOS)
+	gfov := NewFOV(rg)
+	check := func(lns, glns []LightNode) {
+		if len(lns) != len(glns) {
+			t.Fatalf("bad length: %d vs %d", len(lns), len(glns))
+		}
+		for i, n := range lns {
+			if n != glns[i] {
+				t.Errorf("bad node: %v vs %v", n, glns[i])
+			}
+		}
+	}
+	check(fov.VisionMap(olt, gruid.Point{20, 15}), gfov.VisionMap(glt, gruid.Point{20, 15}))
+	check(fov.VisionMap(olt, gruid.Point{2, 3}), gfov.VisionMap(glt, gruid.Point{2, 3}))
+	srcs := []gruid.Point{{5, 5}, {10, 8}, {30, 20}}
+	check(fov.LightMap(olt, srcs), gfov.LightMap(glt, srcs))
+}
+
+func TestFOVResizeVisionMap(t *testing.T) {
+	rg := gruid.NewRange(0, 0, 40, 30)
+	gd := NewGrid(40, 30)
+	rand := rand.New(rand.NewSource(42))
+	gd.FillFunc(func() Cell {
+		if rand.Intn(4) == 0 {
+			return wall
+		}
+		return ground
+	})
+	olt := &OpaqueLighter{
+		Passable: func(p gruid.Point) bool { return gd.At(p) == ground },
+		MaxDist:  maxLOS,
+	}
+	src := gruid.Point{20, 15}
+	for _, lt := range []Lighter{olt, genericLighter{olt}} {
+		fov := NewFOV(rg)
+		ffov := NewFOV(rg)
+		fov.VisionMapRadius(lt, src, 5)
+		for _, r := range []int{6, 5, 4, 7, 10, 2, 0, 3} {
+			lns := fov.ResizeVisionMap(lt, r)
+			flns := ffov.VisionMapRadius(lt, src, r)
+			if len(lns) != len(flns) {
+				t.Fatalf("bad length for radius %d: %d vs %d", r, len(lns), len(flns))
+			}
+			for i, n := range lns {
+				if n != flns[i] {
+					t.Errorf("bad node for radius %d: %v vs %v", r, n, flns[i])
+				}
+			}
+			rg.Iter(func(p gruid.Point) {
+				c, ok := fov.At(p)
+				fc, fok := ffov.At(p)
+				if c != fc || ok != fok {
+					t.Errorf("bad cost at %v for radius %d: %d vs %d", p, r, c, fc)
+				}
+			})
+		}
+	}
+	fov := NewFOV(rg)
+	fov.LightMap(olt, []gruid.Point{src})
+	if lns := fov.ResizeVisionMap(olt, 3); len(lns) != len(NewFOV(rg).VisionMapRadius(olt, src, 3)) {
+		t.Errorf("bad resize after light map")
+	}
+}
+
+func TestFOVCone(t *testing.T) {
+	rg := gruid.NewRange(0, 0, 40, 30)
+	gd := NewGrid(40, 30)
+	rand := rand.New(rand.NewSource(7))
+	gd.FillFunc(func() Cell {
+		if rand.Intn(5) == 0 {
+			return wall
+		}
+		return ground
+	})
+	passable := func(p gruid.Point) bool { return gd.At(p) == ground }
+	lt := &OpaqueLighter{Passable: passable, MaxDist: maxLOS}
+	src := gruid.Point{20, 15}
+	full := NewFOV(rg)
+	fov := NewFOV(rg)
+	for _, dir := range []gruid.Point{{1, 0}, {0, -1}, {-1, 1}, {2, 1}} {
+		for _, angle := range []int{30, 90, 200} {
+			cn := newCone(dir, angle)
+			lns := fov.VisionCone(lt, src, dir, angle)
+			count := 0
+			for _, n := range full.VisionMap(lt, src) {
+				c, ok := fov.At(n.P)
+				if !cn.contains(n.P.Sub(src)) {
+					if ok || c != 0 {
+						t.Errorf("visible position outside cone: %v (cost %d)", n.P, c)
+					}
+					continue
+				}
+				count++
+				if !ok || c != n.Cost {
+					t.Errorf("bad cone cost at %v: %d vs %d", n.P, c, n.Cost)
+				}
+			}
+			if count != len(lns) {
+				t.Errorf("bad cone length: %d vs %d", len(lns), count)
+			}
+			vis := fov.SSCVisionCone(src, dir, angle, maxLOS, passable, true)
+			count = 0
+			for _, p := range full.SSCVisionMap(src, maxLOS, passable, true) {
+				if cn.contains(p.Sub(src)) {
+					count++
+					if !fov.Visible(p) {
+						t.Errorf("not visible in SSC cone: %v", p)
+					}
+				} else if fov.Visible(p) {
+					t.Errorf("visible outside SSC cone: %v", p)
+				}
+			}
+			if count != len(vis) {
+				t.Errorf("bad SSC cone length: %d vs %d", len(vis), count)
+			}
+		}
+	}
+}
+
+func BenchmarkFOVOpaque(b *testing.B) {
+	fov := NewFOV(gruid.NewRange(0, 0, 80, 24))
+	fov.Reserve(maxLOS)
+	lt := &OpaqueLighter{Passable: func(p gruid.Point) bool { return true }, MaxDist: maxLOS}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fov.VisionMap(lt, gruid.Point{20, 10})
+	}
+}
+
+func BenchmarkFOVOpaqueGeneric(b *testing.B) {
+	fov := NewFOV(gruid.NewRange(0, 0, 80, 24))
+	fov.Reserve(maxLOS)
+	lt := &genericLighter{&OpaqueLighter{Passable: func(p gruid.Point) bool { return true }, MaxDist: maxLOS}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fov.VisionMap(lt, gruid.Point{20, 10})
+	}
+}
+
 func BenchmarkFOV(b *testing.B) {
 	fov := NewFOV(gruid.NewRange(-maxLOS, -maxLOS, maxLOS+1, maxLOS+1))
 	lt := &lighter{max: maxLOS}
@@ -214,3 +383,35 @@ func BenchmarkFOV100x100(b *testing.B) {
 //fov.VisionMap(lt, gruid.Point{200, 200})
 //}
 //}
+
+func TestFacingLighter(t *testing.T) {
+	rg := gruid.NewRange(0, 0, 40, 30)
+	passable := func(p gruid.Point) bool { return true }
+	lt := &FacingLighter{
+		Lighter:    &OpaqueLighter{Passable: passable, MaxDist: 10},
+		Dir:        gruid.Point{1, 0},
+		Forward:    90,
+		Peripheral: 180,
+		Multiplier: 2,
+	}
+	src := gruid.Point{20, 15}
+	fov := NewFOV(rg)
+	fov.VisionMap(lt, src)
+	if c, ok := fov.At(src.Add(gruid.Point{8, 0})); !ok || c != 8 {
+		t.Errorf("bad forward cost: %d (%v)", c, ok)
+	}
+	if c, ok := fov.At(src.Add(gruid.Point{0, 4})); !ok || c != 8 {
+		t.Errorf("bad peripheral cost: %d (%v)", c, ok)
+	}
+	if c, _ := fov.At(src.Add(gruid.Point{0, 6})); c <= 10 {
+		t.Errorf("peripheral position too far is visible: %d", c)
+	}
+	if c, _ := fov.At(src.Add(gruid.Point{-1, 0})); c <= 10 {
+		t.Errorf("blind spot position is visible: %d", c)
+	}
+	lt.Dir = gruid.Point{}
+	fov.VisionMap(lt, src)
+	if c, ok := fov.At(src.Add(gruid.Point{-5, 0})); !ok || c != 5 {
+		t.Errorf("bad cost without facing: %d (%v)", c, ok)
+	}
+}
@@ -149,6 +149,24 @@ func TestMap(t *testing.T) {
 	}
 }
 
+func TestDraw(t *testing.T) {
+	gd := NewGrid(10, 10)
+	gd.DrawLine(gruid.Point{0, 0}, gruid.Point{9, 9}, Cell(1))
+	if gd.Count(Cell(1)) != 10 {
+		t.Errorf("bad line count: %d", gd.Count(Cell(1)))
+	}
+	gd.Fill(Cell(0))
+	gd.DrawRect(gruid.NewRange(1, 1, 5, 4), Cell(1))
+	if gd.Count(Cell(1)) != 10 {
+		t.Errorf("bad rect count: %d", gd.Count(Cell(1)))
+	}
+	gd.Fill(Cell(0))
+	gd.DrawCircle(gruid.Point{5, 5}, 0, Cell(1))
+	if gd.Count(Cell(1)) != 1 || gd.At(gruid.Point{5, 5}) != Cell(1) {
+		t.Errorf("bad null radius circle")
+	}
+}
+
 func TestCount(t *testing.T) {
 	gd := NewGrid(80, 10)
 	if gd.Count(Cell(0)) != 800 {
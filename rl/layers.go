@@ -0,0 +1,34 @@
+package rl
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// Layer8 is a side table associating a uint8 value with each position, such
+// as a danger level or a flag set. It is a generic grid, so it has the same
+// slicing semantics as Grid: it is a slice type representing a rectangular
+// range within an underlying original layer. It can be used to maintain
+// metadata in parallel to a map grid of the same size, using the same
+// positions.
+//
+// Layer8 elements must be created with NewLayer8.
+type Layer8 = gruid.GridOf[uint8]
+
+// NewLayer8 returns a new layer with given width and height in cells, filled
+// with zero values.
+func NewLayer8(w, h int) Layer8 {
+	return gruid.NewGridOf[uint8](w, h)
+}
+
+// Layer16 is a side table associating a uint16 value with each position,
+// such as a region identifier. Like Layer8, it is a generic grid with the same
+// slicing semantics as Grid.
+//
+// Layer16 elements must be created with NewLayer16.
+type Layer16 = gruid.GridOf[uint16]
+
+// NewLayer16 returns a new layer with given width and height in cells, filled
+// with zero values.
+func NewLayer16(w, h int) Layer16 {
+	return gruid.NewGridOf[uint16](w, h)
+}
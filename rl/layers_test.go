@@ -0,0 +1,67 @@
+package rl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestLayer8(t *testing.T) {
+	l := NewLayer8(10, 8)
+	if l.Size() != (gruid.Point{10, 8}) {
+		t.Errorf("bad size: %v", l.Size())
+	}
+	l.Set(gruid.Point{3, 4}, 7)
+	if v := l.At(gruid.Point{3, 4}); v != 7 {
+		t.Errorf("bad value: %d", v)
+	}
+	if v := l.At(gruid.Point{-1, 4}); v != 0 {
+		t.Errorf("bad out of range value: %d", v)
+	}
+	sl := l.Slice(gruid.NewRange(2, 2, 6, 6))
+	if v := sl.At(gruid.Point{1, 2}); v != 7 {
+		t.Errorf("bad slice value: %d", v)
+	}
+	sl.Fill(3)
+	count := 0
+	l.Iter(func(p gruid.Point, v uint8) {
+		if v == 3 {
+			count++
+		}
+	})
+	if count != 16 {
+		t.Errorf("bad fill count: %d", count)
+	}
+	if sl.Contains(gruid.Point{4, 0}) {
+		t.Errorf("slice contains out of range position")
+	}
+	if l.Slice(gruid.NewRange(8, 6, 20, 20)).Size() != (gruid.Point{2, 2}) {
+		t.Errorf("bad clamped slice")
+	}
+}
+
+func TestLayer16Gob(t *testing.T) {
+	l := NewLayer16(6, 5)
+	l.Map(func(p gruid.Point, v uint16) uint16 { return uint16(1000*p.X + p.Y) })
+	sl := l.Slice(gruid.NewRange(1, 1, 4, 4))
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	if err := ge.Encode(&sl); err != nil {
+		t.Fatal(err)
+	}
+	var nl Layer16
+	gd := gob.NewDecoder(&buf)
+	if err := gd.Decode(&nl); err != nil {
+		t.Fatal(err)
+	}
+	if nl.Bounds() != sl.Bounds() {
+		t.Errorf("bad bounds: %v", nl.Bounds())
+	}
+	sl.Iter(func(p gruid.Point, v uint16) {
+		if nl.At(p) != v {
+			t.Errorf("bad decoded value at %v: %d", p, nl.At(p))
+		}
+	})
+}
@@ -3,6 +3,7 @@ package rl
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"unicode/utf8"
 
@@ -206,6 +207,931 @@ func (mg MapGen) countWalls(p gruid.Point, w Cell, radius int, countOut bool) in
 	return count
 }
 
+// Island generates an island-like landmass surrounded by water, suitable for
+// overworld maps, and returns the range of the largest landmass, relative to
+// the destination grid. Land is distributed using random value noise combined
+// with a radial falloff from the center, so that the border is always water.
+// The land fraction landp is a float between 0 and 1. Coastlines are then
+// smoothed with a number of iterations of a majority cellular automata rule.
+// The result may contain several landmasses.
+func (mg MapGen) Island(water, land Cell, landp float64, smoothing int) gruid.Range {
+	if landp > 0.9 {
+		landp = 0.9
+	}
+	if landp < 0.01 {
+		landp = 0.01
+	}
+	max := mg.Grid.Size()
+	if max.X <= 2 || max.Y <= 2 {
+		mg.Grid.Fill(water)
+		return gruid.Range{}
+	}
+	heights := make([]float64, max.X*max.Y)
+	coarse := mg.noiseLattice(max, 8)
+	fine := mg.noiseLattice(max, 3)
+	cx, cy := float64(max.X-1)/2, float64(max.Y-1)/2
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			dx, dy := (float64(x)-cx)/cx, (float64(y)-cy)/cy
+			d := dx*dx + dy*dy
+			h := coarse(x, y) + 0.5*fine(x, y) - 1.5*d
+			if x == 0 || y == 0 || x == max.X-1 || y == max.Y-1 {
+				h = -10
+			}
+			heights[y*max.X+x] = h
+		}
+	}
+	sorted := make([]float64, len(heights))
+	copy(sorted, heights)
+	sort.Float64s(sorted)
+	threshold := sorted[len(sorted)-1-int(float64(len(sorted))*landp)]
+	it := mg.Grid.Iterator()
+	for i := 0; it.Next(); i++ {
+		if heights[i] > threshold {
+			it.SetCell(land)
+		} else {
+			it.SetCell(water)
+		}
+	}
+	bufgd := NewGrid(max.X, max.Y)
+	for i := 0; i < smoothing; i++ {
+		bufgd.Map(func(p gruid.Point, c Cell) Cell {
+			if p.X == 0 || p.Y == 0 || p.X == max.X-1 || p.Y == max.Y-1 {
+				return water
+			}
+			if mg.countWalls(p, land, 1, false) >= 5 {
+				return land
+			}
+			return water
+		})
+		mg.Grid.Copy(bufgd)
+	}
+	return mg.largestLandmass(land)
+}
+
+// noiseLattice returns a function computing random value noise in [0, 1) with
+// a given lattice step, using bilinear interpolation.
+func (mg MapGen) noiseLattice(max gruid.Point, step int) func(x, y int) float64 {
+	w, h := max.X/step+2, max.Y/step+2
+	lattice := make([]float64, w*h)
+	for i := range lattice {
+		lattice[i] = mg.Rand.Float64()
+	}
+	return func(x, y int) float64 {
+		lx, ly := x/step, y/step
+		fx, fy := float64(x%step)/float64(step), float64(y%step)/float64(step)
+		v00, v10 := lattice[ly*w+lx], lattice[ly*w+lx+1]
+		v01, v11 := lattice[(ly+1)*w+lx], lattice[(ly+1)*w+lx+1]
+		top := v00 + fx*(v10-v00)
+		bottom := v01 + fx*(v11-v01)
+		return top + fy*(bottom-top)
+	}
+}
+
+// largestLandmass returns the bounding range of the largest 4-connected group
+// of land cells.
+func (mg MapGen) largestLandmass(land Cell) gruid.Range {
+	max := mg.Grid.Size()
+	seen := make([]bool, max.X*max.Y)
+	stack := []gruid.Point{}
+	best := 0
+	bestrg := gruid.Range{}
+	dirs := [4]gruid.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	mg.Grid.Iter(func(p gruid.Point, c Cell) {
+		if c != land || seen[p.Y*max.X+p.X] {
+			return
+		}
+		seen[p.Y*max.X+p.X] = true
+		stack = append(stack[:0], p)
+		n := 0
+		rg := gruid.Range{Min: p, Max: p.Shift(1, 1)}
+		for len(stack) > 0 {
+			q := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			n++
+			rg = rg.Union(gruid.Range{Min: q, Max: q.Shift(1, 1)})
+			for _, d := range dirs {
+				r := q.Add(d)
+				if !mg.Grid.Contains(r) || seen[r.Y*max.X+r.X] || mg.Grid.AtU(r) != land {
+					continue
+				}
+				seen[r.Y*max.X+r.X] = true
+				stack = append(stack, r)
+			}
+		}
+		if n > best {
+			best = n
+			bestrg = rg
+		}
+	})
+	return bestrg
+}
+
+// RoomsConfig describes the parameters of the RoomsAndCorridors generator.
+type RoomsConfig struct {
+	Rooms   int         // maximum number of rooms to place
+	MinSize gruid.Point // minimum room size (default: 3x3)
+	MaxSize gruid.Point // maximum room size (default: 10x6)
+
+	// Size is an optional custom room size distribution. If non-nil, it
+	// is used instead of MinSize and MaxSize.
+	Size func(*rand.Rand) gruid.Point
+
+	// Tries is the number of placement attempts for each room (default:
+	// 30). Rooms that do not fit anywhere are skipped.
+	Tries int
+
+	// Astar makes corridors be dug with A*, so that they go around rooms
+	// and reuse previous corridors, instead of being simple L-shaped
+	// corridors. The corridor style's digger is then ignored.
+	Astar bool
+
+	Corridor CorridorStyle // corridor style (default: L-shaped, width 1)
+
+	Door     Cell    // cell used for doors
+	DoorProb float64 // probability of placing a door at a room entrance
+}
+
+// Room represents a rectangular room placed by RoomsAndCorridors.
+type Room struct {
+	Range     gruid.Range   // room floor, relative to the destination grid
+	Entrances []gruid.Point // positions of corridors entering the room
+}
+
+// RoomsAndCorridors generates a classic dungeon made of non-overlapping
+// rectangular rooms connected by corridors, and returns the placed rooms.
+// Each room is connected to the closest room among the previously placed
+// ones, so the result is connected. Rooms are separated by at least one
+// wall, and never touch the border of the destination grid.
+//
+// Doors can only be placed at entrances that are surrounded by walls on both
+// sides, as in a doorway.
+func (mg MapGen) RoomsAndCorridors(wall, floor Cell, cfg RoomsConfig) []Room {
+	if cfg.MinSize.X <= 0 || cfg.MinSize.Y <= 0 {
+		cfg.MinSize = gruid.Point{3, 3}
+	}
+	if cfg.MaxSize.X < cfg.MinSize.X || cfg.MaxSize.Y < cfg.MinSize.Y {
+		cfg.MaxSize = gruid.Point{10, 6}
+		if cfg.MaxSize.X < cfg.MinSize.X {
+			cfg.MaxSize.X = cfg.MinSize.X
+		}
+		if cfg.MaxSize.Y < cfg.MinSize.Y {
+			cfg.MaxSize.Y = cfg.MinSize.Y
+		}
+	}
+	if cfg.Tries <= 0 {
+		cfg.Tries = 30
+	}
+	mg.Grid.Fill(wall)
+	max := mg.Grid.Size()
+	inner := mg.Grid.Range().Shift(1, 1, -1, -1)
+	rooms := []Room{}
+	for i := 0; i < cfg.Rooms; i++ {
+		for j := 0; j < cfg.Tries; j++ {
+			var size gruid.Point
+			if cfg.Size != nil {
+				size = cfg.Size(mg.Rand)
+			} else {
+				size.X = cfg.MinSize.X + mg.rand(cfg.MaxSize.X-cfg.MinSize.X+1)
+				size.Y = cfg.MinSize.Y + mg.rand(cfg.MaxSize.Y-cfg.MinSize.Y+1)
+			}
+			if size.X <= 0 || size.Y <= 0 || size.X > inner.Size().X || size.Y > inner.Size().Y {
+				continue
+			}
+			p := gruid.Point{1 + mg.rand(max.X-1-size.X), 1 + mg.rand(max.Y-1-size.Y)}
+			rg := gruid.Range{Min: p, Max: p.Add(size)}
+			if !rg.In(inner) || overlapsRooms(rooms, rg.Shift(-1, -1, 1, 1)) {
+				continue
+			}
+			mg.Grid.Slice(rg).Fill(floor)
+			rooms = append(rooms, Room{Range: rg})
+			break
+		}
+	}
+	var cp *corridorPather
+	if cfg.Astar {
+		cp = newCorridorPather(mg.Grid, rooms, floor)
+	}
+	for i := 1; i < len(rooms); i++ {
+		j := closestRoom(rooms[:i], rooms[i].Range)
+		from, to := mg.roomPoint(rooms[i].Range), mg.roomPoint(rooms[j].Range)
+		dig := func(p gruid.Point) { mg.Grid.Set(p, floor) }
+		if cp != nil {
+			mg.digPath(cfg.Corridor, cp.pr.AstarPath(cp, from, to), dig)
+			continue
+		}
+		mg.dig(cfg.Corridor, from, to, dig)
+	}
+	for i := range rooms {
+		mg.roomEntrances(&rooms[i], wall, cfg.Door, cfg.DoorProb)
+	}
+	return rooms
+}
+
+func overlapsRooms(rooms []Room, rg gruid.Range) bool {
+	for _, r := range rooms {
+		if r.Range.Overlaps(rg) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestRoom returns the index of the room whose center is the closest to
+// the center of the given range.
+func closestRoom(rooms []Room, rg gruid.Range) int {
+	c := roomCenter(rg)
+	best, bestd := 0, -1
+	for i, r := range rooms {
+		d := paths.DistanceManhattan(c, roomCenter(r.Range))
+		if bestd < 0 || d < bestd {
+			best, bestd = i, d
+		}
+	}
+	return best
+}
+
+func roomCenter(rg gruid.Range) gruid.Point {
+	return gruid.Point{(rg.Min.X + rg.Max.X) / 2, (rg.Min.Y + rg.Max.Y) / 2}
+}
+
+// roomPoint returns a random position in a room.
+func (mg MapGen) roomPoint(rg gruid.Range) gruid.Point {
+	size := rg.Size()
+	return rg.Min.Shift(mg.rand(size.X), mg.rand(size.Y))
+}
+
+// lPath calls fn on the positions of an L-shaped path between two positions,
+// starting randomly either horizontally or vertically. The corner position
+// may be visited twice.
+func (mg MapGen) lPath(from, to gruid.Point, fn func(gruid.Point)) {
+	corner := gruid.Point{to.X, from.Y}
+	if mg.rand(2) == 0 {
+		corner = gruid.Point{from.X, to.Y}
+	}
+	gruid.NewRange(from.X, from.Y, corner.X, corner.Y).Shift(0, 0, 1, 1).Iter(fn)
+	gruid.NewRange(to.X, to.Y, corner.X, corner.Y).Shift(0, 0, 1, 1).Iter(fn)
+}
+
+// Digger describes the shape of corridors dug between two positions by map
+// generators, such as RoomsAndCorridors, BSP or Connect. Custom diggers can
+// be used for other aesthetics, such as cavernous passages.
+type Digger interface {
+	// Dig calls dig on the positions of a corridor from a position to
+	// another, both included, within the map generator's grid. The
+	// positions should form a corridor connected using cardinal moves,
+	// so that it is walkable, but they may be visited in any order, and
+	// more than once.
+	Dig(mg MapGen, from, to gruid.Point, dig func(gruid.Point))
+}
+
+// CorridorStyle describes the style of corridors dug by map generators.
+type CorridorStyle struct {
+	Digger Digger // corridor shape (default: LCorridor)
+	Width  int    // corridor width (default: 1)
+}
+
+// LCorridor is a Digger for L-shaped corridors, starting randomly either
+// horizontally or vertically.
+type LCorridor struct{}
+
+// Dig implements Digger.Dig.
+func (LCorridor) Dig(mg MapGen, from, to gruid.Point, dig func(gruid.Point)) {
+	mg.lPath(from, to, dig)
+}
+
+// StraightCorridor is a Digger for corridors that follow as much as possible
+// the straight line between both positions, with a staircase shape for
+// diagonal directions.
+type StraightCorridor struct{}
+
+// Dig implements Digger.Dig.
+func (StraightCorridor) Dig(mg MapGen, from, to gruid.Point, dig func(gruid.Point)) {
+	delta := to.Sub(from)
+	dx, dy := abs(delta.X), abs(delta.Y)
+	sx, sy := sign(delta.X), sign(delta.Y)
+	p := from
+	dig(p)
+	for ix, iy := 0, 0; ix < dx || iy < dy; {
+		if (1+2*ix)*dy < (1+2*iy)*dx {
+			p.X += sx
+			ix++
+		} else {
+			p.Y += sy
+			iy++
+		}
+		dig(p)
+	}
+}
+
+// WindingCorridor is a Digger for winding corridors, made by a random walk
+// biased toward the destination.
+type WindingCorridor struct {
+	// Wiggle is the probability, between 0 and 1, of a random step in any
+	// direction, instead of a step toward the destination. Values above
+	// 0.5 produce very erratic corridors. With zero wiggle, the corridor
+	// is a random staircase between both positions.
+	Wiggle float64
+}
+
+// Dig implements Digger.Dig.
+func (wc WindingCorridor) Dig(mg MapGen, from, to gruid.Point, dig func(gruid.Point)) {
+	rg := mg.Grid.Range()
+	if inner := rg.Shift(1, 1, -1, -1); from.In(inner) && to.In(inner) {
+		// keep the grid's border
+		rg = inner
+	}
+	max := 4 * (paths.DistanceManhattan(from, to) + 1)
+	p := from
+	dig(p)
+	for steps := 0; p != to; steps++ {
+		if steps > max {
+			// avoid long walks with big wiggle values
+			mg.lPath(p, to, dig)
+			return
+		}
+		q := p
+		if mg.Rand.Float64() < wc.Wiggle {
+			q = q.Add(Dirs4[mg.rand(4)])
+			if !q.In(rg) {
+				continue
+			}
+		} else {
+			delta := to.Sub(p)
+			if mg.rand(abs(delta.X)+abs(delta.Y)) < abs(delta.X) {
+				q.X += sign(delta.X)
+			} else {
+				q.Y += sign(delta.Y)
+			}
+		}
+		p = q
+		dig(p)
+	}
+}
+
+// dig digs a corridor between two positions with the given style.
+func (mg MapGen) dig(cs CorridorStyle, from, to gruid.Point, fn func(gruid.Point)) {
+	d := cs.Digger
+	if d == nil {
+		d = LCorridor{}
+	}
+	d.Dig(mg, from, to, mg.widen(cs.Width, fn))
+}
+
+// digPath digs a corridor along a path with the given style's width.
+func (mg MapGen) digPath(cs CorridorStyle, path []gruid.Point, fn func(gruid.Point)) {
+	fn = mg.widen(cs.Width, fn)
+	for _, p := range path {
+		fn(p)
+	}
+}
+
+// widen returns a function that calls fn on a square of the given width
+// around a position, instead of only the position. Extra positions on the
+// grid's border are excluded.
+func (mg MapGen) widen(w int, fn func(gruid.Point)) func(gruid.Point) {
+	if w <= 1 {
+		return fn
+	}
+	inner := mg.Grid.Range().Shift(1, 1, -1, -1)
+	return func(p gruid.Point) {
+		fn(p)
+		min := p.Shift(-(w-1)/2, -(w-1)/2)
+		rg := gruid.Range{Min: min, Max: min.Shift(w, w)}.Intersect(inner)
+		rg.Iter(func(q gruid.Point) {
+			if q != p {
+				fn(q)
+			}
+		})
+	}
+}
+
+// roomEntrances records the entrances of a room, placing doors where
+// appropriate.
+func (mg MapGen) roomEntrances(r *Room, wall, door Cell, doorProb float64) {
+	rg := r.Range
+	outline := rg.Shift(-1, -1, 1, 1)
+	outline.Iter(func(p gruid.Point) {
+		if p.In(rg) || mg.Grid.At(p) == wall || !p.In(mg.Grid.Range()) {
+			return
+		}
+		horizontal := p.Y == outline.Min.Y || p.Y == outline.Max.Y-1
+		vertical := p.X == outline.Min.X || p.X == outline.Max.X-1
+		if horizontal && vertical {
+			// corner
+			return
+		}
+		r.Entrances = append(r.Entrances, p)
+		if doorProb <= 0 || mg.Rand.Float64() >= doorProb {
+			return
+		}
+		var q, q2 gruid.Point
+		if horizontal {
+			q, q2 = p.Shift(-1, 0), p.Shift(1, 0)
+		} else {
+			q, q2 = p.Shift(0, -1), p.Shift(0, 1)
+		}
+		if mg.Grid.At(q) == wall && mg.Grid.At(q2) == wall {
+			mg.Grid.Set(p, door)
+		}
+	})
+}
+
+// BSPConfig describes the parameters of the BSP generator.
+type BSPConfig struct {
+	// MinLeaf is the minimum size of a leaf region, walls included
+	// (default: 8x6).
+	MinLeaf gruid.Point
+
+	// MaxLeaf is the maximum size of a leaf region: bigger regions are
+	// always split when possible, while smaller ones may be randomly kept
+	// as leaves (default: 20x12).
+	MaxLeaf gruid.Point
+
+	// MaxRatio is the maximum aspect ratio of a region (width/height or
+	// height/width) before it is forced to be split along its longest
+	// dimension (default: 2).
+	MaxRatio float64
+
+	MinRoom gruid.Point // minimum room size (default: 3x3)
+
+	Corridor CorridorStyle // corridor style (default: L-shaped, width 1)
+
+	Door     Cell    // cell used for doors
+	DoorProb float64 // probability of placing a door at a room entrance
+}
+
+// BSPNode represents a region of a map generated by BSP. Internal nodes have
+// two children, and leaves contain a room.
+type BSPNode struct {
+	Range    gruid.Range // region range, relative to the destination grid
+	Children []*BSPNode  // sub-regions, or nil for a leaf
+	Room     Room        // room in a leaf region
+}
+
+// Leaf reports whether the node is a leaf.
+func (n *BSPNode) Leaf() bool {
+	return len(n.Children) == 0
+}
+
+// Leaves returns the leaf nodes under this node, from left/top to
+// right/bottom.
+func (n *BSPNode) Leaves() []*BSPNode {
+	if n.Leaf() {
+		return []*BSPNode{n}
+	}
+	leaves := []*BSPNode{}
+	for _, c := range n.Children {
+		leaves = append(leaves, c.Leaves()...)
+	}
+	return leaves
+}
+
+// BSP generates a dungeon using binary space partitioning: the destination
+// grid is recursively split into regions, a room is carved within each leaf
+// region, and sibling regions are connected by corridors, along the tree, so
+// that the result is connected. It returns the root of the tree, which can be
+// used, for example, to assign themes per region.
+func (mg MapGen) BSP(wall, floor Cell, cfg BSPConfig) *BSPNode {
+	if cfg.MinRoom.X <= 0 || cfg.MinRoom.Y <= 0 {
+		cfg.MinRoom = gruid.Point{3, 3}
+	}
+	if cfg.MinLeaf.X <= 0 || cfg.MinLeaf.Y <= 0 {
+		cfg.MinLeaf = gruid.Point{8, 6}
+	}
+	if cfg.MinLeaf.X < cfg.MinRoom.X+2 {
+		cfg.MinLeaf.X = cfg.MinRoom.X + 2
+	}
+	if cfg.MinLeaf.Y < cfg.MinRoom.Y+2 {
+		cfg.MinLeaf.Y = cfg.MinRoom.Y + 2
+	}
+	if cfg.MaxLeaf.X < cfg.MinLeaf.X || cfg.MaxLeaf.Y < cfg.MinLeaf.Y {
+		cfg.MaxLeaf = gruid.Point{20, 12}
+		if cfg.MaxLeaf.X < cfg.MinLeaf.X {
+			cfg.MaxLeaf.X = cfg.MinLeaf.X
+		}
+		if cfg.MaxLeaf.Y < cfg.MinLeaf.Y {
+			cfg.MaxLeaf.Y = cfg.MinLeaf.Y
+		}
+	}
+	if cfg.MaxRatio < 1 {
+		cfg.MaxRatio = 2
+	}
+	mg.Grid.Fill(wall)
+	root := mg.bspSplit(mg.Grid.Range(), cfg)
+	for _, leaf := range root.Leaves() {
+		mg.bspRoom(leaf, floor, cfg)
+	}
+	mg.bspConnect(root, floor, cfg.Corridor)
+	for _, leaf := range root.Leaves() {
+		if !leaf.Room.Range.Empty() {
+			mg.roomEntrances(&leaf.Room, wall, cfg.Door, cfg.DoorProb)
+		}
+	}
+	return root
+}
+
+// bspSplit recursively splits a region.
+func (mg MapGen) bspSplit(rg gruid.Range, cfg BSPConfig) *BSPNode {
+	n := &BSPNode{Range: rg}
+	size := rg.Size()
+	columns := size.X >= 2*cfg.MinLeaf.X
+	lines := size.Y >= 2*cfg.MinLeaf.Y
+	if !columns && !lines {
+		return n
+	}
+	if size.X <= cfg.MaxLeaf.X && size.Y <= cfg.MaxLeaf.Y && mg.rand(3) == 0 {
+		return n
+	}
+	switch {
+	case !lines:
+	case !columns:
+		columns = false
+	case float64(size.X) > cfg.MaxRatio*float64(size.Y):
+	case float64(size.Y) > cfg.MaxRatio*float64(size.X):
+		columns = false
+	default:
+		columns = mg.rand(2) == 0
+	}
+	var a, b gruid.Range
+	if columns {
+		x := cfg.MinLeaf.X + mg.rand(size.X-2*cfg.MinLeaf.X+1)
+		a, b = rg.Columns(0, x), rg.Columns(x, size.X)
+	} else {
+		y := cfg.MinLeaf.Y + mg.rand(size.Y-2*cfg.MinLeaf.Y+1)
+		a, b = rg.Lines(0, y), rg.Lines(y, size.Y)
+	}
+	n.Children = []*BSPNode{mg.bspSplit(a, cfg), mg.bspSplit(b, cfg)}
+	return n
+}
+
+// bspRoom carves a random room in a leaf, leaving at least one wall between
+// the room and the leaf's border.
+func (mg MapGen) bspRoom(leaf *BSPNode, floor Cell, cfg BSPConfig) {
+	inner := leaf.Range.Shift(1, 1, -1, -1)
+	max := inner.Size()
+	if max.X < cfg.MinRoom.X || max.Y < cfg.MinRoom.Y {
+		return
+	}
+	size := gruid.Point{
+		cfg.MinRoom.X + mg.rand(max.X-cfg.MinRoom.X+1),
+		cfg.MinRoom.Y + mg.rand(max.Y-cfg.MinRoom.Y+1),
+	}
+	p := inner.Min.Shift(mg.rand(max.X-size.X+1), mg.rand(max.Y-size.Y+1))
+	rg := gruid.Range{Min: p, Max: p.Add(size)}
+	mg.Grid.Slice(rg).Fill(floor)
+	leaf.Room = Room{Range: rg}
+}
+
+// bspConnect connects the children of each internal node, using the rooms
+// of both sub-trees that are the closest to each other.
+func (mg MapGen) bspConnect(n *BSPNode, floor Cell, cs CorridorStyle) {
+	if n.Leaf() {
+		return
+	}
+	for _, c := range n.Children {
+		mg.bspConnect(c, floor, cs)
+	}
+	a, b := bspRooms(n.Children[0]), bspRooms(n.Children[1])
+	if len(a) == 0 || len(b) == 0 {
+		return
+	}
+	best, bestd := [2]int{}, -1
+	for i, ra := range a {
+		j := closestRoom(b, ra.Range)
+		d := paths.DistanceManhattan(roomCenter(ra.Range), roomCenter(b[j].Range))
+		if bestd < 0 || d < bestd {
+			best, bestd = [2]int{i, j}, d
+		}
+	}
+	mg.dig(cs, mg.roomPoint(a[best[0]].Range), mg.roomPoint(b[best[1]].Range), func(p gruid.Point) {
+		mg.Grid.Set(p, floor)
+	})
+}
+
+// bspRooms returns the rooms of the leaves under a node.
+func bspRooms(n *BSPNode) []Room {
+	rooms := []Room{}
+	for _, leaf := range n.Leaves() {
+		if !leaf.Room.Range.Empty() {
+			rooms = append(rooms, leaf.Room)
+		}
+	}
+	return rooms
+}
+
+// corridorPather is used to dig corridors with A*, going around rooms and
+// reusing existing corridors when possible.
+type corridorPather struct {
+	pr    *paths.PathRange
+	nbs   paths.Neighbors
+	kinds Layer8
+	gd    Grid
+	floor Cell
+	inner gruid.Range
+}
+
+const (
+	rockTile uint8 = iota
+	roomTile
+	roomWallTile
+)
+
+func newCorridorPather(gd Grid, rooms []Room, floor Cell) *corridorPather {
+	max := gd.Size()
+	cp := &corridorPather{
+		pr:    paths.NewPathRange(gd.Range()),
+		kinds: NewLayer8(max.X, max.Y),
+		gd:    gd,
+		floor: floor,
+		inner: gd.Range().Shift(1, 1, -1, -1),
+	}
+	for _, r := range rooms {
+		cp.kinds.Slice(r.Range.Shift(-1, -1, 1, 1)).Fill(roomWallTile)
+	}
+	for _, r := range rooms {
+		cp.kinds.Slice(r.Range).Fill(roomTile)
+	}
+	return cp
+}
+
+func (cp *corridorPather) Neighbors(p gruid.Point) []gruid.Point {
+	return cp.nbs.Cardinal(p, func(q gruid.Point) bool { return q.In(cp.inner) })
+}
+
+func (cp *corridorPather) Cost(p, q gruid.Point) int {
+	switch cp.kinds.At(q) {
+	case roomTile:
+		return 2
+	case roomWallTile:
+		if cp.gd.At(q) == cp.floor {
+			// existing entrance
+			return 1
+		}
+		return 10
+	}
+	if cp.gd.At(q) == cp.floor {
+		return 1
+	}
+	return 3
+}
+
+func (cp *corridorPather) Estimation(p, q gruid.Point) int {
+	return paths.DistanceManhattan(p, q)
+}
+
+// MazeAlgorithm represents a maze generation algorithm.
+type MazeAlgorithm int
+
+// These constants represent the available maze generation algorithms.
+const (
+	// MazeBacktracker uses a randomized depth-first search, producing
+	// long winding corridors with few dead ends.
+	MazeBacktracker MazeAlgorithm = iota
+
+	// MazeWilson uses loop-erased random walks, producing an unbiased
+	// maze: all possible mazes are equally likely.
+	MazeWilson
+
+	// MazeKruskal uses a randomized version of Kruskal's minimum spanning
+	// tree algorithm, producing many short dead ends.
+	MazeKruskal
+)
+
+// MazeConfig describes the parameters of the Maze generator.
+type MazeConfig struct {
+	Algorithm MazeAlgorithm // maze generation algorithm
+	Width     int           // corridor width in cells (default: 1)
+
+	// Braid is the probability, between 0 and 1, for each dead end to be
+	// removed by connecting it with a neighbor cell, which adds loops.
+	// Zero produces a perfect maze, with exactly one path between any two
+	// positions.
+	Braid float64
+}
+
+// Maze directions, as bit flags for maze cell connections.
+const (
+	mazeE uint8 = 1 << iota
+	mazeS
+	mazeW
+	mazeN
+)
+
+var mazeDirs = [4]struct {
+	bit, opp uint8
+	d        gruid.Point
+}{
+	{mazeE, mazeW, gruid.Point{1, 0}},
+	{mazeS, mazeN, gruid.Point{0, 1}},
+	{mazeW, mazeE, gruid.Point{-1, 0}},
+	{mazeN, mazeS, gruid.Point{0, -1}},
+}
+
+// maze represents the abstract cells of a maze and their connections.
+type maze struct {
+	conns []uint8 // connections of each cell
+	size  gruid.Point
+}
+
+func (mz *maze) idx(p gruid.Point) int {
+	return p.Y*mz.size.X + p.X
+}
+
+func (mz *maze) contains(p gruid.Point) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < mz.size.X && p.Y < mz.size.Y
+}
+
+func (mz *maze) point(i int) gruid.Point {
+	return gruid.Point{i % mz.size.X, i / mz.size.X}
+}
+
+// connect connects a cell with its neighbor in the given direction.
+func (mz *maze) connect(p gruid.Point, dir int) {
+	md := mazeDirs[dir]
+	mz.conns[mz.idx(p)] |= md.bit
+	mz.conns[mz.idx(p.Add(md.d))] |= md.opp
+}
+
+// Maze generates a maze in the destination grid, and returns the number of
+// floor cells. The maze is made of square cells of the configured corridor
+// width, separated by walls of width one, and surrounded by walls. Remaining
+// space on the right and bottom sides, if any, is filled with walls.
+func (mg MapGen) Maze(wall, floor Cell, cfg MazeConfig) int {
+	if cfg.Width <= 0 {
+		cfg.Width = 1
+	}
+	mg.Grid.Fill(wall)
+	max := mg.Grid.Size()
+	mz := &maze{size: gruid.Point{(max.X - 1) / (cfg.Width + 1), (max.Y - 1) / (cfg.Width + 1)}}
+	if mz.size.X <= 0 || mz.size.Y <= 0 {
+		return 0
+	}
+	mz.conns = make([]uint8, mz.size.X*mz.size.Y)
+	switch cfg.Algorithm {
+	case MazeWilson:
+		mg.mazeWilson(mz)
+	case MazeKruskal:
+		mg.mazeKruskal(mz)
+	default:
+		mg.mazeBacktracker(mz)
+	}
+	if cfg.Braid > 0 {
+		mg.mazeBraid(mz, cfg.Braid)
+	}
+	return mg.mazeDraw(mz, floor, cfg.Width)
+}
+
+func (mg MapGen) mazeBacktracker(mz *maze) {
+	visited := make([]bool, len(mz.conns))
+	start := mg.rand(len(mz.conns))
+	visited[start] = true
+	stack := []gruid.Point{mz.point(start)}
+	dirs := make([]int, 0, 4)
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		dirs = dirs[:0]
+		for i, md := range mazeDirs {
+			q := p.Add(md.d)
+			if mz.contains(q) && !visited[mz.idx(q)] {
+				dirs = append(dirs, i)
+			}
+		}
+		if len(dirs) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		dir := dirs[mg.rand(len(dirs))]
+		q := p.Add(mazeDirs[dir].d)
+		mz.connect(p, dir)
+		visited[mz.idx(q)] = true
+		stack = append(stack, q)
+	}
+}
+
+func (mg MapGen) mazeWilson(mz *maze) {
+	in := make([]bool, len(mz.conns))
+	walk := make([]int, len(mz.conns)) // last direction taken from each cell
+	in[mg.rand(len(mz.conns))] = true
+	for _, i := range mg.Rand.Perm(len(mz.conns)) {
+		if in[i] {
+			continue
+		}
+		// loop-erased random walk until the maze is reached: only the
+		// last direction taken from each cell is remembered, which
+		// erases loops.
+		p := mz.point(i)
+		for !in[mz.idx(p)] {
+			dir := mg.rand(4)
+			q := p.Add(mazeDirs[dir].d)
+			if !mz.contains(q) {
+				continue
+			}
+			walk[mz.idx(p)] = dir
+			p = q
+		}
+		p = mz.point(i)
+		for !in[mz.idx(p)] {
+			dir := walk[mz.idx(p)]
+			in[mz.idx(p)] = true
+			mz.connect(p, dir)
+			p = p.Add(mazeDirs[dir].d)
+		}
+	}
+}
+
+func (mg MapGen) mazeKruskal(mz *maze) {
+	type edge struct {
+		i   int // cell index
+		dir int // east or south direction
+	}
+	edges := []edge{}
+	for i := range mz.conns {
+		p := mz.point(i)
+		for dir := 0; dir < 2; dir++ {
+			if mz.contains(p.Add(mazeDirs[dir].d)) {
+				edges = append(edges, edge{i, dir})
+			}
+		}
+	}
+	mg.Rand.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+	parent := make([]int, len(mz.conns))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, e := range edges {
+		p := mz.point(e.i)
+		a, b := find(e.i), find(mz.idx(p.Add(mazeDirs[e.dir].d)))
+		if a == b {
+			continue
+		}
+		parent[a] = b
+		mz.connect(p, e.dir)
+	}
+}
+
+// mazeBraid removes dead ends with the given probability, preferably by
+// connecting them to neighbor dead ends.
+func (mg MapGen) mazeBraid(mz *maze, braid float64) {
+	isDeadEnd := func(c uint8) bool {
+		return c == mazeE || c == mazeS || c == mazeW || c == mazeN
+	}
+	dirs := make([]int, 0, 4)
+	for _, i := range mg.Rand.Perm(len(mz.conns)) {
+		if !isDeadEnd(mz.conns[i]) || mg.Rand.Float64() >= braid {
+			continue
+		}
+		p := mz.point(i)
+		dirs = dirs[:0]
+		deadEnds := false
+		for dir, md := range mazeDirs {
+			q := p.Add(md.d)
+			if !mz.contains(q) || mz.conns[i]&md.bit != 0 {
+				continue
+			}
+			de := isDeadEnd(mz.conns[mz.idx(q)])
+			if de && !deadEnds {
+				deadEnds = true
+				dirs = dirs[:0]
+			}
+			if de || !deadEnds {
+				dirs = append(dirs, dir)
+			}
+		}
+		if len(dirs) > 0 {
+			mz.connect(p, dirs[mg.rand(len(dirs))])
+		}
+	}
+}
+
+// mazeDraw draws the maze cells and their connections with the given
+// corridor width, and returns the number of floor cells.
+func (mg MapGen) mazeDraw(mz *maze, floor Cell, w int) int {
+	count := 0
+	for i, c := range mz.conns {
+		p := mz.point(i)
+		min := gruid.Point{1 + p.X*(w+1), 1 + p.Y*(w+1)}
+		mg.Grid.Slice(gruid.Range{Min: min, Max: min.Shift(w, w)}).Fill(floor)
+		count += w * w
+		if c&mazeE != 0 {
+			mg.Grid.Slice(gruid.Range{Min: min.Shift(w, 0), Max: min.Shift(w+1, w)}).Fill(floor)
+			count += w
+		}
+		if c&mazeS != 0 {
+			mg.Grid.Slice(gruid.Range{Min: min.Shift(0, w), Max: min.Shift(w, w+1)}).Fill(floor)
+			count += w
+		}
+	}
+	return count
+}
+
 // KeepCC puts walls in all the positions unreachable from p according to last
 // CCMap or CCMapAll call on pr. Paths are supposed to be bidirectional. It
 // returns the number of cells in the remaining connected component.
@@ -227,6 +1153,270 @@ func (mg MapGen) KeepCC(pr *paths.PathRange, p gruid.Point, wall Cell) int {
 	return count
 }
 
+// ConnectConfig describes the configuration options for Connect.
+type ConnectConfig struct {
+	// Passable reports whether a cell is walkable. If nil, only cells
+	// equal to the floor cell are walkable. The floor cell itself should
+	// be walkable.
+	Passable func(Cell) bool
+
+	// Astar, if true, means that tunnels are dug using A*, so that they
+	// go preferably through existing walkable cells, instead of being
+	// dug by the corridor style's digger.
+	Astar bool
+
+	Corridor CorridorStyle // corridor style (default: L-shaped, width 1)
+}
+
+// Connect digs tunnels with the given floor cell between the walkable
+// connected components of the destination grid, until there is only one.
+// Connectivity is computed using cardinal moves. Unlike KeepCC, which fills
+// with walls all but one component, it keeps all the walkable cells. It
+// returns the number of cells that were dug.
+//
+// Starting from the largest component, tunnels are dug each time between the
+// component already connected and the closest component, according to
+// Manhattan distance.
+func (mg MapGen) Connect(floor Cell, cfg ConnectConfig) int {
+	passable := cfg.Passable
+	if passable == nil {
+		passable = func(c Cell) bool { return c == floor }
+	}
+	cnp := &connectPather{gd: mg.Grid, passable: passable}
+	pr := paths.NewPathRange(mg.Grid.Range())
+	var cp *corridorPather
+	if cfg.Astar {
+		cp = newCorridorPather(mg.Grid, nil, floor)
+	}
+	dug := 0
+	for {
+		pr.CCMapAll(cnp)
+		from, to, ok := mg.closestComponent(pr, cnp)
+		if !ok {
+			break
+		}
+		n := dug
+		dig := func(p gruid.Point) {
+			if !passable(mg.Grid.At(p)) {
+				mg.Grid.Set(p, floor)
+				dug++
+			}
+		}
+		var path []gruid.Point
+		if cp != nil {
+			path = cp.pr.AstarPath(cp, from, to)
+		}
+		if path != nil {
+			mg.digPath(cfg.Corridor, path, dig)
+		} else {
+			mg.dig(cfg.Corridor, from, to, dig)
+		}
+		if dug == n {
+			// floor cell is not walkable
+			break
+		}
+	}
+	return dug
+}
+
+// connectPather is used to compute the walkable connected components in
+// Connect.
+type connectPather struct {
+	nbs      paths.Neighbors
+	gd       Grid
+	passable func(Cell) bool
+}
+
+func (cnp *connectPather) Neighbors(p gruid.Point) []gruid.Point {
+	if !cnp.passable(cnp.gd.At(p)) {
+		return nil
+	}
+	return cnp.nbs.Cardinal(p, func(q gruid.Point) bool {
+		return cnp.passable(cnp.gd.At(q))
+	})
+}
+
+// closestComponent returns the closest positions between the largest walkable
+// component and another one, according to last CCMapAll call on pr. It
+// returns false if there is at most one walkable component.
+func (mg MapGen) closestComponent(pr *paths.PathRange, cnp *connectPather) (from, to gruid.Point, ok bool) {
+	counts := map[int]int{}
+	it := mg.Grid.Iterator()
+	for it.Next() {
+		if cnp.passable(it.Cell()) {
+			counts[pr.CCMapAt(it.P())]++
+		}
+	}
+	if len(counts) <= 1 {
+		return from, to, false
+	}
+	root, n := -1, 0
+	for id, c := range counts {
+		if c > n || c == n && id < root {
+			root, n = id, c
+		}
+	}
+	// multi-source breadth first search from the largest component
+	rg := mg.Grid.Range()
+	origins := make([]gruid.Point, rg.Size().X*rg.Size().Y)
+	seen := make([]bool, len(origins))
+	idx := func(p gruid.Point) int { return p.Y*rg.Size().X + p.X }
+	queue := []gruid.Point{}
+	it.Reset()
+	for it.Next() {
+		p := it.P()
+		if cnp.passable(it.Cell()) && pr.CCMapAt(p) == root {
+			queue = append(queue, p)
+			seen[idx(p)] = true
+			origins[idx(p)] = p
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		p := queue[i]
+		for _, q := range cnp.nbs.Cardinal(p, func(q gruid.Point) bool { return q.In(rg) }) {
+			if seen[idx(q)] {
+				continue
+			}
+			seen[idx(q)] = true
+			origins[idx(q)] = origins[idx(p)]
+			if cnp.passable(mg.Grid.At(q)) {
+				return origins[idx(q)], q, true
+			}
+			queue = append(queue, q)
+		}
+	}
+	return from, to, false
+}
+
+// RegionDoor represents a door connecting two regions of a level, such as
+// rooms, identified by their index.
+type RegionDoor struct {
+	P    gruid.Point // door position
+	A, B int         // indices of the connected regions
+}
+
+// Lock represents a lock-and-key assignment returned by LockAndKey.
+type Lock struct {
+	Doors []int // indices of the doors that are locked by this lock
+	Key   int   // index of the region where the key is to be placed
+}
+
+// LockAndKey assigns locked doors and key placements to a level described as
+// a graph of nregions regions connected by doors, such that the level is
+// always completable from the start region. It returns at most nlocks locks,
+// in an order in which they can be opened: the key of each lock is reachable
+// using only the keys of the previous locks. It does not modify the
+// destination grid: it is up to the caller to draw locked doors and keys.
+//
+// A random spanning tree of the region graph is first computed from the start
+// region. Some of its doors are then chosen to be locked, which splits the
+// tree into zones, each one entered by a locked door. Remaining doors, which
+// form the back edges of the tree, connecting regions of different zones are
+// locked by the lock of the zone opened last, so that they cannot be used to
+// bypass a lock. Keys are placed in regions belonging to zones that are
+// opened earlier.
+//
+// Regions unreachable from the start region are ignored, and their doors are
+// never locked.
+func (mg MapGen) LockAndKey(nregions int, doors []RegionDoor, start, nlocks int) []Lock {
+	if start < 0 || start >= nregions || nlocks <= 0 {
+		return nil
+	}
+	adj := make([][]int, nregions)
+	for i, d := range doors {
+		if d.A == d.B || d.A < 0 || d.B < 0 || d.A >= nregions || d.B >= nregions {
+			continue
+		}
+		adj[d.A] = append(adj[d.A], i)
+		adj[d.B] = append(adj[d.B], i)
+	}
+	// random spanning tree, using random frontier expansion
+	visited := make([]bool, nregions)
+	entry := make([]int, nregions) // tree door used to enter each region
+	order := []int{start}          // regions in discovery order
+	treeDoors := []int{}
+	visited[start] = true
+	entry[start] = -1
+	frontier := append([]int{}, adj[start]...)
+	for len(frontier) > 0 {
+		i := mg.rand(len(frontier))
+		di := frontier[i]
+		frontier[i] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+		d := doors[di]
+		r := d.B
+		if visited[r] {
+			r = d.A
+		}
+		if visited[r] {
+			continue
+		}
+		visited[r] = true
+		entry[r] = di
+		order = append(order, r)
+		treeDoors = append(treeDoors, di)
+		frontier = append(frontier, adj[r]...)
+	}
+	if nlocks > len(treeDoors) {
+		nlocks = len(treeDoors)
+	}
+	if nlocks == 0 {
+		return nil
+	}
+	locked := map[int]bool{}
+	for _, i := range mg.Rand.Perm(len(treeDoors))[:nlocks] {
+		locked[treeDoors[i]] = true
+	}
+	// zones, numbered in discovery order, so that a zone is always
+	// numbered after the zone from which it is entered.
+	zone := make([]int, nregions)
+	locks := make([]Lock, nlocks)
+	nzones := 1
+	for _, r := range order[1:] {
+		di := entry[r]
+		d := doors[di]
+		parent := d.A
+		if parent == r {
+			parent = d.B
+		}
+		if locked[di] {
+			zone[r] = nzones
+			locks[nzones-1].Doors = append(locks[nzones-1].Doors, di)
+			nzones++
+		} else {
+			zone[r] = zone[parent]
+		}
+	}
+	for di, d := range doors {
+		if locked[di] || d.A == d.B || d.A < 0 || d.B < 0 || d.A >= nregions || d.B >= nregions {
+			continue
+		}
+		if !visited[d.A] || entry[d.A] == di || entry[d.B] == di {
+			continue
+		}
+		za, zb := zone[d.A], zone[d.B]
+		if za == zb {
+			continue
+		}
+		if zb > za {
+			za = zb
+		}
+		locks[za-1].Doors = append(locks[za-1].Doors, di)
+	}
+	// key placement: regions sorted by zone
+	regions := make([]int, 0, len(order))
+	regions = append(regions, order...)
+	sort.SliceStable(regions, func(i, j int) bool { return zone[regions[i]] < zone[regions[j]] })
+	n := 0
+	for i := range locks {
+		for n < len(regions) && zone[regions[n]] <= i {
+			n++
+		}
+		locks[i].Key = regions[mg.rand(n)]
+	}
+	return locks
+}
+
 // Vault represents a prefabricated room or level section built from a textual
 // description using Parse.
 type Vault struct {
@@ -334,6 +1524,52 @@ func (v *Vault) Draw(gd Grid, fn func(rune) Cell) Grid {
 	return gd.Slice(gruid.NewRange(0, 0, v.size.X, v.size.Y))
 }
 
+// VaultEntry describes the multi-layer content associated with a rune in a
+// VaultPalette: a terrain cell, and optional values for additional layers,
+// such as item or actor spawn kinds, where zero usually means none.
+type VaultEntry struct {
+	Cell   Cell     // terrain cell
+	Layers []uint16 // values for additional layers, in order
+}
+
+// VaultPalette maps the runes of a vault's textual content to multi-layer
+// content, so that prefabricated rooms can carry their contents, and not just
+// terrain. A same palette may be shared among many vaults.
+type VaultPalette map[rune]VaultEntry
+
+// Runes returns a string containing the runes defined by the palette, in
+// unspecified order. It can be used with Vault.SetRunes.
+func (pal VaultPalette) Runes() string {
+	sb := strings.Builder{}
+	for r := range pal {
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// DrawPalette draws the vault into a grid and additional layers at once,
+// using a palette. The i-th layer receives the i-th value of palette
+// entries' Layers, or zero if there is no such value. Positions with runes
+// missing from the palette are left unchanged in all layers. It returns the
+// grid slice that was drawn.
+func (v *Vault) DrawPalette(gd Grid, pal VaultPalette, layers ...Layer16) Grid {
+	v.Iter(func(p gruid.Point, r rune) {
+		e, ok := pal[r]
+		if !ok {
+			return
+		}
+		gd.Set(p, e.Cell)
+		for i, l := range layers {
+			var n uint16
+			if i < len(e.Layers) {
+				n = e.Layers[i]
+			}
+			l.Set(p, n)
+		}
+	})
+	return gd.Slice(gruid.NewRange(0, 0, v.size.X, v.size.Y))
+}
+
 // Reflect changes the content with its reflection with respect to a middle
 // vertical axis (order of characters in each line reversed). The result has
 // the same size.
@@ -411,3 +1647,370 @@ func (v *Vault) rotate180() {
 	}
 	v.content = sb.String()
 }
+
+// VaultPlacement describes a constraint on the position of a vault placed by
+// PlaceVaults.
+type VaultPlacement int
+
+// These constants represent the available vault placement constraints.
+const (
+	PlaceAnywhere VaultPlacement = iota // anywhere in the grid
+	PlaceEdge                           // touching an edge of the grid
+	PlaceCenter                         // centered in the middle half of the grid
+)
+
+// VaultSpec describes a vault candidate for placement with PlaceVaults.
+type VaultSpec struct {
+	Vault *Vault
+
+	// Weight is the relative probability of choosing this vault. A
+	// non-positive weight is treated as 1.
+	Weight int
+
+	// Max is the maximum number of times the vault can be placed. If zero,
+	// there is no limit.
+	Max int
+
+	// Placement is a constraint on the vault's position.
+	Placement VaultPlacement
+
+	// Transform, if true, means that a random rotation and reflection are
+	// applied to the vault before each placement. The original vault is
+	// not modified.
+	Transform bool
+}
+
+// VaultsConfig describes the configuration options for PlaceVaults.
+type VaultsConfig struct {
+	// Vaults is the list of vault candidates.
+	Vaults []VaultSpec
+
+	// N is the number of vaults to place.
+	N int
+
+	// MinDistance is the minimum number of cells between any two placed
+	// vaults. If zero, vaults may be adjacent, but never overlap.
+	MinDistance int
+
+	// Connectors contains the runes that mark connection points in the
+	// vaults' textual content, such as possible entrances. Placements
+	// with a connection point on the border of the destination grid are
+	// rejected, as nothing could be connected to it.
+	Connectors string
+
+	// Tries is the maximum number of random positions tried for each
+	// vault. If zero, a default of 100 is used.
+	Tries int
+}
+
+// PlacedVault describes a vault placed by PlaceVaults.
+type PlacedVault struct {
+	Index      int           // index of the vault in VaultsConfig.Vaults
+	Vault      *Vault        // placed vault, possibly transformed
+	Range      gruid.Range   // range occupied in the destination grid
+	Connectors []gruid.Point // positions of connection points in the destination grid
+}
+
+// PlaceVaults scatters randomly chosen vaults into the destination grid,
+// drawing them with the given mapping from runes to cells, as in Vault.Draw.
+// Placed vaults never overlap, and they satisfy their placement constraints.
+// Vaults are chosen according to their weights among those that have not
+// yet been placed Max times.
+//
+// It returns the placed vaults, in placement order. Less than cfg.N vaults
+// may be placed, if no valid position was found for some after cfg.Tries
+// attempts.
+func (mg MapGen) PlaceVaults(cfg VaultsConfig, fn func(rune) Cell) []PlacedVault {
+	tries := cfg.Tries
+	if tries <= 0 {
+		tries = 100
+	}
+	counts := make([]int, len(cfg.Vaults))
+	placed := []PlacedVault{}
+	for n := 0; n < cfg.N; n++ {
+		i := mg.chooseVault(cfg.Vaults, counts)
+		if i < 0 {
+			break
+		}
+		counts[i]++
+		spec := cfg.Vaults[i]
+		v := spec.Vault
+		if spec.Transform {
+			vc := *v
+			vc.Rotate(mg.rand(4))
+			if mg.rand(2) == 0 {
+				vc.Reflect()
+			}
+			v = &vc
+		}
+		for j := 0; j < tries; j++ {
+			p, ok := mg.vaultPosition(v.Size(), spec.Placement)
+			if !ok {
+				break
+			}
+			pv, ok := mg.vaultPlacement(v, p, placed, cfg)
+			if !ok {
+				continue
+			}
+			pv.Index = i
+			v.Draw(mg.Grid.Slice(pv.Range), fn)
+			placed = append(placed, pv)
+			break
+		}
+	}
+	return placed
+}
+
+// chooseVault returns the index of a random vault according to weights, or
+// -1 if no vault is available anymore.
+func (mg MapGen) chooseVault(vaults []VaultSpec, counts []int) int {
+	weight := func(i int) int {
+		vs := vaults[i]
+		if vs.Vault == nil || vs.Max > 0 && counts[i] >= vs.Max {
+			return 0
+		}
+		if vs.Weight <= 0 {
+			return 1
+		}
+		return vs.Weight
+	}
+	total := 0
+	for i := range vaults {
+		total += weight(i)
+	}
+	if total == 0 {
+		return -1
+	}
+	n := mg.rand(total)
+	for i := range vaults {
+		n -= weight(i)
+		if n < 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// vaultPosition returns a random position for the top-left corner of a vault
+// of given size, satisfying the given placement constraint. It returns false
+// if the vault does not fit in the grid.
+func (mg MapGen) vaultPosition(size gruid.Point, placement VaultPlacement) (gruid.Point, bool) {
+	max := mg.Grid.Size().Sub(size)
+	if max.X < 0 || max.Y < 0 {
+		return gruid.Point{}, false
+	}
+	p := gruid.Point{mg.rand(max.X + 1), mg.rand(max.Y + 1)}
+	switch placement {
+	case PlaceEdge:
+		switch mg.rand(4) {
+		case 0:
+			p.X = 0
+		case 1:
+			p.X = max.X
+		case 2:
+			p.Y = 0
+		default:
+			p.Y = max.Y
+		}
+	case PlaceCenter:
+		gsize := mg.Grid.Size()
+		c := gruid.Point{gsize.X/4 + mg.rand((gsize.X+1)/2), gsize.Y/4 + mg.rand((gsize.Y+1)/2)}
+		p = c.Sub(gruid.Point{size.X / 2, size.Y / 2})
+		if p.X < 0 || p.Y < 0 || p.X > max.X || p.Y > max.Y {
+			// vault too big for its center to be chosen freely
+			p = gruid.Point{max.X / 2, max.Y / 2}
+		}
+	}
+	return p, true
+}
+
+// vaultPlacement checks whether a vault can be placed at position p, and
+// returns the corresponding placement.
+func (mg MapGen) vaultPlacement(v *Vault, p gruid.Point, placed []PlacedVault, cfg VaultsConfig) (PlacedVault, bool) {
+	rg := gruid.NewRange(0, 0, v.Size().X, v.Size().Y).Add(p)
+	d := cfg.MinDistance
+	for _, pv := range placed {
+		if rg.Overlaps(pv.Range.Shift(-d, -d, d, d)) {
+			return PlacedVault{}, false
+		}
+	}
+	pv := PlacedVault{Vault: v, Range: rg}
+	if cfg.Connectors == "" {
+		return pv, true
+	}
+	max := mg.Grid.Size()
+	ok := true
+	v.Iter(func(q gruid.Point, r rune) {
+		if !strings.ContainsRune(cfg.Connectors, r) {
+			return
+		}
+		q = q.Add(p)
+		if q.X == 0 || q.Y == 0 || q.X == max.X-1 || q.Y == max.Y-1 {
+			ok = false
+		}
+		pv.Connectors = append(pv.Connectors, q)
+	})
+	return pv, ok
+}
+
+// MapStats contains quality metrics for a generated map, as computed by
+// MapGen.Stats. They can be used to tune generation parameters, or to reject
+// and regenerate maps that do not satisfy some objective thresholds.
+type MapStats struct {
+	Passable int     // number of passable cells
+	Openness float64 // ratio of passable cells to the total number of cells
+
+	// Components contains the sizes of the 4-connected components of
+	// passable cells, in decreasing order.
+	Components []int
+
+	// DeadEnds is the number of passable cells with exactly one passable
+	// cardinal neighbor.
+	DeadEnds int
+
+	// CorridorWidth is the average local width of passable cells. The
+	// local width of a cell is the size of the largest square of passable
+	// cells containing it: it is one in a corridor of width one, and the
+	// smaller side in a rectangular room.
+	CorridorWidth float64
+
+	// Diameter is the length, in cardinal moves, of the longest shortest
+	// path in the largest component. It is computed with two breadth first
+	// searches: the result is exact for maps without loops, such as
+	// perfect mazes, and a lower bound otherwise.
+	Diameter int
+}
+
+// Stats returns quality metrics for the map in the destination grid slice,
+// using the given function to determine which cells are passable.
+func (mg MapGen) Stats(passable func(Cell) bool) MapStats {
+	max := mg.Grid.Size()
+	st := MapStats{}
+	if max.X <= 0 || max.Y <= 0 {
+		return st
+	}
+	pass := make([]bool, max.X*max.Y)
+	mg.Grid.Iter(func(p gruid.Point, c Cell) {
+		if passable(c) {
+			pass[p.Y*max.X+p.X] = true
+			st.Passable++
+		}
+	})
+	st.Openness = float64(st.Passable) / float64(max.X*max.Y)
+	if st.Passable == 0 {
+		return st
+	}
+	at := func(p gruid.Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < max.X && p.Y < max.Y && pass[p.Y*max.X+p.X]
+	}
+	dirs := [4]gruid.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+	// dead ends
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			p := gruid.Point{x, y}
+			if !at(p) {
+				continue
+			}
+			n := 0
+			for _, d := range dirs {
+				if at(p.Add(d)) {
+					n++
+				}
+			}
+			if n == 1 {
+				st.DeadEnds++
+			}
+		}
+	}
+
+	// local widths: sq contains the size of the largest square of
+	// passable cells whose bottom-right corner is at a given position.
+	sq := make([]int, max.X*max.Y)
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			i := y*max.X + x
+			if !pass[i] {
+				continue
+			}
+			if x == 0 || y == 0 {
+				sq[i] = 1
+				continue
+			}
+			k := sq[i-1]
+			if up := sq[i-max.X]; up < k {
+				k = up
+			}
+			if diag := sq[i-max.X-1]; diag < k {
+				k = diag
+			}
+			sq[i] = k + 1
+		}
+	}
+	widths := make([]int, max.X*max.Y)
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			k := sq[y*max.X+x]
+			for j := y - k + 1; j <= y; j++ {
+				for i := x - k + 1; i <= x; i++ {
+					if widths[j*max.X+i] < k {
+						widths[j*max.X+i] = k
+					}
+				}
+			}
+		}
+	}
+	width := 0
+	for _, w := range widths {
+		width += w
+	}
+	st.CorridorWidth = float64(width) / float64(st.Passable)
+
+	// components and diameter
+	dist := make([]int, max.X*max.Y)
+	for i := range dist {
+		dist[i] = -1
+	}
+	queue := []gruid.Point{}
+	// bfs computes distances from p in its component, and returns the
+	// component size along with the farthest position and its distance.
+	bfs := func(p gruid.Point, mark int) (int, gruid.Point, int) {
+		queue = append(queue[:0], p)
+		dist[p.Y*max.X+p.X] = mark
+		far, fard := p, 0
+		for i := 0; i < len(queue); i++ {
+			q := queue[i]
+			d := dist[q.Y*max.X+q.X] - mark
+			if d > fard {
+				far, fard = q, d
+			}
+			for _, dir := range dirs {
+				r := q.Add(dir)
+				if !at(r) || dist[r.Y*max.X+r.X] >= mark {
+					continue
+				}
+				dist[r.Y*max.X+r.X] = mark + d + 1
+				queue = append(queue, r)
+			}
+		}
+		return len(queue), far, fard
+	}
+	largest, best := gruid.Point{}, 0
+	mg.Grid.Iter(func(p gruid.Point, c Cell) {
+		if !pass[p.Y*max.X+p.X] || dist[p.Y*max.X+p.X] >= 0 {
+			return
+		}
+		n, _, _ := bfs(p, 0)
+		if n > best {
+			largest, best = p, n
+		}
+		st.Components = append(st.Components, n)
+	})
+	sort.Sort(sort.Reverse(sort.IntSlice(st.Components)))
+	// Distances are offset by a mark greater than any previous distance,
+	// so that each search sees previous ones as unvisited.
+	mark := max.X * max.Y
+	_, far, _ := bfs(largest, mark)
+	_, _, st.Diameter = bfs(far, 2*mark)
+	return st
+}
@@ -97,6 +97,41 @@ func TestVaultSetRunes(t *testing.T) {
 	}
 }
 
+func TestVaultPalette(t *testing.T) {
+	const (
+		itemPotion uint16 = 1 + iota
+		actorOrc
+	)
+	pal := VaultPalette{
+		'#': {Cell: wall},
+		'.': {Cell: ground},
+		'!': {Cell: ground, Layers: []uint16{itemPotion}},
+		'o': {Cell: ground, Layers: []uint16{0, actorOrc}},
+	}
+	v := &Vault{}
+	v.SetRunes(pal.Runes() + "?")
+	if err := v.Parse("#!#\n.o?"); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	gd := NewGrid(4, 3)
+	gd.Fill(Cell(9))
+	items, actors := NewLayer16(4, 3), NewLayer16(4, 3)
+	actors.Fill(5)
+	drawn := v.DrawPalette(gd, pal, items, actors)
+	if drawn.Size() != (gruid.Point{3, 2}) {
+		t.Errorf("bad drawn size: %v", drawn.Size())
+	}
+	if gd.At(gruid.Point{1, 0}) != ground || items.At(gruid.Point{1, 0}) != itemPotion || actors.At(gruid.Point{1, 0}) != 0 {
+		t.Errorf("bad potion position content")
+	}
+	if gd.At(gruid.Point{1, 1}) != ground || items.At(gruid.Point{1, 1}) != 0 || actors.At(gruid.Point{1, 1}) != actorOrc {
+		t.Errorf("bad orc position content")
+	}
+	if gd.At(gruid.Point{2, 1}) != Cell(9) || actors.At(gruid.Point{2, 1}) != 5 {
+		t.Errorf("undefined rune should be left unchanged")
+	}
+}
+
 // walker implements rl.RandomWalker.
 type walker struct {
 	neighbors *paths.Neighbors
@@ -179,6 +214,102 @@ func TestCellularAutomataCave(t *testing.T) {
 	}
 }
 
+func TestIsland(t *testing.T) {
+	mapgd := NewGrid(80, 40)
+	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	mgen := MapGen{Rand: rd, Grid: mapgd}
+	rg := mgen.Island(wall, ground, 0.4, 3)
+	n := mapgd.Count(ground)
+	if n < 80*40/5 || n > 80*40*3/5 {
+		t.Errorf("bad land count: %d", n)
+	}
+	if rg.Empty() || !rg.In(mapgd.Range()) {
+		t.Errorf("bad landmass range: %v", rg)
+	}
+	if mapgd.Slice(rg).Count(ground) == 0 {
+		t.Errorf("no land in landmass range")
+	}
+	mapgd.Iter(func(p gruid.Point, c Cell) {
+		if (p.X == 0 || p.Y == 0 || p.X == 79 || p.Y == 39) && c != wall {
+			t.Errorf("land on border at %v", p)
+		}
+	})
+}
+
+func TestLockAndKey(t *testing.T) {
+	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	mgen := MapGen{Rand: rd}
+	// 6x5 grid of rooms, with a few missing vertical doors, and an
+	// isolated room.
+	const w, h = 6, 5
+	doors := []RegionDoor{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x+1 < w {
+				doors = append(doors, RegionDoor{A: y*w + x, B: y*w + x + 1})
+			}
+			if y+1 < h && rd.Intn(2) == 0 {
+				doors = append(doors, RegionDoor{A: y*w + x, B: (y+1)*w + x})
+			}
+		}
+		if y+1 < h {
+			doors = append(doors, RegionDoor{A: y * w, B: (y + 1) * w})
+		}
+	}
+	nregions := w*h + 1
+	for i := 0; i < 50; i++ {
+		locks := mgen.LockAndKey(nregions, doors, 0, 5)
+		if len(locks) != 5 {
+			t.Fatalf("bad number of locks: %d", len(locks))
+		}
+		lockOf := map[int]int{}
+		for j, l := range locks {
+			for _, di := range l.Doors {
+				if _, ok := lockOf[di]; ok {
+					t.Errorf("door locked twice: %d", di)
+				}
+				lockOf[di] = j
+			}
+		}
+		// simulate collecting keys in order
+		reach := func(keys int) []bool {
+			seen := make([]bool, nregions)
+			seen[0] = true
+			for changed := true; changed; {
+				changed = false
+				for di, d := range doors {
+					if j, ok := lockOf[di]; ok && j >= keys {
+						continue
+					}
+					if seen[d.A] != seen[d.B] {
+						seen[d.A], seen[d.B] = true, true
+						changed = true
+					}
+				}
+			}
+			return seen
+		}
+		for j, l := range locks {
+			seen := reach(j)
+			if !seen[l.Key] {
+				t.Errorf("key %d not reachable with previous keys", j)
+			}
+			if seen[doors[l.Doors[0]].A] && seen[doors[l.Doors[0]].B] {
+				t.Errorf("lock %d can be bypassed", j)
+			}
+		}
+		seen := reach(len(locks))
+		for r := 0; r < w*h; r++ {
+			if !seen[r] {
+				t.Errorf("region %d not reachable with all keys", r)
+			}
+		}
+		if seen[w*h] {
+			t.Errorf("isolated region reachable")
+		}
+	}
+}
+
 func BenchmarkMapGenRandomWalkCave(b *testing.B) {
 	mapgd := NewGrid(80, 24)
 	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -203,3 +334,337 @@ func BenchmarkMapGenCellularAutomataCave(b *testing.B) {
 		mgen.CellularAutomataCave(wall, ground, 0.40, rules)
 	}
 }
+
+func TestRoomsAndCorridors(t *testing.T) {
+	const door Cell = 2
+	for _, astar := range []bool{false, true} {
+		mapgd := NewGrid(80, 24)
+		rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		mgen := MapGen{Rand: rd, Grid: mapgd}
+		rooms := mgen.RoomsAndCorridors(wall, ground, RoomsConfig{Rooms: 9, Astar: astar, Door: door, DoorProb: 1})
+		if len(rooms) < 2 {
+			t.Fatalf("not enough rooms: %d", len(rooms))
+		}
+		inner := mapgd.Range().Shift(1, 1, -1, -1)
+		for i, r := range rooms {
+			if !r.Range.In(inner) || mapgd.Slice(r.Range).Count(ground) != r.Range.Size().X*r.Range.Size().Y {
+				t.Errorf("bad room %d: %v", i, r.Range)
+			}
+			if len(r.Entrances) == 0 {
+				t.Errorf("room without entrance: %v", r.Range)
+			}
+			for _, r2 := range rooms[:i] {
+				if r.Range.Shift(-1, -1, 1, 1).Overlaps(r2.Range) {
+					t.Errorf("overlapping rooms: %v %v", r.Range, r2.Range)
+				}
+			}
+		}
+		pr := paths.NewPathRange(mapgd.Range())
+		pp := &playerPath{neighbors: &paths.Neighbors{}, mapgd: mapgd}
+		pr.CCMapAll(pp)
+		id := pr.CCMapAt(rooms[0].Range.Min)
+		for _, r := range rooms[1:] {
+			if pr.CCMapAt(r.Range.Min) != id {
+				t.Errorf("unconnected room: %v (astar: %v)", r.Range, astar)
+			}
+		}
+		if mapgd.Count(door) == 0 {
+			t.Errorf("no doors (astar: %v)", astar)
+		}
+	}
+}
+
+func TestBSP(t *testing.T) {
+	const door Cell = 2
+	mapgd := NewGrid(80, 24)
+	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	mgen := MapGen{Rand: rd, Grid: mapgd}
+	root := mgen.BSP(wall, ground, BSPConfig{Door: door, DoorProb: 0.5})
+	leaves := root.Leaves()
+	if len(leaves) < 2 {
+		t.Fatalf("not enough leaves: %d", len(leaves))
+	}
+	var check func(n *BSPNode)
+	check = func(n *BSPNode) {
+		if n.Leaf() {
+			size := n.Range.Size()
+			if size.X < 8 || size.Y < 6 {
+				t.Errorf("leaf too small: %v", n.Range)
+			}
+			if !n.Room.Range.In(n.Range.Shift(1, 1, -1, -1)) || n.Room.Range.Empty() {
+				t.Errorf("bad room %v in leaf %v", n.Room.Range, n.Range)
+			}
+			return
+		}
+		if n.Children[0].Range.Union(n.Children[1].Range) != n.Range || n.Children[0].Range.Overlaps(n.Children[1].Range) {
+			t.Errorf("bad split of %v: %v %v", n.Range, n.Children[0].Range, n.Children[1].Range)
+		}
+		for _, c := range n.Children {
+			check(c)
+		}
+	}
+	check(root)
+	pr := paths.NewPathRange(mapgd.Range())
+	pp := &playerPath{neighbors: &paths.Neighbors{}, mapgd: mapgd}
+	pr.CCMapAll(pp)
+	id := pr.CCMapAt(leaves[0].Room.Range.Min)
+	for _, leaf := range leaves[1:] {
+		if pr.CCMapAt(leaf.Room.Range.Min) != id {
+			t.Errorf("unconnected room: %v", leaf.Room.Range)
+		}
+	}
+}
+
+func TestMaze(t *testing.T) {
+	for _, alg := range []MazeAlgorithm{MazeBacktracker, MazeWilson, MazeKruskal} {
+		for _, w := range []int{1, 2} {
+			mapgd := NewGrid(41, 21)
+			rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+			mgen := MapGen{Rand: rd, Grid: mapgd}
+			n := mgen.Maze(wall, ground, MazeConfig{Algorithm: alg, Width: w})
+			if n != mapgd.Count(ground) {
+				t.Errorf("bad count: %d vs %d", n, mapgd.Count(ground))
+			}
+			ncells := (40 / (w + 1)) * (20 / (w + 1))
+			if n != ncells*w*w+(ncells-1)*w {
+				t.Errorf("not a perfect maze (algorithm %d, width %d): %d floor cells", alg, w, n)
+			}
+			pr := paths.NewPathRange(mapgd.Range())
+			pp := &playerPath{neighbors: &paths.Neighbors{}, mapgd: mapgd}
+			if m := len(pr.CCMap(pp, gruid.Point{1, 1})); m != n {
+				t.Errorf("unconnected maze (algorithm %d): %d vs %d", alg, m, n)
+			}
+			braided := mgen.Maze(wall, ground, MazeConfig{Algorithm: alg, Width: w, Braid: 1})
+			if braided <= n {
+				t.Errorf("no loops added (algorithm %d): %d", alg, braided)
+			}
+		}
+	}
+}
+
+func TestPlaceVaults(t *testing.T) {
+	room, err := NewVault("#+##\n#..#\n####")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	big, err := NewVault(strings.Repeat("#", 10) + "\n" + strings.Repeat("#", 10))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	mapgd := NewGrid(40, 20)
+	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	mgen := MapGen{Rand: rd, Grid: mapgd}
+	const wallVault Cell = 5
+	cfg := VaultsConfig{
+		Vaults: []VaultSpec{
+			{Vault: room, Weight: 3, Transform: true},
+			{Vault: room, Placement: PlaceEdge, Max: 2},
+			{Vault: big, Placement: PlaceCenter, Max: 1},
+		},
+		N:           10,
+		MinDistance: 1,
+		Connectors:  "+",
+	}
+	placed := mgen.PlaceVaults(cfg, func(r rune) Cell {
+		switch r {
+		case '#', '+':
+			return wallVault
+		default:
+			return ground
+		}
+	})
+	if len(placed) == 0 {
+		t.Fatalf("no vaults placed")
+	}
+	counts := make([]int, len(cfg.Vaults))
+	max := mapgd.Size()
+	for i, pv := range placed {
+		counts[pv.Index]++
+		if !pv.Range.In(mapgd.Range()) {
+			t.Errorf("vault out of grid: %v", pv.Range)
+		}
+		if pv.Range.Size() != pv.Vault.Size() {
+			t.Errorf("bad range size: %v vs %v", pv.Range.Size(), pv.Vault.Size())
+		}
+		for _, q := range placed[:i] {
+			if pv.Range.Overlaps(q.Range.Shift(-1, -1, 1, 1)) {
+				t.Errorf("vaults too close: %v and %v", pv.Range, q.Range)
+			}
+		}
+		switch pv.Index {
+		case 0, 1:
+			if len(pv.Connectors) != 1 {
+				t.Errorf("bad connectors: %v", pv.Connectors)
+			}
+		case 2:
+			c := pv.Range.Min.Add(pv.Range.Size().Div(2))
+			if c.X < max.X/4 || c.X >= 3*max.X/4 || c.Y < max.Y/4 || c.Y >= 3*max.Y/4 {
+				t.Errorf("vault not centered: %v", pv.Range)
+			}
+		}
+		if pv.Index == 1 {
+			rg := pv.Range
+			if rg.Min.X != 0 && rg.Min.Y != 0 && rg.Max.X != max.X && rg.Max.Y != max.Y {
+				t.Errorf("vault not on edge: %v", rg)
+			}
+		}
+		for _, q := range pv.Connectors {
+			if !q.In(pv.Range) || q.X == 0 || q.Y == 0 || q.X == max.X-1 || q.Y == max.Y-1 {
+				t.Errorf("bad connector: %v", q)
+			}
+		}
+		floors := 2
+		if pv.Index == 2 {
+			floors = 0
+		}
+		if n := mapgd.Slice(pv.Range).Count(ground); n != floors {
+			t.Errorf("bad drawing: %d floor cells", n)
+		}
+	}
+	if counts[1] > 2 || counts[2] > 1 {
+		t.Errorf("Max not respected: %v", counts)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	for _, astar := range []bool{false, true} {
+		mapgd := NewGrid(80, 24)
+		rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		mgen := MapGen{Rand: rd, Grid: mapgd}
+		rules := []CellularAutomataRule{
+			{WCutoff1: 5, WCutoff2: 2, Reps: 4, WallsOutOfRange: true},
+			{WCutoff1: 5, WCutoff2: 25, Reps: 3, WallsOutOfRange: true},
+		}
+		n := mgen.CellularAutomataCave(wall, ground, 0.45, rules)
+		before := NewGrid(80, 24)
+		before.Copy(mapgd)
+		dug := mgen.Connect(ground, ConnectConfig{Astar: astar})
+		if count := mapgd.Count(ground); count != n+dug {
+			t.Errorf("bad dug count (astar: %v): %d + %d vs %d", astar, n, dug, count)
+		}
+		it := before.Iterator()
+		var p gruid.Point
+		for it.Next() {
+			if it.Cell() == ground {
+				p = it.P()
+				if mapgd.At(p) != ground {
+					t.Errorf("walkable cell removed at %v", p)
+				}
+			}
+		}
+		pr := paths.NewPathRange(mapgd.Range())
+		pp := &playerPath{neighbors: &paths.Neighbors{}, mapgd: mapgd}
+		if m := len(pr.CCMap(pp, p)); m != n+dug {
+			t.Errorf("not connected (astar: %v): %d vs %d", astar, m, n+dug)
+		}
+		if mgen.Connect(ground, ConnectConfig{Astar: astar}) != 0 {
+			t.Errorf("dug in connected map")
+		}
+	}
+}
+
+func TestDiggers(t *testing.T) {
+	mapgd := NewGrid(80, 24)
+	rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	mgen := MapGen{Rand: rd, Grid: mapgd}
+	diggers := []Digger{LCorridor{}, StraightCorridor{}, WindingCorridor{}, WindingCorridor{Wiggle: 0.3}, WindingCorridor{Wiggle: 0.9}}
+	for _, d := range diggers {
+		for i := 0; i < 50; i++ {
+			from := gruid.Point{1 + rd.Intn(78), 1 + rd.Intn(22)}
+			to := gruid.Point{1 + rd.Intn(78), 1 + rd.Intn(22)}
+			ps := []gruid.Point{}
+			d.Dig(mgen, from, to, func(p gruid.Point) { ps = append(ps, p) })
+			dug := map[gruid.Point]bool{}
+			for _, p := range ps {
+				dug[p] = true
+				if !p.In(mapgd.Range().Shift(1, 1, -1, -1)) {
+					t.Errorf("position out of range (%T): %v", d, p)
+				}
+			}
+			// check that the corridor is connected
+			seen := map[gruid.Point]bool{from: true}
+			queue := []gruid.Point{from}
+			for j := 0; j < len(queue); j++ {
+				for _, q := range Dirs4 {
+					q = queue[j].Add(q)
+					if dug[q] && !seen[q] {
+						seen[q] = true
+						queue = append(queue, q)
+					}
+				}
+			}
+			if !dug[from] || !seen[to] || len(seen) != len(dug) {
+				t.Errorf("bad corridor (%T) from %v to %v: %v", d, from, to, ps)
+			}
+		}
+	}
+	ps := []gruid.Point{}
+	StraightCorridor{}.Dig(mgen, gruid.Point{1, 1}, gruid.Point{5, 1}, func(p gruid.Point) { ps = append(ps, p) })
+	if len(ps) != 5 {
+		t.Errorf("bad straight corridor: %v", ps)
+	}
+}
+
+func TestCorridorStyle(t *testing.T) {
+	styles := []CorridorStyle{
+		{Digger: StraightCorridor{}},
+		{Digger: WindingCorridor{Wiggle: 0.3}, Width: 2},
+		{Width: 3},
+	}
+	for _, cs := range styles {
+		mapgd := NewGrid(80, 24)
+		rd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		mgen := MapGen{Rand: rd, Grid: mapgd}
+		rooms := mgen.RoomsAndCorridors(wall, ground, RoomsConfig{Rooms: 9, Corridor: cs})
+		pr := paths.NewPathRange(mapgd.Range())
+		pp := &playerPath{neighbors: &paths.Neighbors{}, mapgd: mapgd}
+		pr.CCMapAll(pp)
+		id := pr.CCMapAt(rooms[0].Range.Min)
+		for _, r := range rooms[1:] {
+			if pr.CCMapAt(r.Range.Min) != id {
+				t.Errorf("unconnected room: %v (style: %+v)", r.Range, cs)
+			}
+		}
+		border := mapgd.Range().Lines(0, 1)
+		if mapgd.Slice(border).Count(ground) != 0 {
+			t.Errorf("dug border (style: %+v)", cs)
+		}
+	}
+}
+
+func TestMapStats(t *testing.T) {
+	mapgd := NewGrid(8, 5)
+	mapgd.Fill(wall)
+	mapgd.Slice(gruid.NewRange(1, 1, 4, 3)).Fill(ground)
+	mapgd.Set(gruid.Point{1, 3}, ground)
+	mapgd.Set(gruid.Point{5, 1}, ground)
+	mapgd.Set(gruid.Point{6, 1}, ground)
+	mgen := MapGen{Grid: mapgd}
+	st := mgen.Stats(func(c Cell) bool { return c == ground })
+	if st.Passable != 9 || st.Openness != 9.0/40 {
+		t.Errorf("bad passable count: %d (%v)", st.Passable, st.Openness)
+	}
+	if len(st.Components) != 2 || st.Components[0] != 7 || st.Components[1] != 2 {
+		t.Errorf("bad components: %v", st.Components)
+	}
+	if st.DeadEnds != 3 {
+		t.Errorf("bad dead-end count: %d", st.DeadEnds)
+	}
+	if st.CorridorWidth != 15.0/9 {
+		t.Errorf("bad corridor width: %v", st.CorridorWidth)
+	}
+	// the room has loops, so the diameter is only a lower bound
+	if st.Diameter < 3 || st.Diameter > 4 {
+		t.Errorf("bad diameter: %d", st.Diameter)
+	}
+	if st := mgen.Stats(func(c Cell) bool { return false }); st.Passable != 0 || st.Components != nil {
+		t.Errorf("bad stats without passable cells: %+v", st)
+	}
+	mazegd := NewGrid(41, 21)
+	mgen = MapGen{Rand: rand.New(rand.NewSource(1)), Grid: mazegd}
+	n := mgen.Maze(wall, ground, MazeConfig{})
+	st = mgen.Stats(func(c Cell) bool { return c == ground })
+	if len(st.Components) != 1 || st.Components[0] != n || st.CorridorWidth != 1 || st.DeadEnds == 0 {
+		t.Errorf("bad maze stats: %+v", st)
+	}
+}
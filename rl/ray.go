@@ -0,0 +1,109 @@
+// This file implements standalone ray casting utilities.
+
+package rl
+
+import "github.com/anaseto/gruid"
+
+// Ray returns the positions of a straight line from a position to another,
+// computed using Bresenham's line algorithm, and stopping at the first
+// non-passable position after the starting one, if any. Both the starting
+// position and the blocking one are included, so the last position of the
+// ray is either the destination or the obstacle.
+//
+// Unlike FOV.Ray, it does not require prior computation of a field of
+// vision. It may be used, for example, for projectile paths.
+func Ray(from, to gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+	return ray(line(nil, from, to), passable)
+}
+
+// SymmetricRay is like Ray, but the line from a position to another always
+// contains the same positions as the line in the other direction.
+func SymmetricRay(from, to gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+	return ray(symmetricLine(nil, from, to), passable)
+}
+
+// FirstBlock returns the first non-passable position after the starting one
+// in the line from a position to another, as computed by Ray. If there is no
+// such position, the destination is returned. It can be used for quick
+// occlusion checks.
+func FirstBlock(from, to gruid.Point, passable func(gruid.Point) bool) gruid.Point {
+	last := to
+	bresenham(from, to, func(p gruid.Point) bool {
+		if p != from && !passable(p) {
+			last = p
+			return false
+		}
+		return true
+	})
+	return last
+}
+
+// SymmetricFirstBlock is like FirstBlock, but uses the same lines as
+// SymmetricRay.
+func SymmetricFirstBlock(from, to gruid.Point, passable func(gruid.Point) bool) gruid.Point {
+	ps := SymmetricRay(from, to, passable)
+	return ps[len(ps)-1]
+}
+
+// ray truncates the line after the first non-passable position, excluding
+// the starting one.
+func ray(ps []gruid.Point, passable func(gruid.Point) bool) []gruid.Point {
+	for i := 1; i < len(ps); i++ {
+		if !passable(ps[i]) {
+			return ps[:i+1]
+		}
+	}
+	return ps
+}
+
+// line appends to ps the positions in the line from p to q, both included.
+func line(ps []gruid.Point, p, q gruid.Point) []gruid.Point {
+	bresenham(p, q, func(r gruid.Point) bool {
+		ps = append(ps, r)
+		return true
+	})
+	return ps
+}
+
+// symmetricLine is like line, but it always computes the line in the same
+// direction, so that the positions do not depend on the order of p and q.
+func symmetricLine(ps []gruid.Point, p, q gruid.Point) []gruid.Point {
+	if p.Y < q.Y || p.Y == q.Y && p.X <= q.X {
+		return line(ps, p, q)
+	}
+	n := len(ps)
+	ps = line(ps, q, p)
+	for i, j := n, len(ps)-1; i < j; i, j = i+1, j-1 {
+		ps[i], ps[j] = ps[j], ps[i]
+	}
+	return ps
+}
+
+// bresenham calls fn on the positions of the line from p to q, according to
+// Bresenham's line algorithm, until fn returns false.
+func bresenham(p, q gruid.Point, fn func(gruid.Point) bool) {
+	dx := abs(q.X - p.X)
+	dy := -abs(q.Y - p.Y)
+	sx, sy := 1, 1
+	if p.X > q.X {
+		sx = -1
+	}
+	if p.Y > q.Y {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		if !fn(p) || p == q {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			p.Y += sy
+		}
+	}
+}
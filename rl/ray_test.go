@@ -0,0 +1,56 @@
+package rl
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestRay(t *testing.T) {
+	wall := gruid.Point{3, 1}
+	passable := func(p gruid.Point) bool { return p != wall }
+	ps := Ray(gruid.Point{0, 0}, gruid.Point{6, 2}, passable)
+	if len(ps) != 4 || ps[0] != (gruid.Point{0, 0}) || ps[len(ps)-1] != wall {
+		t.Errorf("bad blocked ray: %v", ps)
+	}
+	ps = Ray(gruid.Point{0, 0}, gruid.Point{6, 0}, passable)
+	if len(ps) != 7 || ps[6] != (gruid.Point{6, 0}) {
+		t.Errorf("bad free ray: %v", ps)
+	}
+	if p := FirstBlock(gruid.Point{0, 0}, gruid.Point{6, 2}, passable); p != wall {
+		t.Errorf("bad first block: %v", p)
+	}
+	if p := FirstBlock(gruid.Point{0, 0}, gruid.Point{6, 0}, passable); p != (gruid.Point{6, 0}) {
+		t.Errorf("bad first block without obstacle: %v", p)
+	}
+	if p := FirstBlock(wall, gruid.Point{0, 0}, passable); p != (gruid.Point{0, 0}) {
+		t.Errorf("starting position should not block: %v", p)
+	}
+	if ps := Ray(wall, wall, passable); len(ps) != 1 {
+		t.Errorf("bad null ray: %v", ps)
+	}
+}
+
+func TestSymmetricRay(t *testing.T) {
+	free := func(gruid.Point) bool { return true }
+	for i := 0; i < 100; i++ {
+		p := gruid.Point{rand.Intn(20), rand.Intn(20)}
+		q := gruid.Point{rand.Intn(20), rand.Intn(20)}
+		ps := SymmetricRay(p, q, free)
+		qs := SymmetricRay(q, p, free)
+		if len(ps) != len(qs) || ps[0] != p || ps[len(ps)-1] != q {
+			t.Fatalf("bad symmetric rays: %v %v", ps, qs)
+		}
+		for j := range ps {
+			if ps[j] != qs[len(qs)-1-j] {
+				t.Fatalf("asymmetric rays: %v %v", ps, qs)
+			}
+		}
+		mid := ps[len(ps)/2]
+		block := func(r gruid.Point) bool { return r != mid }
+		if len(ps) > 1 && SymmetricFirstBlock(p, q, block) != mid {
+			t.Errorf("bad symmetric first block from %v to %v", p, q)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package rl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// saveMagic identifies save files written by SaveManager, and is followed by
+// the save format version.
+const saveMagic = "GRUIDSV"
+
+// saveFormat is the current version of the save file format.
+const saveFormat = 1
+
+// SaveManager handles the serialization of game state, such as a struct
+// containing a Grid, an FOV, an EventQueue and application-specific data. The
+// state is gob-encoded and compressed with gzip. A header stores the game's
+// save version and a checksum of the compressed data, so that incompatible or
+// corrupted save files are detected before decoding.
+//
+// Save files have the following layout: the magic "GRUIDSV", a format version
+// byte, a big-endian uint32 for Version, a big-endian uint32 for the CRC-32
+// (IEEE) checksum, and the gzip stream.
+type SaveManager struct {
+	// Version is the game save version, stored in save files. It should be
+	// increased when the game state changes in incompatible ways.
+	Version uint32
+
+	// Compatible optionally reports whether save files with a given
+	// version can be loaded. If nil, only save files with the same version
+	// can be loaded.
+	Compatible func(version uint32) bool
+}
+
+// Save writes a save of data to w.
+func (sm SaveManager) Save(w io.Writer, data interface{}) error {
+	buf := bytes.Buffer{}
+	zw := gzip.NewWriter(&buf)
+	err := gob.NewEncoder(zw).Encode(data)
+	if err != nil {
+		return fmt.Errorf("save: gob encoding: %v", err)
+	}
+	err = zw.Close()
+	if err != nil {
+		return fmt.Errorf("save: gzip: %v", err)
+	}
+	hdr := make([]byte, len(saveMagic)+9)
+	copy(hdr, saveMagic)
+	hdr[len(saveMagic)] = saveFormat
+	binary.BigEndian.PutUint32(hdr[len(saveMagic)+1:], sm.Version)
+	binary.BigEndian.PutUint32(hdr[len(saveMagic)+5:], crc32.ChecksumIEEE(buf.Bytes()))
+	for _, bs := range [][]byte{hdr, buf.Bytes()} {
+		_, err = w.Write(bs)
+		if err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	}
+	return nil
+}
+
+// Load reads a save from r into data, which should be a pointer. It returns an
+// error if the save is invalid, corrupted, or has an incompatible version.
+func (sm SaveManager) Load(r io.Reader, data interface{}) error {
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("load: %v", err)
+	}
+	hlen := len(saveMagic) + 9
+	if len(bs) < hlen || string(bs[:len(saveMagic)]) != saveMagic {
+		return fmt.Errorf("load: not a save file")
+	}
+	if format := bs[len(saveMagic)]; format != saveFormat {
+		return fmt.Errorf("load: unsupported save format %d", format)
+	}
+	version := binary.BigEndian.Uint32(bs[len(saveMagic)+1:])
+	if !sm.compatible(version) {
+		return fmt.Errorf("load: incompatible save version %d (current: %d)", version, sm.Version)
+	}
+	sum := binary.BigEndian.Uint32(bs[len(saveMagic)+5:])
+	payload := bs[hlen:]
+	if crc32.ChecksumIEEE(payload) != sum {
+		return fmt.Errorf("load: checksum mismatch: corrupted save")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("load: gzip: %v", err)
+	}
+	defer zr.Close()
+	err = gob.NewDecoder(zr).Decode(data)
+	if err != nil {
+		return fmt.Errorf("load: gob decoding: %v", err)
+	}
+	return nil
+}
+
+func (sm SaveManager) compatible(version uint32) bool {
+	if sm.Compatible != nil {
+		return sm.Compatible(version)
+	}
+	return version == sm.Version
+}
+
+// SaveFile writes a save of data to the file with the given path. The save is
+// first written to a temporary file in the same directory, which then
+// replaces the file, so that an existing save is not lost if saving fails.
+func (sm SaveManager) SaveFile(path string, data interface{}) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("save: %v", err)
+	}
+	tmp := f.Name()
+	err = sm.Save(f, data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("save: %v", cerr)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// LoadFile reads a save from the file with the given path into data, which
+// should be a pointer.
+func (sm SaveManager) LoadFile(path string, data interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("load: %v", err)
+	}
+	defer f.Close()
+	return sm.Load(f, data)
+}
@@ -0,0 +1,90 @@
+package rl
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+type saveState struct {
+	Map   Grid
+	FOV   *FOV
+	Turns int
+}
+
+func newSaveState() *saveState {
+	st := &saveState{Map: NewGrid(20, 10), FOV: NewFOV(gruid.NewRange(0, 0, 20, 10)), Turns: 42}
+	st.Map.Fill(1)
+	st.Map.Set(gruid.Point{3, 4}, 2)
+	return st
+}
+
+func TestSaveLoad(t *testing.T) {
+	sm := SaveManager{Version: 3}
+	buf := bytes.Buffer{}
+	err := sm.Save(&buf, newSaveState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &saveState{}
+	err = sm.Load(bytes.NewReader(buf.Bytes()), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Turns != 42 {
+		t.Errorf("bad turns: %d", st.Turns)
+	}
+	if c := st.Map.At(gruid.Point{3, 4}); c != 2 {
+		t.Errorf("bad cell: %d", c)
+	}
+	if st.FOV.Range() != gruid.NewRange(0, 0, 20, 10) {
+		t.Errorf("bad fov range: %v", st.FOV.Range())
+	}
+	bs := buf.Bytes()
+	bs[len(bs)-5] ^= 0xff
+	if err := sm.Load(bytes.NewReader(bs), &saveState{}); err == nil {
+		t.Error("no error for corrupted save")
+	}
+	if err := sm.Load(bytes.NewReader([]byte("not a save")), &saveState{}); err == nil {
+		t.Error("no error for invalid save")
+	}
+}
+
+func TestSaveVersion(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := SaveManager{Version: 1}.Save(&buf, newSaveState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := SaveManager{Version: 2}
+	if err := sm.Load(bytes.NewReader(buf.Bytes()), &saveState{}); err == nil {
+		t.Error("no error for incompatible version")
+	}
+	sm.Compatible = func(version uint32) bool { return version >= 1 }
+	if err := sm.Load(bytes.NewReader(buf.Bytes()), &saveState{}); err != nil {
+		t.Errorf("compatible version: %v", err)
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	sm := SaveManager{Version: 1}
+	path := filepath.Join(t.TempDir(), "save")
+	err := sm.SaveFile(path, newSaveState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &saveState{}
+	err = sm.LoadFile(path, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Turns != 42 {
+		t.Errorf("bad turns: %d", st.Turns)
+	}
+	matches, _ := filepath.Glob(path + ".tmp*")
+	if len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
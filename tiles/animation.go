@@ -0,0 +1,142 @@
+package tiles
+
+import (
+	"image"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// Animation represents an animated tile: a looping sequence of frames, each
+// one displayed for its corresponding duration.
+type Animation struct {
+	Frames    []image.Image
+	Durations []time.Duration
+}
+
+// period returns the total duration of a loop.
+func (an *Animation) period() time.Duration {
+	var d time.Duration
+	for _, fd := range an.Durations {
+		d += fd
+	}
+	return d
+}
+
+// frame returns the frame index for a given elapsed time, along with the
+// time remaining until the next frame change.
+func (an *Animation) frame(elapsed time.Duration) (int, time.Duration) {
+	period := an.period()
+	if period <= 0 || len(an.Frames) < 2 {
+		return 0, -1
+	}
+	t := elapsed % period
+	for i, fd := range an.Durations {
+		if t < fd {
+			return i, fd - t
+		}
+		t -= fd
+	}
+	return 0, -1
+}
+
+// At returns the frame image to be displayed after the given elapsed time
+// since the start of the animation.
+func (an *Animation) At(elapsed time.Duration) image.Image {
+	if len(an.Frames) == 0 {
+		return nil
+	}
+	i, _ := an.frame(elapsed)
+	return an.Frames[i]
+}
+
+// AnimatedManager is an optional interface that a tile manager can implement
+// to provide animated tiles, such as water or torches.
+type AnimatedManager interface {
+	// GetAnimation returns the animation for a given cell, or nil if the
+	// cell is not animated.
+	GetAnimation(gruid.Cell) *Animation
+}
+
+// FrameChange reports a new frame image to be drawn at a given position.
+type FrameChange struct {
+	P     gruid.Point
+	Image image.Image
+}
+
+// Animator keeps track of animated cells on the screen and selects their
+// frames over time, without any model involvement. It is intended to be used
+// by tile-based drivers: they call Set for each cell of a flushed frame, and
+// periodically call Changes to redraw the animated cells whose frame changed,
+// using NextChange to schedule the next redraw.
+//
+// Elapsed times are durations since an arbitrary start chosen by the caller,
+// usually the start of the application, so that all animations stay in sync.
+type Animator struct {
+	am    AnimatedManager
+	cells map[gruid.Point]animCell
+}
+
+type animCell struct {
+	anim  *Animation
+	frame int
+}
+
+// NewAnimator returns a new animator using the given animated tile manager.
+func NewAnimator(am AnimatedManager) *Animator {
+	return &Animator{am: am, cells: map[gruid.Point]animCell{}}
+}
+
+// Set records the cell drawn at a given position. It returns the frame image
+// to draw for an animated cell, and true, or nil and false if the cell is not
+// animated, in which case the regular tile image should be used.
+func (a *Animator) Set(p gruid.Point, c gruid.Cell, elapsed time.Duration) (image.Image, bool) {
+	anim := a.am.GetAnimation(c)
+	if anim == nil || len(anim.Frames) == 0 {
+		delete(a.cells, p)
+		return nil, false
+	}
+	i, _ := anim.frame(elapsed)
+	a.cells[p] = animCell{anim: anim, frame: i}
+	return anim.Frames[i], true
+}
+
+// Clear forgets all the animated cells, for example after a resize.
+func (a *Animator) Clear() {
+	a.cells = map[gruid.Point]animCell{}
+}
+
+// Len returns the number of animated cells currently tracked.
+func (a *Animator) Len() int {
+	return len(a.cells)
+}
+
+// Changes returns the animated cells whose frame changed since the last call
+// to Set or Changes, along with their new frame image.
+func (a *Animator) Changes(elapsed time.Duration) []FrameChange {
+	var changes []FrameChange
+	for p, ac := range a.cells {
+		i, _ := ac.anim.frame(elapsed)
+		if i == ac.frame {
+			continue
+		}
+		ac.frame = i
+		a.cells[p] = ac
+		changes = append(changes, FrameChange{P: p, Image: ac.anim.Frames[i]})
+	}
+	return changes
+}
+
+// NextChange returns the time remaining until the next frame change among
+// tracked animated cells. It returns a negative duration if there is no
+// animated cell.
+func (a *Animator) NextChange(elapsed time.Duration) time.Duration {
+	next := time.Duration(-1)
+	for _, ac := range a.cells {
+		_, d := ac.anim.frame(elapsed)
+		if d >= 0 && (next < 0 || d < next) {
+			next = d
+		}
+	}
+	return next
+}
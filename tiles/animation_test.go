@@ -0,0 +1,61 @@
+package tiles
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+type testAnimManager struct {
+	water *Animation
+}
+
+func (m testAnimManager) GetAnimation(c gruid.Cell) *Animation {
+	if c.Rune == '~' {
+		return m.water
+	}
+	return nil
+}
+
+func TestAnimator(t *testing.T) {
+	frames := []image.Image{image.NewRGBA(image.Rect(0, 0, 1, 1)), image.NewRGBA(image.Rect(0, 0, 2, 2))}
+	water := &Animation{Frames: frames, Durations: []time.Duration{100 * time.Millisecond, 50 * time.Millisecond}}
+	if water.At(120*time.Millisecond) != frames[1] || water.At(160*time.Millisecond) != frames[0] {
+		t.Errorf("bad frame selection")
+	}
+	a := NewAnimator(testAnimManager{water: water})
+	if _, ok := a.Set(gruid.Point{0, 0}, gruid.Cell{Rune: '.'}, 0); ok {
+		t.Errorf("static cell reported as animated")
+	}
+	img, ok := a.Set(gruid.Point{1, 0}, gruid.Cell{Rune: '~'}, 0)
+	if !ok || img != frames[0] {
+		t.Errorf("bad animated cell image")
+	}
+	a.Set(gruid.Point{2, 0}, gruid.Cell{Rune: '~'}, 0)
+	if a.Len() != 2 {
+		t.Errorf("bad animated cells count: %d", a.Len())
+	}
+	if d := a.NextChange(30 * time.Millisecond); d != 70*time.Millisecond {
+		t.Errorf("bad next change: %v", d)
+	}
+	if changes := a.Changes(50 * time.Millisecond); len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+	changes := a.Changes(110 * time.Millisecond)
+	if len(changes) != 2 || changes[0].Image != frames[1] {
+		t.Errorf("bad changes: %v", changes)
+	}
+	if changes := a.Changes(120 * time.Millisecond); len(changes) != 0 {
+		t.Errorf("repeated changes: %v", changes)
+	}
+	a.Set(gruid.Point{1, 0}, gruid.Cell{Rune: '#'}, 120*time.Millisecond)
+	if a.Len() != 1 {
+		t.Errorf("animated cell not removed")
+	}
+	a.Clear()
+	if a.NextChange(0) >= 0 {
+		t.Errorf("next change without animated cells")
+	}
+}
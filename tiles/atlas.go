@@ -0,0 +1,129 @@
+package tiles
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/anaseto/gruid"
+)
+
+// Atlas packs tile images into a single image, with a fixed number of slots
+// of the same size, such as a texture atlas that a GPU-based driver uploads
+// once, so that it can then draw all the changed cells of a frame in a
+// single batched draw call. Tiles are added lazily, the first time a cell is
+// drawn.
+//
+// Tile images bigger than the tile size, such as the ones drawn for wide
+// runes, are clipped.
+type Atlas struct {
+	img    *image.RGBA
+	tile   gruid.Point
+	cols   int
+	slots  int
+	index  map[gruid.Cell]int
+	damage image.Rectangle
+}
+
+// AtlasSprite represents a cell to be drawn from an atlas.
+type AtlasSprite struct {
+	P   gruid.Point     // cell position in the grid
+	Src image.Rectangle // tile bounds in the atlas image
+}
+
+// NewAtlas returns a new atlas for tiles of the given size in pixels, with a
+// given number of slots per line and column.
+func NewAtlas(tile gruid.Point, cols, lines int) *Atlas {
+	if cols < 0 {
+		cols = 0
+	}
+	if lines < 0 {
+		lines = 0
+	}
+	return &Atlas{
+		img:   image.NewRGBA(image.Rect(0, 0, cols*tile.X, lines*tile.Y)),
+		tile:  tile,
+		cols:  cols,
+		slots: cols * lines,
+		index: map[gruid.Cell]int{},
+	}
+}
+
+// Get returns the bounds, in the atlas image, of the tile of a given cell. If
+// the cell is not in the atlas yet, its tile is obtained using fn, such as a
+// tile manager's GetImage method, and added to the atlas. It returns false if
+// the atlas is full or fn returned nil. A full atlas can be reset with Reset.
+func (a *Atlas) Get(c gruid.Cell, fn func(gruid.Cell) image.Image) (image.Rectangle, bool) {
+	if i, ok := a.index[c]; ok {
+		return a.slot(i), true
+	}
+	if len(a.index) >= a.slots {
+		return image.Rectangle{}, false
+	}
+	img := fn(c)
+	if img == nil {
+		return image.Rectangle{}, false
+	}
+	i := len(a.index)
+	a.index[c] = i
+	r := a.slot(i)
+	draw.Draw(a.img, r, img, img.Bounds().Min, draw.Src)
+	a.damage = a.damage.Union(r)
+	return r, true
+}
+
+func (a *Atlas) slot(i int) image.Rectangle {
+	min := image.Point{X: (i % a.cols) * a.tile.X, Y: (i / a.cols) * a.tile.Y}
+	return image.Rectangle{Min: min, Max: min.Add(image.Point{X: a.tile.X, Y: a.tile.Y})}
+}
+
+// Sprites appends to sprites the changed cells of a frame along with their
+// tile bounds in the atlas, adding new tiles as needed using fn, as in Get.
+// It returns false if some cell could not be added because the atlas is full.
+// In that case, the driver can reset the atlas and call Sprites again.
+func (a *Atlas) Sprites(sprites []AtlasSprite, frame gruid.Frame, fn func(gruid.Cell) image.Image) ([]AtlasSprite, bool) {
+	ok := true
+	for _, fc := range frame.Cells {
+		r, added := a.Get(fc.Cell, fn)
+		if !added {
+			if len(a.index) >= a.slots {
+				ok = false
+			}
+			continue
+		}
+		sprites = append(sprites, AtlasSprite{P: fc.P, Src: r})
+	}
+	return sprites, ok
+}
+
+// Image returns the atlas image. It should not be modified.
+func (a *Atlas) Image() *image.RGBA {
+	return a.img
+}
+
+// Len returns the number of tiles in the atlas.
+func (a *Atlas) Len() int {
+	return len(a.index)
+}
+
+// Damage returns the area of the atlas image, in pixels, that changed since
+// the last call to Upload.
+func (a *Atlas) Damage() image.Rectangle {
+	return a.damage
+}
+
+// Upload marks the atlas image as uploaded, for example to a GPU texture, and
+// returns the area that changed since the previous call, as reported by
+// Damage. It is empty if nothing needs to be uploaded.
+func (a *Atlas) Upload() image.Rectangle {
+	r := a.damage
+	a.damage = image.Rectangle{}
+	return r
+}
+
+// Reset removes all the tiles from the atlas, for example when it is full, or
+// after the tile manager changed.
+func (a *Atlas) Reset() {
+	a.index = map[gruid.Cell]int{}
+	draw.Draw(a.img, a.img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	a.damage = a.img.Bounds()
+}
@@ -0,0 +1,47 @@
+package tiles
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestAtlas(t *testing.T) {
+	a := NewAtlas(gruid.Point{7, 13}, 2, 2)
+	calls := 0
+	fn := func(c gruid.Cell) image.Image {
+		calls++
+		img := image.NewRGBA(image.Rect(0, 0, 7, 13))
+		img.Set(0, 0, color.RGBA{uint8(c.Rune), 0, 0, 255})
+		return img
+	}
+	frame := gruid.Frame{Cells: []gruid.FrameCell{
+		{P: gruid.Point{0, 0}, Cell: gruid.Cell{Rune: 'a'}},
+		{P: gruid.Point{1, 0}, Cell: gruid.Cell{Rune: 'b'}},
+		{P: gruid.Point{2, 0}, Cell: gruid.Cell{Rune: 'a'}},
+		{P: gruid.Point{3, 0}, Cell: gruid.Cell{Rune: 'c'}},
+	}}
+	sprites, ok := a.Sprites(nil, frame, fn)
+	if !ok || len(sprites) != 4 || a.Len() != 3 || calls != 3 {
+		t.Fatalf("bad sprites: %v (ok: %v, len: %d, calls: %d)", sprites, ok, a.Len(), calls)
+	}
+	if sprites[0].Src != sprites[2].Src || sprites[1].Src != image.Rect(7, 0, 14, 13) {
+		t.Errorf("bad sprite bounds: %v", sprites)
+	}
+	if c := a.Image().RGBAAt(0, 13); c != (color.RGBA{'c', 0, 0, 255}) {
+		t.Errorf("bad atlas content: %v", c)
+	}
+	if r := a.Upload(); r != image.Rect(0, 0, 14, 26) {
+		t.Errorf("bad damage: %v", r)
+	}
+	frame.Cells = append(frame.Cells, gruid.FrameCell{Cell: gruid.Cell{Rune: 'd'}}, gruid.FrameCell{Cell: gruid.Cell{Rune: 'e'}})
+	if _, ok := a.Sprites(nil, frame, fn); ok {
+		t.Errorf("no error for full atlas")
+	}
+	a.Reset()
+	if a.Len() != 0 || a.Damage() != a.Image().Bounds() {
+		t.Errorf("bad reset")
+	}
+}
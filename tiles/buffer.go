@@ -0,0 +1,123 @@
+package tiles
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/anaseto/gruid"
+)
+
+// Buffer is an off-screen image of the whole screen grid, made of tiles. A
+// tile-based driver can use it as a back buffer: it draws the tiles of each
+// frame's changed cells into the buffer, and only presents the damaged area
+// on the screen when there is one. Because the buffer keeps the last rendered
+// screen, the window can be repainted from it, for example on an expose
+// event, without requesting a full redraw from the model.
+type Buffer struct {
+	img    *image.RGBA
+	tile   gruid.Point
+	damage image.Rectangle
+}
+
+// NewBuffer returns a new buffer for a grid of the given size in cells,
+// using tiles of the given size in pixels.
+func NewBuffer(size, tile gruid.Point) *Buffer {
+	b := &Buffer{tile: tile}
+	b.Resize(size)
+	return b
+}
+
+// Resize changes the size in cells of the buffer's grid, usually after a
+// window resize. The buffer's content is cleared, so the whole buffer is
+// reported as damaged.
+func (b *Buffer) Resize(size gruid.Point) {
+	if size.X < 0 {
+		size.X = 0
+	}
+	if size.Y < 0 {
+		size.Y = 0
+	}
+	b.img = image.NewRGBA(image.Rect(0, 0, size.X*b.tile.X, size.Y*b.tile.Y))
+	b.damage = b.img.Bounds()
+}
+
+// Size returns the size of the buffer's grid, in cells.
+func (b *Buffer) Size() gruid.Point {
+	if b.tile.X <= 0 || b.tile.Y <= 0 {
+		return gruid.Point{}
+	}
+	sz := b.img.Bounds().Size()
+	return gruid.Point{X: sz.X / b.tile.X, Y: sz.Y / b.tile.Y}
+}
+
+// Draw draws a tile image at the given cell position. Tiles wider than a
+// cell, such as the ones drawn for wide runes, cover the following cells.
+// Parts outside the buffer are ignored.
+func (b *Buffer) Draw(p gruid.Point, tile image.Image) {
+	min := image.Point{X: p.X * b.tile.X, Y: p.Y * b.tile.Y}
+	r := image.Rectangle{Min: min, Max: min.Add(tile.Bounds().Size())}.Intersect(b.img.Bounds())
+	if r.Empty() {
+		return
+	}
+	draw.Draw(b.img, r, tile, tile.Bounds().Min, draw.Src)
+	b.damage = b.damage.Union(r)
+}
+
+// DrawFrame draws the changed cells of a frame, using the given function to
+// get the tile image of a cell, such as a tile manager's GetImage method.
+// Cells for which the function returns nil are skipped.
+func (b *Buffer) DrawFrame(frame gruid.Frame, fn func(gruid.Cell) image.Image) {
+	for _, fc := range frame.Cells {
+		img := fn(fc.Cell)
+		if img == nil {
+			continue
+		}
+		b.Draw(fc.P, img)
+	}
+}
+
+// Image returns the buffer's image, in pixels. It should not be modified.
+func (b *Buffer) Image() *image.RGBA {
+	return b.img
+}
+
+// Screenshot writes the current content of the buffer to w, encoded in a
+// given image format: "png" (the default for an empty format) or "jpeg". It
+// can be used by tile-based drivers to produce screenshots without OS-level
+// tools.
+func (b *Buffer) Screenshot(w io.Writer, format string) error {
+	switch format {
+	case "", "png":
+		return png.Encode(w, b.img)
+	case "jpeg", "jpg":
+		return jpeg.Encode(w, b.img, nil)
+	default:
+		return fmt.Errorf("unsupported screenshot format: %q", format)
+	}
+}
+
+// Damage returns the area of the image, in pixels, that changed since the
+// last call to Present. It is empty if no redraw of the screen is needed.
+func (b *Buffer) Damage() image.Rectangle {
+	return b.damage
+}
+
+// Present marks the buffer as presented on the screen, and returns the area
+// that changed since the previous call, as reported by Damage. Drivers that
+// only redraw on demand can skip presenting when it is empty.
+func (b *Buffer) Present() image.Rectangle {
+	r := b.damage
+	b.damage = image.Rectangle{}
+	return r
+}
+
+// Invalidate marks the whole buffer as damaged, so that next Present reports
+// the whole image. It can be used when the screen content was lost, for
+// example after the window was exposed or the renderer reset.
+func (b *Buffer) Invalidate() {
+	b.damage = b.img.Bounds()
+}
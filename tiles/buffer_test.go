@@ -0,0 +1,84 @@
+package tiles
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer(gruid.Point{10, 5}, gruid.Point{7, 13})
+	if b.Size() != (gruid.Point{10, 5}) {
+		t.Errorf("bad size: %v", b.Size())
+	}
+	if r := b.Present(); r != image.Rect(0, 0, 70, 65) {
+		t.Errorf("bad initial damage: %v", r)
+	}
+	if r := b.Present(); !r.Empty() {
+		t.Errorf("unexpected damage: %v", r)
+	}
+	red := image.NewUniform(color.RGBA{255, 0, 0, 255})
+	b.DrawFrame(gruid.Frame{Cells: []gruid.FrameCell{
+		{P: gruid.Point{1, 1}, Cell: gruid.Cell{Rune: 'a'}},
+		{P: gruid.Point{2, 1}, Cell: gruid.Cell{Rune: 'b'}},
+		{P: gruid.Point{5, 2}, Cell: gruid.Cell{Rune: ' '}},
+	}}, func(c gruid.Cell) image.Image {
+		if c.Rune == ' ' {
+			return nil
+		}
+		tile := image.NewRGBA(image.Rect(0, 0, 7, 13))
+		tile.Set(0, 0, red)
+		return tile
+	})
+	if r := b.Damage(); r != image.Rect(7, 13, 21, 26) {
+		t.Errorf("bad damage: %v", r)
+	}
+	if c := b.Image().RGBAAt(14, 13); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("bad color: %v", c)
+	}
+	b.Present()
+	b.Draw(gruid.Point{9, 4}, image.NewRGBA(image.Rect(0, 0, 14, 13)))
+	if r := b.Present(); r != image.Rect(63, 52, 70, 65) {
+		t.Errorf("bad clipped damage: %v", r)
+	}
+	b.Invalidate()
+	if r := b.Damage(); r != b.Image().Bounds() {
+		t.Errorf("bad invalidated damage: %v", r)
+	}
+	b.Resize(gruid.Point{4, 3})
+	if b.Size() != (gruid.Point{4, 3}) {
+		t.Errorf("bad size after resize: %v", b.Size())
+	}
+}
+
+func TestBufferScreenshot(t *testing.T) {
+	b := NewBuffer(gruid.Point{3, 2}, gruid.Point{7, 13})
+	tile := image.NewRGBA(image.Rect(0, 0, 7, 13))
+	tile.Set(0, 0, color.RGBA{0, 255, 0, 255})
+	b.Draw(gruid.Point{1, 1}, tile)
+	var buf bytes.Buffer
+	if err := b.Screenshot(&buf, "png"); err != nil {
+		t.Fatalf("png screenshot: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 21, 26) {
+		t.Errorf("bad bounds: %v", img.Bounds())
+	}
+	if c := color.RGBAModel.Convert(img.At(7, 13)).(color.RGBA); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("bad color: %v", c)
+	}
+	buf.Reset()
+	if err := b.Screenshot(&buf, "jpeg"); err != nil || buf.Len() == 0 {
+		t.Errorf("jpeg screenshot: %v", err)
+	}
+	if err := b.Screenshot(&buf, "bmp"); err == nil {
+		t.Errorf("no error for unsupported format")
+	}
+}
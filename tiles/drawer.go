@@ -40,18 +40,37 @@ func NewDrawer(face font.Face) (*Drawer, error) {
 }
 
 // Draw draws a rune and returns the produced image with foreground and
-// background colors given by images fg and bg.
+// background colors given by images fg and bg. Runes representing grapheme
+// clusters (see gruid.Grapheme) are drawn as the whole cluster.
 func (d *Drawer) Draw(r rune, fg, bg image.Image) image.Image {
+	return d.draw(r, fg, bg, false, false)
+}
+
+func (d *Drawer) draw(r rune, fg, bg image.Image, fauxBold, wide bool) *image.RGBA {
 	d.drawer.Dot = d.dot
 	d.drawer.Src = fg
-	d.drawer.Dst = image.NewRGBA(d.rect)
-	rect := d.drawer.Dst.Bounds()
-	draw.Draw(d.drawer.Dst, rect, bg, rect.Min, draw.Src)
-	d.drawer.DrawString(string(r))
-	img := d.drawer.Dst
+	img := image.NewRGBA(d.tileRect(wide))
+	d.drawer.Dst = img
+	rect := img.Bounds()
+	draw.Draw(img, rect, bg, rect.Min, draw.Src)
+	text := gruid.RuneContent(r)
+	d.drawer.DrawString(text)
+	if fauxBold {
+		d.drawer.Dot = d.dot.Add(fixed.P(1, 0))
+		d.drawer.DrawString(text)
+	}
 	return img
 }
 
+// tileRect returns the bounds of a tile, which are twice as wide for wide
+// cells.
+func (d *Drawer) tileRect(wide bool) image.Rectangle {
+	if wide {
+		return image.Rect(d.rect.Min.X, d.rect.Min.Y, d.rect.Min.X+2*d.rect.Dx(), d.rect.Max.Y)
+	}
+	return d.rect
+}
+
 // Size returns the size of drawn tiles, in pixel points.
 func (d *Drawer) Size() gruid.Point {
 	p := d.rect.Size()
@@ -63,3 +82,18 @@ func (d *Drawer) Size() gruid.Point {
 	}
 	return gruid.Point{X: p.X, Y: p.Y}
 }
+
+// GridSize returns the size in cells of the largest grid whose tiles of the
+// given size in pixels fit in a window of the given size in pixels, with at
+// least one cell in each dimension. Tile-based drivers can use it to compute
+// the new screen size after a window resize, before reporting a MsgScreen.
+func GridSize(window, tile gruid.Point) gruid.Point {
+	size := gruid.Point{X: 1, Y: 1}
+	if tile.X > 0 && window.X/tile.X > 1 {
+		size.X = window.X / tile.X
+	}
+	if tile.Y > 0 && window.Y/tile.Y > 1 {
+		size.Y = window.Y / tile.Y
+	}
+	return size
+}
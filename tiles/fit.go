@@ -0,0 +1,85 @@
+package tiles
+
+import (
+	"image"
+
+	"github.com/anaseto/gruid"
+)
+
+// FitMode describes how a screen grid of tiles is adapted to the size of its
+// container, such as a window or a web page element.
+type FitMode int
+
+// These constants represent the available fit modes.
+const (
+	// FitScale scales the tiles, preserving their aspect ratio, so that the
+	// whole grid fits in the container. The grid is centered, with empty
+	// borders in the direction that does not fill the container
+	// (letterboxing).
+	FitScale FitMode = iota
+
+	// FitReflow keeps the tile size, and changes the size of the grid in
+	// cells so that it fills the container. Remaining pixels are split
+	// evenly as borders.
+	FitReflow
+)
+
+// Layout describes the placement of a grid of tiles in a container, as
+// computed by Fit.
+type Layout struct {
+	Grid   gruid.Point     // grid size in cells
+	Scale  float64         // scale factor for tiles
+	Bounds image.Rectangle // area occupied by the grid, in container pixels
+}
+
+// Fit returns the layout for a grid of the given size in cells, using tiles of
+// the given size in pixels, in a container of the given size in pixels. The
+// grid size is only used by FitScale: with FitReflow, the returned grid size
+// is the number of whole tiles fitting in the container, and at least one in
+// each direction.
+//
+// Drivers typically call Fit after a container resize, and report a
+// gruid.MsgScreen when the returned grid size changed.
+func Fit(container, tile, grid gruid.Point, mode FitMode) Layout {
+	if tile.X <= 0 || tile.Y <= 0 {
+		return Layout{Grid: grid, Scale: 1}
+	}
+	switch mode {
+	case FitReflow:
+		grid = gruid.Point{X: container.X / tile.X, Y: container.Y / tile.Y}
+		if grid.X < 1 {
+			grid.X = 1
+		}
+		if grid.Y < 1 {
+			grid.Y = 1
+		}
+		size := image.Point{X: grid.X * tile.X, Y: grid.Y * tile.Y}
+		return Layout{Grid: grid, Scale: 1, Bounds: center(container, size)}
+	default:
+		if grid.X <= 0 || grid.Y <= 0 {
+			return Layout{Grid: grid, Scale: 1}
+		}
+		w, h := grid.X*tile.X, grid.Y*tile.Y
+		scale := float64(container.X) / float64(w)
+		if sy := float64(container.Y) / float64(h); sy < scale {
+			scale = sy
+		}
+		if scale <= 0 {
+			return Layout{Grid: grid}
+		}
+		size := image.Point{X: int(float64(w) * scale), Y: int(float64(h) * scale)}
+		return Layout{Grid: grid, Scale: scale, Bounds: center(container, size)}
+	}
+}
+
+// center returns a rectangle of the given size centered in the container.
+func center(container gruid.Point, size image.Point) image.Rectangle {
+	min := image.Point{X: (container.X - size.X) / 2, Y: (container.Y - size.Y) / 2}
+	if min.X < 0 {
+		min.X = 0
+	}
+	if min.Y < 0 {
+		min.Y = 0
+	}
+	return image.Rectangle{Min: min, Max: min.Add(size)}
+}
@@ -0,0 +1,40 @@
+package tiles
+
+import (
+	"image"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestFitScale(t *testing.T) {
+	l := Fit(gruid.Point{1000, 500}, gruid.Point{10, 20}, gruid.Point{80, 24}, FitScale)
+	if l.Grid != (gruid.Point{80, 24}) {
+		t.Errorf("bad grid: %v", l.Grid)
+	}
+	// 800x480 grid: height limits the scale
+	if want := 500.0 / 480.0; l.Scale != want {
+		t.Errorf("bad scale: %v, want %v", l.Scale, want)
+	}
+	if l.Bounds.Dy() != 500 || l.Bounds.Min.Y != 0 || l.Bounds.Min.X != (1000-l.Bounds.Dx())/2 {
+		t.Errorf("bad bounds: %v", l.Bounds)
+	}
+	l = Fit(gruid.Point{400, 1000}, gruid.Point{10, 20}, gruid.Point{80, 24}, FitScale)
+	if l.Scale != 0.5 || l.Bounds != image.Rect(0, 380, 400, 620) {
+		t.Errorf("bad layout: %+v", l)
+	}
+}
+
+func TestFitReflow(t *testing.T) {
+	l := Fit(gruid.Point{1005, 510}, gruid.Point{10, 20}, gruid.Point{80, 24}, FitReflow)
+	if l.Grid != (gruid.Point{100, 25}) || l.Scale != 1 {
+		t.Errorf("bad layout: %+v", l)
+	}
+	if l.Bounds != image.Rect(2, 5, 1002, 505) {
+		t.Errorf("bad bounds: %v", l.Bounds)
+	}
+	l = Fit(gruid.Point{5, 5}, gruid.Point{10, 20}, gruid.Point{80, 24}, FitReflow)
+	if l.Grid != (gruid.Point{1, 1}) {
+		t.Errorf("bad minimal grid: %v", l.Grid)
+	}
+}
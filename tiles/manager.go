@@ -0,0 +1,223 @@
+package tiles
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/colors"
+)
+
+// ManagerConfig contains the configuration for a font tile Manager.
+type ManagerConfig struct {
+	Face     font.Face // monospace font face
+	BoldFace font.Face // optional bold face (default: faux bold from Face)
+
+	// Color maps a gruid color to a concrete color, for foreground (fg is
+	// true) or background. If nil, ColorDefault is mapped to white
+	// foreground and black background, and other colors are interpreted
+	// as RGB or xterm 256-color palette colors, as defined by the colors
+	// package.
+	Color func(c gruid.Color, fg bool) color.Color
+
+	// Attributes used for special styling. Zero values default to the
+	// standard attributes, such as gruid.AttrBold.
+	Bold          gruid.AttrMask // bold text
+	Reverse       gruid.AttrMask // reversed foreground and background
+	Underline     gruid.AttrMask // underlined text
+	Strikethrough gruid.AttrMask // struck through text
+}
+
+// Manager is a tile manager that rasterizes the runes of a monospace font
+// into cell images, with foreground and background colors and basic
+// attributes. Images are cached, so each distinct cell is only drawn once.
+//
+// It provides the GetImage and TileSize methods expected by tile-based
+// drivers, such as the SDL and js ones. It implements GlyphManager too.
+type Manager struct {
+	drawer *Drawer
+	bold   *Drawer
+	color  func(gruid.Color, bool) color.Color
+	cfg    ManagerConfig
+	cache  map[gruid.Cell]image.Image
+	glyphs map[gruid.Cell]image.Image
+}
+
+// GlyphManager is an optional interface that a tile manager can implement so
+// that a driver can colorize glyph shapes itself, for example using texture
+// color modulation. The driver then fills the tile with the background color,
+// and draws over it the glyph modulated by the foreground color. This way,
+// the number of cached textures depends only on the glyphs, and not on their
+// combinations with colors.
+type GlyphManager interface {
+	// GetGlyph returns the glyph shape for a given cell, as a white
+	// image, whose alpha channel gives the coverage of the foreground
+	// color. It does not depend on the cell's colors, but it should take
+	// into account shape attributes, such as gruid.AttrBold or
+	// gruid.AttrUnderline.
+	GetGlyph(gruid.Cell) image.Image
+
+	// Colors returns the concrete foreground and background colors for a
+	// given style, taking into account color attributes, such as
+	// gruid.AttrReverse.
+	Colors(gruid.Style) (fg, bg color.Color)
+}
+
+// NewManager returns a new tile manager with the given configuration.
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	m := &Manager{cfg: cfg, cache: map[gruid.Cell]image.Image{}, glyphs: map[gruid.Cell]image.Image{}}
+	var err error
+	m.drawer, err = NewDrawer(cfg.Face)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BoldFace != nil {
+		m.bold, err = NewDrawer(cfg.BoldFace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.cfg.Bold == 0 {
+		m.cfg.Bold = gruid.AttrBold
+	}
+	if m.cfg.Reverse == 0 {
+		m.cfg.Reverse = gruid.AttrReverse
+	}
+	if m.cfg.Underline == 0 {
+		m.cfg.Underline = gruid.AttrUnderline
+	}
+	if m.cfg.Strikethrough == 0 {
+		m.cfg.Strikethrough = gruid.AttrStrikethrough
+	}
+	m.color = cfg.Color
+	if m.color == nil {
+		m.color = defaultColor
+	}
+	return m, nil
+}
+
+// TileSize returns the size of tiles, in pixel points.
+func (m *Manager) TileSize() gruid.Point {
+	return m.drawer.Size()
+}
+
+// GetImage returns the image for a given cell. Wide cells (see gruid.AttrWide)
+// get an image twice as wide as TileSize.
+func (m *Manager) GetImage(c gruid.Cell) image.Image {
+	if img, ok := m.cache[c]; ok {
+		return img
+	}
+	fg, bg := m.Colors(c.Style)
+	img := m.render(c, fg, bg)
+	m.cache[c] = img
+	return img
+}
+
+// GetGlyph implements GlyphManager.GetGlyph. Images are cached, and shared by
+// cells that only differ in colors, or in the reverse attribute.
+func (m *Manager) GetGlyph(c gruid.Cell) image.Image {
+	c.Style.Fg = gruid.ColorDefault
+	c.Style.Bg = gruid.ColorDefault
+	if m.cfg.Reverse != 0 {
+		c.Style.Attrs &^= m.cfg.Reverse
+	}
+	if img, ok := m.glyphs[c]; ok {
+		return img
+	}
+	img := m.render(c, color.White, color.Transparent)
+	m.glyphs[c] = img
+	return img
+}
+
+// Colors implements GlyphManager.Colors, using the configured color mapping.
+func (m *Manager) Colors(st gruid.Style) (fg, bg color.Color) {
+	fg, bg = m.color(st.Fg, true), m.color(st.Bg, false)
+	if has(st.Attrs, m.cfg.Reverse) {
+		fg, bg = bg, fg
+	}
+	return fg, bg
+}
+
+// render draws a cell's glyph with the given colors.
+func (m *Manager) render(c gruid.Cell, fg, bg color.Color) *image.RGBA {
+	st := c.Style
+	fgu, bgu := image.NewUniform(fg), image.NewUniform(bg)
+	wide := c.Wide()
+	var img *image.RGBA
+	switch {
+	case !has(st.Attrs, m.cfg.Bold):
+		img = m.drawer.draw(c.Rune, fgu, bgu, false, wide)
+	case m.bold != nil:
+		img = m.bold.draw(c.Rune, fgu, bgu, false, wide)
+		if m.bold.rect != m.drawer.rect {
+			// keep tile size consistent
+			nimg := image.NewRGBA(m.drawer.tileRect(wide))
+			copyImage(nimg, img)
+			img = nimg
+		}
+	default:
+		img = m.drawer.draw(c.Rune, fgu, bgu, true, wide)
+	}
+	if has(st.Attrs, m.cfg.Underline) {
+		rect := img.Bounds()
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.Set(x, rect.Max.Y-1, fg)
+		}
+	}
+	if has(st.Attrs, m.cfg.Strikethrough) {
+		rect := img.Bounds()
+		y := (rect.Min.Y + rect.Max.Y) / 2
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.Set(x, y, fg)
+		}
+	}
+	return img
+}
+
+// ClearCache clears the image cache, for example after changing the
+// colors mapping.
+func (m *Manager) ClearCache() {
+	m.cache = map[gruid.Cell]image.Image{}
+	m.glyphs = map[gruid.Cell]image.Image{}
+}
+
+// Colorize returns the image obtained by drawing a glyph, as returned by
+// GlyphManager.GetGlyph, with the given foreground color over the given
+// background color. It produces in software the same result that drivers
+// obtain with color modulation.
+func Colorize(glyph image.Image, fg, bg color.Color) image.Image {
+	rect := glyph.Bounds()
+	img := image.NewRGBA(rect)
+	draw.Draw(img, rect, image.NewUniform(bg), rect.Min, draw.Src)
+	draw.DrawMask(img, rect, image.NewUniform(fg), image.Point{}, glyph, rect.Min, draw.Over)
+	return img
+}
+
+func has(attrs, a gruid.AttrMask) bool {
+	return a != 0 && attrs&a == a
+}
+
+func copyImage(dst *image.RGBA, src *image.RGBA) {
+	rect := dst.Bounds().Intersect(src.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			dst.Set(x, y, src.At(x, y))
+		}
+	}
+}
+
+func defaultColor(c gruid.Color, fg bool) color.Color {
+	if c == gruid.ColorDefault {
+		if fg {
+			return color.White
+		}
+		return color.Black
+	}
+	if rgb, ok := colors.ToRGBA(c); ok {
+		return rgb
+	}
+	return color.White
+}
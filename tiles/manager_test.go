@@ -0,0 +1,147 @@
+package tiles
+
+import (
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+
+	"github.com/anaseto/gruid"
+	"github.com/anaseto/gruid/colors"
+)
+
+func TestManager(t *testing.T) {
+	const (
+		attrBold gruid.AttrMask = 1 << iota
+		attrReverse
+		attrUnderline
+	)
+	m, err := NewManager(ManagerConfig{
+		Face:      basicfont.Face7x13,
+		Bold:      attrBold,
+		Reverse:   attrReverse,
+		Underline: attrUnderline,
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if m.TileSize() != (gruid.Point{7, 13}) {
+		t.Errorf("bad tile size: %v", m.TileSize())
+	}
+	img := m.GetImage(gruid.Cell{Rune: ' '})
+	if img.Bounds().Size().X != 7 || img.Bounds().Size().Y != 13 {
+		t.Errorf("bad image size: %v", img.Bounds().Size())
+	}
+	if r, g, b, _ := img.At(0, 0).RGBA(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("bad background")
+	}
+	img = m.GetImage(gruid.Cell{Rune: ' ', Style: gruid.Style{Attrs: attrReverse | attrUnderline}})
+	if c := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("bad reversed background: %v", c)
+	}
+	if c := color.RGBAModel.Convert(img.At(0, 12)).(color.RGBA); c != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("bad underline: %v", c)
+	}
+	if m.GetImage(gruid.Cell{Rune: '@', Style: gruid.Style{Attrs: attrBold}}) == nil {
+		t.Errorf("nil bold image")
+	}
+	if c := defaultColor(colors.Xterm(196), true); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("bad xterm color: %v", c)
+	}
+}
+
+func TestManagerWide(t *testing.T) {
+	m, err := NewManager(ManagerConfig{Face: basicfont.Face7x13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := m.TileSize()
+	img := m.GetImage(gruid.Cell{Rune: '@', Style: gruid.Style{Attrs: gruid.AttrWide}})
+	if p := img.Bounds().Size(); p.X != 2*size.X || p.Y != size.Y {
+		t.Errorf("bad wide tile size: %v", p)
+	}
+}
+
+func TestManagerGlyph(t *testing.T) {
+	const (
+		attrBold gruid.AttrMask = 1 << iota
+		attrReverse
+		attrUnderline
+	)
+	m, err := NewManager(ManagerConfig{
+		Face:      basicfont.Face7x13,
+		Bold:      attrBold,
+		Reverse:   attrReverse,
+		Underline: attrUnderline,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gm GlyphManager = m
+	cells := []gruid.Cell{
+		{Rune: '@', Style: gruid.Style{Fg: 197, Bg: 18}},
+		{Rune: '#', Style: gruid.Style{Fg: 3, Attrs: attrBold | attrUnderline}},
+		{Rune: 'g', Style: gruid.Style{Fg: 40, Bg: 100, Attrs: attrReverse}},
+	}
+	for _, c := range cells {
+		fg, bg := gm.Colors(c.Style)
+		img, cimg := m.GetImage(c), Colorize(gm.GetGlyph(c), fg, bg)
+		if img.Bounds() != cimg.Bounds() {
+			t.Fatalf("bad bounds: %v vs %v", img.Bounds(), cimg.Bounds())
+		}
+		rect := img.Bounds()
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				if !closeColors(img.At(x, y), cimg.At(x, y)) {
+					t.Errorf("bad colorized pixel at (%d,%d) for %c: %v vs %v", x, y, c.Rune, img.At(x, y), cimg.At(x, y))
+				}
+			}
+		}
+	}
+	g := gm.GetGlyph(gruid.Cell{Rune: '@', Style: gruid.Style{Fg: 5, Attrs: attrReverse}})
+	if g != gm.GetGlyph(cells[0]) {
+		t.Errorf("glyph not shared among colors")
+	}
+	if len(m.glyphs) != 3 {
+		t.Errorf("bad glyph cache size: %d", len(m.glyphs))
+	}
+}
+
+func closeColors(c1, c2 color.Color) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	near := func(x, y uint32) bool {
+		return x <= y+0x200 && y <= x+0x200
+	}
+	return near(r1, r2) && near(g1, g2) && near(b1, b2) && near(a1, a2)
+}
+
+func TestGridSize(t *testing.T) {
+	m, err := NewManager(ManagerConfig{Face: basicfont.Face7x13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := GridSize(gruid.Point{800, 600}, m.TileSize()); size != (gruid.Point{114, 46}) {
+		t.Errorf("bad grid size: %v", size)
+	}
+	if size := GridSize(gruid.Point{5, 0}, m.TileSize()); size != (gruid.Point{1, 1}) {
+		t.Errorf("bad minimal grid size: %v", size)
+	}
+}
+
+func TestManagerStandardAttrs(t *testing.T) {
+	m, err := NewManager(ManagerConfig{Face: basicfont.Face7x13})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	img := m.GetImage(gruid.Cell{Rune: ' ', Style: gruid.Style{Attrs: gruid.AttrReverse}})
+	if c := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("bad reversed background: %v", c)
+	}
+	img = m.GetImage(gruid.Cell{Rune: ' ', Style: gruid.Style{Attrs: gruid.AttrStrikethrough | gruid.AttrUnderline}})
+	for _, y := range []int{6, 12} {
+		if c := color.RGBAModel.Convert(img.At(3, y)).(color.RGBA); c != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("bad line at %d: %v", y, c)
+		}
+	}
+}
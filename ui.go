@@ -95,6 +95,9 @@ import (
 	"io"
 	"log"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -138,6 +141,62 @@ type Driver interface {
 	Close()
 }
 
+// DriverInfo is an optional interface that can be satisfied by drivers, so
+// that applications can query the features they support, and adapt their user
+// interface accordingly, for example by hiding mouse hints when no mouse is
+// available.
+type DriverInfo interface {
+	// Capabilities returns the features supported by the driver. It
+	// should be valid after Init.
+	Capabilities() DriverCapabilities
+}
+
+// DriverCapabilities describes the features supported by a driver.
+type DriverCapabilities struct {
+	Colors    int  // number of supported colors (0 if unknown)
+	Mouse     bool // whether MsgMouse messages may be reported
+	KeyUp     bool // whether MsgKeyUp messages may be reported
+	Gamepad   bool // whether MsgGamepad messages may be reported
+	Tiles     bool // whether cells are drawn with tiles instead of glyphs
+	Resizable bool // whether the screen can be resized
+	Clipboard bool // whether clipboard access is supported
+	Cursor    bool // whether the hardware cursor can be shown
+}
+
+// DriverClipboard is an optional interface that can be satisfied by drivers
+// with clipboard support. Its methods are always called from the same
+// goroutine as Flush. Applications use them through the SetClipboard and
+// RequestClipboard commands.
+type DriverClipboard interface {
+	// SetClipboard sets the clipboard contents, if the platform allows.
+	// Terminal drivers may, for example, use an OSC 52 escape sequence,
+	// which many terminals support for copying even over ssh.
+	SetClipboard(string) error
+
+	// RequestClipboard requests the clipboard contents, if the platform
+	// allows. They are reported asynchronously as a MsgPaste input
+	// message, because access may be asynchronous or subject to user
+	// permission in some platforms. Drivers that can only copy, like
+	// most terminal drivers, should return an error.
+	RequestClipboard() error
+}
+
+// DriverCursor is an optional interface that can be satisfied by drivers
+// with a hardware cursor, typically terminal drivers. Its methods are always
+// called from the same goroutine as Flush. Applications use them through the
+// ShowCursor and HideCursor commands. The cursor is hidden by default.
+type DriverCursor interface {
+	// ShowCursor shows the cursor at the given screen position, starting
+	// from the next Flush. The cursor keeps its position after subsequent
+	// flushes. If the position is out of the screen, for example after a
+	// resize, the cursor should not be shown until the screen becomes
+	// big enough again.
+	ShowCursor(Point)
+
+	// HideCursor hides the cursor, starting from the next Flush.
+	HideCursor()
+}
+
 // DriverPollMsg is an optional interface that can be satisfied by drivers.
 // Such drivers will be run such that the message polling is executed in the
 // same thread as main using a non-blocking polling message method, instead of
@@ -192,13 +251,95 @@ func (sub Sub) implementsEffect() {}
 // End returns a special command that signals the application to end its Start
 // loop. Note that the application does not wait for pending effects to
 // complete before exiting the Start loop, so you may have to wait for any of
-// those commands messages before using End.
+// those commands messages before using End. See EndAfterDraw for showing an
+// exit screen.
 func End() Cmd {
 	return func() Msg {
 		return msgEnd{}
 	}
 }
 
+// EndAfterDraw returns a special command that signals the application to end
+// its Start loop, like End, but with a final call to the model's Draw, whose
+// resulting frame is flushed to the driver before ending. This allows the
+// model to show an exit screen, such as a farewell message. Messages received
+// after this one are not passed to Update anymore.
+//
+// The optional function fn, if not nil, is called after the final frame has
+// been flushed, and before the driver is closed, so that the exit screen
+// stays visible during final tasks, such as saving the game.
+func EndAfterDraw(fn func()) Cmd {
+	return func() Msg {
+		return msgEndAfterDraw{fn: fn}
+	}
+}
+
+// SetClipboard returns a special command that sets the clipboard contents, if
+// the driver implements DriverClipboard. Otherwise, it does nothing. It can be
+// used, for example, to offer copying a game seed or a character dump.
+func SetClipboard(s string) Cmd {
+	return func() Msg {
+		return msgSetClipboard(s)
+	}
+}
+
+// RequestClipboard returns a special command that requests the clipboard
+// contents, if the driver implements DriverClipboard. They are then reported
+// later as a MsgPaste message. Otherwise, it does nothing.
+func RequestClipboard() Cmd {
+	return func() Msg {
+		return msgRequestClipboard{}
+	}
+}
+
+// ShowCursor returns a special command that shows the hardware cursor at a
+// given screen position, if the driver implements DriverCursor. Otherwise, it
+// does nothing. Text input heavy applications can use it so that input
+// methods and screen readers follow the text cursor, for example with the
+// position returned by ui.TextInput.CursorPos.
+func ShowCursor(p Point) Cmd {
+	return func() Msg {
+		return msgShowCursor{p: p}
+	}
+}
+
+// HideCursor returns a special command that hides the hardware cursor, if the
+// driver implements DriverCursor. Otherwise, it does nothing.
+func HideCursor() Cmd {
+	return func() Msg {
+		return msgHideCursor{}
+	}
+}
+
+// AttachMirror returns a special command that attaches a new mirror driver
+// while the application is running, as if it had been provided in the
+// AppConfig.Mirrors field. The driver is initialized and then receives a
+// frame with the whole current screen, followed by the same frames as the
+// main driver. Initialization errors are logged.
+func AttachMirror(dr Driver) Cmd {
+	return func() Msg {
+		return msgAttachMirror{dr: dr}
+	}
+}
+
+// DetachMirror returns a special command that closes and detaches a mirror
+// driver previously provided with AttachMirror or AppConfig.Mirrors.
+func DetachMirror(dr Driver) Cmd {
+	return func() Msg {
+		return msgDetachMirror{dr: dr}
+	}
+}
+
+// Emit returns a command that simply sends the given message. It can be used
+// by widgets and other components to emit semantic events, such as a menu
+// entry being invoked, that are then received by the model's Update like any
+// other message.
+func Emit(msg Msg) Cmd {
+	return func() Msg {
+		return msg
+	}
+}
+
 // Batch peforms a bunch of effects concurrently with no ordering guarantees
 // about the potential results.
 func Batch(effs ...Effect) Effect {
@@ -219,20 +360,67 @@ type App struct {
 	// true.
 	CatchPanics bool
 
-	driver Driver
-	model  Model
-	enc    *frameEncoder
-	logger *log.Logger
+	driver  Driver
+	mirrors []Driver // secondary drivers for output only
+	model   Model
+	enc     *frameEncoder
+	recIn   bool         // record inputs
+	inrec   []FrameInput // inputs recorded since last frame
+	logger  *log.Logger
 
-	grid  Grid
-	frame Frame
+	grid   Grid
+	frame  Frame
+	redraw []Point // positions of cells with AttrRedraw in grid
+	rdirty bool    // redraw positions have to be recomputed
 
 	effects  chan Effect
 	errs     chan error
 	inputs   chan Msg
 	msgs     chan Msg
+	lossy    chan Msg // droppable messages (BackpressureDropOldest)
 	polldone chan struct{}
 	t        *time.Timer
+
+	singleThread bool
+	queue        []Msg // queued command messages (single thread mode)
+	batchMsgs    bool
+	msgsSize     int // message queue capacity
+	effectsSize  int // effect queue capacity
+	backpressure BackpressurePolicy
+
+	dmu     sync.Mutex
+	dropped DroppedMsgs
+}
+
+// DroppedMsgs reports messages that were produced during a Start session but
+// never delivered to the model's Update, either because the context was
+// cancelled before they could be sent, or because they were still queued when
+// the main loop ended. It can help diagnosing subtle input-loss issues.
+type DroppedMsgs struct {
+	Count int            // total number of dropped messages
+	Types map[string]int // number of dropped messages by type name
+}
+
+// String returns a short textual description of the dropped messages, sorted
+// by type name.
+func (dm DroppedMsgs) String() string {
+	if dm.Count == 0 {
+		return "no dropped messages"
+	}
+	types := make([]string, 0, len(dm.Types))
+	for t := range dm.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d dropped messages:", dm.Count)
+	for i, t := range types {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, " %s (%d)", t, dm.Types[t])
+	}
+	return sb.String()
 }
 
 // AppConfig contains the configuration options for creating a new App.
@@ -246,32 +434,193 @@ type AppConfig struct {
 	// call Close on the Writer after Start returns.
 	FrameWriter io.Writer
 
-	// Logger is optional and is used to log non-fatal IO errors.
+	// FrameIndexWriter is an optional io.Writer for recording an index of
+	// the frames written to FrameWriter. The recorded frames are then
+	// organized in independent chunks starting with a key frame, so that
+	// a FrameDecoder provided with the index can seek efficiently. It is
+	// ignored if FrameWriter is nil.
+	FrameIndexWriter io.Writer
+
+	// FrameHeader contains optional metadata, such as an application
+	// name, recorded at the start of the FrameWriter stream. The version,
+	// grid size and time fields are filled automatically. It can be
+	// retrieved with FrameDecoder.Header.
+	FrameHeader FrameHeader
+
+	// RecordInputs makes the key and mouse messages handled by the
+	// application be recorded along with the frames, if FrameWriter is
+	// not nil. They are available in the Inputs field of decoded frames,
+	// and can be shown by ui.Replay. A frame without cell changes is
+	// recorded when needed, so that inputs keep accurate timings.
+	RecordInputs bool
+
+	// Logger is optional and is used to log non-fatal IO errors. At the
+	// end of a Start session, a summary of dropped messages is logged too,
+	// if any.
 	Logger *log.Logger
+
+	// SingleThread makes the application run its main loop cooperatively
+	// on the goroutine that called Start. Commands are then executed
+	// sequentially on that same goroutine just after Update returns, and
+	// their resulting messages are handled in order before any new input.
+	// If the driver implements DriverPollMsg, input is polled from the
+	// main loop too. This may be useful on platforms such as wasm, where
+	// goroutine scheduling can produce timing jitter.
+	//
+	// Note that subscriptions, as well as PollMsgs for drivers that do not
+	// implement DriverPollMsg, still run on their own goroutine, because
+	// they represent long running functions.
+	SingleThread bool
+
+	// Mirrors are optional secondary drivers that receive the same frames
+	// as Driver, for example for recording or live spectating. Input is
+	// only taken from Driver: the PollMsgs method of mirrors is never
+	// called. Mirrors are initialized after Driver, and closed before it.
+	// See also the AttachMirror command.
+	Mirrors []Driver
+
+	// BatchMsgs makes the application call Update on all the messages
+	// that are already queued before calling Draw only once, instead of
+	// drawing after each message. This reduces the cost of handling bursts
+	// of messages, such as pasted text or held keys, for models that do
+	// not rely on Draw being called after every Update.
+	BatchMsgs bool
+
+	// MsgQueueSize is the capacity of the queue of messages waiting for
+	// Update (default: 4).
+	MsgQueueSize int
+
+	// EffectQueueSize is the capacity of the queue of effects returned by
+	// Update waiting to be processed (default: 4).
+	EffectQueueSize int
+
+	// Backpressure is the policy used when a message produced by a
+	// command or subscription cannot be queued because the message queue
+	// is full. Dropped messages are reported by DroppedMsgs. Input
+	// messages from the driver are never dropped.
+	Backpressure BackpressurePolicy
 }
 
+// BackpressurePolicy describes what happens when a message produced by a
+// command or subscription is sent while the message queue is full.
+type BackpressurePolicy int
+
+// These constants represent the available backpressure policies.
+const (
+	// BackpressureBlock makes the sender wait until there is room in the
+	// message queue. This is the default.
+	BackpressureBlock BackpressurePolicy = iota
+
+	// BackpressureDropOldest drops the oldest queued command or
+	// subscription message to make room for the new one. Input messages,
+	// as well as special messages produced by commands such as End or
+	// Batch, are never dropped nor reordered: a new special message waits
+	// until there is room in the queue instead.
+	BackpressureDropOldest
+
+	// BackpressureDropNewest drops the new message.
+	BackpressureDropNewest
+)
+
 // NewApp creates a new App with the given configuration options.
 func NewApp(cfg AppConfig) *App {
 	app := &App{
-		model:       cfg.Model,
-		driver:      cfg.Driver,
-		logger:      cfg.Logger,
-		CatchPanics: true,
+		model:        cfg.Model,
+		driver:       cfg.Driver,
+		logger:       cfg.Logger,
+		singleThread: cfg.SingleThread,
+		batchMsgs:    cfg.BatchMsgs,
+		mirrors:      append([]Driver(nil), cfg.Mirrors...),
+		msgsSize:     cfg.MsgQueueSize,
+		effectsSize:  cfg.EffectQueueSize,
+		backpressure: cfg.Backpressure,
+		CatchPanics:  true,
+	}
+	if app.msgsSize <= 0 {
+		app.msgsSize = 4
+	}
+	if app.effectsSize <= 0 {
+		app.effectsSize = 4
 	}
 	if cfg.FrameWriter != nil {
-		app.enc = newFrameEncoder(cfg.FrameWriter)
+		app.enc = newFrameEncoder(cfg.FrameWriter, cfg.FrameIndexWriter, cfg.FrameHeader)
+		app.recIn = cfg.RecordInputs
 	}
 	return app
 }
 
+// DroppedMsgs returns a summary of the messages that were dropped during the
+// last Start session. It should be called after Start returns.
+func (app *App) DroppedMsgs() DroppedMsgs {
+	app.dmu.Lock()
+	defer app.dmu.Unlock()
+	dm := DroppedMsgs{Count: app.dropped.Count, Types: make(map[string]int, len(app.dropped.Types))}
+	for t, n := range app.dropped.Types {
+		dm.Types[t] = n
+	}
+	return dm
+}
+
+// drop records an undelivered message.
+func (app *App) drop(msg Msg) {
+	if msg == nil {
+		return
+	}
+	app.dmu.Lock()
+	app.dropped.Count++
+	if app.dropped.Types == nil {
+		app.dropped.Types = map[string]int{}
+	}
+	app.dropped.Types[fmt.Sprintf("%T", msg)]++
+	app.dmu.Unlock()
+}
+
+// drainMsgs records as dropped the messages remaining in the message queues
+// at the end of a session.
+func (app *App) drainMsgs() {
+	for _, msg := range app.queue {
+		app.drop(msg)
+	}
+	app.queue = app.queue[:0]
+	for {
+		select {
+		case msg := <-app.msgs:
+			app.drop(msg)
+		case msg := <-app.lossy:
+			app.drop(msg)
+		case msg := <-app.inputs:
+			app.drop(msg)
+		default:
+			return
+		}
+	}
+}
+
+// DriverCapabilities returns the features supported by the application's
+// driver. It returns false if the driver does not implement DriverInfo.
+func (app *App) DriverCapabilities() (DriverCapabilities, bool) {
+	di, ok := app.driver.(DriverInfo)
+	if !ok {
+		return DriverCapabilities{}, false
+	}
+	return di.Capabilities(), true
+}
+
 // Start initializes the application and runs its main loop. The context
 // argument can be used as a means to prematurely cancel the loop. You can
 // usually use an empty context here.
 func (app *App) Start(ctx context.Context) (err error) {
-	app.msgs = make(chan Msg, 4)
+	app.msgs = make(chan Msg, app.msgsSize)
+	app.lossy = nil
+	if app.backpressure == BackpressureDropOldest {
+		app.lossy = make(chan Msg, app.msgsSize)
+	}
 	app.errs = make(chan error)        // for driver input errors
 	app.polldone = make(chan struct{}) // PollMsgs subscription finished
-	app.effects = make(chan Effect, 4)
+	app.effects = make(chan Effect, app.effectsSize)
+	app.dmu.Lock()
+	app.dropped = DroppedMsgs{}
+	app.dmu.Unlock()
 
 	pollMsgNonBlocking := false
 	switch app.driver.(type) {
@@ -280,10 +629,21 @@ func (app *App) Start(ctx context.Context) (err error) {
 		app.inputs = make(chan Msg, 4)
 	}
 
+	// dropped messages summary
+	defer func() {
+		app.drainMsgs()
+		if app.logger == nil {
+			return
+		}
+		if dm := app.DroppedMsgs(); dm.Count > 0 {
+			app.logger.Print(dm)
+		}
+	}()
+
 	// frame encoder finalization
 	defer func() {
 		if app.enc != nil {
-			nerr := app.enc.gzw.Close()
+			nerr := app.enc.close()
 			if err == nil {
 				err = nerr
 			} else if app.logger != nil {
@@ -297,6 +657,16 @@ func (app *App) Start(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
+	for i, dr := range app.mirrors {
+		err = dr.Init()
+		if err != nil {
+			for _, dr := range app.mirrors[:i] {
+				dr.Close()
+			}
+			app.driver.Close()
+			return fmt.Errorf("mirror: %v", err)
+		}
+	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -321,14 +691,26 @@ func (app *App) Start(ctx context.Context) (err error) {
 			app.driver.Close()
 		}()
 	}
+	defer app.closeMirrors()
 	defer cancel()
 
 	// initialization message (non-blocking, buffered)
 	app.msgs <- MsgInit{}
 
+	if app.singleThread {
+		if pollMsgNonBlocking {
+			close(app.polldone)
+		} else {
+			go app.startPollMsgs(ctx)
+		}
+		app.queue = app.queue[:0]
+		return app.startSingleThread(ctx, cancel, pollMsgNonBlocking)
+	}
+
 	// input messages queueing
 	if pollMsgNonBlocking {
-		go app.startPollMsgSub(ctx)
+		// input is polled and buffered by the main loop
+		close(app.polldone)
 	} else {
 		go app.startPollMsgs(ctx)
 	}
@@ -347,56 +729,159 @@ func (app *App) Start(ctx context.Context) (err error) {
 
 func (app *App) start(ctx context.Context, cancel context.CancelFunc) error {
 	for {
+		var msg Msg
 		select {
 		case <-ctx.Done():
 			return nil
 		case err := <-app.errs:
 			cancel()
 			return err
-		case msg := <-app.msgs:
-			if msg == nil {
-				continue
-			}
+		case msg = <-app.msgs:
+		case msg = <-app.lossy:
+		}
+		if msg == nil {
+			continue
+		}
 
-			// Handle quit message
-			if _, ok := msg.(msgEnd); ok {
-				cancel()
-				return nil
-			}
+		// Handle quit message
+		if isEnd(msg) {
+			app.end(msg)
+			cancel()
+			return nil
+		}
 
-			app.handleMsg(ctx, msg)
+		if end, err := app.handleMsgs(ctx, msg, false); end != nil || err != nil {
+			app.end(end)
+			cancel()
+			return err
 		}
 	}
 }
 
 func (app *App) startWithPollMsg(ctx context.Context, cancel context.CancelFunc) error {
 	for {
+		var msg Msg
 		select {
 		case <-ctx.Done():
 			return nil
 		case err := <-app.errs:
 			cancel()
 			return err
-		case msg := <-app.msgs:
-			if msg == nil {
-				continue
-			}
-
-			// Handle quit message
-			if _, ok := msg.(msgEnd); ok {
+		case msg = <-app.msgs:
+		case msg = <-app.lossy:
+		case msg = <-app.inputs:
+		default:
+			err := app.pollMsg(ctx)
+			if err != nil {
 				cancel()
-				return nil
+				return err
 			}
+		}
+		if msg == nil {
+			continue
+		}
 
-			app.handleMsg(ctx, msg)
-		default:
-			err := app.pollMsg(ctx)
+		// Handle quit message
+		if isEnd(msg) {
+			app.end(msg)
+			cancel()
+			return nil
+		}
+
+		if end, err := app.handleMsgs(ctx, msg, true); end != nil || err != nil {
+			app.end(end)
+			cancel()
+			return err
+		}
+	}
+}
+
+// startSingleThread runs the main loop when in single thread mode: command
+// messages are handled first, then messages from subscriptions, and then
+// input messages.
+func (app *App) startSingleThread(ctx context.Context, cancel context.CancelFunc, pollMsgNonBlocking bool) error {
+	for {
+		var msg Msg
+		if len(app.queue) > 0 {
+			msg = app.queue[0]
+			app.queue = app.queue[1:]
+		} else {
+			var err error
+			msg, err = app.nextMsg(ctx, pollMsgNonBlocking)
 			if err != nil {
 				cancel()
 				return err
 			}
 		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if msg == nil {
+			continue
+		}
+
+		// Handle quit message
+		if isEnd(msg) {
+			app.end(msg)
+			cancel()
+			return nil
+		}
+
+		if end, err := app.handleMsgs(ctx, msg, pollMsgNonBlocking); end != nil || err != nil {
+			app.end(end)
+			cancel()
+			return err
+		}
+	}
+}
+
+// nextMsg returns the next message from subscriptions or input in single
+// thread mode. It may return a nil message if none is available yet.
+func (app *App) nextMsg(ctx context.Context, pollMsgNonBlocking bool) (Msg, error) {
+	if !pollMsgNonBlocking {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case err := <-app.errs:
+			return nil, err
+		case msg := <-app.msgs:
+			return msg, nil
+		case msg := <-app.lossy:
+			return msg, nil
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return nil, nil
+	case msg := <-app.msgs:
+		return msg, nil
+	case msg := <-app.lossy:
+		return msg, nil
+	default:
+	}
+	dr := app.driver.(DriverPollMsg)
+	msg, err := dr.PollMsg()
+	if err != nil || msg != nil {
+		return msg, err
+	}
+	if app.t == nil {
+		app.t = time.NewTimer(2 * time.Millisecond)
+	} else {
+		app.t.Reset(2 * time.Millisecond)
 	}
+	select {
+	case <-ctx.Done():
+	case msg = <-app.msgs:
+		if !app.t.Stop() {
+			<-app.t.C
+		}
+	case msg = <-app.lossy:
+		if !app.t.Stop() {
+			<-app.t.C
+		}
+	case <-app.t.C:
+	}
+	return msg, nil
 }
 
 func (app *App) pollMsg(ctx context.Context) error {
@@ -409,20 +894,11 @@ func (app *App) pollMsg(ctx context.Context) error {
 		return err
 	}
 	if msg != nil {
-		select {
-		case app.msgs <- msg:
-			// if there is room
-			return nil
-		default:
-			// otherwise
-			select {
-			case <-ctx.Done():
-			case app.inputs <- msg:
-				return nil
-			}
-		}
+		// There is room, as only the main loop uses the input buffer.
+		app.inputs <- msg
+		return nil
 	}
-	if len(app.msgs) > 0 || len(app.inputs) > 0 {
+	if len(app.msgs) > 0 || len(app.lossy) > 0 || len(app.inputs) > 0 {
 		return nil
 	}
 	if app.t == nil {
@@ -437,23 +913,6 @@ func (app *App) pollMsg(ctx context.Context) error {
 	return nil
 }
 
-func (app *App) startPollMsgSub(ctx context.Context) {
-	defer func() {
-		close(app.polldone)
-	}()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case msg := <-app.inputs:
-			select {
-			case <-ctx.Done():
-			case app.msgs <- msg:
-			}
-		}
-	}
-}
-
 func (app *App) startPollMsgs(ctx context.Context) {
 	defer func() {
 		close(app.polldone)
@@ -467,47 +926,250 @@ func (app *App) startPollMsgs(ctx context.Context) {
 	}
 }
 
+// handleMsgs handles a message. If message batching is enabled, Update is
+// then called on every already queued message too, before drawing only once.
+// It returns the end message found among the queued messages, if any.
+func (app *App) handleMsgs(ctx context.Context, msg Msg, poll bool) (end Msg, err error) {
+	if !app.batchMsgs {
+		app.handleMsg(ctx, msg)
+		return nil, nil
+	}
+	var updated, exposed bool
+	for {
+		u, e := app.update(ctx, msg)
+		updated = updated || u
+		exposed = exposed || e
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		msg, err = app.queuedMsg(poll)
+		if err != nil || msg == nil {
+			break
+		}
+		if isEnd(msg) {
+			end = msg
+			break
+		}
+	}
+	if updated {
+		app.draw(exposed)
+	}
+	return end, err
+}
+
+// queuedMsg returns the next already available message without blocking, or
+// nil if there is none. If poll is true, the driver implements DriverPollMsg
+// and may be polled for input, once already buffered input has been handled.
+func (app *App) queuedMsg(poll bool) (Msg, error) {
+	if len(app.queue) > 0 {
+		msg := app.queue[0]
+		app.queue = app.queue[1:]
+		return msg, nil
+	}
+	select {
+	case msg := <-app.msgs:
+		return msg, nil
+	case msg := <-app.lossy:
+		return msg, nil
+	case msg := <-app.inputs:
+		return msg, nil
+	default:
+	}
+	if poll {
+		return app.driver.(DriverPollMsg).PollMsg()
+	}
+	return nil, nil
+}
+
+// isEnd reports whether a message signals the end of the application.
+func isEnd(msg Msg) bool {
+	switch msg.(type) {
+	case msgEnd, msgEndAfterDraw:
+		return true
+	}
+	return false
+}
+
+// end handles an end message before ending the application: for an
+// EndAfterDraw message, it draws a final frame and then calls the optional
+// function.
+func (app *App) end(msg Msg) {
+	em, ok := msg.(msgEndAfterDraw)
+	if !ok {
+		return
+	}
+	app.draw(false)
+	if em.fn != nil {
+		em.fn()
+	}
+}
+
 func (app *App) handleMsg(ctx context.Context, msg Msg) {
+	if updated, exposed := app.update(ctx, msg); updated {
+		app.draw(exposed)
+	}
+}
+
+// update handles a message without drawing. It reports whether Update was
+// called and the effect (if any) successfully sent, as well as whether a
+// screen redraw should be forced.
+func (app *App) update(ctx context.Context, msg Msg) (updated, exposed bool) {
 	// Process batched effects
 	if batchedEffects, ok := msg.(msgBatch); ok {
 		for _, eff := range batchedEffects {
-			if eff != nil {
-				select {
-				case app.effects <- eff:
-				case <-ctx.Done():
-					break
-				}
+			if eff != nil && !app.sendEffect(ctx, eff) {
+				break
 			}
 		}
-		return
+		return false, false
+	}
+
+	switch msg := msg.(type) {
+	case msgSetClipboard:
+		if dc, ok := app.driver.(DriverClipboard); ok {
+			app.logError("set clipboard", dc.SetClipboard(string(msg)))
+		}
+		return false, false
+	case msgRequestClipboard:
+		if dc, ok := app.driver.(DriverClipboard); ok {
+			app.logError("request clipboard", dc.RequestClipboard())
+		}
+		return false, false
+	case msgShowCursor:
+		if dc, ok := app.driver.(DriverCursor); ok {
+			dc.ShowCursor(msg.p)
+		}
+		return false, false
+	case msgHideCursor:
+		if dc, ok := app.driver.(DriverCursor); ok {
+			dc.HideCursor()
+		}
+		return false, false
+	case msgAttachMirror:
+		app.attachMirror(msg.dr)
+		return false, false
+	case msgDetachMirror:
+		app.detachMirror(msg.dr)
+		return false, false
 	}
 
 	// force redraw on screen message
-	_, exposed := msg.(MsgScreen)
+	_, exposed = msg.(MsgScreen)
+
+	if app.recIn {
+		app.recordInput(msg)
+	}
 
 	eff := app.model.Update(msg)
-	if eff != nil {
-		select {
-		case app.effects <- eff: // process effect (if any)
-		case <-ctx.Done():
-			return
-		}
+	if eff != nil && !app.sendEffect(ctx, eff) {
+		return false, exposed
 	}
+	return true, exposed
+}
 
+// draw calls the model's Draw and flushes the resulting frame, if any.
+func (app *App) draw(exposed bool) {
 	gd := app.model.Draw()
 	frame := app.computeFrame(gd, exposed)
 	if len(frame.Cells) > 0 {
 		app.flush(frame)
+	} else if len(app.inrec) > 0 && app.grid.Ug != nil {
+		app.encode(Frame{Time: time.Now(), Width: app.frame.Width, Height: app.frame.Height})
+	}
+}
+
+// recordInput records a key or mouse message for the next encoded frame.
+func (app *App) recordInput(msg Msg) {
+	switch msg := msg.(type) {
+	case MsgKeyDown:
+		app.inrec = append(app.inrec, FrameInput{Key: &msg})
+	case MsgMouse:
+		app.inrec = append(app.inrec, FrameInput{Mouse: &msg})
+	}
+}
+
+// attachMirror initializes a new mirror driver and sends it the current
+// state of the screen.
+func (app *App) attachMirror(dr Driver) {
+	err := dr.Init()
+	if err != nil {
+		app.logError("attach mirror", err)
+		return
+	}
+	app.mirrors = append(app.mirrors, dr)
+	if app.grid.Ug == nil {
+		return
+	}
+	dr.Flush(app.keyFrame(Frame{Time: time.Now(), Width: app.frame.Width, Height: app.frame.Height}))
+}
+
+// detachMirror closes and removes a mirror driver, if it is attached.
+func (app *App) detachMirror(dr Driver) {
+	for i, mdr := range app.mirrors {
+		if mdr == dr {
+			app.mirrors = append(app.mirrors[:i], app.mirrors[i+1:]...)
+			dr.Close()
+			return
+		}
+	}
+}
+
+// closeMirrors closes all the mirror drivers.
+func (app *App) closeMirrors() {
+	for _, dr := range app.mirrors {
+		dr.Close()
+	}
+}
+
+// logError logs a non-nil error, if a logger is configured.
+func (app *App) logError(prefix string, err error) {
+	if err != nil && app.logger != nil {
+		app.logger.Printf("%s: %v", prefix, err)
 	}
 }
 
 func (app *App) flush(frame Frame) {
 	app.driver.Flush(frame)
-	if app.enc != nil {
-		err := app.enc.encode(frame)
-		if err != nil && app.logger != nil {
-			app.logger.Printf("frame encoding: %v", err)
+	for _, dr := range app.mirrors {
+		dr.Flush(frame)
+	}
+	app.encode(frame)
+}
+
+// encode records a frame, if a frame writer was provided.
+func (app *App) encode(frame Frame) {
+	if app.enc == nil {
+		return
+	}
+	if app.enc.keyFrame() {
+		frame = app.keyFrame(frame)
+	}
+	frame.Inputs = app.inrec
+	err := app.enc.encode(frame)
+	if err != nil && app.logger != nil {
+		app.logger.Printf("frame encoding: %v", err)
+	}
+	app.inrec = app.inrec[:0]
+}
+
+// sendEffect sends a non-nil effect for processing. In single thread mode,
+// commands are executed immediately instead. It returns false if the context
+// was cancelled.
+func (app *App) sendEffect(ctx context.Context, eff Effect) bool {
+	if app.singleThread {
+		switch eff := eff.(type) {
+		case Cmd:
+			app.queue = append(app.queue, eff())
+		case Sub:
+			app.startSub(ctx, eff)
 		}
+		return true
+	}
+	select {
+	case app.effects <- eff: // process effect (if any)
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -518,16 +1180,91 @@ func (app *App) processEffects(ctx context.Context) {
 			switch eff := eff.(type) {
 			case Cmd:
 				go func(ctx context.Context, cmd Cmd) {
-					select {
-					case app.msgs <- cmd():
-					case <-ctx.Done():
-					}
+					app.sendMsg(ctx, cmd())
 				}(ctx, eff)
 			case Sub:
-				go eff(ctx, app.msgs)
+				app.startSub(ctx, eff)
 			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
+
+// startSub starts a subscription. Unless the backpressure policy is
+// BackpressureBlock, subscription messages are forwarded to the message
+// queue according to the policy.
+func (app *App) startSub(ctx context.Context, sub Sub) {
+	if app.backpressure == BackpressureBlock {
+		go sub(ctx, app.msgs)
+		return
+	}
+	ch := make(chan Msg)
+	go func() {
+		sub(ctx, ch)
+		close(ch)
+	}()
+	go func() {
+		for msg := range ch {
+			app.sendMsg(ctx, msg)
+		}
+	}()
+}
+
+// sendMsg sends a command or subscription message to the message queue,
+// according to the backpressure policy.
+func (app *App) sendMsg(ctx context.Context, msg Msg) {
+	switch app.backpressure {
+	case BackpressureDropNewest:
+		select {
+		case app.msgs <- msg:
+		default:
+			app.drop(msg)
+		}
+	case BackpressureDropOldest:
+		if special(msg) {
+			// special messages are never dropped nor reordered
+			select {
+			case app.msgs <- msg:
+			case <-ctx.Done():
+				app.drop(msg)
+			}
+			return
+		}
+		// The lossy queue only contains droppable messages, so dropping
+		// the oldest one does not affect the order of other messages.
+		for {
+			select {
+			case app.lossy <- msg:
+				return
+			case <-ctx.Done():
+				app.drop(msg)
+				return
+			default:
+			}
+			select {
+			case old := <-app.lossy:
+				app.drop(old)
+			default:
+			}
+		}
+	default:
+		select {
+		case app.msgs <- msg:
+		case <-ctx.Done():
+			app.drop(msg)
+		}
+	}
+}
+
+// special reports whether a message is an input or special message that
+// should not be dropped by the backpressure policy.
+func special(msg Msg) bool {
+	switch msg.(type) {
+	case MsgInit, MsgKeyDown, MsgKeyUp, MsgMouse, MsgGamepad, MsgPaste, MsgScreen, MsgQuit,
+		msgEnd, msgEndAfterDraw, msgBatch, msgSetClipboard, msgRequestClipboard,
+		msgShowCursor, msgHideCursor, msgAttachMirror, msgDetachMirror:
+		return true
+	}
+	return false
+}
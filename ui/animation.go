@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// Animation represents a lightweight time-based animation that can be drawn
+// over a grid as an overlay, and managed by Animations.
+type Animation interface {
+	// Duration returns the total duration of the animation.
+	Duration() time.Duration
+
+	// Draw draws the state of the animation at a given elapsed time since
+	// its start, which is less than its duration.
+	Draw(gd gruid.Grid, elapsed time.Duration)
+}
+
+// Flash is an animation that shows a cell at a given position during some
+// time.
+type Flash struct {
+	P    gruid.Point   // flashed position
+	Cell gruid.Cell    // cell to be shown
+	D    time.Duration // flash duration
+}
+
+// Duration implements Animation.Duration.
+func (fl Flash) Duration() time.Duration {
+	return fl.D
+}
+
+// Draw implements Animation.Draw.
+func (fl Flash) Draw(gd gruid.Grid, elapsed time.Duration) {
+	gd.Set(fl.P, fl.Cell)
+}
+
+// Move is an animation that moves a cell from a position to another along a
+// straight line, at constant speed, such as a projectile.
+type Move struct {
+	From gruid.Point   // starting position
+	To   gruid.Point   // ending position
+	Cell gruid.Cell    // moving cell
+	D    time.Duration // movement duration
+}
+
+// Duration implements Animation.Duration.
+func (mv Move) Duration() time.Duration {
+	return mv.D
+}
+
+// Draw implements Animation.Draw. The position is interpolated between the
+// starting and ending positions.
+func (mv Move) Draw(gd gruid.Grid, elapsed time.Duration) {
+	gd.Set(mv.At(elapsed), mv.Cell)
+}
+
+// At returns the interpolated position of the moving cell at a given elapsed
+// time.
+func (mv Move) At(elapsed time.Duration) gruid.Point {
+	if mv.D <= 0 || elapsed >= mv.D {
+		return mv.To
+	}
+	delta := mv.To.Sub(mv.From)
+	interp := func(x int) int {
+		n := int64(x) * int64(elapsed)
+		d := int64(mv.D)
+		// rounded division
+		if n < 0 {
+			return int((n - d/2) / d)
+		}
+		return int((n + d/2) / d)
+	}
+	return mv.From.Add(gruid.Point{interp(delta.X), interp(delta.Y)})
+}
+
+// Pause is an animation that draws nothing during some time. It is useful
+// within sequences.
+type Pause time.Duration
+
+// Duration implements Animation.Duration.
+func (pa Pause) Duration() time.Duration {
+	return time.Duration(pa)
+}
+
+// Draw implements Animation.Draw. It does nothing.
+func (pa Pause) Draw(gd gruid.Grid, elapsed time.Duration) {}
+
+// Sequence is an animation that plays a list of animations one after another.
+type Sequence []Animation
+
+// Duration implements Animation.Duration. It is the sum of the durations of
+// the animations in the sequence.
+func (seq Sequence) Duration() time.Duration {
+	var d time.Duration
+	for _, a := range seq {
+		d += a.Duration()
+	}
+	return d
+}
+
+// Draw implements Animation.Draw.
+func (seq Sequence) Draw(gd gruid.Grid, elapsed time.Duration) {
+	for _, a := range seq {
+		d := a.Duration()
+		if elapsed < d {
+			a.Draw(gd, elapsed)
+			return
+		}
+		elapsed -= d
+	}
+}
+
+// Animations manages a set of running animations. The model advances time by
+// calling Update with the elapsed time, usually in response to messages
+// produced by the command returned by Tick, and then draws the animations over
+// its grid with Draw. Animations are removed automatically once finished.
+//
+// The zero value is ready to use.
+type Animations struct {
+	anims []animEntry
+	now   time.Duration // time since creation
+}
+
+type animEntry struct {
+	anim  Animation
+	start time.Duration
+}
+
+// MsgAnimationTick is the message produced by the command returned by
+// Animations.Tick. It contains the time at which it was produced.
+type MsgAnimationTick time.Time
+
+// Add starts a new animation at the current time.
+func (as *Animations) Add(a Animation) {
+	as.anims = append(as.anims, animEntry{anim: a, start: as.now})
+}
+
+// Update advances the time of running animations by a given duration, and
+// removes finished animations.
+func (as *Animations) Update(elapsed time.Duration) {
+	as.now += elapsed
+	j := 0
+	for _, e := range as.anims {
+		if as.now-e.start < e.anim.Duration() {
+			as.anims[j] = e
+			j++
+		}
+	}
+	for i := j; i < len(as.anims); i++ {
+		as.anims[i] = animEntry{}
+	}
+	as.anims = as.anims[:j]
+}
+
+// Running reports whether some animation is still running.
+func (as *Animations) Running() bool {
+	return len(as.anims) > 0
+}
+
+// Clear stops all the running animations.
+func (as *Animations) Clear() {
+	as.anims = as.anims[:0]
+}
+
+// Draw draws the current state of the running animations over the given grid,
+// in the order they were added, and returns the grid.
+func (as *Animations) Draw(gd gruid.Grid) gruid.Grid {
+	for _, e := range as.anims {
+		elapsed := as.now - e.start
+		if elapsed < e.anim.Duration() {
+			e.anim.Draw(gd, elapsed)
+		}
+	}
+	return gd
+}
+
+// Tick returns a command that sends a MsgAnimationTick after a given interval,
+// if there are running animations, or nil otherwise. It can be used to
+// schedule the next animation frame.
+func (as *Animations) Tick(interval time.Duration) gruid.Cmd {
+	if !as.Running() {
+		return nil
+	}
+	return func() gruid.Msg {
+		t := time.NewTimer(interval)
+		<-t.C
+		return MsgAnimationTick(time.Now())
+	}
+}
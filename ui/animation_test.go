@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestAnimations(t *testing.T) {
+	gd := gruid.NewGrid(10, 3)
+	as := &Animations{}
+	as.Add(Flash{P: gruid.Point{0, 0}, Cell: gruid.Cell{Rune: '!'}, D: 100 * time.Millisecond})
+	as.Add(Sequence{
+		Pause(50 * time.Millisecond),
+		Move{From: gruid.Point{0, 1}, To: gruid.Point{8, 1}, Cell: gruid.Cell{Rune: '*'}, D: 80 * time.Millisecond},
+	})
+	as.Draw(gd)
+	if c := gd.At(gruid.Point{0, 0}); c.Rune != '!' {
+		t.Errorf("bad flash: %c", c.Rune)
+	}
+	gd.Iter(func(p gruid.Point, c gruid.Cell) {
+		if c.Rune == '*' {
+			t.Errorf("move drawn during pause at %v", p)
+		}
+	})
+	as.Update(90 * time.Millisecond)
+	gd.Fill(gruid.Cell{})
+	as.Draw(gd)
+	if c := gd.At(gruid.Point{4, 1}); c.Rune != '*' {
+		t.Errorf("bad interpolated position: %c", c.Rune)
+	}
+	as.Update(20 * time.Millisecond)
+	if !as.Running() || len(as.anims) != 1 {
+		t.Errorf("flash not finished")
+	}
+	as.Update(30 * time.Millisecond)
+	if as.Running() || as.Tick(time.Millisecond) != nil {
+		t.Errorf("animations not finished")
+	}
+}
@@ -16,54 +16,203 @@ const (
 	AlignRight
 )
 
+// VAlignment represents top, middle or bottom vertical alignment.
+type VAlignment int16
+
+// Those constants represent the possible vertical alignment options. The
+// default alignment is AlignTop.
+const (
+	AlignTop VAlignment = iota
+	AlignMiddle
+	AlignBottom
+)
+
+// BorderStyle describes the set of runes used to draw the borders of a box.
+// The zero value represents the default single-line border set.
+type BorderStyle struct {
+	Horizontal  rune // top and bottom sides
+	Vertical    rune // left and right sides
+	TopLeft     rune // top-left corner
+	TopRight    rune // top-right corner
+	BottomLeft  rune // bottom-left corner
+	BottomRight rune // bottom-right corner
+}
+
+// These variables represent common border styles. BorderASCII only uses ASCII
+// characters, and can be used as a fallback for terminals or fonts lacking
+// box-drawing characters.
+var (
+	BorderSingle  = BorderStyle{'─', '│', '┌', '┐', '└', '┘'}
+	BorderDouble  = BorderStyle{'═', '║', '╔', '╗', '╚', '╝'}
+	BorderRounded = BorderStyle{'─', '│', '╭', '╮', '╰', '╯'}
+	BorderThick   = BorderStyle{'━', '┃', '┏', '┓', '┗', '┛'}
+	BorderASCII   = BorderStyle{'-', '|', '+', '+', '+', '+'}
+)
+
+// BoxSide represents a set of box sides.
+type BoxSide int
+
+// These constants represent the sides of a box. They can be combined with
+// bitwise or.
+const (
+	SideTop BoxSide = 1 << iota
+	SideBottom
+	SideLeft
+	SideRight
+)
+
 // Box contains information to draw a rectangle using box characters, with an
-// optional title.
+// optional title. Boxed widgets, such as Menu, Pager or TextInput, take into
+// account hidden sides and shadow when placing their content.
 type Box struct {
 	Style       gruid.Style // box style
 	Title       StyledText  // optional top text
 	Footer      StyledText  // optional bottom text
 	AlignTitle  Alignment   // title alignment
 	AlignFooter Alignment   // footer alignment
+	Border      BorderStyle // border runes (default: BorderSingle)
+	Hidden      BoxSide     // sides that are not drawn (default: none)
+
+	// Shadow enables a drop shadow, drawn with ShadowStyle using the last
+	// column and line of the grid.
+	Shadow      bool
+	ShadowStyle gruid.Style
+}
+
+// margins returns the number of cells taken by the box on the top-left and
+// bottom-right sides, including shadow. It returns zero values for a nil
+// box.
+func (b *Box) margins() (min, max gruid.Point) {
+	if b == nil {
+		return min, max
+	}
+	if b.Hidden&SideLeft == 0 {
+		min.X++
+	}
+	if b.Hidden&SideTop == 0 {
+		min.Y++
+	}
+	if b.Hidden&SideRight == 0 {
+		max.X++
+	}
+	if b.Hidden&SideBottom == 0 {
+		max.Y++
+	}
+	if b.Shadow {
+		max = max.Shift(1, 1)
+	}
+	return min, max
+}
+
+// inner returns the content range of a box drawn in the given range.
+func (b *Box) inner(rg gruid.Range) gruid.Range {
+	min, max := b.margins()
+	return rg.Shift(min.X, min.Y, -max.X, -max.Y)
+}
+
+// outer returns the range of a box whose content range is rg.
+func (b *Box) outer(rg gruid.Range) gruid.Range {
+	min, max := b.margins()
+	return rg.Shift(-min.X, -min.Y, max.X, max.Y)
+}
+
+// size returns the total size taken by the box borders and shadow.
+func (b *Box) size() gruid.Point {
+	min, max := b.margins()
+	return min.Add(max)
 }
 
 // Draw draws a rectangular box in a grid, taking the whole grid. It does not
 // draw anything in the interior region. It returns the grid slice that was
 // drawn, which usually is the whole grid, except if the grid was too small to
-// draw a box.
+// draw a box. The title and footer are only drawn if the top and bottom sides
+// are shown, respectively.
 func (b Box) Draw(gd gruid.Grid) gruid.Grid {
 	rg := gd.Range()
 	max := rg.Size()
-	if max.X < 2 || max.Y < 2 {
+	bs := b.size()
+	if max.X < bs.X || max.Y < bs.Y || max.X < 1 || max.Y < 1 {
 		return gd.Slice(gruid.Range{})
 	}
-	cgrid := gd.Slice(rg.Shift(1, 0, -1, 0))
-	crg := cgrid.Range()
+	if b.Shadow {
+		b.drawShadow(gd)
+		rg = rg.Shift(0, 0, -1, -1)
+		max = rg.Size()
+	}
+	border := b.Border
+	if border == (BorderStyle{}) {
+		border = BorderSingle
+	}
+	top, bottom := b.Hidden&SideTop == 0, b.Hidden&SideBottom == 0
+	left, right := b.Hidden&SideLeft == 0, b.Hidden&SideRight == 0
 	cell := gruid.Cell{Style: b.Style}
-	cell.Rune = '─'
-	max = crg.Size()
-	line := cgrid.Slice(crg.Line(0))
-	line.Fill(cell)
-	if b.Title.Text() != "" {
-		b.Title.drawTextLine(line, b.AlignTitle)
-	}
-	line = cgrid.Slice(crg.Line(max.Y - 1))
-	line.Fill(cell)
-	if b.Footer.Text() != "" {
-		b.Footer.drawTextLine(line, b.AlignFooter)
-	}
-	max = rg.Size()
-	gd.Set(rg.Min, cell.WithRune('┌'))
-	gd.Set(gruid.Point{X: max.X - 1}, cell.WithRune('┐'))
-	gd.Set(gruid.Point{Y: max.Y - 1}, cell.WithRune('└'))
-	gd.Set(rg.Max.Shift(-1, -1), cell.WithRune('┘'))
-	cell.Rune = '│'
-	col := gd.Slice(rg.Shift(0, 1, 0, -1).Column(0))
-	col.Fill(cell)
-	col = gd.Slice(rg.Shift(0, 1, 0, -1).Column(max.X - 1))
-	col.Fill(cell)
+	// horizontal lines span the whole width, except for corners
+	hrg := rg
+	if left {
+		hrg = hrg.Shift(1, 0, 0, 0)
+	}
+	if right {
+		hrg = hrg.Shift(0, 0, -1, 0)
+	}
+	cell.Rune = border.Horizontal
+	if top {
+		line := gd.Slice(hrg.Line(0))
+		line.Fill(cell)
+		if b.Title.Text() != "" {
+			b.Title.drawTextLine(line, b.AlignTitle)
+		}
+	}
+	if bottom {
+		line := gd.Slice(hrg.Line(max.Y - 1))
+		line.Fill(cell)
+		if b.Footer.Text() != "" {
+			b.Footer.drawTextLine(line, b.AlignFooter)
+		}
+	}
+	// vertical lines span the whole height, except for corners
+	vrg := rg
+	if top {
+		vrg = vrg.Shift(0, 1, 0, 0)
+	}
+	if bottom {
+		vrg = vrg.Shift(0, 0, 0, -1)
+	}
+	cell.Rune = border.Vertical
+	if left {
+		gd.Slice(vrg.Column(0)).Fill(cell)
+	}
+	if right {
+		gd.Slice(vrg.Column(max.X - 1)).Fill(cell)
+	}
+	if top && left {
+		gd.Set(gruid.Point{}, cell.WithRune(border.TopLeft))
+	}
+	if top && right {
+		gd.Set(gruid.Point{X: max.X - 1}, cell.WithRune(border.TopRight))
+	}
+	if bottom && left {
+		gd.Set(gruid.Point{Y: max.Y - 1}, cell.WithRune(border.BottomLeft))
+	}
+	if bottom && right {
+		gd.Set(gruid.Point{X: max.X - 1, Y: max.Y - 1}, cell.WithRune(border.BottomRight))
+	}
 	return gd
 }
 
+// drawShadow draws a drop shadow in the last column and line of the grid,
+// shifted by one cell from the top-left corner.
+func (b Box) drawShadow(gd gruid.Grid) {
+	rg := gd.Range()
+	max := rg.Size()
+	cell := gruid.Cell{Rune: ' ', Style: b.ShadowStyle}
+	gd.Slice(rg.Shift(1, max.Y-1, 0, 0)).Fill(cell)
+	gd.Slice(rg.Shift(max.X-1, 1, 0, 0)).Fill(cell)
+	// clear the corners left uncovered by the shadow
+	cell.Style = gruid.Style{}
+	gd.Set(gruid.Point{X: max.X - 1}, cell)
+	gd.Set(gruid.Point{Y: max.Y - 1}, cell)
+}
+
 func (stt StyledText) drawTextLine(gd gruid.Grid, align Alignment) {
 	switch align {
 	case AlignCenter:
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func gridString(gd gruid.Grid) string {
+	sb := strings.Builder{}
+	max := gd.Size()
+	for y := 0; y < max.Y; y++ {
+		for x := 0; x < max.X; x++ {
+			sb.WriteRune(gd.At(gruid.Point{x, y}).Rune)
+		}
+		sb.WriteRune('\n')
+	}
+	return sb.String()
+}
+
+func TestBoxBorders(t *testing.T) {
+	tests := []struct {
+		box  Box
+		want string
+	}{
+		{Box{}, "┌──┐\n│  │\n└──┘\n"},
+		{Box{Border: BorderDouble, Title: Text("T"), AlignTitle: AlignLeft}, "╔T═╗\n║  ║\n╚══╝\n"},
+		{Box{Border: BorderASCII, Hidden: SideTop | SideRight}, "|   \n|   \n+---\n"},
+		{Box{Border: BorderRounded, Hidden: SideBottom, Footer: Text("F")}, "╭──╮\n│  │\n│  │\n"},
+		{Box{Shadow: true}, "┌─┐ \n└─┘▒\n ▒▒▒\n"},
+	}
+	for i, test := range tests {
+		gd := gruid.NewGrid(4, 3)
+		if test.box.Shadow {
+			test.box.ShadowStyle = gruid.Style{Bg: 1}
+		}
+		test.box.Draw(gd)
+		got := gridString(gd)
+		if test.box.Shadow {
+			// make shadow visible
+			gd.Map(func(p gruid.Point, c gruid.Cell) gruid.Cell {
+				if c.Style.Bg == 1 {
+					c.Rune = '▒'
+				}
+				return c
+			})
+			got = gridString(gd)
+		}
+		if got != test.want {
+			t.Errorf("box %d:\n%swant:\n%s", i, got, test.want)
+		}
+	}
+	if gd := (Box{Shadow: true}).Draw(gruid.NewGrid(2, 2)); !gd.Range().Empty() {
+		t.Errorf("box drawn in too small grid: %v", gd.Range())
+	}
+}
+
+func TestBoxMenuLayout(t *testing.T) {
+	gd := gruid.NewGrid(10, 10)
+	menu := NewMenu(MenuConfig{
+		Grid:    gd,
+		Entries: []MenuEntry{{Text: Text("one")}, {Text: Text("two")}},
+		Box:     &Box{Hidden: SideLeft, Shadow: true},
+	})
+	drawn := menu.Draw()
+	if size := drawn.Size(); size.Y != 5 {
+		t.Errorf("bad menu height: %d", size.Y)
+	}
+	if c := gd.At(gruid.Point{0, 1}); c.Rune != 'o' {
+		t.Errorf("bad first entry position: %q", c.Rune)
+	}
+	menu.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{1, 2}})
+	if menu.Action() != MenuInvoke || menu.Active() != 1 {
+		t.Errorf("bad click: %v %d", menu.Action(), menu.Active())
+	}
+}
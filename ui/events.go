@@ -0,0 +1,53 @@
+// This file defines the semantic event messages emitted by widgets.
+//
+// Widgets created with a non-empty ID in their configuration emit, in
+// addition to reporting actions, event messages for their main actions, by
+// returning a command from Update, which should be returned in turn by the
+// model's Update. The event messages are then received by the model's Update
+// like any other message, so that a model with many widgets can handle them
+// in a single place, instead of checking each widget's Action after each
+// Update.
+
+package ui
+
+import "github.com/anaseto/gruid"
+
+// MenuInvoked is emitted by a menu with an ID when an entry is invoked.
+type MenuInvoked struct {
+	ID    string // menu ID
+	Index int    // index of the invoked entry
+}
+
+// ListInvoked is emitted by a list with an ID when an item is invoked.
+type ListInvoked struct {
+	ID    string // list ID
+	Index int    // index of the invoked item
+}
+
+// TableInvoked is emitted by a table with an ID when a row is invoked.
+type TableInvoked struct {
+	ID    string // table ID
+	Index int    // index of the invoked row, in the table rows
+}
+
+// InputAccepted is emitted by a text input with an ID when its content is
+// accepted.
+type InputAccepted struct {
+	ID   string // text input ID
+	Text string // accepted content
+}
+
+// WidgetClosed is emitted by a menu, list, table, text input, text area or
+// pager with an ID when the user requests to quit it.
+type WidgetClosed struct {
+	ID string // widget ID
+}
+
+// emit returns a command emitting an event message for a widget with the
+// given ID, or nil if the ID is empty.
+func emit(id string, msg gruid.Msg) gruid.Effect {
+	if id == "" {
+		return nil
+	}
+	return gruid.Emit(msg)
+}
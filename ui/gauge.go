@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// GaugeStyle describes styling options for a Gauge.
+type GaugeStyle struct {
+	Filled gruid.Style // style for the filled part of the bar
+	Empty  gruid.Style // style for the empty part of the bar
+
+	// Label is the style used for the label foreground and attributes.
+	// The background is the one of the bar cell below.
+	Label gruid.Style
+}
+
+// GaugeThreshold associates a style for the filled part of a gauge to a
+// minimal fill ratio.
+type GaugeThreshold struct {
+	Ratio float64     // minimal fill ratio
+	Style gruid.Style // style for the filled part
+}
+
+// Gauge represents a horizontal or vertical bar showing a fill ratio, such as
+// a health or experience bar, or a progress indicator. It takes the whole
+// grid it is drawn into.
+//
+// By default, the bar is drawn using spaces, so the Filled and Empty styles
+// should have different backgrounds. In smooth mode, the filled part is drawn
+// using block characters with the Filled style foreground, and partially
+// filled cells are drawn with eighth blocks.
+type Gauge struct {
+	Ratio    float64    // fill ratio, between 0 and 1
+	Label    string     // optional text drawn centered on the middle line
+	Vertical bool       // fill from bottom to top instead of left to right
+	Smooth   bool       // use block characters for sub-cell precision
+	Style    GaugeStyle // gauge styling
+
+	// Thresholds override the Filled style depending on the fill ratio:
+	// the style of the last threshold whose ratio is not greater than
+	// the gauge's ratio is used. They should be sorted by increasing
+	// ratio, for example red, yellow, then green for a health bar.
+	Thresholds []GaugeThreshold
+}
+
+// Draw draws the gauge into the given grid. It returns the grid.
+func (g Gauge) Draw(gd gruid.Grid) gruid.Grid {
+	max := gd.Size()
+	if max.X <= 0 || max.Y <= 0 {
+		return gd
+	}
+	ratio := g.Ratio
+	if ratio < 0 || ratio != ratio {
+		ratio = 0
+	} else if ratio > 1 {
+		ratio = 1
+	}
+	n := max.X
+	if g.Vertical {
+		n = max.Y
+	}
+	var full, part int
+	if g.Smooth {
+		eighths := int(ratio*float64(8*n) + 0.5)
+		full, part = eighths/8, eighths%8
+	} else {
+		full = int(ratio*float64(n) + 0.5)
+	}
+	fst := g.Style.Filled
+	for _, th := range g.Thresholds {
+		if ratio >= th.Ratio {
+			fst = th.Style
+		}
+	}
+	filled := gruid.Cell{Rune: ' ', Style: fst}
+	if g.Smooth {
+		filled.Rune = '█'
+	}
+	empty := gruid.Cell{Rune: ' ', Style: g.Style.Empty}
+	gd.Fill(empty)
+	rg := gd.Range()
+	if g.Vertical {
+		gd.Slice(rg.Lines(n-full, n)).Fill(filled)
+		if part > 0 {
+			c := gruid.Cell{Rune: []rune("▁▂▃▄▅▆▇")[part-1], Style: fst.WithBg(g.Style.Empty.Bg)}
+			gd.Slice(rg.Line(n - full - 1)).Fill(c)
+		}
+	} else {
+		gd.Slice(rg.Columns(0, full)).Fill(filled)
+		if part > 0 {
+			c := gruid.Cell{Rune: []rune("▏▎▍▌▋▊▉")[part-1], Style: fst.WithBg(g.Style.Empty.Bg)}
+			gd.Slice(rg.Column(full)).Fill(c)
+		}
+	}
+	if g.Label != "" {
+		g.drawLabel(gd)
+	}
+	return gd
+}
+
+// drawLabel draws the label centered on the middle line, keeping the
+// background of the bar.
+func (g Gauge) drawLabel(gd gruid.Grid) {
+	max := gd.Size()
+	label := []rune(g.Label)
+	x := (max.X - len(label)) / 2
+	if x < 0 {
+		x = 0
+	}
+	lst := g.Style.Label
+	for i, r := range label {
+		p := gruid.Point{X: x + i, Y: max.Y / 2}
+		if !gd.Contains(p) {
+			break
+		}
+		c := gd.At(p)
+		c.Rune = r
+		c.Style.Fg = lst.Fg
+		c.Style.Attrs = lst.Attrs
+		gd.Set(p, c)
+	}
+}
+
+// Sparkline represents a small chart showing a history of values, such as
+// recent damage or turn times, using eighth block characters. Each column
+// represents a value, with the most recent values on the right. It takes the
+// whole grid it is drawn into: only the last values fitting in the grid width
+// are shown.
+type Sparkline struct {
+	Values []float64   // non-negative values, most recent last
+	Max    float64     // value for a full column (default: maximum value)
+	Style  gruid.Style // sparkline style
+}
+
+// Draw draws the sparkline into the given grid. It returns the grid.
+func (sl Sparkline) Draw(gd gruid.Grid) gruid.Grid {
+	max := gd.Size()
+	gd.Fill(gruid.Cell{Rune: ' ', Style: sl.Style})
+	values := sl.Values
+	if len(values) > max.X {
+		values = values[len(values)-max.X:]
+	}
+	vmax := sl.Max
+	if vmax <= 0 {
+		for _, v := range values {
+			if v > vmax {
+				vmax = v
+			}
+		}
+	}
+	if vmax <= 0 || max.Y <= 0 {
+		return gd
+	}
+	x0 := max.X - len(values)
+	for i, v := range values {
+		if v > vmax {
+			v = vmax
+		}
+		eighths := 0
+		if v > 0 {
+			eighths = int(v/vmax*float64(8*max.Y) + 0.5)
+		}
+		for y := max.Y - 1; y >= 0 && eighths > 0; y-- {
+			r := '█'
+			if eighths < 8 {
+				r = []rune("▁▂▃▄▅▆▇")[eighths-1]
+			}
+			gd.Set(gruid.Point{X: x0 + i, Y: y}, gruid.Cell{Rune: r, Style: sl.Style})
+			eighths -= 8
+		}
+	}
+	return gd
+}
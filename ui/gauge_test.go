@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestGauge(t *testing.T) {
+	gd := gruid.NewGrid(10, 1)
+	g := Gauge{
+		Ratio: 0.25,
+		Label: "5/20",
+		Style: GaugeStyle{Filled: gruid.Style{Bg: 1}, Empty: gruid.Style{Bg: 2}, Label: gruid.Style{Fg: 3}},
+		Thresholds: []GaugeThreshold{
+			{Ratio: 0, Style: gruid.Style{Bg: 4}},
+			{Ratio: 0.5, Style: gruid.Style{Bg: 5}},
+		},
+	}
+	g.Draw(gd)
+	if s := gridString(gd); s != "   5/20   \n" {
+		t.Errorf("bad gauge label: %q", s)
+	}
+	for x, want := range []gruid.Color{4, 4, 4, 2, 2, 2} {
+		if c := gd.At(gruid.Point{x, 0}); c.Style.Bg != want {
+			t.Errorf("bad background at %d: %v, want %v", x, c.Style.Bg, want)
+		}
+	}
+	if c := gd.At(gruid.Point{3, 0}); c.Style.Fg != 3 {
+		t.Errorf("bad label style: %v", c.Style)
+	}
+	g = Gauge{Ratio: 0.55, Smooth: true}
+	g.Draw(gd)
+	if s := gridString(gd); s != "█████▌    \n" {
+		t.Errorf("bad smooth gauge: %q", s)
+	}
+	vgd := gruid.NewGrid(1, 4)
+	g = Gauge{Ratio: 0.6, Smooth: true, Vertical: true}
+	g.Draw(vgd)
+	if s := gridString(vgd); s != " \n▃\n█\n█\n" {
+		t.Errorf("bad vertical gauge: %q", s)
+	}
+	g.Ratio = 2
+	g.Draw(vgd)
+	if s := gridString(vgd); s != "█\n█\n█\n█\n" {
+		t.Errorf("bad full gauge: %q", s)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	gd := gruid.NewGrid(4, 2)
+	sl := Sparkline{Values: []float64{9, 0, 1, 2, 4}}
+	sl.Draw(gd)
+	if s := gridString(gd); s != "   █\n ▄██\n" {
+		t.Errorf("bad sparkline: %q", s)
+	}
+	sl.Max = 8
+	sl.Values = sl.Values[3:]
+	sl.Draw(gd)
+	if s := gridString(gd); s != "    \n  ▄█\n" {
+		t.Errorf("bad sparkline with max: %q", s)
+	}
+}
@@ -10,14 +10,29 @@ type Label struct {
 	Content     StyledText // label's styled text content
 	Box         *Box       // draw optional box around the label
 	AdjustWidth bool       // reduce the width of the label if possible
+	Align       Alignment  // content lines horizontal alignment (NewLabel: AlignLeft)
+	VAlign      VAlignment // content vertical alignment (default: AlignTop)
+	Padding     Padding    // space between the content and the box or grid borders
+
+	// FullHeight makes the label use the whole grid height, instead of
+	// the height of its content. It is useful with vertical alignment.
+	FullHeight bool
+
+	spans Spans // actionable spans drawn in last Draw
 }
 
-// NewLabel returns a new label with given styled text and AdjustWidth set to
-// true.
+// Padding represents empty space around some content, in cells.
+type Padding struct {
+	Top, Bottom, Left, Right int
+}
+
+// NewLabel returns a new label with given styled text, AdjustWidth set to
+// true, and left alignment.
 func NewLabel(content StyledText) *Label {
 	lb := &Label{
 		Content:     content,
 		AdjustWidth: true,
+		Align:       AlignLeft,
 	}
 	return lb
 }
@@ -29,20 +44,23 @@ func (lb *Label) SetText(text string) {
 
 func (lb *Label) drawGrid(gd gruid.Grid) gruid.Grid {
 	max := lb.Content.Size()
-	w, h := max.X, max.Y
+	pad := lb.Padding
+	w, h := max.X+pad.Left+pad.Right, max.Y+pad.Top+pad.Bottom
 	if lb.Box != nil {
 		ts := lb.Box.Title.Size()
 		if w < ts.X {
 			w = ts.X
 		}
 	}
-	if lb.Box != nil {
-		h += 2 // borders height
-		w += 2
-	}
+	bs := lb.Box.size() // borders and shadow
+	w += bs.X
+	h += bs.Y
 	if !lb.AdjustWidth {
 		w = gd.Size().X
 	}
+	if lb.FullHeight {
+		h = gd.Size().Y
+	}
 	return gd.Slice(gruid.NewRange(0, 0, w, h))
 }
 
@@ -53,10 +71,60 @@ func (lb *Label) Draw(gd gruid.Grid) gruid.Grid {
 	cgrid := grid
 	if lb.Box != nil {
 		lb.Box.Draw(grid)
-		rg := grid.Range()
-		cgrid = grid.Slice(rg.Shift(1, 1, -1, -1))
+		cgrid = grid.Slice(lb.Box.inner(grid.Range()))
 	}
 	cgrid.Fill(gruid.Cell{Rune: ' ', Style: lb.Content.Style()})
-	lb.Content.Draw(cgrid)
+	pad := lb.Padding
+	cgrid = cgrid.Slice(cgrid.Range().Shift(pad.Left, pad.Top, -pad.Right, -pad.Bottom))
+	lb.drawContent(cgrid)
 	return grid
 }
+
+// drawContent draws the label's content with alignment.
+func (lb *Label) drawContent(gd gruid.Grid) {
+	stt := lb.Content.resolve()
+	var widths []int // line widths
+	size := stt.Iter(func(p gruid.Point, c gruid.Cell) {
+		for len(widths) <= p.Y {
+			widths = append(widths, 0)
+		}
+		widths[p.Y] = p.X + 1
+	})
+	max := gd.Size()
+	y0 := 0
+	switch lb.VAlign {
+	case AlignMiddle:
+		y0 = (max.Y - size.Y) / 2
+	case AlignBottom:
+		y0 = max.Y - size.Y
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	lb.spans = Spans{}
+	min := gd.Bounds().Min
+	stt.iter(func(p gruid.Point, c gruid.Cell, span string) {
+		x0 := 0
+		switch lb.Align {
+		case AlignCenter:
+			x0 = (max.X - widths[p.Y]) / 2
+		case AlignRight:
+			x0 = max.X - widths[p.Y]
+		}
+		if x0 < 0 {
+			x0 = 0
+		}
+		p = p.Shift(x0, y0)
+		gd.Set(p, c)
+		if span != "" && gd.Contains(p) {
+			lb.spans.add(p.Add(min), span)
+		}
+	})
+}
+
+// HitTest returns the identifier of the actionable span, as created with
+// named markup, drawn at the given absolute position by the last Draw, if
+// any. It can be used to report clicks on specific words of the label.
+func (lb *Label) HitTest(p gruid.Point) (string, bool) {
+	return lb.spans.HitTest(p)
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestLabel(t *testing.T) {
+	gd := gruid.NewGrid(8, 4)
+	lb := NewLabel(Text("ab\nabcd"))
+	if drawn := lb.Draw(gd); drawn.Size() != (gruid.Point{4, 2}) {
+		t.Errorf("bad adjusted size: %v", drawn.Size())
+	}
+	if s := gridString(gd); s != "ab      \nabcd    \n        \n        \n" {
+		t.Errorf("bad left label:\n%s", s)
+	}
+	gd.Fill(gruid.Cell{Rune: '.'})
+	lb.Align = AlignRight
+	lb.Padding = Padding{Left: 1, Top: 1}
+	if drawn := lb.Draw(gd); drawn.Size() != (gruid.Point{5, 3}) {
+		t.Errorf("bad padded size: %v", drawn.Size())
+	}
+	if s := gridString(gd); s != "     ...\n   ab...\n abcd...\n........\n" {
+		t.Errorf("bad right padded label:\n%s", s)
+	}
+	gd.Fill(gruid.Cell{Rune: '.'})
+	lb = NewLabel(Text("ab"))
+	lb.Box = &Box{Border: BorderASCII}
+	lb.AdjustWidth = false
+	lb.FullHeight = true
+	lb.Align = AlignCenter
+	lb.VAlign = AlignBottom
+	lb.Draw(gd)
+	if s := gridString(gd); s != "+------+\n|      |\n|  ab  |\n+------+\n" {
+		t.Errorf("bad boxed label:\n%s", s)
+	}
+}
@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// Constraint describes the size of a row or column in a Split. It can be
+// created with Fixed, Percent or Flex.
+type Constraint struct {
+	kind constraintKind
+	n    int
+}
+
+type constraintKind int
+
+const (
+	constraintFixed constraintKind = iota
+	constraintPercent
+	constraintFlex
+)
+
+// Fixed returns a constraint for a part of fixed size n, in cells.
+func Fixed(n int) Constraint {
+	return Constraint{kind: constraintFixed, n: n}
+}
+
+// Percent returns a constraint for a part whose size is a percentage p of the
+// size of the split grid slice, rounded down.
+func Percent(p int) Constraint {
+	return Constraint{kind: constraintPercent, n: p}
+}
+
+// Flex returns a constraint for a part that shares the space remaining after
+// fixed and percentage parts with other flexible parts, proportionally to
+// their weights.
+func Flex(weight int) Constraint {
+	return Constraint{kind: constraintFlex, n: weight}
+}
+
+// Split describes a division of a grid slice into rows or columns.
+type Split struct {
+	Columns bool        // split into columns instead of rows
+	Parts   []SplitPart // parts, from top to bottom or left to right
+}
+
+// SplitPart describes a row or column of a split.
+type SplitPart struct {
+	Name  string     // optional name for retrieving the part's grid slice
+	Size  Constraint // size constraint
+	Split *Split     // optional nested split of the part
+}
+
+// LayoutConfig contains configuration options for creating a layout.
+type LayoutConfig struct {
+	Grid  gruid.Grid // grid slice to be split, usually the whole screen
+	Split Split      // root split
+}
+
+// Layout computes grid slices for the named parts of a possibly nested split
+// of a grid slice, using fixed, percentage and flexible size constraints. It
+// can be used for complex HUDs that should adapt to the screen size.
+//
+// Fixed and percentage parts are allocated first. If there is not enough
+// space for them, the last ones get truncated. Remaining space is then shared
+// between flexible parts.
+type Layout struct {
+	grid  gruid.Grid
+	split Split
+	parts map[string]gruid.Grid
+}
+
+// NewLayout returns a new layout with the given configuration.
+func NewLayout(cfg LayoutConfig) *Layout {
+	l := &Layout{split: cfg.Split}
+	l.SetGrid(cfg.Grid)
+	return l
+}
+
+// SetGrid updates the split grid slice and computes again the parts' grid
+// slices. It should typically be called after resizing the screen grid on a
+// gruid.MsgScreen message.
+func (l *Layout) SetGrid(gd gruid.Grid) {
+	l.grid = gd
+	l.parts = map[string]gruid.Grid{}
+	l.compute(gd, l.split)
+}
+
+// Grid returns the layout's split grid slice.
+func (l *Layout) Grid() gruid.Grid {
+	return l.grid
+}
+
+// Part returns the grid slice computed for the part with the given name. It
+// returns an empty slice of the layout's grid if there is no such part.
+func (l *Layout) Part(name string) gruid.Grid {
+	gd, ok := l.parts[name]
+	if !ok {
+		return l.grid.Slice(gruid.Range{})
+	}
+	return gd
+}
+
+func (l *Layout) compute(gd gruid.Grid, sp Split) {
+	size := gd.Size()
+	total := size.Y
+	if sp.Columns {
+		total = size.X
+	}
+	cs := make([]Constraint, len(sp.Parts))
+	for i, part := range sp.Parts {
+		cs[i] = part.Size
+	}
+	rg := gd.Range()
+	start := 0
+	for i, n := range splitSizes(total, cs) {
+		part := sp.Parts[i]
+		var pgd gruid.Grid
+		if sp.Columns {
+			pgd = gd.Slice(rg.Columns(start, start+n))
+		} else {
+			pgd = gd.Slice(rg.Lines(start, start+n))
+		}
+		start += n
+		if part.Name != "" {
+			l.parts[part.Name] = pgd
+		}
+		if part.Split != nil {
+			l.compute(pgd, *part.Split)
+		}
+	}
+}
+
+// splitSizes computes the sizes of parts with given constraints, so that
+// they sum at most to total.
+func splitSizes(total int, cs []Constraint) []int {
+	sizes := make([]int, len(cs))
+	rem := total
+	weights := 0
+	for i, c := range cs {
+		var n int
+		switch c.kind {
+		case constraintFixed:
+			n = c.n
+		case constraintPercent:
+			n = total * c.n / 100
+		case constraintFlex:
+			if c.n > 0 {
+				weights += c.n
+			}
+			continue
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n > rem {
+			n = rem
+		}
+		sizes[i] = n
+		rem -= n
+	}
+	if weights == 0 || rem == 0 {
+		return sizes
+	}
+	flex := rem
+	for i, c := range cs {
+		if c.kind == constraintFlex && c.n > 0 {
+			n := flex * c.n / weights
+			sizes[i] = n
+			rem -= n
+		}
+	}
+	// distribute rounding remainder
+	for i := 0; rem > 0; i = (i + 1) % len(cs) {
+		if cs[i].kind == constraintFlex && cs[i].n > 0 {
+			sizes[i]++
+			rem--
+		}
+	}
+	return sizes
+}
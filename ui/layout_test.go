@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestLayout(t *testing.T) {
+	gd := gruid.NewGrid(80, 24)
+	l := NewLayout(LayoutConfig{
+		Grid: gd,
+		Split: Split{
+			Parts: []SplitPart{
+				{Name: "top", Size: Fixed(1)},
+				{Size: Flex(1), Split: &Split{
+					Columns: true,
+					Parts: []SplitPart{
+						{Name: "map", Size: Flex(2)},
+						{Name: "side", Size: Percent(25)},
+						{Name: "info", Size: Flex(1)},
+					},
+				}},
+				{Name: "log", Size: Fixed(3)},
+			},
+		},
+	})
+	if rg := l.Part("top").Bounds(); rg != gruid.NewRange(0, 0, 80, 1) {
+		t.Errorf("bad top range: %v", rg)
+	}
+	if rg := l.Part("log").Bounds(); rg != gruid.NewRange(0, 21, 80, 24) {
+		t.Errorf("bad log range: %v", rg)
+	}
+	if rg := l.Part("side").Bounds(); rg != gruid.NewRange(40, 1, 60, 21) {
+		t.Errorf("bad side range: %v", rg)
+	}
+	if rg := l.Part("map").Bounds(); rg != gruid.NewRange(0, 1, 40, 21) {
+		t.Errorf("bad map range: %v", rg)
+	}
+	if rg := l.Part("info").Bounds(); rg != gruid.NewRange(60, 1, 80, 21) {
+		t.Errorf("bad info range: %v", rg)
+	}
+	if !l.Part("none").Range().Empty() {
+		t.Errorf("unknown part not empty")
+	}
+	gd = gd.Resize(10, 3)
+	l.SetGrid(gd)
+	if rg := l.Part("log").Bounds(); rg != gruid.NewRange(0, 1, 10, 3) {
+		t.Errorf("bad truncated log range: %v", rg)
+	}
+	if rg := l.Part("map").Range(); !rg.Empty() {
+		t.Errorf("bad map range without space: %v", rg)
+	}
+}
+
+func TestSplitSizes(t *testing.T) {
+	sizes := splitSizes(10, []Constraint{Flex(1), Fixed(2), Flex(1), Flex(1)})
+	if sizes[0] != 3 || sizes[1] != 2 || sizes[2] != 3 || sizes[3] != 2 {
+		t.Errorf("bad sizes: %v", sizes)
+	}
+}
@@ -0,0 +1,362 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/anaseto/gruid"
+)
+
+// ListConfig contains configuration options for creating a list.
+type ListConfig struct {
+	Grid  gruid.Grid // grid slice where the list is drawn
+	Len   int        // number of items
+	Box   *Box       // draw optional box around the list
+	Keys  ListKeys   // optional custom key bindings
+	ID    string     // optional identifier for emitted events (see ListInvoked)
+	Style ListStyle
+
+	// DrawItem draws the item with index i into the given one-line grid
+	// slice. The active argument reports whether the item is the active
+	// one. It is only called for visible items, so the list can handle
+	// large numbers of items.
+	DrawItem func(gd gruid.Grid, i int, active bool)
+}
+
+// ListStyle describes styling options for a List.
+type ListStyle struct {
+	Item    gruid.Style // style used to clear item lines before drawing
+	LineNum gruid.Style // line num display style (for boxed list)
+}
+
+// ListKeys contains key bindings configuration for the list.
+type ListKeys struct {
+	Down     []gruid.Key // move down active item (default: ArrowDown, j)
+	Up       []gruid.Key // move up active item (default: ArrowUp, k)
+	PageDown []gruid.Key // go one page down (default: PageDown, f)
+	PageUp   []gruid.Key // go one page up (default: PageUp, b)
+	Top      []gruid.Key // go to the first item (default: Home, g)
+	Bottom   []gruid.Key // go to the last item (default: End, G)
+	Invoke   []gruid.Key // invoke active item (default: Enter)
+	Quit     []gruid.Key // requist list quit (default: Escape, q, Q)
+}
+
+// List is a widget that displays a scrollable list of items, and allows to
+// move the active item, as well as invoke it. Unlike Menu, items are not laid
+// out upfront: only the visible ones are drawn, using a custom drawing
+// function, so that the list can handle an arbitrary number of items, such
+// as a long message log.
+//
+// List implements gruid.Model, but is not suitable for use as main model of an
+// application.
+type List struct {
+	grid   gruid.Grid
+	n      int
+	box    *Box
+	keys   ListKeys
+	style  ListStyle
+	draw   func(gruid.Grid, int, bool)
+	active int // active item index
+	offset int // index of the first visible item
+	action ListAction
+	dirty  bool       // state changed in Update and Draw was still not called
+	drawn  gruid.Grid // last drawn grid slice
+	id     string     // identifier for emitted events
+}
+
+// ListAction represents an user action with the list.
+type ListAction int
+
+// These constants represent the available actions in a list.
+const (
+	// ListPass reports that the list state did not change.
+	ListPass ListAction = iota
+
+	// ListMove reports that the user moved the active item, or scrolled
+	// the list.
+	ListMove
+
+	// ListInvoke reports that the user clicked or pressed enter to invoke
+	// the active item.
+	ListInvoke
+
+	// ListQuit reports that the user requested to quit the list, either by
+	// clicking outside the list, or by using a key shortcut.
+	ListQuit
+)
+
+// NewList returns a new list with the given configuration.
+func NewList(cfg ListConfig) *List {
+	l := &List{
+		grid:  cfg.Grid,
+		n:     cfg.Len,
+		box:   cfg.Box,
+		keys:  cfg.Keys,
+		style: cfg.Style,
+		draw:  cfg.DrawItem,
+		id:    cfg.ID,
+	}
+	if l.keys.Down == nil {
+		l.keys.Down = []gruid.Key{gruid.KeyArrowDown, "j"}
+	}
+	if l.keys.Up == nil {
+		l.keys.Up = []gruid.Key{gruid.KeyArrowUp, "k"}
+	}
+	if l.keys.PageDown == nil {
+		l.keys.PageDown = []gruid.Key{gruid.KeyPageDown, "f"}
+	}
+	if l.keys.PageUp == nil {
+		l.keys.PageUp = []gruid.Key{gruid.KeyPageUp, "b"}
+	}
+	if l.keys.Top == nil {
+		l.keys.Top = []gruid.Key{gruid.KeyHome, "g"}
+	}
+	if l.keys.Bottom == nil {
+		l.keys.Bottom = []gruid.Key{gruid.KeyEnd, "G"}
+	}
+	if l.keys.Invoke == nil {
+		l.keys.Invoke = []gruid.Key{gruid.KeyEnter}
+	}
+	if l.keys.Quit == nil {
+		l.keys.Quit = []gruid.Key{gruid.KeyEscape, "q", "Q"}
+	}
+	l.dirty = true
+	return l
+}
+
+// Len returns the number of items.
+func (l *List) Len() int {
+	return l.n
+}
+
+// SetLen updates the number of items. The active item is kept if it still
+// exists, and the view is adjusted if necessary.
+func (l *List) SetLen(n int) {
+	if n < 0 {
+		n = 0
+	}
+	l.n = n
+	l.setActive(l.active)
+	l.dirty = true
+}
+
+// Active returns the index of the active item. It returns -1 if the list is
+// empty.
+func (l *List) Active() int {
+	if l.n == 0 {
+		return -1
+	}
+	return l.active
+}
+
+// SetActive updates the active item, scrolling the view if necessary so that
+// it is visible.
+func (l *List) SetActive(i int) {
+	l.setActive(i)
+	l.dirty = true
+}
+
+// View returns the range of indices of the visible items, between Min and
+// Max-1.
+func (l *List) View() (min, max int) {
+	max = l.offset + l.nlines()
+	if max > l.n {
+		max = l.n
+	}
+	return l.offset, max
+}
+
+// SetBox updates the list surrounding box.
+func (l *List) SetBox(b *Box) {
+	l.box = b
+	l.setActive(l.active)
+	l.dirty = true
+}
+
+// Action returns the last action performed with the list.
+func (l *List) Action() ListAction {
+	return l.action
+}
+
+// content returns the grid slice where items are drawn.
+func (l *List) content() gruid.Grid {
+	return l.grid.Slice(l.box.inner(l.grid.Range()))
+}
+
+func (l *List) nlines() int {
+	return l.content().Size().Y
+}
+
+// setActive updates the active item and scrolls the minimal amount needed to
+// keep it visible.
+func (l *List) setActive(i int) {
+	if i >= l.n {
+		i = l.n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	l.active = i
+	nlines := l.nlines()
+	if l.offset > l.active {
+		l.offset = l.active
+	}
+	if nlines > 0 && l.active >= l.offset+nlines {
+		l.offset = l.active - nlines + 1
+	}
+	if l.offset > l.n-nlines {
+		l.offset = l.n - nlines
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+}
+
+func (l *List) moveTo(i int) {
+	oactive, ooffset := l.active, l.offset
+	l.setActive(i)
+	if l.active != oactive || l.offset != ooffset {
+		l.action = ListMove
+	}
+}
+
+func (l *List) scroll(shift int) {
+	nlines := l.nlines()
+	ooffset := l.offset
+	l.offset += shift
+	if l.offset > l.n-nlines {
+		l.offset = l.n - nlines
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+	if l.offset == ooffset {
+		return
+	}
+	l.action = ListMove
+	// keep active item visible
+	if l.active < l.offset {
+		l.active = l.offset
+	} else if l.active >= l.offset+nlines {
+		l.active = l.offset + nlines - 1
+	}
+}
+
+// Update implements gruid.Model.Update and updates the list state in response
+// to user input messages. It considers mouse message coordinates to be
+// absolute in its grid.
+func (l *List) Update(msg gruid.Msg) gruid.Effect {
+	l.action = ListPass
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		l.updateKeyDown(msg)
+	case gruid.MsgMouse:
+		l.updateMouse(msg)
+	}
+	if l.action == ListPass {
+		return nil
+	}
+	l.dirty = true
+	switch l.action {
+	case ListInvoke:
+		return emit(l.id, ListInvoked{ID: l.id, Index: l.Active()})
+	case ListQuit:
+		return emit(l.id, WidgetClosed{ID: l.id})
+	}
+	return nil
+}
+
+func (l *List) updateKeyDown(msg gruid.MsgKeyDown) {
+	key := msg.Key
+	page := l.nlines() - 1
+	if page < 1 {
+		page = 1
+	}
+	switch {
+	case key.In(l.keys.Quit):
+		l.action = ListQuit
+	case key.In(l.keys.Down):
+		l.moveTo(l.active + 1)
+	case key.In(l.keys.Up):
+		l.moveTo(l.active - 1)
+	case key.In(l.keys.PageDown):
+		l.moveTo(l.active + page)
+	case key.In(l.keys.PageUp):
+		l.moveTo(l.active - page)
+	case key.In(l.keys.Top):
+		l.moveTo(0)
+	case key.In(l.keys.Bottom):
+		l.moveTo(l.n - 1)
+	case key.In(l.keys.Invoke) && l.n > 0:
+		l.action = ListInvoke
+	}
+}
+
+func (l *List) updateMouse(msg gruid.MsgMouse) {
+	rg := l.grid.Bounds()
+	crg := l.content().Bounds()
+	p := msg.P
+	switch msg.Action {
+	case gruid.MouseMove:
+		if i, ok := l.itemAt(p, crg); ok {
+			l.moveTo(i)
+		}
+	case gruid.MouseWheelDown:
+		if p.In(rg) {
+			l.scroll(1)
+		}
+	case gruid.MouseWheelUp:
+		if p.In(rg) {
+			l.scroll(-1)
+		}
+	case gruid.MouseMain:
+		if !p.In(rg) {
+			l.action = ListQuit
+			break
+		}
+		if i, ok := l.itemAt(p, crg); ok {
+			l.active = i
+			l.action = ListInvoke
+		}
+	}
+}
+
+// itemAt returns the index of the item drawn at absolute position p, if any.
+func (l *List) itemAt(p gruid.Point, crg gruid.Range) (int, bool) {
+	if !p.In(crg) {
+		return 0, false
+	}
+	i := l.offset + p.Y - crg.Min.Y
+	if i >= l.n {
+		return 0, false
+	}
+	return i, true
+}
+
+// Draw implements gruid.Model.Draw. It draws the visible items using the
+// configured drawing function, and returns the grid slice that was drawn.
+func (l *List) Draw() gruid.Grid {
+	if !l.dirty {
+		return l.drawn
+	}
+	if l.box != nil {
+		foot := l.box.Footer
+		if foot.Text() == "" && l.n > l.nlines() {
+			l.box.Footer = NewStyledText(fmt.Sprintf("%d/%d", l.active+1, l.n), l.style.LineNum)
+		}
+		l.box.Draw(l.grid)
+		l.box.Footer = foot
+	}
+	content := l.content()
+	content.Fill(gruid.Cell{Rune: ' ', Style: l.style.Item})
+	min, max := l.View()
+	w := content.Size().X
+	for i := min; i < max; i++ {
+		line := content.Slice(gruid.NewRange(0, i-min, w, i-min+1))
+		if l.draw != nil {
+			l.draw(line, i, i == l.active)
+		}
+	}
+	l.dirty = false
+	l.drawn = l.grid
+	return l.drawn
+}
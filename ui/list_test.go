@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestList(t *testing.T) {
+	gd := gruid.NewGrid(10, 7)
+	drawn := 0
+	l := NewList(ListConfig{
+		Grid: gd,
+		Len:  50000,
+		Box:  &Box{},
+		DrawItem: func(gd gruid.Grid, i int, active bool) {
+			drawn++
+			st := gruid.Style{}
+			if active {
+				st.Attrs = 1
+			}
+			NewStyledText(fmt.Sprint(i), st).Draw(gd)
+		},
+	})
+	l.Draw()
+	if drawn != 5 {
+		t.Errorf("bad number of drawn items: %d", drawn)
+	}
+	if c := gd.At(gruid.Point{1, 1}); c.Rune != '0' || c.Style.Attrs != 1 {
+		t.Errorf("bad first item: %v", c)
+	}
+	l.Update(gruid.MsgKeyDown{Key: gruid.KeyEnd})
+	if l.Action() != ListMove || l.Active() != 49999 {
+		t.Errorf("bad bottom: %v %d", l.Action(), l.Active())
+	}
+	if min, max := l.View(); min != 49995 || max != 50000 {
+		t.Errorf("bad view: %d-%d", min, max)
+	}
+	l.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if l.Action() != ListPass {
+		t.Errorf("bad move beyond end: %v", l.Action())
+	}
+	l.Update(gruid.MsgKeyDown{Key: gruid.KeyPageUp})
+	if l.Active() != 49995 {
+		t.Errorf("bad page up: %d", l.Active())
+	}
+	l.Update(gruid.MsgMouse{Action: gruid.MouseWheelUp, P: gruid.Point{2, 2}})
+	if min, _ := l.View(); min != 49994 {
+		t.Errorf("bad wheel scroll: %d", min)
+	}
+	l.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 3}})
+	if l.Action() != ListInvoke || l.Active() != 49996 {
+		t.Errorf("bad click: %v %d", l.Action(), l.Active())
+	}
+	drawn = 0
+	l.Draw()
+	if drawn != 5 {
+		t.Errorf("bad number of drawn items: %d", drawn)
+	}
+	l.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{20, 3}})
+	if l.Action() != ListQuit {
+		t.Errorf("bad click outside: %v", l.Action())
+	}
+	l.SetLen(3)
+	if l.Active() != 2 {
+		t.Errorf("bad active after SetLen: %d", l.Active())
+	}
+	if min, max := l.View(); min != 0 || max != 3 {
+		t.Errorf("bad view after SetLen: %d-%d", min, max)
+	}
+	l.SetLen(0)
+	if l.Active() != -1 {
+		t.Errorf("bad active for empty list: %d", l.Active())
+	}
+	l.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter})
+	if l.Action() != ListPass {
+		t.Errorf("invoke on empty list: %v", l.Action())
+	}
+}
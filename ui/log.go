@@ -0,0 +1,357 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// LogEntry represents a message in a log.
+type LogEntry struct {
+	Text     StyledText // message text
+	Category string     // optional category, for filtering
+	Time     time.Time  // optional message time, shown with LogConfig.TimeFormat
+
+	// Count is the number of repetitions of the message. It is
+	// incremented when the same message is added several times in a row.
+	Count int
+}
+
+// LogConfig contains configuration options for creating a message log.
+type LogConfig struct {
+	Grid  gruid.Grid // grid slice where the log is drawn
+	Box   *Box       // draw optional box around the log
+	Keys  LogKeys    // optional custom key bindings for the log
+	Style LogStyle
+	Max   int // maximum number of kept entries (default: 1000)
+
+	// TimeFormat is an optional time layout, as used by time.Time.Format,
+	// for showing entry times before messages, such as "15:04:05".
+	TimeFormat string
+
+	// Filter optionally reports whether an entry should be shown, for
+	// example depending on its category.
+	Filter func(LogEntry) bool
+}
+
+// LogStyle describes styling options for a Log.
+type LogStyle struct {
+	Time  gruid.Style // style for entry times
+	Count gruid.Style // style for repetition counts, such as x3
+}
+
+// LogKeys contains key bindings configuration for the log.
+type LogKeys struct {
+	PageUp   []gruid.Key // scroll back one page (default: PageUp)
+	PageDown []gruid.Key // scroll forward one page (default: PageDown)
+	Top      []gruid.Key // go to the oldest messages (default: Home)
+	Bottom   []gruid.Key // go to the most recent messages (default: End)
+}
+
+// Log represents a message log widget, such as a combat log. Messages are
+// appended at the bottom and wrapped to the widget width, with most recent
+// messages visible by default. Scrollback is available with keys and the
+// mouse wheel. When a message is the same as the last one, it is coalesced
+// into it, and shown with a repetition count, as in "You hit the orc x3".
+//
+// Log implements gruid.Model, but it is usually part of a bigger screen.
+type Log struct {
+	grid    gruid.Grid
+	box     *Box
+	keys    LogKeys
+	style   LogStyle
+	max     int
+	tfmt    string
+	filter  func(LogEntry) bool
+	entries []LogEntry
+	lines   []logLines // formatted visible entries
+	valid   bool       // whether lines are valid
+	width   int        // width used for lines
+	nlines  int        // total number of formatted lines
+	offset  int        // number of lines scrolled back from the bottom
+	action  LogAction
+	dirty   bool
+}
+
+// logLines represents a formatted log entry.
+type logLines struct {
+	prefix StyledText // time prefix
+	text   StyledText // wrapped message text
+	count  int        // number of cells of the repetition count
+	h      int        // number of lines
+}
+
+// LogAction represents an user action with the log.
+type LogAction int
+
+// These constants represent the available actions in a log.
+const (
+	// LogPass reports that the log view did not change.
+	LogPass LogAction = iota
+
+	// LogMove reports a scrolling movement.
+	LogMove
+)
+
+// NewLog returns a new message log with the given configuration.
+func NewLog(cfg LogConfig) *Log {
+	l := &Log{
+		grid:   cfg.Grid,
+		box:    cfg.Box,
+		keys:   cfg.Keys,
+		style:  cfg.Style,
+		max:    cfg.Max,
+		tfmt:   cfg.TimeFormat,
+		filter: cfg.Filter,
+		dirty:  true,
+	}
+	if l.max <= 0 {
+		l.max = 1000
+	}
+	if l.keys.PageUp == nil {
+		l.keys.PageUp = []gruid.Key{gruid.KeyPageUp}
+	}
+	if l.keys.PageDown == nil {
+		l.keys.PageDown = []gruid.Key{gruid.KeyPageDown}
+	}
+	if l.keys.Top == nil {
+		l.keys.Top = []gruid.Key{gruid.KeyHome}
+	}
+	if l.keys.Bottom == nil {
+		l.keys.Bottom = []gruid.Key{gruid.KeyEnd}
+	}
+	return l
+}
+
+// Add appends a message to the log, with the current time.
+func (l *Log) Add(stt StyledText) {
+	l.AddEntry(LogEntry{Text: stt, Time: time.Now()})
+}
+
+// AddEntry appends an entry to the log. If the entry has the same text, style
+// and category as the last entry, it is coalesced into it instead: the count
+// of the last entry is increased, and its time updated. If the log view is
+// scrolled back, it stays on the same messages.
+func (l *Log) AddEntry(e LogEntry) {
+	if e.Count <= 0 {
+		e.Count = 1
+	}
+	if n := len(l.entries); n > 0 && l.entries[n-1].same(e) {
+		last := &l.entries[n-1]
+		last.Count += e.Count
+		last.Time = e.Time
+	} else {
+		l.entries = append(l.entries, e)
+		if len(l.entries) > l.max {
+			l.entries = l.entries[len(l.entries)-l.max:]
+		}
+	}
+	l.invalidate()
+}
+
+// same reports whether two entries represent the same message.
+func (e LogEntry) same(f LogEntry) bool {
+	return e.Text.Text() == f.Text.Text() && e.Text.Style() == f.Text.Style() && e.Category == f.Category
+}
+
+// Entries returns the log entries, from oldest to most recent.
+func (l *Log) Entries() []LogEntry {
+	return l.entries
+}
+
+// Clear removes all the entries.
+func (l *Log) Clear() {
+	l.entries = nil
+	l.invalidate()
+}
+
+// SetFilter updates the function reporting whether an entry should be shown.
+// A nil filter shows all entries. The view goes back to the most recent
+// messages.
+func (l *Log) SetFilter(fn func(LogEntry) bool) {
+	l.filter = fn
+	l.offset = 0
+	l.invalidate()
+}
+
+// SetBox updates the log surrounding box.
+func (l *Log) SetBox(b *Box) {
+	l.box = b
+	l.invalidate()
+}
+
+// invalidate marks formatted lines for recomputation, keeping the view on
+// the same lines if scrolled back.
+func (l *Log) invalidate() {
+	if l.offset > 0 && l.valid {
+		n := l.nlines
+		l.format()
+		l.offset += l.nlines - n
+		l.clampOffset()
+	} else {
+		l.valid = false
+	}
+	l.dirty = true
+}
+
+// content returns the grid slice where messages are drawn.
+func (l *Log) content() gruid.Grid {
+	return l.grid.Slice(l.box.inner(l.grid.Range()))
+}
+
+// format computes the formatted lines for the visible entries.
+func (l *Log) format() {
+	w := l.content().Size().X
+	l.lines = l.lines[:0]
+	l.valid = true
+	l.width = w
+	l.nlines = 0
+	for _, e := range l.entries {
+		if l.filter != nil && !l.filter(e) {
+			continue
+		}
+		var ll logLines
+		if l.tfmt != "" && !e.Time.IsZero() {
+			ll.prefix = NewStyledText(e.Time.Format(l.tfmt)+" ", l.style.Time)
+		}
+		text := e.Text
+		if e.Count > 1 {
+			count := fmt.Sprintf("x%d", e.Count)
+			ll.count = len(count)
+			text = text.WithText(text.Text() + " " + count)
+		}
+		ll.text = text.Format(w - ll.prefix.Size().X)
+		ll.h = ll.text.Size().Y
+		if ll.h == 0 {
+			ll.h = 1
+		}
+		l.lines = append(l.lines, ll)
+		l.nlines += ll.h
+	}
+}
+
+// ensureFormat formats lines if they are invalid or the width changed.
+func (l *Log) ensureFormat() {
+	if !l.valid || l.width != l.content().Size().X {
+		l.format()
+		l.clampOffset()
+	}
+}
+
+func (l *Log) clampOffset() {
+	if max := l.nlines - l.content().Size().Y; l.offset > max {
+		l.offset = max
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+}
+
+// scroll scrolls back by the given number of lines, or forward if negative.
+func (l *Log) scroll(delta int) {
+	l.ensureFormat()
+	offset := l.offset
+	l.offset += delta
+	l.clampOffset()
+	if l.offset != offset {
+		l.action = LogMove
+	}
+}
+
+// Offset returns the number of lines the view is scrolled back from the most
+// recent messages.
+func (l *Log) Offset() int {
+	l.ensureFormat()
+	return l.offset
+}
+
+// Action returns the last action performed with the log.
+func (l *Log) Action() LogAction {
+	return l.action
+}
+
+// Update implements gruid.Model.Update for Log. It considers mouse message
+// coordinates to be absolute in its grid.
+func (l *Log) Update(msg gruid.Msg) gruid.Effect {
+	l.action = LogPass
+	h := l.content().Size().Y
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		key := msg.Key
+		switch {
+		case key.In(l.keys.PageUp):
+			l.scroll(h - 1)
+		case key.In(l.keys.PageDown):
+			l.scroll(-(h - 1))
+		case key.In(l.keys.Top):
+			l.ensureFormat()
+			l.scroll(l.nlines)
+		case key.In(l.keys.Bottom):
+			l.scroll(-l.Offset())
+		}
+	case gruid.MsgMouse:
+		if !msg.P.In(l.grid.Bounds()) {
+			break
+		}
+		switch msg.Action {
+		case gruid.MouseWheelUp:
+			l.scroll(1)
+		case gruid.MouseWheelDown:
+			l.scroll(-1)
+		}
+	}
+	if l.action != LogPass {
+		l.dirty = true
+	}
+	return nil
+}
+
+// Draw implements gruid.Model.Draw for Log. It returns the log's grid, or an
+// empty grid if nothing changed since last Draw.
+func (l *Log) Draw() gruid.Grid {
+	if !l.dirty {
+		return l.grid.Slice(gruid.Range{})
+	}
+	l.ensureFormat()
+	if l.box != nil {
+		l.box.Draw(l.grid)
+	}
+	cgrid := l.content()
+	cgrid.Fill(gruid.Cell{Rune: ' '})
+	h := cgrid.Size().Y
+	// y is the position of the bottom of the current entry, relative to
+	// the top of the view
+	y := h + l.offset
+	for i := len(l.lines) - 1; i >= 0 && y > 0; i-- {
+		ll := l.lines[i]
+		y -= ll.h
+		if y >= h {
+			continue
+		}
+		l.drawEntry(cgrid, ll, y)
+	}
+	l.dirty = false
+	return l.grid
+}
+
+// drawEntry draws formatted lines starting at line y, that may be negative,
+// of the grid.
+func (l *Log) drawEntry(gd gruid.Grid, ll logLines, y int) {
+	if y >= 0 {
+		ll.prefix.Draw(gd.Slice(gd.Range().Line(y)))
+	}
+	pw := ll.prefix.Size().X
+	var cells []gruid.Cell
+	var ps []gruid.Point
+	ll.text.Iter(func(p gruid.Point, c gruid.Cell) {
+		ps = append(ps, p.Shift(pw, y))
+		cells = append(cells, c)
+	})
+	for i := len(cells) - ll.count; i < len(cells); i++ {
+		cells[i].Style = l.style.Count
+	}
+	for i, p := range ps {
+		gd.Set(p, cells[i])
+	}
+}
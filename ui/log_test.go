@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestLog(t *testing.T) {
+	gd := gruid.NewGrid(12, 3)
+	log := NewLog(LogConfig{
+		Grid:  gd,
+		Style: LogStyle{Count: gruid.Style{Fg: 1}},
+	})
+	lines := func() string {
+		log.Draw()
+		return strings.TrimRight(gridString(gd), "\n")
+	}
+	log.AddEntry(LogEntry{Text: Text("one")})
+	if s := lines(); s != "            \n            \none         " {
+		t.Errorf("bad first message:\n%s", s)
+	}
+	log.AddEntry(LogEntry{Text: Text("You hit the orc."), Category: "combat"})
+	log.AddEntry(LogEntry{Text: Text("You hit the orc."), Category: "combat"})
+	log.AddEntry(LogEntry{Text: Text("You hit the orc."), Category: "combat"})
+	if n := len(log.Entries()); n != 2 || log.Entries()[1].Count != 3 {
+		t.Errorf("bad coalescing: %d entries", n)
+	}
+	if s := lines(); s != "one         \nYou hit the \norc. x3     " {
+		t.Errorf("bad coalesced message:\n%s", s)
+	}
+	if c := gd.At(gruid.Point{5, 2}); c.Rune != 'x' || c.Style.Fg != 1 {
+		t.Errorf("bad count style: %v", c)
+	}
+	log.Add(Text("two"))
+	log.Add(Text("three"))
+	log.Update(gruid.MsgKeyDown{Key: gruid.KeyPageUp})
+	if log.Action() != LogMove || log.Offset() != 2 {
+		t.Errorf("bad page up: %v %d", log.Action(), log.Offset())
+	}
+	if s := lines(); s != "one         \nYou hit the \norc. x3     " {
+		t.Errorf("bad scrollback:\n%s", s)
+	}
+	// new messages keep the scrolled view
+	log.Add(Text("four"))
+	if s := lines(); s != "one         \nYou hit the \norc. x3     " || log.Offset() != 3 {
+		t.Errorf("bad scrollback after new message (offset %d):\n%s", log.Offset(), s)
+	}
+	log.Update(gruid.MsgKeyDown{Key: gruid.KeyEnd})
+	if s := lines(); s != "two         \nthree       \nfour        " {
+		t.Errorf("bad bottom:\n%s", s)
+	}
+	log.Update(gruid.MsgMouse{Action: gruid.MouseWheelUp, P: gruid.Point{1, 1}})
+	if log.Offset() != 1 {
+		t.Errorf("bad wheel scroll: %d", log.Offset())
+	}
+	log.SetFilter(func(e LogEntry) bool { return e.Category == "combat" })
+	if s := lines(); s != "            \nYou hit the \norc. x3     " {
+		t.Errorf("bad filter:\n%s", s)
+	}
+}
+
+func TestLogTimeFormat(t *testing.T) {
+	gd := gruid.NewGrid(12, 2)
+	log := NewLog(LogConfig{Grid: gd, TimeFormat: "15:04"})
+	log.AddEntry(LogEntry{Text: Text("hello world"), Time: time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)})
+	log.Draw()
+	if s := gridString(gd); s != "10:30 hello \n      world \n" {
+		t.Errorf("bad time prefix:\n%s", s)
+	}
+}
@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/anaseto/gruid"
+)
+
+// StyleRegistry associates style names to styles, for use with named markup.
+type StyleRegistry map[string]gruid.Style
+
+// WithNamedMarkup returns a derived styled text using named markup tags, as
+// an alternative to the one-rune @r markup. A tag {name} starts using the
+// style associated with name in the registry, until the matching {/name}
+// closing tag. Tags can be nested, as in {red}{bold}text{/bold}{/red}: the
+// style of an inner tag is combined with the outer style, overriding non-zero
+// colors and adding attributes. A {/} tag closes the last opened tag. Tags
+// with unknown names are kept as is, and {{ represents a literal brace.
+//
+// Tags of the form {#id} start an actionable span with the given identifier,
+// until the matching {/#id} or {/} tag, without changing the style. Spans are
+// reported by DrawSpans, so that widgets can report clicks on specific words,
+// such as item names or links. Spans can be used without named styles by
+// using an empty registry.
+//
+// Named markup is processed by Iter, Size, Format, Draw and Plain. As Format
+// returns a styled text using the equivalent @r markup, Text of the result
+// does not return named markup. One-rune @r markup is not processed in text
+// using named markup.
+func (stt StyledText) WithNamedMarkup(reg StyleRegistry) StyledText {
+	stt.named = reg
+	return stt
+}
+
+// markupRuneStart is the first rune used for @r markups equivalent to named
+// markup styles. It is in a Unicode private use plane.
+const markupRuneStart rune = 0xF0000
+
+// resolve returns an equivalent styled text using @r markup, if named markup
+// is used.
+func (stt StyledText) resolve() StyledText {
+	if stt.named == nil {
+		return stt
+	}
+	type markup struct {
+		st   gruid.Style
+		span string
+	}
+	var stack []string // opened tags
+	markups := map[rune]gruid.Style{}
+	spans := map[rune]string{}
+	runes := map[markup]rune{}
+	sb := strings.Builder{}
+	setStyle := func() {
+		if len(stack) == 0 {
+			sb.WriteString("@N")
+			return
+		}
+		m := markup{st: stt.style}
+		for _, name := range stack {
+			if strings.HasPrefix(name, "#") {
+				m.span = name[1:]
+				continue
+			}
+			m.st = mergeStyle(m.st, stt.named[name])
+		}
+		r, ok := runes[m]
+		if !ok {
+			r = markupRuneStart + rune(len(runes))
+			runes[m] = r
+			markups[r] = m.st
+			if m.span != "" {
+				spans[r] = m.span
+			}
+		}
+		sb.WriteRune('@')
+		sb.WriteRune(r)
+	}
+	text := stt.text
+	for len(text) > 0 {
+		i := strings.IndexAny(text, "{@")
+		if i < 0 {
+			sb.WriteString(text)
+			break
+		}
+		sb.WriteString(text[:i])
+		text = text[i:]
+		if text[0] == '@' {
+			sb.WriteString("@@")
+			text = text[1:]
+			continue
+		}
+		if strings.HasPrefix(text, "{{") {
+			sb.WriteByte('{')
+			text = text[2:]
+			continue
+		}
+		j := strings.IndexByte(text, '}')
+		if j < 0 {
+			sb.WriteString(text)
+			break
+		}
+		tag := text[1:j]
+		switch {
+		case tag == "/" && len(stack) > 0:
+			stack = stack[:len(stack)-1]
+			setStyle()
+		case strings.HasPrefix(tag, "/") && closeTag(&stack, tag[1:]):
+			setStyle()
+		case !strings.HasPrefix(tag, "/") && (hasName(stt.named, tag) || len(tag) > 1 && tag[0] == '#'):
+			stack = append(stack, tag)
+			setStyle()
+		default:
+			// not a tag
+			sb.WriteByte('{')
+			text = text[1:]
+			continue
+		}
+		text = text[j+1:]
+	}
+	stt.text = sb.String()
+	stt.markups = markups
+	stt.spans = spans
+	stt.named = nil
+	return stt
+}
+
+// hasName reports whether a style name is registered.
+func hasName(reg StyleRegistry, name string) bool {
+	_, ok := reg[name]
+	return ok
+}
+
+// closeTag removes the last opened tag with the given name, if any, and
+// reports whether it did.
+func closeTag(stack *[]string, name string) bool {
+	s := *stack
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == name {
+			*stack = append(s[:i], s[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// mergeStyle returns the style st with non-zero colors of inner, and with
+// additional attributes.
+func mergeStyle(st, inner gruid.Style) gruid.Style {
+	if inner.Fg != gruid.ColorDefault {
+		st.Fg = inner.Fg
+	}
+	if inner.Bg != gruid.ColorDefault {
+		st.Bg = inner.Bg
+	}
+	st.Attrs |= inner.Attrs
+	return st
+}
+
+// Spans records the positions of the cells of actionable spans drawn in a
+// grid. The zero value contains no spans.
+type Spans struct {
+	cells map[gruid.Point]string
+}
+
+// HitTest returns the identifier of the span drawn at the given absolute
+// position, if any.
+func (sp Spans) HitTest(p gruid.Point) (string, bool) {
+	id, ok := sp.cells[p]
+	return id, ok
+}
+
+// Bounds returns the smallest absolute range containing the cells of the span
+// with the given identifier, or the zero range if there is no such span.
+func (sp Spans) Bounds(id string) gruid.Range {
+	var rg gruid.Range
+	for p, sid := range sp.cells {
+		if sid != id {
+			continue
+		}
+		cell := gruid.Range{Min: p, Max: p.Shift(1, 1)}
+		if rg.Empty() {
+			rg = cell
+		} else {
+			rg = rg.Union(cell)
+		}
+	}
+	return rg
+}
+
+// add records a span cell.
+func (sp *Spans) add(p gruid.Point, id string) {
+	if sp.cells == nil {
+		sp.cells = map[gruid.Point]string{}
+	}
+	sp.cells[p] = id
+}
+
+// DrawSpans is the same as Draw, but it returns instead the positions of the
+// cells of actionable spans that were drawn.
+func (stt StyledText) DrawSpans(gd gruid.Grid) Spans {
+	stt = stt.resolve()
+	stt.Draw(gd)
+	var sp Spans
+	stt.addSpans(&sp, gd, gruid.Point{})
+	return sp
+}
+
+// addSpans adds to sp the actionable span cells of the resolved styled text
+// drawn in gd, with relative positions shifted by delta.
+func (stt StyledText) addSpans(sp *Spans, gd gruid.Grid, delta gruid.Point) {
+	if len(stt.spans) == 0 {
+		return
+	}
+	min := gd.Bounds().Min
+	stt.iter(func(p gruid.Point, c gruid.Cell, span string) {
+		p = p.Add(delta)
+		if span != "" && gd.Contains(p) {
+			sp.add(p.Add(min), span)
+		}
+	})
+}
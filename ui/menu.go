@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/anaseto/gruid"
 )
@@ -12,7 +13,23 @@ type MenuConfig struct {
 	Entries []MenuEntry // menu entries
 	Keys    MenuKeys    // optional custom key bindings
 	Box     *Box        // draw optional box around the menu
+	ID      string      // optional identifier for emitted events (see MenuInvoked)
 	Style   MenuStyle
+
+	// EntryID is an optional function returning a string identifying an
+	// entry. If provided, SetEntries will try to keep the active entry on
+	// the new entry with the same identifier, instead of moving it when
+	// its index changes.
+	EntryID func(MenuEntry) string
+
+	// MultiSelect enables multi-selection mode: enabled entries can be
+	// toggled with the Toggle keys or a mouse click, and are marked with
+	// a prefix. Invoke keys can then be used to confirm the selection.
+	MultiSelect bool
+
+	// LoadingInterval is the time between two frames of the spinner shown
+	// in loading state (default: 100ms).
+	LoadingInterval time.Duration
 }
 
 // MenuEntry represents an entry in the menu. By default they behave much like
@@ -28,6 +45,14 @@ type MenuEntry struct {
 	// Keys contains entry shortcuts, if any, and only for activable
 	// entries. Other menu key bindings take precedence over those.
 	Keys []gruid.Key
+
+	// Header marks the entry as a collapsible group header. Its children
+	// are the following entries, up to the next header. Invoking a header
+	// expands or collapses its group instead.
+	Header bool
+
+	// Collapsed is the initial state of a group header.
+	Collapsed bool
 }
 
 // MenuKeys contains key bindings configuration for the menu. One step movement
@@ -40,6 +65,8 @@ type MenuKeys struct {
 	PageDown []gruid.Key // go one page down (default: PageDown)
 	PageUp   []gruid.Key // go one page up (default: PageUp)
 	Invoke   []gruid.Key // invoke selection (default: Enter)
+	Toggle   []gruid.Key // toggle entry in multi-select mode (default: Space)
+	Group    []gruid.Key // expand or collapse active entry's group (default: Tab)
 	Quit     []gruid.Key // requist menu quit (default: Escape, q, Q)
 }
 
@@ -48,6 +75,20 @@ type MenuStyle struct {
 	Layout  gruid.Point // menu layout in (columns, lines); 0 means any
 	Active  gruid.Style // specific styling for active entry (no change if default)
 	PageNum gruid.Style // page num display style (for boxed menu)
+
+	// Multi-select mode options.
+	Selected         gruid.Style // specific styling for toggled entries (no change if default)
+	SelectedPrefix   string      // prefix for toggled entries (default: "[x] ")
+	UnselectedPrefix string      // prefix for other entries (default: "[ ] ")
+
+	// Group headers options.
+	ExpandedPrefix  string // prefix for expanded group headers (default: "▾ ")
+	CollapsedPrefix string // prefix for collapsed group headers (default: "▸ ")
+
+	// Loading state options.
+	Loading     gruid.Style // style of the loading placeholder line
+	LoadingText string      // loading placeholder text (default: "Loading…")
+	Spinner     string      // spinner frames, one per rune (default: "|/-\\")
 }
 
 // Menu is a widget that displays a list of entries to the user. It allows to
@@ -70,6 +111,17 @@ type Menu struct {
 	layout  gruid.Point // current menu layout
 	dirty   bool        // state changed in Update and Draw was still not called
 	drawn   gruid.Grid  // last grid slice that was drawn
+	entryID func(MenuEntry) string
+	multi   bool   // multi-select mode
+	sel     []bool // toggled entries in multi-select mode
+	folded  []bool // collapsed group headers
+	hidden  []bool // entries hidden in a collapsed group
+	visible int    // number of visible entries
+	loading bool   // entries are being recomputed
+	spin    int    // spinner frame
+	spinGen int    // spinner generation, for ignoring stale ticks
+	spinInt time.Duration
+	id      string // identifier for emitted events
 }
 
 // item represents a visible entry in the menu at a given position and with a
@@ -101,6 +153,19 @@ const (
 	// MenuQuit reports that the user requested to quit the menu, either by
 	// clicking outside the menu, or by using a key shortcut.
 	MenuQuit
+
+	// MenuToggle reports that the user toggled the active entry in
+	// multi-select mode, either by clicking on it, or by using a toggle
+	// key.
+	MenuToggle
+
+	// MenuGroup reports that the user expanded or collapsed a group, by
+	// invoking its header, or by using a group key on one of its entries.
+	MenuGroup
+
+	// MenuLoading reports that the user tried to interact with the menu,
+	// by using a key or clicking on it, while in loading state.
+	MenuLoading
 )
 
 // NewMenu returns a menu with a given configuration.
@@ -111,6 +176,30 @@ func NewMenu(cfg MenuConfig) *Menu {
 		box:     cfg.Box,
 		style:   cfg.Style,
 		keys:    cfg.Keys,
+		entryID: cfg.EntryID,
+		multi:   cfg.MultiSelect,
+		spinInt: cfg.LoadingInterval,
+		id:      cfg.ID,
+	}
+	if m.spinInt <= 0 {
+		m.spinInt = 100 * time.Millisecond
+	}
+	if m.style.LoadingText == "" {
+		m.style.LoadingText = "Loading…"
+	}
+	if m.style.Spinner == "" {
+		m.style.Spinner = `|/-\`
+	}
+	if m.multi {
+		if m.keys.Toggle == nil {
+			m.keys.Toggle = []gruid.Key{gruid.KeySpace}
+		}
+		if m.style.SelectedPrefix == "" {
+			m.style.SelectedPrefix = "[x] "
+		}
+		if m.style.UnselectedPrefix == "" {
+			m.style.UnselectedPrefix = "[ ] "
+		}
 	}
 	if m.keys.Invoke == nil {
 		m.keys.Invoke = []gruid.Key{gruid.KeyEnter}
@@ -136,6 +225,16 @@ func NewMenu(cfg MenuConfig) *Menu {
 	if m.keys.Quit == nil {
 		m.keys.Quit = []gruid.Key{gruid.KeyEscape, "q", "Q"}
 	}
+	if m.keys.Group == nil {
+		m.keys.Group = []gruid.Key{gruid.KeyTab}
+	}
+	if m.style.ExpandedPrefix == "" {
+		m.style.ExpandedPrefix = "▾ "
+	}
+	if m.style.CollapsedPrefix == "" {
+		m.style.CollapsedPrefix = "▸ "
+	}
+	m.initGroups(nil)
 	m.placeItems()
 	m.cursorAtFirstChoice()
 	m.dirty = true
@@ -163,16 +262,199 @@ func (m *Menu) Action() MenuAction {
 	return m.action
 }
 
-// SetEntries updates the list of menu entries.
+// Selected returns the indices of the toggled entries in multi-select mode, in
+// increasing order.
+func (m *Menu) Selected() []int {
+	var is []int
+	for i, b := range m.sel {
+		if b && i < len(m.entries) {
+			is = append(is, i)
+		}
+	}
+	return is
+}
+
+// SetSelected updates the toggled entries in multi-select mode. Invalid and
+// disabled entries are ignored.
+func (m *Menu) SetSelected(is []int) {
+	m.sel = m.sel[:0]
+	for _, i := range is {
+		if i < 0 || i >= len(m.entries) || m.entries[i].Disabled {
+			continue
+		}
+		m.setSelected(i, true)
+	}
+	m.dirty = true
+}
+
+func (m *Menu) setSelected(i int, b bool) {
+	for len(m.sel) <= i {
+		m.sel = append(m.sel, false)
+	}
+	m.sel[i] = b
+}
+
+func (m *Menu) selected(i int) bool {
+	return i < len(m.sel) && m.sel[i]
+}
+
+// SetEntries updates the list of menu entries. If an EntryID function was
+// provided in the configuration, the active entry will be the new entry with
+// the same identifier as the previously active one, if any, and toggled
+// entries in multi-select mode are preserved in the same way. Otherwise,
+// toggled entries are cleared. It ends the loading state, if any.
 func (m *Menu) SetEntries(entries []MenuEntry) {
+	m.loading = false
+	var id string
+	keep := m.entryID != nil && m.contains(m.active)
+	if keep {
+		id = m.entryID(m.entries[m.Active()])
+	}
+	var foldIDs map[string]bool
+	if m.entryID != nil {
+		foldIDs = map[string]bool{}
+		for i, e := range m.entries {
+			if e.Header {
+				foldIDs[m.entryID(e)] = m.collapsed(i)
+			}
+		}
+	}
+	var selIDs map[string]bool
+	if m.entryID != nil && len(m.sel) > 0 {
+		selIDs = map[string]bool{}
+		for _, i := range m.Selected() {
+			selIDs[m.entryID(m.entries[i])] = true
+		}
+	}
+	m.sel = m.sel[:0]
 	m.entries = entries
+	m.initGroups(foldIDs)
+	if selIDs != nil {
+		for i, e := range m.entries {
+			if selIDs[m.entryID(e)] && !e.Disabled {
+				m.setSelected(i, true)
+			}
+		}
+	}
 	m.placeItems()
+	m.dirty = true
+	if keep {
+		for i, e := range m.entries {
+			if !e.Disabled && !m.isHidden(i) && m.entryID(e) == id {
+				m.active = m.idxToPos(i)
+				return
+			}
+		}
+	}
 	if !m.contains(m.active) {
 		m.cursorAtLastChoice()
 	}
+}
+
+// SetEntry updates the i-th menu entry in place, without changing the active
+// entry, nor the selection in multi-select mode. It is intended for content
+// changes, such as an updated value in a settings menu (see Settings):
+// changes in the Disabled or Header fields require SetEntries instead.
+func (m *Menu) SetEntry(i int, e MenuEntry) {
+	if i < 0 || i >= len(m.entries) {
+		return
+	}
+	m.entries[i] = e
+	m.dirty = true
+}
+
+// initGroups initializes the collapsed state of group headers, either from
+// the given states by entry identifier, or from the entries themselves.
+func (m *Menu) initGroups(foldIDs map[string]bool) {
+	m.folded = m.folded[:0]
+	for _, e := range m.entries {
+		folded := e.Header && e.Collapsed
+		if e.Header && foldIDs != nil {
+			if b, ok := foldIDs[m.entryID(e)]; ok {
+				folded = b
+			}
+		}
+		m.folded = append(m.folded, folded)
+	}
+	m.updateHidden()
+}
+
+// updateHidden computes the entries hidden in collapsed groups.
+func (m *Menu) updateHidden() {
+	m.hidden = m.hidden[:0]
+	m.visible = 0
+	header := -1
+	for i, e := range m.entries {
+		if e.Header {
+			header = i
+		}
+		hidden := !e.Header && header >= 0 && m.folded[header]
+		m.hidden = append(m.hidden, hidden)
+		if !hidden {
+			m.visible++
+		}
+	}
+}
+
+func (m *Menu) collapsed(i int) bool {
+	return i < len(m.folded) && m.folded[i]
+}
+
+func (m *Menu) isHidden(i int) bool {
+	return i < len(m.hidden) && m.hidden[i]
+}
+
+// header returns the index of the group header of the i-th entry, or -1 if
+// it does not belong to a group.
+func (m *Menu) header(i int) int {
+	for j := i; j >= 0; j-- {
+		if m.entries[j].Header {
+			return j
+		}
+	}
+	return -1
+}
+
+// Collapsed reports whether the group with the given header entry index is
+// collapsed.
+func (m *Menu) Collapsed(i int) bool {
+	return m.collapsed(i)
+}
+
+// SetCollapsed expands or collapses the group with the given header entry
+// index. If the active entry becomes hidden, the header becomes active.
+func (m *Menu) SetCollapsed(i int, collapsed bool) {
+	if i < 0 || i >= len(m.entries) || !m.entries[i].Header || m.collapsed(i) == collapsed {
+		return
+	}
+	active := -1
+	if m.contains(m.active) {
+		active = m.Active()
+	}
+	m.folded[i] = collapsed
+	m.updateHidden()
+	m.placeItems()
+	switch {
+	case active >= 0 && m.isHidden(active):
+		m.active = m.idxToPos(i)
+	case active >= 0:
+		m.active = m.idxToPos(active)
+	}
+	if !m.contains(m.active) {
+		m.cursorAtFirstChoice()
+	}
 	m.dirty = true
 }
 
+func (m *Menu) toggleGroup(i int) {
+	h := m.header(i)
+	if h < 0 || m.entries[h].Disabled {
+		return
+	}
+	m.SetCollapsed(h, !m.collapsed(h))
+	m.action = MenuGroup
+}
+
 // SetBox updates the menu surrounding box.
 func (m *Menu) SetBox(b *Box) {
 	m.box = b
@@ -188,7 +470,7 @@ func (m *Menu) contains(p gruid.Point) bool {
 // SetActive updates the active entry among entries. It may be used
 // to launch the menu at a specific default starting index.
 func (m *Menu) SetActive(i int) {
-	if i < 0 || i >= len(m.entries) {
+	if i < 0 || i >= len(m.entries) || m.isHidden(i) {
 		return
 	}
 	if !m.entries[i].Disabled {
@@ -240,6 +522,9 @@ func (m *Menu) nextPage(p gruid.Point) (gruid.Point, bool) {
 		return gruid.Point{}, false
 	}
 	for i := it.i + 1; i < len(m.entries); i++ {
+		if m.isHidden(i) {
+			continue
+		}
 		q := m.idxToPos(i)
 		switch p {
 		case gruid.Point{0, 1}:
@@ -253,6 +538,9 @@ func (m *Menu) nextPage(p gruid.Point) (gruid.Point, bool) {
 		}
 	}
 	for i := it.i - 1; i >= 0; i-- {
+		if m.isHidden(i) {
+			continue
+		}
 		q := m.idxToPos(i)
 		switch p {
 		case gruid.Point{0, -1}:
@@ -273,14 +561,100 @@ func (m *Menu) nextPage(p gruid.Point) (gruid.Point, bool) {
 // its grid.
 func (m *Menu) Update(msg gruid.Msg) gruid.Effect {
 	m.action = MenuPass
+	if m.loading {
+		return m.updateLoading(msg)
+	}
 	switch msg := msg.(type) {
 	case gruid.MsgKeyDown:
 		m.updateKeyDown(msg)
 	case gruid.MsgMouse:
 		m.updateMouse(msg)
 	}
-	if m.Action() != MenuPass {
+	switch m.Action() {
+	case MenuPass:
+		return nil
+	case MenuInvoke:
+		m.dirty = true
+		return emit(m.id, MenuInvoked{ID: m.id, Index: m.Active()})
+	case MenuQuit:
 		m.dirty = true
+		return emit(m.id, WidgetClosed{ID: m.id})
+	}
+	m.dirty = true
+	return nil
+}
+
+// SetLoading sets the loading state of the menu. It should be used when the
+// entries are being recomputed, for example by a command, until SetEntries is
+// called with the new entries. While loading, the menu displays a placeholder
+// line with a spinner, and user interactions, other than quitting, are
+// reported as MenuLoading.
+//
+// The returned command, if any, animates the spinner, and should be returned
+// by the model's Update. Resulting messages should be passed to the menu's
+// Update.
+func (m *Menu) SetLoading(loading bool) gruid.Cmd {
+	if m.loading == loading {
+		return nil
+	}
+	m.loading = loading
+	m.dirty = true
+	if !loading {
+		return nil
+	}
+	m.spin = 0
+	m.spinGen++
+	return m.spinnerTick()
+}
+
+// Loading reports whether the menu is in loading state.
+func (m *Menu) Loading() bool {
+	return m.loading
+}
+
+// msgSpinner is sent to animate the loading spinner of a menu.
+type msgSpinner struct {
+	m   *Menu
+	gen int
+}
+
+func (m *Menu) spinnerTick() gruid.Cmd {
+	d := m.spinInt
+	msg := msgSpinner{m: m, gen: m.spinGen}
+	return func() gruid.Msg {
+		t := time.NewTimer(d)
+		<-t.C
+		return msg
+	}
+}
+
+func (m *Menu) updateLoading(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case msgSpinner:
+		if msg.m != m || msg.gen != m.spinGen {
+			break
+		}
+		m.spin++
+		m.dirty = true
+		return m.spinnerTick()
+	case gruid.MsgKeyDown:
+		if msg.Key.In(m.keys.Quit) {
+			m.action = MenuQuit
+		} else {
+			m.action = MenuLoading
+		}
+	case gruid.MsgMouse:
+		if msg.Action != gruid.MouseMain {
+			break
+		}
+		if msg.P.In(m.loadingGrid().Bounds()) {
+			m.action = MenuLoading
+		} else {
+			m.action = MenuQuit
+		}
+	}
+	if m.action == MenuQuit {
+		return emit(m.id, WidgetClosed{ID: m.id})
 	}
 	return nil
 }
@@ -309,6 +683,9 @@ func (m *Menu) pageUp() {
 
 func (m *Menu) keyInvoke(key gruid.Key) {
 	for i, e := range m.entries {
+		if m.isHidden(i) {
+			continue
+		}
 		for _, k := range e.Keys {
 			if k == key {
 				m.active = m.idxToPos(i)
@@ -337,9 +714,17 @@ func (m *Menu) updateKeyDown(msg gruid.MsgKeyDown) {
 		m.pageUp()
 	case msg.Key.In(m.keys.Invoke) && m.contains(m.active):
 		it, ok := m.table[m.active]
-		if ok && !m.entries[it.i].Disabled {
+		switch {
+		case !ok || m.entries[it.i].Disabled:
+		case m.entries[it.i].Header:
+			m.toggleGroup(it.i)
+		default:
 			m.action = MenuInvoke
 		}
+	case msg.Key.In(m.keys.Group) && m.contains(m.active):
+		m.toggleGroup(m.table[m.active].i)
+	case m.multi && msg.Key.In(m.keys.Toggle) && m.contains(m.active):
+		m.toggle(m.table[m.active].i)
 	default:
 		m.keyInvoke(msg.Key)
 	}
@@ -350,7 +735,7 @@ func (m *Menu) updateMouse(msg gruid.MsgMouse) {
 	rg := grid.Bounds()
 	crg := rg // content range
 	if m.box != nil {
-		crg = crg.Shift(1, 1, -1, -1)
+		crg = m.box.inner(crg)
 	}
 	p := msg.P
 	switch msg.Action {
@@ -405,15 +790,75 @@ func (m *Menu) invokePoint(p gruid.Point) {
 	for q, it := range m.table {
 		if it.page == page && p.In(it.grid.Bounds()) {
 			m.active = q
-			if m.entries[it.i].Disabled {
+			switch {
+			case m.entries[it.i].Disabled:
 				m.action = MenuMove
-			} else {
+			case m.entries[it.i].Header:
+				m.toggleGroup(it.i)
+				return
+			case m.multi:
+				m.toggle(it.i)
+			default:
 				m.action = MenuInvoke
 			}
 		}
 	}
 }
 
+func (m *Menu) toggle(i int) {
+	if m.entries[i].Disabled || m.entries[i].Header {
+		return
+	}
+	m.setSelected(i, !m.selected(i))
+	m.action = MenuToggle
+}
+
+// prefixWidth returns the width of entry prefixes in multi-select mode.
+func (m *Menu) prefixWidth() int {
+	if !m.multi {
+		return 0
+	}
+	w := Text(m.style.SelectedPrefix).Size().X
+	if uw := Text(m.style.UnselectedPrefix).Size().X; uw > w {
+		w = uw
+	}
+	return w
+}
+
+// groupPrefixWidth returns the width of group header prefixes.
+func (m *Menu) groupPrefixWidth() int {
+	w := Text(m.style.ExpandedPrefix).Size().X
+	if cw := Text(m.style.CollapsedPrefix).Size().X; cw > w {
+		w = cw
+	}
+	return w
+}
+
+// entryPrefixWidth returns the width of the prefix drawn before an entry's
+// text: a group prefix for headers, and a selection prefix for other entries
+// in multi-select mode.
+func (m *Menu) entryPrefixWidth(e MenuEntry) int {
+	if e.Header {
+		return m.groupPrefixWidth()
+	}
+	return m.prefixWidth()
+}
+
+// entryPrefix returns the prefix drawn before the i-th entry's text.
+func (m *Menu) entryPrefix(i int) string {
+	switch {
+	case m.entries[i].Header && m.collapsed(i):
+		return m.style.CollapsedPrefix
+	case m.entries[i].Header:
+		return m.style.ExpandedPrefix
+	case m.multi && m.selected(i):
+		return m.style.SelectedPrefix
+	case m.multi:
+		return m.style.UnselectedPrefix
+	}
+	return ""
+}
+
 func (m *Menu) pageGrid() gruid.Grid {
 	if m.layout.Y > 0 && m.layout.X == 0 {
 		rg := gruid.Range{}
@@ -425,7 +870,7 @@ func (m *Menu) pageGrid() gruid.Grid {
 			rg = rg.Union(it.grid.Bounds())
 		}
 		if m.box != nil {
-			rg = rg.Shift(-1, -1, 1, 1)
+			rg = m.box.outer(rg)
 		}
 		return m.grid.Slice(rg)
 	}
@@ -440,22 +885,26 @@ func (m *Menu) pageGrid() gruid.Grid {
 		}
 		h++
 	}
-	if m.box != nil {
-		h += 2 // borders height
-	}
+	h += m.box.size().Y // borders height
+	max := m.grid.Size()
+	return m.grid.Slice(gruid.NewRange(0, 0, max.X, h))
+}
+
+// loadingGrid returns the grid slice where the loading placeholder is drawn.
+func (m *Menu) loadingGrid() gruid.Grid {
+	h := 1
+	h += m.box.size().Y // borders height
 	max := m.grid.Size()
 	return m.grid.Slice(gruid.NewRange(0, 0, max.X, h))
 }
 
 func (m *Menu) drawGrid() gruid.Grid {
-	h := len(m.entries) // menu content height
+	h := m.visible // menu content height
 	layout := m.layout
 	if layout.Y > 0 {
 		h = layout.Y
 	}
-	if m.box != nil {
-		h += 2 // borders height
-	}
+	h += m.box.size().Y // borders height
 	max := m.grid.Size()
 	return m.grid.Slice(gruid.NewRange(0, 0, max.X, h))
 }
@@ -473,11 +922,11 @@ func (m *Menu) updateLayout() {
 	if m.layout.Y > m.grid.Size().Y {
 		m.layout.Y = m.grid.Size().Y
 	}
-	if m.layout.Y > len(m.entries) {
-		m.layout.Y = len(m.entries)
+	if m.layout.Y > m.visible {
+		m.layout.Y = m.visible
 	}
-	if m.layout.X > len(m.entries) {
-		m.layout.X = len(m.entries)
+	if m.layout.X > m.visible {
+		m.layout.X = m.visible
 	}
 }
 
@@ -485,18 +934,18 @@ func (m *Menu) getLayout(w, h int) (ml mlayout, nw, columns int) {
 	lines := m.layout.Y
 	nw = w
 	if lines <= 0 {
-		lines = len(m.entries)
+		lines = m.visible
 	}
 	columns = m.layout.X
 	if columns <= 0 {
-		if lines == len(m.entries) {
+		if lines == m.visible {
 			columns = 1
 		} else {
-			columns = len(m.entries)
+			columns = m.visible
 		}
 	}
-	if lines*columns > len(m.entries) {
-		columns = len(m.entries) / lines
+	if lines > 0 && lines*columns > m.visible {
+		columns = m.visible / lines
 	}
 	if columns > 1 && lines > 1 {
 		ml = table
@@ -525,7 +974,7 @@ func (m *Menu) placeItems() {
 	grid := m.drawGrid()
 	rg := grid.Bounds()
 	if m.box != nil {
-		grid = grid.Slice(rg.Shift(1, 1, -1, -1))
+		grid = grid.Slice(m.box.inner(rg))
 	}
 	m.size = grid.Size()
 	w, h := m.size.X, m.size.Y
@@ -548,43 +997,67 @@ func (m *Menu) placeItems() {
 	m.updatePages()
 }
 
+// hiddenPoint records the position of a hidden entry as the position of its
+// group header, and reports whether the entry was hidden.
+func (m *Menu) hiddenPoint(i int) bool {
+	if !m.isHidden(i) {
+		return false
+	}
+	m.points = append(m.points, m.points[m.header(i)])
+	return true
+}
+
 func (m *Menu) columnArrangement(grid gruid.Grid, w, h int) {
+	k := 0 // visible entry index
 	for i := range m.entries {
-		p := gruid.Point{0, i}
+		if m.hiddenPoint(i) {
+			continue
+		}
+		p := gruid.Point{0, k}
 		m.table[p] = item{
-			grid: grid.Slice(gruid.NewRange(0, i%h, w, (i%h)+1)),
+			grid: grid.Slice(gruid.NewRange(0, k%h, w, (k%h)+1)),
 			i:    i,
-			page: gruid.Point{0, i / h},
+			page: gruid.Point{0, k / h},
 		}
 		m.points = append(m.points, p)
+		k++
 	}
 }
 
 func (m *Menu) lineArrangement(grid gruid.Grid, w int) {
 	var to, hpage int
+	k := 0 // visible entry index
 	for i, e := range m.entries {
+		if m.hiddenPoint(i) {
+			continue
+		}
 		from := to
-		tw := e.Text.Size().X
+		tw := e.Text.Size().X + m.entryPrefixWidth(e)
 		to += tw
 		if from > 0 && to > w {
 			from = 0
 			to = tw
 			hpage++
 		}
-		p := gruid.Point{i, 0}
+		p := gruid.Point{k, 0}
 		m.table[p] = item{
 			grid: grid.Slice(gruid.NewRange(from, 0, to, 1)),
 			i:    i,
 			page: gruid.Point{hpage, 0},
 		}
 		m.points = append(m.points, p)
+		k++
 	}
 }
 
 func (m *Menu) tableArrangement(grid gruid.Grid, w, h, columns int) {
+	k := 0 // visible entry index
 	for i := range m.entries {
-		page := i / (columns * h)
-		pageidx := i % (columns * h)
+		if m.hiddenPoint(i) {
+			continue
+		}
+		page := k / (columns * h)
+		pageidx := k % (columns * h)
 		ln := pageidx % h
 		col := pageidx / h
 		p := gruid.Point{col, ln + page*h}
@@ -594,10 +1067,12 @@ func (m *Menu) tableArrangement(grid gruid.Grid, w, h, columns int) {
 			page: gruid.Point{0, page},
 		}
 		m.points = append(m.points, p)
+		k++
 	}
 }
 
 func (m *Menu) updatePages() {
+	m.pages = gruid.Point{}
 	for _, p := range m.points {
 		pg := m.table[p].page
 		if pg.X > m.pages.X {
@@ -612,7 +1087,7 @@ func (m *Menu) updatePages() {
 func (m *Menu) cursorAtFirstChoice() {
 	j := 0
 	for i, c := range m.entries {
-		if !c.Disabled {
+		if !c.Disabled && !m.isHidden(i) {
 			j = i
 			break
 		}
@@ -623,7 +1098,7 @@ func (m *Menu) cursorAtFirstChoice() {
 func (m *Menu) cursorAtLastChoice() {
 	j := len(m.entries) - 1
 	for i, c := range m.entries {
-		if !c.Disabled {
+		if !c.Disabled && !m.isHidden(i) {
 			j = i
 		}
 	}
@@ -635,6 +1110,9 @@ func (m *Menu) Draw() gruid.Grid {
 	if !m.dirty {
 		return m.drawn
 	}
+	if m.loading {
+		return m.drawLoading()
+	}
 	grid := m.pageGrid()
 	if m.box != nil {
 		pg := m.table[m.active].page
@@ -662,7 +1140,22 @@ func (m *Menu) Draw() gruid.Grid {
 		i := it.i
 		c := m.entries[i]
 		st := c.Text.Style()
+		tgd := it.grid
+		if pw := m.entryPrefixWidth(c); pw > 0 {
+			tgd = it.grid.Slice(it.grid.Range().Shift(pw, 0, 0, 0))
+		}
 		if !c.Disabled {
+			if m.selected(i) {
+				if m.style.Selected.Fg != gruid.ColorDefault {
+					st.Fg = m.style.Selected.Fg
+				}
+				if m.style.Selected.Bg != gruid.ColorDefault {
+					st.Bg = m.style.Selected.Bg
+				}
+				if m.style.Selected.Attrs != gruid.AttrsDefault {
+					st.Attrs = m.style.Selected.Attrs
+				}
+			}
 			if p == m.active {
 				if m.style.Active.Fg != gruid.ColorDefault {
 					st.Fg = m.style.Active.Fg
@@ -676,14 +1169,34 @@ func (m *Menu) Draw() gruid.Grid {
 			}
 			cell := gruid.Cell{Rune: ' ', Style: st}
 			it.grid.Fill(cell)
-			c.Text.WithStyle(st).Draw(it.grid)
+			NewStyledText(m.entryPrefix(i), st).Draw(it.grid)
+			c.Text.WithStyle(st).Draw(tgd)
 		} else {
 			cell := gruid.Cell{Rune: ' ', Style: st}
 			it.grid.Fill(cell)
-			c.Text.Draw(it.grid)
+			if c.Header {
+				NewStyledText(m.entryPrefix(i), st).Draw(it.grid)
+			}
+			c.Text.Draw(tgd)
 		}
 	}
 	m.dirty = false
 	m.drawn = grid
 	return m.drawn
 }
+
+func (m *Menu) drawLoading() gruid.Grid {
+	grid := m.loadingGrid()
+	lgd := grid
+	if m.box != nil {
+		m.box.Draw(grid)
+		lgd = grid.Slice(m.box.inner(grid.Range()))
+	}
+	spinner := []rune(m.style.Spinner)
+	text := fmt.Sprintf("%c %s", spinner[m.spin%len(spinner)], m.style.LoadingText)
+	lgd.Fill(gruid.Cell{Rune: ' ', Style: m.style.Loading})
+	NewStyledText(text, m.style.Loading).Draw(lgd)
+	m.dirty = false
+	m.drawn = grid
+	return m.drawn
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/anaseto/gruid"
 )
@@ -138,3 +139,237 @@ func TestMenuLine(t *testing.T) {
 	}
 
 }
+
+func TestMenuEntryID(t *testing.T) {
+	gd := gruid.NewGrid(10, 10)
+	entries := []MenuEntry{
+		{Text: Text("one")},
+		{Text: Text("two")},
+		{Text: Text("three")},
+	}
+	menu := NewMenu(MenuConfig{
+		Grid:    gd,
+		Entries: entries,
+		EntryID: func(e MenuEntry) string { return e.Text.Text() },
+	})
+	menu.SetActive(1)
+	menu.SetEntries(append([]MenuEntry{{Text: Text("zero")}}, entries...))
+	if menu.Active() != 2 {
+		t.Errorf("bad active entry after insertion: %d", menu.Active())
+	}
+	menu.SetEntries(entries[2:])
+	if menu.Active() != 0 {
+		t.Errorf("bad active entry after removal: %d", menu.Active())
+	}
+}
+
+func TestMenuMultiSelect(t *testing.T) {
+	gd := gruid.NewGrid(12, 10)
+	entries := []MenuEntry{
+		{Text: Text("one")},
+		{Text: Text("two")},
+		{Text: Text("header"), Disabled: true},
+		{Text: Text("three")},
+	}
+	menu := NewMenu(MenuConfig{
+		Grid:        gd,
+		Entries:     entries,
+		MultiSelect: true,
+		EntryID:     func(e MenuEntry) string { return e.Text.Text() },
+	})
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeySpace})
+	if menu.Action() != MenuToggle {
+		t.Errorf("bad action: %v", menu.Action())
+	}
+	menu.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{5, 3}})
+	if menu.Action() != MenuToggle || menu.Active() != 3 {
+		t.Errorf("bad click toggle: %v (active %d)", menu.Action(), menu.Active())
+	}
+	menu.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{5, 2}})
+	if menu.Action() != MenuMove {
+		t.Errorf("disabled entry toggled: %v", menu.Action())
+	}
+	if sel := fmt.Sprint(menu.Selected()); sel != "[0 3]" {
+		t.Errorf("bad selection: %s", sel)
+	}
+	gd = menu.Draw()
+	if c := gd.At(gruid.Point{1, 0}); c.Rune != 'x' {
+		t.Errorf("bad selected prefix: %c", c.Rune)
+	}
+	if c := gd.At(gruid.Point{1, 1}); c.Rune != ' ' {
+		t.Errorf("bad unselected prefix: %c", c.Rune)
+	}
+	if c := gd.At(gruid.Point{4, 1}); c.Rune != 't' {
+		t.Errorf("bad entry text: %c", c.Rune)
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter})
+	if menu.Action() != MenuInvoke {
+		t.Errorf("bad invoke action: %v", menu.Action())
+	}
+	menu.SetEntries(append([]MenuEntry{{Text: Text("zero")}}, entries...))
+	if sel := fmt.Sprint(menu.Selected()); sel != "[1 4]" {
+		t.Errorf("bad selection after SetEntries: %s", sel)
+	}
+	menu.SetSelected([]int{2, 3, 10})
+	if sel := fmt.Sprint(menu.Selected()); sel != "[2]" {
+		t.Errorf("bad SetSelected: %s", sel)
+	}
+}
+
+func TestMenuGroups(t *testing.T) {
+	gd := gruid.NewGrid(12, 10)
+	entries := []MenuEntry{
+		{Text: Text("Weapons"), Header: true},
+		{Text: Text("sword")},
+		{Text: Text("axe")},
+		{Text: Text("Potions"), Header: true, Collapsed: true},
+		{Text: Text("heal")},
+		{Text: Text("speed"), Keys: []gruid.Key{"s"}},
+		{Text: Text("scroll")},
+	}
+	menu := NewMenu(MenuConfig{
+		Grid:    gd,
+		Entries: entries,
+		EntryID: func(e MenuEntry) string { return e.Text.Text() },
+	})
+	if h := menu.Draw().Size().Y; h != 4 {
+		t.Errorf("bad collapsed height: %d", h)
+	}
+	if c := gd.At(gruid.Point{0, 3}); c.Rune != '▸' {
+		t.Errorf("bad collapsed prefix: %c", c.Rune)
+	}
+	menu.Update(gruid.MsgKeyDown{Key: "s"})
+	if menu.Action() != MenuPass {
+		t.Errorf("hidden entry invoked: %v", menu.Action())
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowUp})
+	if menu.Active() != 3 {
+		t.Errorf("bad active: %d", menu.Active())
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter})
+	if menu.Action() != MenuGroup || menu.Collapsed(3) {
+		t.Errorf("group not expanded: %v", menu.Action())
+	}
+	if h := menu.Draw().Size().Y; h != 7 {
+		t.Errorf("bad expanded height: %d", h)
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if menu.Active() != 5 {
+		t.Errorf("bad active in expanded group: %d", menu.Active())
+	}
+	menu.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{3, 0}})
+	if menu.Action() != MenuGroup || !menu.Collapsed(0) || menu.Active() != 0 {
+		t.Errorf("group not collapsed by click: %v (active %d)", menu.Action(), menu.Active())
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if menu.Active() != 3 {
+		t.Errorf("bad active after collapse: %d", menu.Active())
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	if menu.Action() != MenuGroup || !menu.Collapsed(3) || menu.Active() != 3 {
+		t.Errorf("group not collapsed from child: %v (active %d)", menu.Action(), menu.Active())
+	}
+	menu.SetEntries(entries)
+	if !menu.Collapsed(0) || !menu.Collapsed(3) {
+		t.Errorf("collapsed state not preserved")
+	}
+	if h := menu.Draw().Size().Y; h != 2 {
+		t.Errorf("bad fully collapsed height: %d", h)
+	}
+}
+
+func TestMenuLoading(t *testing.T) {
+	gd := gruid.NewGrid(10, 10)
+	menu := NewMenu(MenuConfig{
+		Grid:            gd,
+		Entries:         []MenuEntry{{Text: Text("one")}, {Text: Text("two")}},
+		Box:             &Box{},
+		LoadingInterval: time.Millisecond,
+	})
+	cmd := menu.SetLoading(true)
+	if cmd == nil || !menu.Loading() {
+		t.Fatalf("not loading")
+	}
+	if menu.SetLoading(true) != nil {
+		t.Errorf("loading twice")
+	}
+	draw := menu.Draw()
+	if draw.Size().Y != 3 {
+		t.Errorf("bad loading size: %v", draw.Size())
+	}
+	if c := draw.At(gruid.Point{1, 1}); c.Rune != '|' {
+		t.Errorf("bad spinner: %c", c.Rune)
+	}
+	eff := menu.Update(cmd())
+	if eff == nil || menu.Action() != MenuPass {
+		t.Errorf("spinner not animated")
+	}
+	if c := menu.Draw().At(gruid.Point{1, 1}); c.Rune != '/' {
+		t.Errorf("bad spinner: %c", c.Rune)
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if menu.Action() != MenuLoading || menu.Active() != 0 {
+		t.Errorf("bad action while loading: %v", menu.Action())
+	}
+	menu.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 1}})
+	if menu.Action() != MenuLoading {
+		t.Errorf("bad click action while loading: %v", menu.Action())
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape})
+	if menu.Action() != MenuQuit {
+		t.Errorf("bad quit while loading: %v", menu.Action())
+	}
+	menu.SetEntries([]MenuEntry{{Text: Text("one")}, {Text: Text("two")}, {Text: Text("three")}})
+	if menu.Loading() {
+		t.Errorf("still loading")
+	}
+	if menu.Update(cmd()) != nil {
+		t.Errorf("stale spinner tick")
+	}
+	if draw := menu.Draw(); draw.Size().Y != 5 {
+		t.Errorf("bad size: %v", draw.Size())
+	}
+	menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if menu.Action() != MenuMove || menu.Active() != 1 {
+		t.Errorf("bad move after loading")
+	}
+}
+
+func TestMenuEvents(t *testing.T) {
+	gd := gruid.NewGrid(10, 5)
+	entries := []MenuEntry{
+		{Text: Text("one")},
+		{Text: Text("two")},
+	}
+	menu := NewMenu(MenuConfig{Grid: gd, Entries: entries})
+	if eff := menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter}); eff != nil {
+		t.Errorf("event without ID")
+	}
+	menu = NewMenu(MenuConfig{Grid: gd, Entries: entries, ID: "main"})
+	if eff := menu.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown}); eff != nil {
+		t.Errorf("event on move")
+	}
+	eff := menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter})
+	cmd, ok := eff.(gruid.Cmd)
+	if !ok {
+		t.Fatalf("no event command: %v", eff)
+	}
+	if msg := cmd(); msg != (MenuInvoked{ID: "main", Index: 1}) {
+		t.Errorf("bad event: %v", msg)
+	}
+	cmd, ok = menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape}).(gruid.Cmd)
+	if !ok || cmd() != (WidgetClosed{ID: "main"}) {
+		t.Errorf("bad quit event")
+	}
+	menu.SetLoading(true)
+	if eff := menu.Update(gruid.MsgKeyDown{Key: "a"}); eff != nil {
+		t.Errorf("event while loading: %v", eff)
+	}
+	cmd, ok = menu.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape}).(gruid.Cmd)
+	if !ok || cmd() != (WidgetClosed{ID: "main"}) || menu.Action() != MenuQuit {
+		t.Errorf("bad quit event while loading")
+	}
+}
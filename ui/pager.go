@@ -2,6 +2,8 @@ package ui
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/anaseto/gruid"
 )
@@ -12,12 +14,30 @@ type PagerConfig struct {
 	Lines []StyledText // content lines to be read
 	Box   *Box         // draw optional box around the  label
 	Keys  PagerKeys    // optional custom key bindings for the pager
+	ID    string       // optional identifier for emitted events (see WidgetClosed)
 	Style PagerStyle
+
+	// Links are optional cross-references from lines to other lines.
+	Links []PagerLink
+
+	// Anchors optionally associates identifiers with line indices, so
+	// that links can refer to their target by identifier.
+	Anchors map[string]int
+}
+
+// PagerLink represents a hyperlink-style cross-reference from a line to
+// another. The target is given either by a line index, or by an anchor
+// identifier.
+type PagerLink struct {
+	Line   int    // index of the line containing the link
+	Target int    // index of the target line
+	ID     string // target anchor identifier (used instead of Target if not empty)
 }
 
 // PagerStyle describes styling options for a Pager.
 type PagerStyle struct {
-	LineNum gruid.Style // line num display style (for boxed pager)
+	LineNum    gruid.Style // line num display style (for boxed pager)
+	ActiveLink gruid.Style // selected link line style (default: reverse of line style)
 }
 
 // PagerKeys contains key bindings configuration for the pager.
@@ -34,24 +54,40 @@ type PagerKeys struct {
 	Top          []gruid.Key // go to the top (default: Home, g)
 	Bottom       []gruid.Key // go to the bottom (default: End, G)
 	Quit         []gruid.Key // quit pager (default: Escape, q, Q)
+	NextLink     []gruid.Key // select next visible link (default: Tab, n)
+	PrevLink     []gruid.Key // select previous visible link (default: N)
+	Follow       []gruid.Key // follow selected link (default: o, ])
+	Back         []gruid.Key // go back to position before last followed link (default: [)
 }
 
 // Pager represents a pager widget for viewing a long list of lines.
 //
+// Lines may contain links to other lines. Visible links can be selected with
+// the NextLink and PrevLink keys, and followed with the Follow key or by
+// clicking on their line. Followed links are recorded in a back-stack, so
+// that the Back key returns to the previous position.
+//
 // Pager implements gruid.Model and can be used as main model of an
 // application.
 type Pager struct {
-	grid   gruid.Grid
-	box    *Box
-	lines  []StyledText
-	style  PagerStyle
-	index  int // current index
-	x      int // x position
-	action PagerAction
-	init   bool // Update received MsgInit
-	keys   PagerKeys
-	dirty  bool       // state changed in Update and Draw was still not called
-	drawn  gruid.Grid // last drawn grid slice
+	grid    gruid.Grid
+	box     *Box
+	lines   []StyledText
+	style   PagerStyle
+	index   int         // current index
+	x       int         // x position
+	links   []PagerLink // links sorted by line
+	anchors map[string]int
+	link    int        // selected link index in links (-1 if none)
+	back    []pagerPos // positions before followed links
+	spans   Spans      // actionable spans drawn in last Draw
+	span    string     // last clicked span
+	action  PagerAction
+	init    bool // Update received MsgInit
+	keys    PagerKeys
+	dirty   bool       // state changed in Update and Draw was still not called
+	drawn   gruid.Grid // last drawn grid slice
+	id      string     // identifier for emitted events
 }
 
 // PagerAction represents an user action with the pager.
@@ -68,8 +104,22 @@ const (
 	// PagerQuit reports that the user clicked outside the menu, or pressed
 	// Esc, Space or X.
 	PagerQuit
+
+	// PagerFollow reports that the user followed a link, or went back to
+	// the position before a followed link.
+	PagerFollow
+
+	// PagerSpan reports that the user clicked on an actionable span of a
+	// line, as created with named markup. Its identifier can be retrieved
+	// with Span.
+	PagerSpan
 )
 
+// pagerPos represents a pager position, used for the links back-stack.
+type pagerPos struct {
+	index, x, link int
+}
+
 // NewPager returns a new pager with given configuration options.
 func NewPager(cfg PagerConfig) *Pager {
 	pg := &Pager{
@@ -78,7 +128,9 @@ func NewPager(cfg PagerConfig) *Pager {
 		lines: cfg.Lines,
 		style: cfg.Style,
 		keys:  cfg.Keys,
+		id:    cfg.ID,
 	}
+	pg.setLinks(cfg.Links, cfg.Anchors)
 	if pg.keys.Down == nil {
 		pg.keys.Down = []gruid.Key{gruid.KeyArrowDown, "j"}
 	}
@@ -115,6 +167,18 @@ func NewPager(cfg PagerConfig) *Pager {
 	if pg.keys.Quit == nil {
 		pg.keys.Quit = []gruid.Key{gruid.KeyEscape, "q", "Q"}
 	}
+	if pg.keys.NextLink == nil {
+		pg.keys.NextLink = []gruid.Key{gruid.KeyTab, "n"}
+	}
+	if pg.keys.PrevLink == nil {
+		pg.keys.PrevLink = []gruid.Key{"N"}
+	}
+	if pg.keys.Follow == nil {
+		pg.keys.Follow = []gruid.Key{"o", "]"}
+	}
+	if pg.keys.Back == nil {
+		pg.keys.Back = []gruid.Key{"["}
+	}
 	pg.dirty = true
 	return pg
 }
@@ -144,10 +208,13 @@ func (pg *Pager) SetBox(b *Box) {
 	pg.dirty = true
 }
 
-// SetLines updates the pager text lines.
+// SetLines updates the pager text lines. It clears the selected link and the
+// links back-stack.
 func (pg *Pager) SetLines(lines []StyledText) {
 	nlines := pg.nlines()
 	pg.lines = lines
+	pg.link = -1
+	pg.back = pg.back[:0]
 	if pg.index+nlines-1 >= len(pg.lines) {
 		pg.index = len(pg.lines) - nlines
 		if pg.index <= 0 {
@@ -157,6 +224,21 @@ func (pg *Pager) SetLines(lines []StyledText) {
 	pg.dirty = true
 }
 
+// SetLinks updates the pager links and anchors. It clears the selected link
+// and the links back-stack.
+func (pg *Pager) SetLinks(links []PagerLink, anchors map[string]int) {
+	pg.setLinks(links, anchors)
+	pg.dirty = true
+}
+
+func (pg *Pager) setLinks(links []PagerLink, anchors map[string]int) {
+	pg.links = append(pg.links[:0], links...)
+	sort.SliceStable(pg.links, func(i, j int) bool { return pg.links[i].Line < pg.links[j].Line })
+	pg.anchors = anchors
+	pg.link = -1
+	pg.back = pg.back[:0]
+}
+
 func (pg *Pager) nlines() int {
 	h, bh := pg.height()
 	return h - bh
@@ -168,10 +250,7 @@ func (pg *Pager) nlines() int {
 func (pg *Pager) View() gruid.Range {
 	size := pg.grid.Size()
 	h := size.Y
-	bh := 0
-	if pg.box != nil {
-		bh = 2
-	}
+	bh := pg.box.size().Y
 	if h > bh+len(pg.lines) {
 		h = bh + len(pg.lines)
 	}
@@ -224,6 +303,84 @@ func (pg *Pager) lineStart() {
 	}
 }
 
+// linkVisible reports whether the i-th link is within the current view.
+func (pg *Pager) linkVisible(i int) bool {
+	l := pg.links[i].Line
+	return l >= pg.index && l < pg.index+pg.nlines() && l < len(pg.lines)
+}
+
+// selectLink selects the next or previous visible link, according to the sign
+// of delta.
+func (pg *Pager) selectLink(delta int) {
+	n := len(pg.links)
+	if n == 0 {
+		return
+	}
+	i := pg.link
+	if i < 0 || !pg.linkVisible(i) {
+		// start from the view boundaries
+		i = -1
+		if delta < 0 {
+			i = n
+		}
+	}
+	for i += delta; i >= 0 && i < n; i += delta {
+		if pg.linkVisible(i) {
+			pg.link = i
+			pg.action = PagerMove
+			return
+		}
+	}
+}
+
+// follow follows the i-th link, if its target exists.
+func (pg *Pager) follow(i int) {
+	if i < 0 || i >= len(pg.links) {
+		return
+	}
+	lnk := pg.links[i]
+	target := lnk.Target
+	if lnk.ID != "" {
+		var ok bool
+		target, ok = pg.anchors[lnk.ID]
+		if !ok {
+			return
+		}
+	}
+	if target < 0 || target >= len(pg.lines) {
+		return
+	}
+	pg.back = append(pg.back, pagerPos{index: pg.index, x: pg.x, link: i})
+	pg.SetCursor(gruid.Point{0, target})
+	pg.link = -1
+	pg.action = PagerFollow
+}
+
+// goBack returns to the position before the last followed link.
+func (pg *Pager) goBack() {
+	if len(pg.back) == 0 {
+		return
+	}
+	pos := pg.back[len(pg.back)-1]
+	pg.back = pg.back[:len(pg.back)-1]
+	pg.SetCursor(gruid.Point{pos.x, pos.index})
+	pg.link = pos.link
+	pg.action = PagerFollow
+}
+
+// Span returns the identifier of the last clicked actionable span.
+func (pg *Pager) Span() string {
+	return pg.span
+}
+
+// Link returns the currently selected link, if any.
+func (pg *Pager) Link() (PagerLink, bool) {
+	if pg.link < 0 {
+		return PagerLink{}, false
+	}
+	return pg.links[pg.link], true
+}
+
 func (pg *Pager) top() {
 	if pg.index != 0 {
 		pg.index = 0
@@ -257,6 +414,9 @@ func (pg *Pager) Update(msg gruid.Msg) gruid.Effect {
 	if pg.Action() != PagerPass {
 		pg.dirty = true
 	}
+	if pg.Action() == PagerQuit && pg.id != "" {
+		return gruid.Batch(eff, gruid.Emit(WidgetClosed{ID: pg.id}))
+	}
 	return eff
 }
 
@@ -294,6 +454,16 @@ func (pg *Pager) updateMsgKeyDown(msg gruid.MsgKeyDown) gruid.Effect {
 		if pg.init {
 			return gruid.End()
 		}
+	case key.In(pg.keys.NextLink):
+		pg.selectLink(1)
+	case key.In(pg.keys.PrevLink):
+		pg.selectLink(-1)
+	case key.In(pg.keys.Follow):
+		if pg.link >= 0 && pg.linkVisible(pg.link) {
+			pg.follow(pg.link)
+		}
+	case key.In(pg.keys.Back):
+		pg.goBack()
 	}
 	return nil
 }
@@ -310,6 +480,15 @@ func (pg *Pager) updateMsgMouse(msg gruid.MsgMouse) gruid.Effect {
 	}
 	switch msg.Action {
 	case gruid.MouseMain:
+		if i := pg.linkAt(msg.P); i >= 0 {
+			pg.follow(i)
+			break
+		}
+		if id, ok := pg.spans.HitTest(msg.P); ok {
+			pg.span = id
+			pg.action = PagerSpan
+			break
+		}
 		if msg.P.Sub(pg.grid.Bounds().Min).Y > nlines/2 {
 			pg.down(nlines - 1)
 		} else {
@@ -323,6 +502,57 @@ func (pg *Pager) updateMsgMouse(msg gruid.MsgMouse) gruid.Effect {
 	return nil
 }
 
+// linkAt returns the index of the first link in the line displayed at
+// absolute position p, or -1 if none.
+func (pg *Pager) linkAt(p gruid.Point) int {
+	y := p.Y - pg.grid.Bounds().Min.Y
+	min, _ := pg.box.margins()
+	y -= min.Y
+	if y < 0 || y >= pg.nlines() {
+		return -1
+	}
+	line := pg.index + y
+	i := sort.Search(len(pg.links), func(i int) bool { return pg.links[i].Line >= line })
+	if i < len(pg.links) && pg.links[i].Line == line {
+		return i
+	}
+	return -1
+}
+
+// Content returns a new grid with the whole content of the pager, and not
+// just the visible part, drawn in it, one line per content line. It can be
+// used, for example, to export a message history as an image.
+func (pg *Pager) Content() gruid.Grid {
+	w := 0
+	for _, stt := range pg.lines {
+		if sw := stt.Size().X; sw > w {
+			w = sw
+		}
+	}
+	gd := gruid.NewGrid(w, len(pg.lines))
+	rg := gd.Range()
+	for i, stt := range pg.lines {
+		line := gd.Slice(rg.Line(i))
+		line.Fill(gruid.Cell{Rune: ' ', Style: stt.Style()})
+		stt.Draw(line)
+	}
+	return gd
+}
+
+// Text returns the whole content of the pager as plain text, with markup
+// removed, and one content line per line. It can be used, for example, to
+// dump a message history to a file.
+func (pg *Pager) Text() string {
+	sb := strings.Builder{}
+	for i, stt := range pg.lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(stt.Plain())
+	}
+	return sb.String()
+}
+
 // Action returns the last action performed with the pager.
 func (pg *Pager) Action() PagerAction {
 	return pg.action
@@ -330,15 +560,25 @@ func (pg *Pager) Action() PagerAction {
 
 func (pg *Pager) height() (h int, bh int) {
 	h = pg.grid.Size().Y
-	if pg.box != nil {
-		bh = 2
-	}
+	bh = pg.box.size().Y
 	if h > bh+len(pg.lines) {
 		h = bh + len(pg.lines)
 	}
 	return h, bh
 }
 
+// drawActiveLink highlights the line of the selected link.
+func (pg *Pager) drawActiveLink(line gruid.Grid, st gruid.Style) {
+	ast := pg.style.ActiveLink
+	if ast == (gruid.Style{}) {
+		ast = st
+		ast.Fg, ast.Bg = st.Bg, st.Fg
+	}
+	line.Map(func(p gruid.Point, c gruid.Cell) gruid.Cell {
+		return c.WithStyle(ast)
+	})
+}
+
 // Draw implements gruid.Model.Draw for Pager. It returns the grid slice that
 // was drawn, or the whole grid if it is used as main model.
 func (pg *Pager) Draw() gruid.Grid {
@@ -368,12 +608,13 @@ func (pg *Pager) Draw() gruid.Grid {
 		pg.box.Draw(grid)
 		pg.box.Footer = foot
 		rg := grid.Range()
-		cgrid = grid.Slice(rg.Shift(1, 1, -1, -1))
+		cgrid = grid.Slice(pg.box.inner(rg))
 	}
 	rg := cgrid.Range()
+	pg.spans = Spans{}
 	for i := 0; i < h-bh; i++ {
 		line := cgrid.Slice(rg.Line(i))
-		stt := pg.lines[i+pg.index]
+		stt := pg.lines[i+pg.index].resolve()
 		line.Fill(gruid.Cell{Rune: ' ', Style: stt.Style()})
 		stt.Iter(func(p gruid.Point, c gruid.Cell) {
 			p = p.Shift(-pg.x, 0)
@@ -381,6 +622,10 @@ func (pg *Pager) Draw() gruid.Grid {
 				line.Set(p, c)
 			}
 		})
+		stt.addSpans(&pg.spans, line, gruid.Point{-pg.x, 0})
+		if pg.link >= 0 && pg.links[pg.link].Line == i+pg.index {
+			pg.drawActiveLink(line, stt.Style())
+		}
 	}
 	pg.dirty = false
 	pg.drawn = grid
@@ -73,3 +73,77 @@ func TestPagerSetCursor(t *testing.T) {
 		}
 	}
 }
+
+func TestPagerLinks(t *testing.T) {
+	gd := gruid.NewGrid(10, 4)
+	var lines []StyledText
+	for i := 0; i < 30; i++ {
+		lines = append(lines, Textf("%d", i))
+	}
+	pager := NewPager(PagerConfig{
+		Grid:    gd,
+		Lines:   lines,
+		Links:   []PagerLink{{Line: 2, ID: "end"}, {Line: 1, Target: 10}},
+		Anchors: map[string]int{"end": 25},
+		Style:   PagerStyle{ActiveLink: gruid.Style{Fg: 2}},
+	})
+	pager.Update(gruid.MsgKeyDown{Key: "n"})
+	if l, ok := pager.Link(); !ok || l.Line != 1 || pager.Action() != PagerMove {
+		t.Errorf("bad first link selection: %+v %v", l, ok)
+	}
+	pager.Draw()
+	if c := gd.At(gruid.Point{0, 1}); c.Style.Fg != 2 || c.Rune != '1' {
+		t.Errorf("bad active link drawing: %v", c)
+	}
+	pager.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	pager.Update(gruid.MsgKeyDown{Key: "o"})
+	if pager.Action() != PagerFollow || pager.View().Min.Y != 25 {
+		t.Errorf("bad anchor follow: %v %v", pager.Action(), pager.View())
+	}
+	if _, ok := pager.Link(); ok {
+		t.Errorf("link still selected after follow")
+	}
+	pager.Update(gruid.MsgKeyDown{Key: "["})
+	if l, ok := pager.Link(); pager.Action() != PagerFollow || pager.View().Min.Y != 0 || !ok || l.Line != 2 {
+		t.Errorf("bad back: %v %v %+v", pager.Action(), pager.View(), l)
+	}
+	pager.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{0, 1}})
+	if pager.Action() != PagerFollow || pager.View().Min.Y != 10 {
+		t.Errorf("bad click follow: %v %v", pager.Action(), pager.View())
+	}
+	pager.Update(gruid.MsgKeyDown{Key: "n"})
+	if pager.Action() != PagerPass {
+		t.Errorf("link selected outside view: %v", pager.Action())
+	}
+}
+
+func TestPagerExport(t *testing.T) {
+	gd := gruid.NewGrid(5, 2)
+	red := gruid.Style{Fg: 2}
+	lines := []StyledText{
+		Text("line one"),
+		Text("@rred@N line").WithMarkup('r', red),
+		Text("3"),
+		Text("mail: a@@b").WithMarkup('r', red),
+	}
+	pager := NewPager(PagerConfig{
+		Grid:  gd,
+		Lines: lines,
+	})
+	if text := pager.Text(); text != "line one\nred line\n3\nmail: a@b" {
+		t.Errorf("bad text:\n%s", text)
+	}
+	cgd := pager.Content()
+	if cgd.Size() != (gruid.Point{9, 4}) {
+		t.Errorf("bad content size: %v", cgd.Size())
+	}
+	if c := cgd.At(gruid.Point{0, 1}); c.Rune != 'r' || c.Style != red {
+		t.Errorf("bad styled cell: %v", c)
+	}
+	if c := cgd.At(gruid.Point{8, 2}); c.Rune != ' ' {
+		t.Errorf("bad padding cell: %v", c)
+	}
+	if c := cgd.At(gruid.Point{7, 0}); c.Rune != 'e' {
+		t.Errorf("content not complete: %v", c)
+	}
+}
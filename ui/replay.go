@@ -1,8 +1,11 @@
-// Package ui defines common UI utilities for gruid: menu/table widget,
-// pager, text input, label, text drawing facilities and replay functionality.
+// Package ui defines common UI utilities for gruid: menu widget, scrollable
+// list, table, pager, text input, text area, label, viewport, animations,
+// layout, text drawing facilities and replay functionality.
 package ui
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -19,6 +22,11 @@ type ReplayKeys struct {
 	FramePrev []gruid.Key // go to previous frame (default: arrow left, h)
 	Forward   []gruid.Key // go 1 minute forward (default: arrow down, j)
 	Backward  []gruid.Key // go 1 minute backward (default: arrow up, k)
+	Start     []gruid.Key // go to first frame (default: Home, g)
+	End       []gruid.Key // go to last frame (default: End, G)
+	LoopStart []gruid.Key // set loop start marker (default: a)
+	LoopEnd   []gruid.Key // set loop end marker (default: b)
+	LoopClear []gruid.Key // clear loop markers (default: c)
 	Help      []gruid.Key // key bindings help (default: ?)
 }
 
@@ -27,15 +35,39 @@ type ReplayConfig struct {
 	Grid         gruid.Grid          // grid to use for drawing
 	FrameDecoder *gruid.FrameDecoder // frame decoder
 	Keys         ReplayKeys          // optional custom key bindings
+
+	// SeekBar enables a seek bar on the last line of the grid, showing
+	// current frame and time over the totals, that can be clicked with
+	// the mouse to jump to a proportional position. The recorded frames
+	// are then drawn in the remaining lines. Note that it implies
+	// decoding all the frames at initialization, unless the decoder has
+	// a frame index (see gruid.FrameDecoder.LoadIndex).
+	SeekBar      bool
+	SeekBarStyle gruid.Style // seek bar style (optional)
+
+	// Inputs enables an overlay in the top-right corner of the grid,
+	// showing the inputs recorded during the last second before the
+	// current frame, if the session was recorded with
+	// gruid.AppConfig.RecordInputs. Mouse motion is not shown.
+	Inputs      bool
+	InputsStyle gruid.Style // inputs overlay style (optional)
 }
 
 // Replay represents an application's session with the given recorded frames.
 //
+// If the frame decoder has a frame index (see gruid.FrameDecoder.LoadIndex),
+// jumps to frames that have not been decoded yet start decoding from the
+// closest key frame, instead of decoding all the frames in between.
+//
 // Replay implements gruid.Model and can be used as main model of an
 // application.
 type Replay struct {
 	decoder *gruid.FrameDecoder
-	frames  []gruid.Frame
+	frames  []gruid.Frame // decoded frames, starting from frame number base
+	base    int           // number of the first decoded frame
+	nframes int           // total number of frames (seek bar)
+	start   time.Time     // time of the first frame (seek bar)
+	end     time.Time     // time of the last frame (seek bar)
 	grid    gruid.Grid
 	undo    [][]gruid.FrameCell
 	fidx    int // frame index
@@ -47,6 +79,14 @@ type Replay struct {
 	dirty   bool
 	help    bool
 	pager   *Pager
+	seekbar bool
+	bstyle  gruid.Style
+	inputs  bool
+	istyle  gruid.Style
+	view    gruid.Grid // grid with frames, seek bar and inputs overlay
+	jump    int        // frame to jump to
+	loopA   int        // loop start frame (-1 if unset)
+	loopB   int        // loop end frame (-1 if unset)
 }
 
 // NewReplay returns a new Replay with a given configuration.
@@ -58,6 +98,12 @@ func NewReplay(cfg ReplayConfig) *Replay {
 		speed:   1,
 		undo:    [][]gruid.FrameCell{},
 		keys:    cfg.Keys,
+		seekbar: cfg.SeekBar,
+		loopA:   -1,
+		loopB:   -1,
+		bstyle:  cfg.SeekBarStyle,
+		inputs:  cfg.Inputs,
+		istyle:  cfg.InputsStyle,
 	}
 	if rep.keys.Quit == nil {
 		rep.keys.Quit = []gruid.Key{gruid.KeyEscape, "Q", "q"}
@@ -83,11 +129,33 @@ func NewReplay(cfg ReplayConfig) *Replay {
 	if rep.keys.Backward == nil {
 		rep.keys.Backward = []gruid.Key{gruid.KeyArrowDown, "j"}
 	}
+	if rep.keys.Start == nil {
+		rep.keys.Start = []gruid.Key{gruid.KeyHome, "g"}
+	}
+	if rep.keys.End == nil {
+		rep.keys.End = []gruid.Key{gruid.KeyEnd, "G"}
+	}
+	if rep.keys.LoopStart == nil {
+		rep.keys.LoopStart = []gruid.Key{"a"}
+	}
+	if rep.keys.LoopEnd == nil {
+		rep.keys.LoopEnd = []gruid.Key{"b"}
+	}
+	if rep.keys.LoopClear == nil {
+		rep.keys.LoopClear = []gruid.Key{"c"}
+	}
 	if rep.keys.Help == nil {
 		rep.keys.Help = []gruid.Key{"?"}
 	}
 	rep.dirty = true
 	max := cfg.Grid.Size()
+	if rep.seekbar {
+		rep.view = cfg.Grid
+		rep.grid = gruid.NewGrid(max.X, max.Y-1)
+	} else if rep.inputs {
+		rep.view = cfg.Grid
+		rep.grid = gruid.NewGrid(max.X, max.Y)
+	}
 	rep.pager = NewPager(PagerConfig{
 		Grid: gruid.NewGrid(max.X, max.Y),
 		Box:  &Box{Title: Text("Help")},
@@ -117,6 +185,11 @@ func (rep *Replay) setPagerLines() {
 	fmtLine("Go to previous frame", rep.keys.FramePrev)
 	fmtLine("Go 1 minute forward", rep.keys.Forward)
 	fmtLine("Go 1 minute backward", rep.keys.Backward)
+	fmtLine("Go to start", rep.keys.Start)
+	fmtLine("Go to end", rep.keys.End)
+	fmtLine("Set loop start", rep.keys.LoopStart)
+	fmtLine("Set loop end", rep.keys.LoopEnd)
+	fmtLine("Clear loop", rep.keys.LoopClear)
 	rep.pager.SetLines(lines)
 }
 
@@ -131,12 +204,25 @@ const (
 	replaySpeedLess
 	replayForward
 	replayBackward
+	replayStart
+	replayEnd
+	replayJump
 )
 
 type msgTick int // frame number
 
+// decoded returns the number of the frame following the last decoded one.
+func (rep *Replay) decoded() int {
+	return rep.base + len(rep.frames)
+}
+
+// frame returns the decoded frame with the given number.
+func (rep *Replay) frame(n int) gruid.Frame {
+	return rep.frames[n-rep.base]
+}
+
 func (rep *Replay) decodeNext() {
-	if rep.fidx >= len(rep.frames)-1 {
+	if rep.fidx >= rep.decoded()-1 {
 		frame := gruid.Frame{}
 		err := rep.decoder.Decode(&frame)
 		if err == nil {
@@ -145,6 +231,81 @@ func (rep *Replay) decodeNext() {
 	}
 }
 
+func (rep *Replay) decodeAll() {
+	for {
+		frame := gruid.Frame{}
+		err := rep.decoder.Decode(&frame)
+		if err != nil {
+			break
+		}
+		rep.frames = append(rep.frames, frame)
+	}
+}
+
+// scan computes the total number of frames and the time span of the
+// recording, for the seek bar. If the decoder has an index, only the first
+// and last chunks of frames are decoded.
+func (rep *Replay) scan() {
+	rep.decodeNext()
+	if len(rep.frames) == 0 {
+		return
+	}
+	rep.start = rep.frames[0].Time
+	k, err := rep.decoder.SeekFrame(math.MaxInt)
+	if err != nil || k == rep.decoded() {
+		rep.decodeAll()
+		rep.nframes = len(rep.frames)
+		rep.end = rep.frames[len(rep.frames)-1].Time
+		return
+	}
+	rep.nframes = k
+	frame := gruid.Frame{}
+	for rep.decoder.Decode(&frame) == nil {
+		rep.nframes++
+		rep.end = frame.Time
+	}
+	// go back to the start, which works, as seeking worked before
+	rep.frames = rep.frames[:0]
+	rep.decoder.SeekFrame(0)
+}
+
+// seek moves the decoder to the closest key frame before or at frame n,
+// provided that the decoder has an index and frame n is not decoded yet, so
+// that the frames in between are not decoded. The key frame is then
+// displayed.
+func (rep *Replay) seek(n int) {
+	k, err := rep.decoder.SeekFrame(n)
+	if err != nil || k == rep.decoded() {
+		return
+	}
+	rep.resetTo(k)
+}
+
+// seekTo is similar to seek, but it moves to the closest key frame before or
+// at the given time.
+func (rep *Replay) seekTo(t time.Time) {
+	k, err := rep.decoder.SeekTo(t)
+	if err != nil || k == rep.decoded() {
+		return
+	}
+	rep.resetTo(k)
+}
+
+// resetTo resets the replay state after the decoder moved to key frame k,
+// and displays that frame, which contains all the grid cells.
+func (rep *Replay) resetTo(k int) {
+	rep.frames = rep.frames[:0]
+	rep.undo = rep.undo[:0]
+	rep.base = k
+	rep.fidx = k
+	rep.grid.Fill(gruid.Cell{Rune: ' '})
+	rep.decodeNext()
+	if rep.fidx < rep.decoded() {
+		rep.fidx++
+		rep.next()
+	}
+}
+
 // Update implements gruid.Model.Update for Replay. It considers mouse message
 // coordinates to be absolute in its grid. If a gruid.MsgInit is passed to
 // Update, the replay will behave as if it is the main model of an application,
@@ -157,6 +318,9 @@ func (rep *Replay) Update(msg gruid.Msg) gruid.Effect {
 	switch msg := msg.(type) {
 	case gruid.MsgInit:
 		rep.init = true
+		if rep.seekbar {
+			rep.scan()
+		}
 		rep.decodeNext()
 		return rep.tick()
 	case gruid.MsgKeyDown:
@@ -168,12 +332,17 @@ func (rep *Replay) Update(msg gruid.Msg) gruid.Effect {
 		rep.updateMsgMouse(msg)
 	case msgTick:
 		if rep.auto && rep.fidx == int(msg) {
-			rep.action = replayNext
+			if rep.looping() && rep.fidx >= rep.loopB {
+				rep.action = replayJump
+				rep.jump = rep.loopA
+			} else {
+				rep.action = replayNext
+			}
 		}
 	}
 	rep.handleAction()
 	rep.draw()
-	if !rep.auto || rep.fidx > len(rep.frames)-1 || rep.action == replayNone {
+	if !rep.auto || rep.fidx > rep.decoded()-1 && !rep.looping() || rep.action == replayNone {
 		return nil
 	}
 	return rep.tick()
@@ -211,6 +380,16 @@ func (rep *Replay) updateMsgKeyDown(msg gruid.MsgKeyDown) gruid.Effect {
 		rep.action = replayForward
 	case key.In(rep.keys.Backward):
 		rep.action = replayBackward
+	case key.In(rep.keys.Start):
+		rep.action = replayStart
+	case key.In(rep.keys.End):
+		rep.action = replayEnd
+	case key.In(rep.keys.LoopStart):
+		rep.SetLoop(rep.fidx, rep.loopB)
+	case key.In(rep.keys.LoopEnd):
+		rep.SetLoop(rep.loopA, rep.fidx)
+	case key.In(rep.keys.LoopClear):
+		rep.SetLoop(-1, -1)
 	case key.In(rep.keys.Help):
 		rep.dirty = true
 		rep.help = true
@@ -220,6 +399,17 @@ func (rep *Replay) updateMsgKeyDown(msg gruid.MsgKeyDown) gruid.Effect {
 }
 
 func (rep *Replay) updateMsgMouse(msg gruid.MsgMouse) {
+	if rep.seekbar && msg.P.Y == rep.grid.Size().Y && msg.Action == gruid.MouseMain {
+		// the bar is drawn on the left of the information text
+		width := rep.view.Size().X
+		w := width - rep.seekBarInfo(width).Size().X
+		if w <= 1 || msg.P.X < 0 || msg.P.X >= w {
+			return
+		}
+		rep.action = replayJump
+		rep.jump = msg.P.X * rep.nframes / (w - 1)
+		return
+	}
 	if !msg.P.In(rep.grid.Bounds()) {
 		return
 	}
@@ -237,16 +427,24 @@ func (rep *Replay) updateMsgMouse(msg gruid.MsgMouse) {
 
 // SetFrame sets the current frame number to be displayed.
 func (rep *Replay) SetFrame(n int) {
+	switch {
+	case n <= 0:
+		if rep.base > 0 {
+			rep.seek(0)
+		}
+	case n-1 < rep.base || n-1 >= rep.decoded():
+		rep.seek(n - 1)
+	}
 	for rep.fidx < n {
 		rep.decodeNext()
-		if rep.fidx >= len(rep.frames) {
+		if rep.fidx >= rep.decoded() {
 			break
 		}
 		rep.fidx++
 		rep.next()
 	}
 	for rep.fidx > n {
-		if rep.fidx <= 0 {
+		if rep.fidx <= rep.base {
 			break
 		}
 		rep.fidx--
@@ -255,44 +453,66 @@ func (rep *Replay) SetFrame(n int) {
 	rep.dirty = true
 }
 
+// SetLoop sets the start and end frame numbers of a region that is then
+// replayed repeatedly during automatic playback. A negative value unsets the
+// corresponding marker. The loop is active only when both markers are set and
+// start is before end.
+func (rep *Replay) SetLoop(start, end int) {
+	rep.loopA = start
+	rep.loopB = end
+	rep.dirty = true
+}
+
+func (rep *Replay) looping() bool {
+	return rep.loopA >= 0 && rep.loopB > rep.loopA
+}
+
 // Seek moves replay forward/backward by the given duration.
 func (rep *Replay) Seek(d time.Duration) {
 	rep.decodeNext()
-	if len(rep.frames) == 0 {
-		return
-	}
-	n := rep.fidx - 1
-	if n < 0 || n >= len(rep.frames) {
+	if rep.fidx <= rep.base || rep.fidx > rep.decoded() {
 		return
 	}
-	var t time.Time
-	t = rep.frames[n].Time.Add(d)
+	t := rep.frame(rep.fidx - 1).Time.Add(d)
 	if d > 0 {
-		for t.After(rep.frames[rep.fidx-1].Time) {
-			rep.decodeNext()
-			if rep.fidx >= len(rep.frames) {
-				break
-			}
-			rep.fidx++
-			rep.next()
+		if t.After(rep.frames[len(rep.frames)-1].Time) {
+			rep.seekTo(t)
 		}
+		rep.forward(t)
 	} else {
-		for t.Before(rep.frames[rep.fidx-1].Time) {
-			if rep.fidx <= 1 {
+		for t.Before(rep.frame(rep.fidx - 1).Time) {
+			if rep.fidx <= rep.base+1 {
 				break
 			}
 			rep.fidx--
 			rep.previous()
 		}
+		if rep.base > 0 && t.Before(rep.frame(rep.fidx-1).Time) {
+			rep.seekTo(t)
+			rep.forward(t)
+		}
 	}
 	rep.dirty = true
 }
 
+// forward displays the next frames until reaching a frame whose time is not
+// before t.
+func (rep *Replay) forward(t time.Time) {
+	for t.After(rep.frame(rep.fidx - 1).Time) {
+		rep.decodeNext()
+		if rep.fidx >= rep.decoded() {
+			break
+		}
+		rep.fidx++
+		rep.next()
+	}
+}
+
 func (rep *Replay) handleAction() {
 	switch rep.action {
 	case replayNext:
 		rep.decodeNext()
-		if rep.fidx >= len(rep.frames) {
+		if rep.fidx >= rep.decoded() {
 			rep.action = replayNone
 			break
 		}
@@ -302,6 +522,12 @@ func (rep *Replay) handleAction() {
 			rep.action = replayNone
 			break
 		}
+		if rep.base > 0 && rep.fidx-1 <= rep.base {
+			// previous frames are not decoded
+			rep.action = replayJump
+			rep.jump = rep.fidx - 1
+			break
+		}
 		rep.fidx--
 	case replayTogglePause:
 		rep.auto = !rep.auto
@@ -319,7 +545,7 @@ func (rep *Replay) handleAction() {
 }
 
 func (rep *Replay) next() {
-	frame := rep.frames[rep.fidx-1]
+	frame := rep.frame(rep.fidx - 1)
 	rep.undo = append(rep.undo, []gruid.FrameCell{})
 	j := len(rep.undo) - 1
 	max := rep.grid.Size()
@@ -353,6 +579,12 @@ func (rep *Replay) draw() {
 		rep.Seek(-time.Minute)
 	case replayForward:
 		rep.Seek(time.Minute)
+	case replayStart:
+		rep.SetFrame(0)
+	case replayEnd:
+		rep.SetFrame(math.MaxInt)
+	case replayJump:
+		rep.SetFrame(rep.jump)
 	}
 	if rep.action != replayNone {
 		rep.dirty = true
@@ -367,13 +599,168 @@ func (rep *Replay) Draw() gruid.Grid {
 	if rep.init && !rep.dirty {
 		return rep.grid.Slice(gruid.Range{})
 	}
+	if rep.seekbar || rep.inputs {
+		return rep.drawView()
+	}
 	return rep.grid
 }
 
+// drawView draws the replayed grid in the view, along with the seek bar and
+// the inputs overlay, if enabled.
+func (rep *Replay) drawView() gruid.Grid {
+	max := rep.grid.Size()
+	vmax := max
+	if rep.seekbar {
+		vmax = max.Shift(0, 1)
+	}
+	if rep.view.Size() != vmax {
+		rep.view = rep.view.Resize(vmax.X, vmax.Y)
+	}
+	rep.view.Copy(rep.grid)
+	if rep.inputs {
+		rep.drawInputs(rep.view.Slice(rep.view.Range().Line(0)))
+	}
+	if rep.seekbar {
+		rep.drawSeekBar(max)
+	}
+	return rep.view
+}
+
+// drawInputs draws the recent inputs, right-aligned, in the given line.
+func (rep *Replay) drawInputs(line gruid.Grid) {
+	s := rep.recentInputs()
+	if s == "" {
+		return
+	}
+	rs := []rune(" " + s + " ")
+	w := line.Size().X
+	if len(rs) > w {
+		// keep most recent inputs
+		rs = rs[len(rs)-w:]
+	}
+	st := NewStyledText(string(rs), rep.istyle)
+	st.Draw(line.Slice(gruid.NewRange(w-len(rs), 0, w, 1)))
+}
+
+// recentInputs returns a description of the inputs recorded in the frames
+// of the last second before the current frame.
+func (rep *Replay) recentInputs() string {
+	if rep.fidx <= rep.base || rep.fidx > rep.decoded() {
+		return ""
+	}
+	now := rep.frame(rep.fidx - 1).Time
+	var inputs []string
+	for i := rep.fidx - 1; i >= rep.base; i-- {
+		fr := rep.frame(i)
+		if now.Sub(fr.Time) >= time.Second {
+			break
+		}
+		for j := len(fr.Inputs) - 1; j >= 0; j-- {
+			if s := inputString(fr.Inputs[j]); s != "" {
+				inputs = append(inputs, s)
+			}
+		}
+	}
+	for i, j := 0, len(inputs)-1; i < j; i, j = i+1, j-1 {
+		inputs[i], inputs[j] = inputs[j], inputs[i]
+	}
+	return strings.Join(inputs, " ")
+}
+
+// inputString returns a short description of a recorded input, or an empty
+// string for mouse motion.
+func inputString(in gruid.FrameInput) string {
+	var s string
+	var mod gruid.ModMask
+	switch {
+	case in.Key != nil:
+		mod = in.Key.Mod
+		s = string(in.Key.Key)
+		if in.Key.Key == gruid.KeySpace {
+			s = "Space"
+		}
+	case in.Mouse != nil:
+		if in.Mouse.Action == gruid.MouseMove {
+			return ""
+		}
+		mod = in.Mouse.Mod
+		s = in.Mouse.Action.String() + in.Mouse.P.String()
+	default:
+		return ""
+	}
+	if mod != 0 {
+		s = mod.String() + "+" + s
+	}
+	return s
+}
+
+func (rep *Replay) drawSeekBar(max gruid.Point) {
+	bar := rep.view.Slice(rep.view.Range().Line(max.Y))
+	line := bar
+	line.Fill(gruid.Cell{Rune: ' ', Style: rep.bstyle})
+	n := rep.nframes
+	info := rep.seekBarInfo(max.X)
+	w := max.X - info.Size().X
+	if w > 0 {
+		filled := w
+		if n > 0 {
+			filled = w * rep.fidx / n
+		}
+		line.Slice(gruid.NewRange(0, 0, filled, 1)).Fill(gruid.Cell{Rune: '=', Style: rep.bstyle})
+		line.Slice(gruid.NewRange(filled, 0, w, 1)).Fill(gruid.Cell{Rune: '-', Style: rep.bstyle})
+		line = line.Slice(gruid.NewRange(w, 0, max.X, 1))
+	}
+	info.Draw(line)
+	if w > 0 && n > 0 {
+		// loop markers, with the same scaling as the filled part
+		col := func(frame int) int {
+			x := w * frame / n
+			if x >= w {
+				x = w - 1
+			}
+			return x
+		}
+		if rep.loopA >= 0 {
+			bar.Set(gruid.Point{col(rep.loopA), 0}, gruid.Cell{Rune: '[', Style: rep.bstyle})
+		}
+		if rep.loopB >= 0 {
+			bar.Set(gruid.Point{col(rep.loopB), 0}, gruid.Cell{Rune: ']', Style: rep.bstyle})
+		}
+	}
+}
+
+// seekBarInfo returns the seek bar text showing current frame and time over
+// the totals, for a seek bar of the given width.
+func (rep *Replay) seekBarInfo(width int) StyledText {
+	var elapsed time.Duration
+	total := rep.end.Sub(rep.start)
+	if rep.fidx > rep.base && rep.fidx <= rep.decoded() {
+		elapsed = rep.frame(rep.fidx - 1).Time.Sub(rep.start)
+	}
+	info := NewStyledText(fmt.Sprintf(" %s/%s %d/%d", fmtDuration(elapsed), fmtDuration(total),
+		rep.fidx, rep.nframes), rep.bstyle)
+	if info.Size().X > width {
+		// not enough space: show only frames
+		info = info.WithTextf(" %d/%d", rep.fidx, rep.nframes)
+	}
+	return info
+}
+
+func fmtDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
 func (rep *Replay) tick() gruid.Cmd {
 	var d time.Duration
-	if rep.fidx > 0 {
-		d = rep.frames[rep.fidx].Time.Sub(rep.frames[rep.fidx-1].Time)
+	if rep.fidx > rep.base && rep.fidx < rep.decoded() {
+		d = rep.frame(rep.fidx).Time.Sub(rep.frame(rep.fidx - 1).Time)
 	} else {
 		d = 0
 	}
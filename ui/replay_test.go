@@ -0,0 +1,303 @@
+package ui
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// newTestDecoder returns a frame decoder for n frames, each one drawing a
+// digit at the first position of a 10x4 grid.
+func newTestDecoder(t *testing.T, n int) *gruid.FrameDecoder {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	enc := gob.NewEncoder(gzw)
+	t0 := time.Unix(0, 0)
+	for i := 0; i < n; i++ {
+		fr := gruid.Frame{Time: t0.Add(time.Duration(i) * time.Second), Width: 10, Height: 4}
+		fr.Cells = []gruid.FrameCell{{Cell: gruid.Cell{Rune: rune('0' + i%10)}}}
+		if err := enc.Encode(fr); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	gzw.Close()
+	dec, err := gruid.NewFrameDecoder(buf)
+	if err != nil {
+		t.Fatalf("decoder: %v", err)
+	}
+	return dec
+}
+
+// newIndexedTestDecoder is similar to newTestDecoder, but the frames are
+// recorded in independent chunks of 10 frames, with an index.
+func newIndexedTestDecoder(t *testing.T, n int) *gruid.FrameDecoder {
+	type indexEntry struct {
+		Offset int64
+		Frame  int
+		Time   time.Time
+	}
+	buf := &bytes.Buffer{}
+	idxbuf := &bytes.Buffer{}
+	idx := gob.NewEncoder(idxbuf)
+	t0 := time.Unix(0, 0)
+	var gzw *gzip.Writer
+	var enc *gob.Encoder
+	for i := 0; i < n; i++ {
+		fr := gruid.Frame{Time: t0.Add(time.Duration(i) * time.Second), Width: 10, Height: 4}
+		fr.Cells = []gruid.FrameCell{{Cell: gruid.Cell{Rune: rune('0' + i%10)}}}
+		if i%10 == 0 {
+			if gzw != nil {
+				gzw.Close()
+			}
+			idx.Encode(indexEntry{Offset: int64(buf.Len()), Frame: i, Time: fr.Time})
+			gzw = gzip.NewWriter(buf)
+			enc = gob.NewEncoder(gzw)
+		}
+		if err := enc.Encode(fr); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	gzw.Close()
+	dec, err := gruid.NewFrameDecoder(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("decoder: %v", err)
+	}
+	if err := dec.LoadIndex(idxbuf); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	return dec
+}
+
+func TestReplayIndex(t *testing.T) {
+	rep := NewReplay(ReplayConfig{
+		Grid:         gruid.NewGrid(10, 5),
+		FrameDecoder: newIndexedTestDecoder(t, 95),
+		SeekBar:      true,
+	})
+	rep.Update(gruid.MsgInit{})
+	rep.Update(gruid.MsgKeyDown{Key: "p"})
+	if rep.nframes != 95 || rep.end.Sub(rep.start) != 94*time.Second {
+		t.Errorf("bad totals: %d frames, %v", rep.nframes, rep.end.Sub(rep.start))
+	}
+	if len(rep.frames) > 2 {
+		t.Errorf("too many decoded frames at start: %d", len(rep.frames))
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "G"})
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != '4' || rep.fidx != 95 {
+		t.Errorf("bad rune at end: %c (frame %d)", c.Rune, rep.fidx)
+	}
+	if rep.base != 90 {
+		t.Errorf("bad first decoded frame: %d", rep.base)
+	}
+	rep.SetFrame(43)
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != '2' || rep.base != 40 {
+		t.Errorf("bad rune after jump: %c (base %d)", c.Rune, rep.base)
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "h"})
+	rep.Update(gruid.MsgKeyDown{Key: "h"})
+	rep.Update(gruid.MsgKeyDown{Key: "h"})
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != '9' || rep.fidx != 40 {
+		t.Errorf("bad rune after previous: %c (frame %d)", c.Rune, rep.fidx)
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "k"})
+	if rep.fidx != 95 {
+		t.Errorf("bad frame after forward: %d", rep.fidx)
+	}
+	rep.Seek(-time.Minute)
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != '4' || rep.fidx != 35 {
+		t.Errorf("bad rune after backward: %c (frame %d)", c.Rune, rep.fidx)
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "g"})
+	if rep.fidx != 0 || rep.base != 0 {
+		t.Errorf("bad start: frame %d (base %d)", rep.fidx, rep.base)
+	}
+}
+
+func TestReplaySeekBar(t *testing.T) {
+	rep := NewReplay(ReplayConfig{
+		Grid:         gruid.NewGrid(10, 5),
+		FrameDecoder: newTestDecoder(t, 10),
+		SeekBar:      true,
+	})
+	rep.Update(gruid.MsgInit{})
+	rep.Update(gruid.MsgKeyDown{Key: "p"})
+	rep.Update(gruid.MsgKeyDown{Key: "G"})
+	gd := rep.Draw()
+	if gd.Size() != (gruid.Point{10, 5}) {
+		t.Errorf("bad grid size: %v", gd.Size())
+	}
+	if c := gd.At(gruid.Point{0, 0}); c.Rune != '9' {
+		t.Errorf("bad rune at end: %c", c.Rune)
+	}
+	if c := gd.At(gruid.Point{9, 4}); c.Rune != '0' {
+		t.Errorf("bad seek bar: %c", c.Rune)
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "g"})
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != 0 && c.Rune != ' ' {
+		t.Errorf("bad rune at start: %c", c.Rune)
+	}
+	// the bar uses the first 5 cells, before the " 0/10" text
+	rep.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{7, 4}})
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != 0 && c.Rune != ' ' {
+		t.Errorf("bad rune after click on text: %c", c.Rune)
+	}
+	rep.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 4}})
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != '4' {
+		t.Errorf("bad rune after click: %c", c.Rune)
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "g"})
+	rep.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{4, 4}})
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != '9' {
+		t.Errorf("bad rune after click at bar end: %c", c.Rune)
+	}
+}
+
+func TestReplayLoop(t *testing.T) {
+	rep := NewReplay(ReplayConfig{
+		Grid:         gruid.NewGrid(10, 4),
+		FrameDecoder: newTestDecoder(t, 10),
+	})
+	rep.Update(gruid.MsgInit{})
+	rep.SetFrame(2)
+	rep.Update(gruid.MsgKeyDown{Key: "a"})
+	rep.SetFrame(5)
+	rep.Update(gruid.MsgKeyDown{Key: "b"})
+	for i := 0; i < 6; i++ {
+		rep.Update(msgTick(rep.fidx))
+	}
+	if rep.fidx < 2 || rep.fidx > 5 {
+		t.Errorf("frame out of loop: %d", rep.fidx)
+	}
+	if c := rep.Draw().At(gruid.Point{0, 0}); c.Rune != rune('0'+rep.fidx-1) {
+		t.Errorf("bad rune: %c (frame %d)", c.Rune, rep.fidx)
+	}
+	rep.Update(gruid.MsgKeyDown{Key: "c"})
+	for i := 0; i < 10; i++ {
+		rep.Update(msgTick(rep.fidx))
+	}
+	if rep.fidx != 10 {
+		t.Errorf("bad final frame: %d", rep.fidx)
+	}
+}
+
+func TestReplaySeekBarLoop(t *testing.T) {
+	rep := NewReplay(ReplayConfig{
+		Grid:         gruid.NewGrid(30, 5),
+		FrameDecoder: newTestDecoder(t, 10),
+		SeekBar:      true,
+	})
+	rep.Update(gruid.MsgInit{})
+	rep.Update(gruid.MsgKeyDown{Key: "p"})
+	rep.SetLoop(3, 7)
+	var sb strings.Builder
+	gd := rep.Draw()
+	gd.Slice(gd.Range().Line(4)).Iter(func(p gruid.Point, c gruid.Cell) {
+		sb.WriteRune(c.Rune)
+	})
+	line := sb.String()
+	w := strings.IndexRune(line, ' ')
+	if i := strings.IndexRune(line, '['); i != w*3/10 {
+		t.Errorf("bad loop start marker at %d: %q", i, line)
+	}
+	if i := strings.IndexRune(line, ']'); i != w*7/10 {
+		t.Errorf("bad loop end marker at %d: %q", i, line)
+	}
+}
+
+func TestReplayInputs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	enc := gob.NewEncoder(gzw)
+	t0 := time.Unix(0, 0)
+	for i := 0; i < 4; i++ {
+		fr := gruid.Frame{Time: t0.Add(time.Duration(i) * time.Second), Width: 20, Height: 3}
+		fr.Cells = []gruid.FrameCell{{Cell: gruid.Cell{Rune: rune('0' + i)}, P: gruid.Point{0, 2}}}
+		if i == 2 {
+			fr.Inputs = []gruid.FrameInput{
+				{Key: &gruid.MsgKeyDown{Key: "a", Mod: gruid.ModCtrl}},
+				{Mouse: &gruid.MsgMouse{Action: gruid.MouseMove}},
+				{Key: &gruid.MsgKeyDown{Key: gruid.KeySpace}},
+			}
+		}
+		if err := enc.Encode(fr); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	gzw.Close()
+	dec, err := gruid.NewFrameDecoder(buf)
+	if err != nil {
+		t.Fatalf("decoder: %v", err)
+	}
+	rep := NewReplay(ReplayConfig{
+		Grid:         gruid.NewGrid(20, 3),
+		FrameDecoder: dec,
+		Inputs:       true,
+	})
+	rep.Update(gruid.MsgInit{})
+	rep.Update(gruid.MsgKeyDown{Key: "G"})
+	rep.SetFrame(3)
+	line := func() string {
+		var sb strings.Builder
+		gd := rep.Draw()
+		gd.Slice(gd.Range().Line(0)).Iter(func(p gruid.Point, c gruid.Cell) {
+			sb.WriteRune(c.Rune)
+		})
+		return sb.String()
+	}
+	if s := line(); !strings.HasSuffix(s, " Ctrl+a Space ") {
+		t.Errorf("bad inputs overlay: %q", s)
+	}
+	rep.SetFrame(4)
+	if s := line(); strings.TrimSpace(s) != "" {
+		t.Errorf("inputs shown after delay: %q", s)
+	}
+	if c := rep.Draw().At(gruid.Point{0, 2}); c.Rune != '3' {
+		t.Errorf("bad rune: %c", c.Rune)
+	}
+}
+
+func TestReplayCompare(t *testing.T) {
+	for _, byTime := range []bool{false, true} {
+		rc := NewReplayCompare(ReplayCompareConfig{
+			Grid:   gruid.NewGrid(21, 5),
+			Left:   newTestDecoder(t, 10),
+			Right:  newTestDecoder(t, 8),
+			ByTime: byTime,
+		})
+		rc.Update(gruid.MsgInit{})
+		if rc.Steps() != 11 {
+			t.Errorf("bad number of steps (by time: %v): %d", byTime, rc.Steps())
+		}
+		rc.Update(gruid.MsgKeyDown{Key: "p"})
+		rc.SetStep(5)
+		gd := rc.Draw()
+		if c := gd.At(gruid.Point{0, 0}); c.Rune != '4' {
+			t.Errorf("bad left rune: %c", c.Rune)
+		}
+		if c := gd.At(gruid.Point{11, 0}); c.Rune != '4' {
+			t.Errorf("bad right rune: %c", c.Rune)
+		}
+		if rc.Diffs() != 0 {
+			t.Errorf("unexpected differences: %d", rc.Diffs())
+		}
+		rc.Update(gruid.MsgKeyDown{Key: "d"})
+		if rc.Step() != 9 || rc.Diffs() != 1 {
+			t.Errorf("bad next diff: step %d, %d diffs", rc.Step(), rc.Diffs())
+		}
+		gd = rc.Draw()
+		if c := gd.At(gruid.Point{0, 0}); c.Rune != '8' {
+			t.Errorf("bad left rune: %c", c.Rune)
+		}
+		if c := gd.At(gruid.Point{11, 0}); c.Rune != '7' {
+			t.Errorf("bad right rune: %c", c.Rune)
+		}
+		if c := gd.At(gruid.Point{10, 0}); c.Rune != '│' {
+			t.Errorf("bad separator: %c", c.Rune)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anaseto/gruid"
+)
+
+// ReplayCompareConfig contains configuration for a side-by-side comparison
+// of two recordings.
+type ReplayCompareConfig struct {
+	Grid  gruid.Grid          // grid to use for drawing both recordings
+	Left  *gruid.FrameDecoder // first recording, drawn on the left
+	Right *gruid.FrameDecoder // second recording, drawn on the right
+
+	// Keys contains optional custom key bindings. Only Quit, Pause,
+	// SpeedMore, SpeedLess, FrameNext, FramePrev, Start and End are used.
+	Keys ReplayKeys
+
+	// NextDiff are the keys for going to the next step with differences
+	// (default: d).
+	NextDiff []gruid.Key
+
+	// ByTime synchronizes the recordings using frame timestamps, relative
+	// to the first frame of each recording, instead of frame indices.
+	ByTime bool
+
+	Diff   gruid.Style // differing cells style (default: reverse of cell style)
+	Status gruid.Style // status line style
+}
+
+// ReplayCompare plays two recordings in lockstep, side by side, and
+// highlights the cells that differ. It can be used to compare the behavior
+// of two builds of an application, when chasing rendering or determinism
+// regressions. The last line of the grid is used as a status line, showing
+// the current step and the number of differing cells.
+//
+// Recordings advance step by step: by default, a step corresponds to a frame
+// of each recording. With ByTime, a step corresponds to each distinct
+// relative frame timestamp in any of the recordings.
+//
+// ReplayCompare implements gruid.Model and can be used as main model of an
+// application.
+type ReplayCompare struct {
+	grid     gruid.Grid
+	left     *Replay
+	right    *Replay
+	steps    []replayStep
+	step     int // current step index
+	diffs    int // number of differing cells at current step
+	auto     bool
+	speed    time.Duration
+	init     bool // Update received MsgInit
+	keys     ReplayKeys
+	nextDiff []gruid.Key
+	dstyle   gruid.Style
+	sstyle   gruid.Style
+	dirty    bool
+}
+
+// replayStep contains the number of frames of each recording displayed at a
+// given step, and the step's time relative to the start.
+type replayStep struct {
+	left, right int
+	t           time.Duration
+}
+
+// msgCompareTick is sent for automatic playback of a comparison.
+type msgCompareTick int // step number
+
+// NewReplayCompare returns a new ReplayCompare with a given configuration.
+// Both recordings are fully decoded.
+func NewReplayCompare(cfg ReplayCompareConfig) *ReplayCompare {
+	rc := &ReplayCompare{
+		grid:     cfg.Grid,
+		auto:     true,
+		speed:    1,
+		keys:     cfg.Keys,
+		nextDiff: cfg.NextDiff,
+		dstyle:   cfg.Diff,
+		sstyle:   cfg.Status,
+		dirty:    true,
+	}
+	if rc.keys.Quit == nil {
+		rc.keys.Quit = []gruid.Key{gruid.KeyEscape, "Q", "q"}
+	}
+	if rc.keys.Pause == nil {
+		rc.keys.Pause = []gruid.Key{gruid.KeySpace, "P", "p"}
+	}
+	if rc.keys.SpeedMore == nil {
+		rc.keys.SpeedMore = []gruid.Key{"+", "}"}
+	}
+	if rc.keys.SpeedLess == nil {
+		rc.keys.SpeedLess = []gruid.Key{"-", "{"}
+	}
+	if rc.keys.FrameNext == nil {
+		rc.keys.FrameNext = []gruid.Key{gruid.KeyArrowRight, "l"}
+	}
+	if rc.keys.FramePrev == nil {
+		rc.keys.FramePrev = []gruid.Key{gruid.KeyArrowLeft, "h"}
+	}
+	if rc.keys.Start == nil {
+		rc.keys.Start = []gruid.Key{gruid.KeyHome, "g"}
+	}
+	if rc.keys.End == nil {
+		rc.keys.End = []gruid.Key{gruid.KeyEnd, "G"}
+	}
+	if rc.nextDiff == nil {
+		rc.nextDiff = []gruid.Key{"d"}
+	}
+	max := cfg.Grid.Size()
+	rc.left = NewReplay(ReplayConfig{Grid: gruid.NewGrid(max.X, max.Y), FrameDecoder: cfg.Left})
+	rc.right = NewReplay(ReplayConfig{Grid: gruid.NewGrid(max.X, max.Y), FrameDecoder: cfg.Right})
+	rc.left.decodeAll()
+	rc.right.decodeAll()
+	if cfg.ByTime {
+		rc.stepsByTime()
+	} else {
+		rc.stepsByIndex()
+	}
+	return rc
+}
+
+func (rc *ReplayCompare) stepsByIndex() {
+	lf, rf := rc.left.frames, rc.right.frames
+	n := len(lf)
+	if len(rf) > n {
+		n = len(rf)
+	}
+	rc.steps = make([]replayStep, n+1)
+	for i := 1; i <= n; i++ {
+		st := replayStep{left: i, right: i}
+		if st.left > len(lf) {
+			st.left = len(lf)
+			st.t = relTime(rf, i)
+		} else {
+			st.t = relTime(lf, i)
+		}
+		if st.right > len(rf) {
+			st.right = len(rf)
+		}
+		rc.steps[i] = st
+	}
+}
+
+func (rc *ReplayCompare) stepsByTime() {
+	lf, rf := rc.left.frames, rc.right.frames
+	ts := []time.Duration{}
+	for i := 1; i <= len(lf); i++ {
+		ts = append(ts, relTime(lf, i))
+	}
+	for i := 1; i <= len(rf); i++ {
+		ts = append(ts, relTime(rf, i))
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	rc.steps = []replayStep{{}}
+	var l, r int
+	for i, t := range ts {
+		if i > 0 && t == ts[i-1] {
+			continue
+		}
+		for l < len(lf) && relTime(lf, l+1) <= t {
+			l++
+		}
+		for r < len(rf) && relTime(rf, r+1) <= t {
+			r++
+		}
+		rc.steps = append(rc.steps, replayStep{left: l, right: r, t: t})
+	}
+}
+
+// relTime returns the time of the n-th frame (starting from 1), relative to
+// the first one.
+func relTime(frames []gruid.Frame, n int) time.Duration {
+	return frames[n-1].Time.Sub(frames[0].Time)
+}
+
+// Step returns the current step index, starting from 0, where no frames have
+// been displayed yet.
+func (rc *ReplayCompare) Step() int {
+	return rc.step
+}
+
+// Steps returns the total number of steps, including the initial one.
+func (rc *ReplayCompare) Steps() int {
+	return len(rc.steps)
+}
+
+// Diffs returns the number of differing cells at the current step.
+func (rc *ReplayCompare) Diffs() int {
+	return rc.diffs
+}
+
+// SetStep sets the current step index.
+func (rc *ReplayCompare) SetStep(n int) {
+	if n < 0 {
+		n = 0
+	}
+	if n >= len(rc.steps) {
+		n = len(rc.steps) - 1
+	}
+	rc.step = n
+	st := rc.steps[n]
+	rc.left.SetFrame(st.left)
+	rc.right.SetFrame(st.right)
+	rc.diffs = rc.countDiffs()
+	rc.dirty = true
+}
+
+// NextDiff goes to the next step with differing cells, if any. Otherwise,
+// it goes to the last step.
+func (rc *ReplayCompare) NextDiff() {
+	for rc.step < len(rc.steps)-1 {
+		rc.SetStep(rc.step + 1)
+		if rc.diffs > 0 {
+			return
+		}
+	}
+}
+
+func (rc *ReplayCompare) countDiffs() int {
+	lg, rg := rc.left.grid, rc.right.grid
+	rg1 := lg.Range().Union(rg.Range())
+	n := 0
+	rg1.Iter(func(p gruid.Point) {
+		if lg.At(p) != rg.At(p) {
+			n++
+		}
+	})
+	return n
+}
+
+// Update implements gruid.Model.Update for ReplayCompare. If a gruid.MsgInit
+// is passed to Update, the comparison will behave as if it is the main model
+// of an application, and send a gruid.End() command on a quit request.
+func (rc *ReplayCompare) Update(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgInit:
+		rc.init = true
+		rc.SetStep(0)
+	case gruid.MsgKeyDown:
+		key := msg.Key
+		switch {
+		case key.In(rc.keys.Quit):
+			if rc.init {
+				return gruid.End()
+			}
+			return nil
+		case key.In(rc.keys.Pause):
+			rc.auto = !rc.auto
+		case key.In(rc.keys.SpeedMore):
+			rc.speed *= 2
+			if rc.speed > 64 {
+				rc.speed = 64
+			}
+		case key.In(rc.keys.SpeedLess):
+			rc.speed /= 2
+			if rc.speed < 1 {
+				rc.speed = 1
+			}
+		case key.In(rc.keys.FrameNext):
+			rc.auto = false
+			rc.SetStep(rc.step + 1)
+		case key.In(rc.keys.FramePrev):
+			rc.auto = false
+			rc.SetStep(rc.step - 1)
+		case key.In(rc.keys.Start):
+			rc.SetStep(0)
+		case key.In(rc.keys.End):
+			rc.SetStep(len(rc.steps) - 1)
+		case key.In(rc.nextDiff):
+			rc.auto = false
+			rc.NextDiff()
+		default:
+			return nil
+		}
+		rc.dirty = true
+	case msgCompareTick:
+		if !rc.auto || rc.step != int(msg) {
+			return nil
+		}
+		rc.SetStep(rc.step + 1)
+	default:
+		return nil
+	}
+	if !rc.auto || rc.step >= len(rc.steps)-1 {
+		return nil
+	}
+	return rc.tick()
+}
+
+func (rc *ReplayCompare) tick() gruid.Cmd {
+	var d time.Duration
+	if rc.step > 0 && rc.step < len(rc.steps)-1 {
+		d = rc.steps[rc.step+1].t - rc.steps[rc.step].t
+	}
+	if d >= 2*time.Second {
+		d = 2 * time.Second
+	}
+	d = d / rc.speed
+	mininterval := time.Second / 240
+	if d <= mininterval {
+		d = mininterval
+	}
+	n := rc.step
+	return func() gruid.Msg {
+		t := time.NewTimer(d)
+		<-t.C
+		return msgCompareTick(n)
+	}
+}
+
+// Draw implements gruid.Model.Draw for ReplayCompare. The left recording is
+// drawn in the left half of the grid, and the right one in the right half,
+// after a separator column.
+func (rc *ReplayCompare) Draw() gruid.Grid {
+	if rc.init && !rc.dirty {
+		return rc.grid.Slice(gruid.Range{})
+	}
+	rc.dirty = false
+	rc.grid.Fill(gruid.Cell{Rune: ' '})
+	max := rc.grid.Size()
+	if max.Y < 2 {
+		return rc.grid
+	}
+	w := (max.X - 1) / 2
+	view := rc.grid.Range().Lines(0, max.Y-1)
+	lgd := rc.grid.Slice(view.Columns(0, w))
+	rgd := rc.grid.Slice(view.Columns(w+1, 2*w+1))
+	rc.grid.Slice(view.Column(w)).Fill(gruid.Cell{Rune: '│'})
+	lgd.Copy(rc.left.grid)
+	rgd.Copy(rc.right.grid)
+	lgd.Range().Iter(func(p gruid.Point) {
+		if rc.left.grid.At(p) == rc.right.grid.At(p) {
+			return
+		}
+		lgd.Set(p, rc.diffCell(lgd.At(p)))
+		rgd.Set(p, rc.diffCell(rgd.At(p)))
+	})
+	status := rc.grid.Slice(rc.grid.Range().Line(max.Y - 1))
+	status.Fill(gruid.Cell{Rune: ' ', Style: rc.sstyle})
+	text := fmt.Sprintf(" %d/%d identical", rc.step, len(rc.steps)-1)
+	if rc.diffs > 0 {
+		text = fmt.Sprintf(" %d/%d %d differing cells", rc.step, len(rc.steps)-1, rc.diffs)
+	}
+	NewStyledText(text, rc.sstyle).Draw(status)
+	return rc.grid
+}
+
+func (rc *ReplayCompare) diffCell(c gruid.Cell) gruid.Cell {
+	if rc.dstyle == (gruid.Style{}) {
+		c.Style.Fg, c.Style.Bg = c.Style.Bg, c.Style.Fg
+		return c
+	}
+	c.Style = rc.dstyle
+	return c
+}
@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// Pane represents a child model of a Router, drawn in a given range of the
+// router's grid.
+type Pane struct {
+	// Model is the child model. It should draw into its own grid with the
+	// size of the pane's range, as it receives range-relative mouse
+	// messages. Widgets such as Menu, Pager or TextInput implement
+	// gruid.Model and can be used directly.
+	Model gruid.Model
+
+	Range   gruid.Range // range of the pane, relative to the router's grid
+	NoFocus bool        // pane that never gets the focus, such as a status bar
+}
+
+// RouterKeys contains key bindings configuration for a router.
+type RouterKeys struct {
+	Next     []gruid.Key // focus next pane (default: Tab)
+	Previous []gruid.Key // focus previous pane (default: none)
+}
+
+// RouterConfig contains configuration options for creating a router.
+type RouterConfig struct {
+	Grid  gruid.Grid // grid where the panes are composited
+	Panes []Pane     // child panes, drawn in order
+	Keys  RouterKeys // optional custom key bindings
+}
+
+// Router composes several child models in the same screen. It forwards key
+// messages to the focused pane, mouse messages to the pane under the mouse,
+// with range-relative positions, and other messages to all the panes. A main
+// mouse click focuses the clicked pane. The Next keys cycle the focus through
+// the panes, and do the same in reverse order when used with the shift
+// modifier.
+//
+// Router implements gruid.Model and can be used as the main model of an
+// application, or as part of one. Panes are drawn in order, so later panes
+// are drawn over previous ones in case of overlap.
+type Router struct {
+	grid  gruid.Grid
+	panes []Pane
+	keys  RouterKeys
+	focus int
+}
+
+// NewRouter returns a new router with the given configuration. The focus is
+// initially on the first focusable pane.
+func NewRouter(cfg RouterConfig) *Router {
+	r := &Router{
+		grid:  cfg.Grid,
+		panes: cfg.Panes,
+		keys:  cfg.Keys,
+		focus: -1,
+	}
+	if r.keys.Next == nil {
+		r.keys.Next = []gruid.Key{gruid.KeyTab}
+	}
+	r.focusNext(1)
+	return r
+}
+
+// Focus returns the index of the focused pane, or -1 if no pane can be
+// focused.
+func (r *Router) Focus() int {
+	return r.focus
+}
+
+// SetFocus focuses the i-th pane, if it exists and is focusable.
+func (r *Router) SetFocus(i int) {
+	if i >= 0 && i < len(r.panes) && !r.panes[i].NoFocus {
+		r.focus = i
+	}
+}
+
+// Pane returns the i-th pane.
+func (r *Router) Pane(i int) Pane {
+	return r.panes[i]
+}
+
+// SetPane replaces the i-th pane, for example to change its range after a
+// screen resize. If the pane is focused and is not focusable anymore, the
+// focus moves to the next focusable pane.
+func (r *Router) SetPane(i int, p Pane) {
+	r.panes[i] = p
+	if r.focus == i && p.NoFocus {
+		r.focusNext(1)
+	}
+}
+
+// focusNext moves the focus to the next focusable pane in the given
+// direction.
+func (r *Router) focusNext(dir int) {
+	n := len(r.panes)
+	i := r.focus
+	for k := 0; k < n; k++ {
+		i = (i + dir + n) % n
+		if !r.panes[i].NoFocus {
+			r.focus = i
+			return
+		}
+	}
+	r.focus = -1
+}
+
+// Update implements gruid.Model.Update. It returns the effects returned by
+// the panes' Update methods.
+func (r *Router) Update(msg gruid.Msg) gruid.Effect {
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		switch {
+		case msg.Key.In(r.keys.Previous) || msg.Key.In(r.keys.Next) && msg.Mod&gruid.ModShift != 0:
+			r.focusNext(-1)
+			return nil
+		case msg.Key.In(r.keys.Next):
+			r.focusNext(1)
+			return nil
+		}
+		if r.focus < 0 {
+			return nil
+		}
+		return r.panes[r.focus].Model.Update(msg)
+	case gruid.MsgMouse:
+		// topmost pane under the mouse
+		for i := len(r.panes) - 1; i >= 0; i-- {
+			p := r.panes[i]
+			if !msg.P.In(p.Range) {
+				continue
+			}
+			if msg.Action == gruid.MouseMain {
+				r.SetFocus(i)
+			}
+			return p.Model.Update(p.Range.RelMsg(msg))
+		}
+		return nil
+	}
+	var effs []gruid.Effect
+	for _, p := range r.panes {
+		if eff := p.Model.Update(msg); eff != nil {
+			effs = append(effs, eff)
+		}
+	}
+	switch len(effs) {
+	case 0:
+		return nil
+	case 1:
+		return effs[0]
+	default:
+		return gruid.Batch(effs...)
+	}
+}
+
+// Draw implements gruid.Model.Draw. It draws the panes into the router's
+// grid.
+func (r *Router) Draw() gruid.Grid {
+	for _, p := range r.panes {
+		gd := p.Model.Draw()
+		r.grid.Slice(p.Range).Slice(gd.Bounds()).Copy(gd)
+	}
+	return r.grid
+}
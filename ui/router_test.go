@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+type paneModel struct {
+	gd   gruid.Grid
+	r    rune
+	msgs []gruid.Msg
+}
+
+func (m *paneModel) Update(msg gruid.Msg) gruid.Effect {
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+func (m *paneModel) Draw() gruid.Grid {
+	m.gd.Fill(gruid.Cell{Rune: m.r})
+	return m.gd
+}
+
+func TestRouter(t *testing.T) {
+	left := &paneModel{gd: gruid.NewGrid(5, 4), r: 'l'}
+	right := &paneModel{gd: gruid.NewGrid(5, 4), r: 'r'}
+	status := &paneModel{gd: gruid.NewGrid(10, 1), r: 's'}
+	r := NewRouter(RouterConfig{
+		Grid: gruid.NewGrid(10, 5),
+		Panes: []Pane{
+			{Model: left, Range: gruid.NewRange(0, 0, 5, 4)},
+			{Model: right, Range: gruid.NewRange(5, 0, 10, 4)},
+			{Model: status, Range: gruid.NewRange(0, 4, 10, 5), NoFocus: true},
+		},
+	})
+	if r.Focus() != 0 {
+		t.Errorf("bad initial focus: %d", r.Focus())
+	}
+	r.Update(gruid.MsgKeyDown{Key: "a"})
+	if len(left.msgs) != 1 || len(right.msgs) != 0 {
+		t.Errorf("key not sent to focused pane: %v %v", left.msgs, right.msgs)
+	}
+	r.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	if r.Focus() != 1 {
+		t.Errorf("bad focus after tab: %d", r.Focus())
+	}
+	r.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	if r.Focus() != 0 {
+		t.Errorf("non focusable pane focused: %d", r.Focus())
+	}
+	r.Update(gruid.MsgKeyDown{Key: gruid.KeyTab, Mod: gruid.ModShift})
+	if r.Focus() != 1 {
+		t.Errorf("bad focus after shift+tab: %d", r.Focus())
+	}
+	r.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 3}})
+	if r.Focus() != 0 {
+		t.Errorf("bad focus after click: %d", r.Focus())
+	}
+	msg, ok := left.msgs[len(left.msgs)-1].(gruid.MsgMouse)
+	if !ok || msg.P != (gruid.Point{2, 3}) {
+		t.Errorf("bad mouse message: %v", left.msgs)
+	}
+	r.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{7, 4}})
+	msg, ok = status.msgs[len(status.msgs)-1].(gruid.MsgMouse)
+	if !ok || msg.P != (gruid.Point{7, 0}) || r.Focus() != 0 {
+		t.Errorf("bad mouse message: %v (focus %d)", status.msgs, r.Focus())
+	}
+	r.Update(gruid.MsgInit{})
+	for i, m := range []*paneModel{left, right, status} {
+		if _, ok := m.msgs[len(m.msgs)-1].(gruid.MsgInit); !ok {
+			t.Errorf("init not sent to pane %d", i)
+		}
+	}
+	gd := r.Draw()
+	for p, want := range map[gruid.Point]rune{{0, 0}: 'l', {4, 3}: 'l', {5, 0}: 'r', {9, 3}: 'r', {3, 4}: 's'} {
+		if c := gd.At(p); c.Rune != want {
+			t.Errorf("bad rune at %v: %q, want %q", p, c.Rune, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anaseto/gruid"
+)
+
+// Setting represents a label and value pair, such as an option name and its
+// current value in a settings menu.
+type Setting struct {
+	Label    string
+	Value    string
+	Disabled bool        // not invokable setting (see MenuEntry.Disabled)
+	Keys     []gruid.Key // optional shortcuts (see MenuEntry.Keys)
+}
+
+// SettingsConfig contains configuration options for creating settings.
+type SettingsConfig struct {
+	Settings []Setting   // initial settings
+	Style    gruid.Style // entries style
+
+	// Width is the total width of entries in cells, typically the menu's
+	// width, without box. Values that do not fit are truncated with an
+	// ellipsis. If zero, values are not truncated.
+	Width int
+
+	// LabelWidth is the width of the labels column (default: the width of
+	// the longest label, but no more than half of Width). Labels that do
+	// not fit are truncated with an ellipsis.
+	LabelWidth int
+
+	// Separator is drawn between labels and values (default: ": ").
+	Separator string
+}
+
+// Settings builds menu entries for a settings-style menu, with labels and
+// values in aligned columns. The entries are typically used in a Menu, and
+// updated with Menu.SetEntry when a value changes:
+//
+//	st.SetValue(i, "on")
+//	menu.SetEntry(i, st.Entry(i))
+type Settings struct {
+	settings []Setting
+	style    gruid.Style
+	width    int
+	lwidth   int
+	sep      string
+}
+
+// NewSettings returns new settings with the given configuration.
+func NewSettings(cfg SettingsConfig) *Settings {
+	st := &Settings{
+		settings: cfg.Settings,
+		style:    cfg.Style,
+		width:    cfg.Width,
+		lwidth:   cfg.LabelWidth,
+		sep:      cfg.Separator,
+	}
+	if st.sep == "" {
+		st.sep = ": "
+	}
+	if st.lwidth <= 0 {
+		for _, s := range st.settings {
+			if w := utf8.RuneCountInString(s.Label); w > st.lwidth {
+				st.lwidth = w
+			}
+		}
+		if st.width > 0 && st.lwidth > st.width/2 {
+			st.lwidth = st.width / 2
+		}
+	}
+	return st
+}
+
+// Len returns the number of settings.
+func (st *Settings) Len() int {
+	return len(st.settings)
+}
+
+// Value returns the value of the i-th setting.
+func (st *Settings) Value(i int) string {
+	return st.settings[i].Value
+}
+
+// SetValue updates the value of the i-th setting. The corresponding menu
+// entry should then be updated with Menu.SetEntry.
+func (st *Settings) SetValue(i int, value string) {
+	st.settings[i].Value = value
+}
+
+// Entries returns menu entries for all the settings.
+func (st *Settings) Entries() []MenuEntry {
+	entries := make([]MenuEntry, len(st.settings))
+	for i := range st.settings {
+		entries[i] = st.Entry(i)
+	}
+	return entries
+}
+
+// Entry returns the menu entry for the i-th setting.
+func (st *Settings) Entry(i int) MenuEntry {
+	s := st.settings[i]
+	label := truncate(s.Label, st.lwidth)
+	var sb strings.Builder
+	sb.WriteString(label)
+	sb.WriteString(strings.Repeat(" ", st.lwidth-utf8.RuneCountInString(label)))
+	sb.WriteString(st.sep)
+	value := s.Value
+	if st.width > 0 {
+		w := st.width - st.lwidth - utf8.RuneCountInString(st.sep)
+		if w < 0 {
+			w = 0
+		}
+		value = truncate(value, w)
+	}
+	sb.WriteString(value)
+	return MenuEntry{
+		Text:     NewStyledText(sb.String(), st.style),
+		Disabled: s.Disabled,
+		Keys:     s.Keys,
+	}
+}
+
+// truncate returns s truncated to w runes, with an ellipsis as last rune if
+// it does not fit.
+func truncate(s string, w int) string {
+	if utf8.RuneCountInString(s) <= w {
+		return s
+	}
+	if w <= 0 {
+		return ""
+	}
+	rs := []rune(s)
+	return string(rs[:w-1]) + "…"
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestSettings(t *testing.T) {
+	st := NewSettings(SettingsConfig{
+		Settings: []Setting{
+			{Label: "Sound", Value: "on"},
+			{Label: "Keyboard layout", Value: "qwerty"},
+			{Label: "A very long label", Value: "a very long value"},
+		},
+		Width: 30,
+	})
+	entries := st.Entries()
+	want := []string{
+		"Sound          : on",
+		"Keyboard layout: qwerty",
+		"A very long la…: a very long …",
+	}
+	for i, e := range entries {
+		if e.Text.Text() != want[i] {
+			t.Errorf("bad entry %d: %q", i, e.Text.Text())
+		}
+	}
+	gd := gruid.NewGrid(30, 3)
+	m := NewMenu(MenuConfig{Grid: gd, Entries: entries})
+	m.SetActive(1)
+	st.SetValue(1, "dvorak")
+	m.SetEntry(1, st.Entry(1))
+	gd = m.Draw()
+	if text := gd.Slice(gd.Range().Line(1)).String(); text != "Keyboard layout: dvorak       \n" {
+		t.Errorf("bad drawn entry: %q", text)
+	}
+	if m.Active() != 1 || st.Value(1) != "dvorak" {
+		t.Errorf("bad state after update: %d %q", m.Active(), st.Value(1))
+	}
+}
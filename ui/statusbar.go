@@ -0,0 +1,214 @@
+package ui
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// StatusSegment represents a segment of a status bar.
+type StatusSegment struct {
+	Text StyledText // single-line segment content
+
+	// Priority determines which segments are truncated or dropped first
+	// when there is not enough space: segments with lower priority are
+	// processed first.
+	Priority int
+}
+
+// StatusBarConfig contains configuration options for creating a status bar.
+type StatusBarConfig struct {
+	Grid      gruid.Grid      // grid slice where the status bar is drawn (first line)
+	Segments  []StatusSegment // initial segments
+	Separator StyledText      // optional separator between segments, such as " > "
+	Style     gruid.Style     // style for the status bar background
+}
+
+// StatusBar represents a single-line widget made of styled segments, such as
+// a breadcrumb or a status line. When space is insufficient, lower priority
+// segments are truncated with an ellipsis, or dropped if too little space
+// remains for them.
+type StatusBar struct {
+	grid     gruid.Grid
+	segments []StatusSegment
+	sep      StyledText
+	style    gruid.Style
+	ranges   []gruid.Range // drawn segment ranges (empty if dropped)
+	action   StatusBarAction
+	clicked  int
+	dirty    bool
+}
+
+// StatusBarAction represents an user action with the status bar.
+type StatusBarAction int
+
+// These constants represent the available actions in a status bar.
+const (
+	// StatusBarPass reports that the status bar state did not change.
+	StatusBarPass StatusBarAction = iota
+
+	// StatusBarClick reports that the user clicked on a segment. The
+	// segment can be retrieved with Clicked.
+	StatusBarClick
+)
+
+// NewStatusBar returns a new status bar with the given configuration.
+func NewStatusBar(cfg StatusBarConfig) *StatusBar {
+	sb := &StatusBar{
+		grid:     cfg.Grid,
+		segments: cfg.Segments,
+		sep:      cfg.Separator,
+		style:    cfg.Style,
+		clicked:  -1,
+		dirty:    true,
+	}
+	return sb
+}
+
+// SetSegments updates all the status bar segments.
+func (sb *StatusBar) SetSegments(segments []StatusSegment) {
+	sb.segments = segments
+	sb.dirty = true
+}
+
+// Segments returns the current segments.
+func (sb *StatusBar) Segments() []StatusSegment {
+	return sb.segments
+}
+
+// SetSegment updates the i-th segment, if it exists.
+func (sb *StatusBar) SetSegment(i int, seg StatusSegment) {
+	if i < 0 || i >= len(sb.segments) {
+		return
+	}
+	sb.segments[i] = seg
+	sb.dirty = true
+}
+
+// SetText updates the text of the i-th segment, if it exists, keeping its
+// priority.
+func (sb *StatusBar) SetText(i int, stt StyledText) {
+	if i < 0 || i >= len(sb.segments) {
+		return
+	}
+	sb.segments[i].Text = stt
+	sb.dirty = true
+}
+
+// Action returns the last action performed with the status bar.
+func (sb *StatusBar) Action() StatusBarAction {
+	return sb.action
+}
+
+// Clicked returns the index of the last clicked segment, or -1 if none.
+func (sb *StatusBar) Clicked() int {
+	return sb.clicked
+}
+
+// Update implements gruid.Model.Update for StatusBar. It considers mouse
+// message coordinates to be absolute in its grid. Clicks on a visible segment
+// are reported with the StatusBarClick action.
+func (sb *StatusBar) Update(msg gruid.Msg) gruid.Effect {
+	sb.action = StatusBarPass
+	switch msg := msg.(type) {
+	case gruid.MsgMouse:
+		if msg.Action != gruid.MouseMain {
+			break
+		}
+		p := msg.P.Sub(sb.grid.Bounds().Min)
+		sb.layout()
+		for i, rg := range sb.ranges {
+			if p.In(rg) {
+				sb.action = StatusBarClick
+				sb.clicked = i
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// layout computes the ranges occupied by each segment.
+func (sb *StatusBar) layout() {
+	w := sb.grid.Size().X
+	n := len(sb.segments)
+	widths := make([]int, n)
+	visible := make([]bool, n)
+	total := 0
+	sepw := sb.sep.Size().X
+	for i, seg := range sb.segments {
+		widths[i] = seg.Text.Size().X
+		visible[i] = true
+		total += widths[i]
+		if i > 0 {
+			total += sepw
+		}
+	}
+	for total > w {
+		// find lowest priority visible segment (rightmost on ties)
+		j := -1
+		for i := n - 1; i >= 0; i-- {
+			if visible[i] && (j < 0 || sb.segments[i].Priority < sb.segments[j].Priority) {
+				j = i
+			}
+		}
+		if j < 0 {
+			break
+		}
+		excess := total - w
+		if widths[j]-excess >= 4 {
+			// enough space for a few cells and the ellipsis
+			widths[j] -= excess
+			total -= excess
+			break
+		}
+		visible[j] = false
+		total -= widths[j]
+		if total > 0 {
+			total -= sepw
+		}
+	}
+	sb.ranges = sb.ranges[:0]
+	x := 0
+	first := true
+	for i := range sb.segments {
+		if !visible[i] {
+			sb.ranges = append(sb.ranges, gruid.Range{})
+			continue
+		}
+		if !first {
+			x += sepw
+		}
+		first = false
+		sb.ranges = append(sb.ranges, gruid.NewRange(x, 0, x+widths[i], 1))
+		x += widths[i]
+	}
+}
+
+// Draw implements gruid.Model.Draw for StatusBar. It returns the grid slice
+// that was drawn, which is the first line of the grid, or an empty slice if
+// nothing changed since last Draw.
+func (sb *StatusBar) Draw() gruid.Grid {
+	line := sb.grid.Slice(sb.grid.Range().Line(0))
+	if !sb.dirty {
+		return line.Slice(gruid.Range{})
+	}
+	sb.dirty = false
+	sb.layout()
+	line.Fill(gruid.Cell{Rune: ' ', Style: sb.style})
+	prev := -1
+	for i, rg := range sb.ranges {
+		if rg.Empty() {
+			continue
+		}
+		if prev >= 0 {
+			sb.sep.Draw(line.Slice(gruid.NewRange(sb.ranges[prev].Max.X, 0, rg.Min.X, 1)))
+		}
+		prev = i
+		stt := sb.segments[i].Text
+		sgd := line.Slice(rg)
+		stt.Draw(sgd)
+		if rg.Size().X < stt.Size().X {
+			sgd.Set(gruid.Point{rg.Size().X - 1, 0}, gruid.Cell{Rune: '…', Style: stt.Style()})
+		}
+	}
+	return line
+}
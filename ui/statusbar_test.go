@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestStatusBar(t *testing.T) {
+	gd := gruid.NewGrid(24, 2)
+	sb := NewStatusBar(StatusBarConfig{
+		Grid: gd.Slice(gruid.NewRange(0, 1, 24, 2)),
+		Segments: []StatusSegment{
+			{Text: Text("World"), Priority: 2},
+			{Text: Text("Dungeon"), Priority: 1},
+			{Text: Text("Level 3"), Priority: 3},
+		},
+		Separator: Text(" > "),
+	})
+	line := func() string {
+		s := []rune{}
+		sb.Draw().Iter(func(p gruid.Point, c gruid.Cell) {
+			s = append(s, c.Rune)
+		})
+		return string(s)
+	}
+	if s := line(); s != "World > Dunge… > Level 3" {
+		t.Errorf("bad truncated status line: %q", s)
+	}
+	sb.SetText(0, Text("The Big World"))
+	if s := line(); s != "The Big World > Level 3 " {
+		t.Errorf("bad status line after drop: %q", s)
+	}
+	sb.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{17, 1}})
+	if sb.Action() != StatusBarClick || sb.Clicked() != 2 {
+		t.Errorf("bad click: %v %d", sb.Action(), sb.Clicked())
+	}
+	sb.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{17, 0}})
+	if sb.Action() != StatusBarPass {
+		t.Errorf("bad click outside")
+	}
+}
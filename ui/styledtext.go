@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/anaseto/gruid"
 )
@@ -14,6 +15,9 @@ type StyledText struct {
 	markups map[rune]gruid.Style
 	text    string
 	style   gruid.Style
+	width   func(rune) int
+	named   StyleRegistry   // named markup styles, if any
+	spans   map[rune]string // actionable spans for markup runes
 }
 
 // Text is a shorthand for StyledText{}.WithText and creates a new styled text
@@ -106,6 +110,55 @@ func (stt StyledText) WithMarkups(markups map[rune]gruid.Style) StyledText {
 	return stt
 }
 
+// WithWidthFunc returns a derived styled text using a given function to
+// compute the number of cells occupied by each rune, instead of assuming one
+// cell per rune. The function should return 0 for combining marks, which are
+// then ignored, and 2 for wide runes, such as CJK characters. See RuneWidth
+// for a simple default.
+//
+// A wide rune is drawn in its first cell, and the second cell is filled with
+// a zero rune with the same style, as a hint for drivers that the previous
+// cell spans two columns.
+func (stt StyledText) WithWidthFunc(fn func(rune) int) StyledText {
+	stt.width = fn
+	return stt
+}
+
+// runeWidth returns the number of cells used by a rune.
+func (stt StyledText) runeWidth(r rune) int {
+	if stt.width == nil {
+		return 1
+	}
+	return stt.width(r)
+}
+
+// RuneWidth returns the number of cells that a rune usually occupies in a
+// terminal: 0 for combining marks and other non-spacing characters, 2 for
+// East Asian wide and fullwidth characters, and 1 otherwise. It can be used
+// with WithWidthFunc.
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0x303E, // CJK radicals and punctuation
+		r >= 0x3041 && r <= 0x33FF, // kana, CJK symbols
+		r >= 0x3400 && r <= 0x4DBF, // CJK extension A
+		r >= 0x4E00 && r <= 0x9FFF, // CJK unified ideographs
+		r >= 0xA000 && r <= 0xA4CF, // Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60, // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // pictographs and emoticons
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
 // Markups returns a copy of the markups currently defined for the styled text.
 func (stt StyledText) Markups() map[rune]gruid.Style {
 	markups := make(map[rune]gruid.Style, len(stt.markups))
@@ -119,6 +172,15 @@ func (stt StyledText) Markups() map[rune]gruid.Style {
 // the styled text, and returns the minimum (w, h) size in cells which can fit
 // the text.
 func (stt StyledText) Iter(fn func(gruid.Point, gruid.Cell)) gruid.Point {
+	stt = stt.resolve()
+	return stt.iter(func(p gruid.Point, c gruid.Cell, _ string) { fn(p, c) })
+}
+
+// iter is the same as Iter, but also provides the identifier of the
+// actionable span containing each cell, if any. The styled text should be
+// resolved.
+func (stt StyledText) iter(fn func(gruid.Point, gruid.Cell, string)) gruid.Point {
+	span := ""
 	x, y := 0, 0
 	xmax := 0
 	c := gruid.Cell{Style: stt.style}
@@ -129,6 +191,7 @@ func (stt StyledText) Iter(fn func(gruid.Point, gruid.Cell)) gruid.Point {
 			if procMarkup(procm, r) {
 				if procm {
 					c.Style = stt.markupStyle(r)
+					span = stt.spans[r]
 				}
 				procm = !procm
 				continue
@@ -143,9 +206,17 @@ func (stt StyledText) Iter(fn func(gruid.Point, gruid.Cell)) gruid.Point {
 			y++
 			continue
 		}
+		w := stt.runeWidth(r)
+		if w <= 0 {
+			continue
+		}
 		c.Rune = r
-		fn(gruid.Point{X: x, Y: y}, c)
-		x++
+		fn(gruid.Point{X: x, Y: y}, c, span)
+		if w > 1 {
+			c.Rune = 0
+			fn(gruid.Point{X: x + 1, Y: y}, c, span)
+		}
+		x += w
 	}
 	if x > xmax {
 		xmax = x
@@ -156,6 +227,26 @@ func (stt StyledText) Iter(fn func(gruid.Point, gruid.Cell)) gruid.Point {
 	return gruid.Point{X: xmax, Y: y}
 }
 
+// Plain returns the text with markup removed, if markup is activated, as it
+// is displayed, but without styling.
+func (stt StyledText) Plain() string {
+	stt = stt.resolve()
+	if stt.markups == nil {
+		return stt.text
+	}
+	sb := strings.Builder{}
+	procm := false // processing markup
+	for _, r := range stt.text {
+		if procMarkup(procm, r) {
+			procm = !procm
+			continue
+		}
+		procm = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
 func procMarkup(procm bool, r rune) bool {
 	if procm {
 		return r != '@'
@@ -165,6 +256,7 @@ func procMarkup(procm bool, r rune) bool {
 
 // Size returns the minimum (w, h) size in cells which can fit the text.
 func (stt StyledText) Size() gruid.Point {
+	stt = stt.resolve()
 	x, y := 0, 0
 	xmax := 0
 	markup := stt.markups != nil // whether markup is activated
@@ -185,7 +277,7 @@ func (stt StyledText) Size() gruid.Point {
 			y++
 			continue
 		}
-		x++
+		x += stt.runeWidth(r)
 	}
 	if x > xmax {
 		xmax = x
@@ -200,11 +292,12 @@ func (stt StyledText) Size() gruid.Point {
 // wrapped at word boundaries, if possible. It preserves spaces at the
 // beginning of a line.
 func (stt StyledText) Format(width int) StyledText {
+	stt = stt.resolve()
 	s := strings.Builder{}
 	wordbuf := bytes.Buffer{}
 	col := 0                     // current column (without counting @r markups)
 	wantspace := false           // whether we expect currently space (start of a new word that is not at line start)
-	wlen := 0                    // current word length
+	wlen := 0                    // current word length (in cells)
 	markup := stt.markups != nil // whether markup is activated
 	procm := false               // processing markup
 	start := true                // whether at line start
@@ -267,7 +360,7 @@ func (stt StyledText) Format(width int) StyledText {
 		}
 		start = false
 		wordbuf.WriteRune(r)
-		wlen++
+		wlen += stt.runeWidth(r)
 	}
 	if wlen > 0 {
 		if wantspace {
@@ -303,10 +396,12 @@ func (stt StyledText) markupStyle(r rune) gruid.Style {
 // spaces beforehand by this function, not even the returned one, you should
 // use the styled text with a label for this.
 func (stt StyledText) Draw(gd gruid.Grid) gruid.Grid {
+	stt = stt.resolve()
 	it := gd.Iterator()
 	if !it.Next() {
 		return gd
 	}
+	width := gd.Size().X
 	x, y := 0, 0
 	xmax := 0
 	c := gruid.Cell{Style: stt.style}
@@ -338,15 +433,33 @@ func (stt StyledText) Draw(gd gruid.Grid) gruid.Grid {
 			}
 			continue
 		}
-		x++
+		w := stt.runeWidth(r)
+		if w <= 0 {
+			continue
+		}
+		x += w
 		if p.Y > y {
 			continue
 		}
+		if w > 1 && p.X+w > width {
+			// not enough space for a wide rune
+			if !it.Next() {
+				break
+			}
+			continue
+		}
 		c.Rune = r
 		it.SetCell(c)
 		if !it.Next() {
 			break
 		}
+		if w > 1 && it.P().Y == y {
+			c.Rune = 0
+			it.SetCell(c)
+			if !it.Next() {
+				break
+			}
+		}
 	}
 	if x > xmax {
 		xmax = x
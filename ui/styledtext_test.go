@@ -293,3 +293,105 @@ func BenchmarkTextFormat(b *testing.B) {
 		stt.Format(30)
 	}
 }
+
+func TestWideRunes(t *testing.T) {
+	stt := Text("日本 é").WithWidthFunc(RuneWidth)
+	if max := stt.Size(); max.X != 6 || max.Y != 1 {
+		t.Errorf("bad text size: %v", max)
+	}
+	stt = stt.WithText("日本 日本 日本").Format(9)
+	if max := stt.Size(); max.X != 9 || max.Y != 2 {
+		t.Errorf("bad formatted text size: %v. Text:\n%s", max, stt.Text())
+	}
+	gd := gruid.NewGrid(5, 2)
+	gd.Fill(gruid.Cell{Rune: ' '})
+	stt.WithText("日本語").Draw(gd)
+	runes := []rune{'日', 0, '本', 0, ' '}
+	for x, r := range runes {
+		if c := gd.At(gruid.Point{x, 0}); c.Rune != r {
+			t.Errorf("bad rune at %d: %q", x, c.Rune)
+		}
+	}
+	if c := gd.At(gruid.Point{0, 1}); c.Rune != ' ' {
+		t.Errorf("unexpected rune on second line: %q", c.Rune)
+	}
+}
+
+func TestNamedMarkup(t *testing.T) {
+	reg := StyleRegistry{
+		"red":  {Fg: 1},
+		"bold": {Attrs: 1},
+		"bg":   {Bg: 2},
+	}
+	stt := NewStyledText("a {red}b{bold}c{/bold}d{/red} {bg}@e{/} {{x} {unknown}", gruid.Style{Fg: 3}).WithNamedMarkup(reg)
+	if s := stt.Plain(); s != "a bcd @e {x} {unknown}" {
+		t.Errorf("bad plain text: %q", s)
+	}
+	if size := stt.Size(); size != (gruid.Point{22, 1}) {
+		t.Errorf("bad size: %v", size)
+	}
+	styles := map[int]gruid.Style{}
+	stt.Iter(func(p gruid.Point, c gruid.Cell) {
+		styles[p.X] = c.Style
+	})
+	want := map[int]gruid.Style{
+		0: {Fg: 3},
+		2: {Fg: 1},
+		3: {Fg: 1, Attrs: 1},
+		4: {Fg: 1},
+		5: {Fg: 3},
+		6: {Fg: 3, Bg: 2},
+		7: {Fg: 3, Bg: 2},
+		8: {Fg: 3},
+	}
+	for x, st := range want {
+		if styles[x] != st {
+			t.Errorf("bad style at %d: %v, want %v", x, styles[x], st)
+		}
+	}
+	stt = NewStyledText("{red}one two{/red} three", gruid.Style{}).WithNamedMarkup(reg).Format(7)
+	if s := stt.Plain(); s != "one two\nthree" {
+		t.Errorf("bad formatted text: %q", s)
+	}
+	gd := gruid.NewGrid(7, 2)
+	stt.Draw(gd)
+	if c := gd.At(gruid.Point{4, 0}); c.Rune != 't' || c.Style.Fg != 1 {
+		t.Errorf("bad formatted drawing: %v", c)
+	}
+	if c := gd.At(gruid.Point{0, 1}); c.Rune != 't' || c.Style.Fg != 0 {
+		t.Errorf("bad formatted drawing: %v", c)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	stt := Text("take the {red}{#sword}long sword{/}{/red} now").WithNamedMarkup(StyleRegistry{"red": {Fg: 1}})
+	gd := gruid.NewGrid(20, 3)
+	sp := stt.Format(14).DrawSpans(gd.Slice(gruid.NewRange(1, 1, 20, 3)))
+	if id, ok := sp.HitTest(gruid.Point{10, 1}); !ok || id != "sword" {
+		t.Errorf("bad span hit: %q %v", id, ok)
+	}
+	if _, ok := sp.HitTest(gruid.Point{2, 1}); ok {
+		t.Errorf("hit outside span")
+	}
+	if rg := sp.Bounds("sword"); rg != gruid.NewRange(1, 1, 14, 3) {
+		t.Errorf("bad span bounds: %v", rg)
+	}
+	if c := gd.At(gruid.Point{10, 1}); c.Rune != 'l' || c.Style.Fg != 1 {
+		t.Errorf("bad span drawing: %v", c)
+	}
+
+	lb := NewLabel(stt)
+	lb.Align = AlignRight
+	lb.AdjustWidth = false
+	lb.Draw(gd)
+	if id, ok := lb.HitTest(gruid.Point{10, 0}); !ok || id != "sword" {
+		t.Errorf("bad label span hit: %q %v", id, ok)
+	}
+
+	pager := NewPager(PagerConfig{Grid: gd, Lines: []StyledText{Text("a"), stt}})
+	pager.Draw()
+	pager.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{9, 1}})
+	if pager.Action() != PagerSpan || pager.Span() != "sword" {
+		t.Errorf("bad pager span click: %v %q", pager.Action(), pager.Span())
+	}
+}
@@ -0,0 +1,455 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/anaseto/gruid"
+)
+
+// TableColumn describes a table column.
+type TableColumn struct {
+	Header StyledText // column header
+	Width  int        // width in cells (0 means a share of remaining space)
+	Align  Alignment  // alignment of cells within the column (default: AlignCenter)
+
+	// Less optionally reports whether row a should sort before row b
+	// when sorting by this column. Row arguments are indices in the
+	// table rows. If nil, the cells text is compared.
+	Less func(a, b int) bool
+}
+
+// TableConfig contains configuration options for creating a table.
+type TableConfig struct {
+	Grid    gruid.Grid     // grid slice where the table is drawn
+	Columns []TableColumn  // table columns
+	Rows    [][]StyledText // rows of cells, one cell per column
+	Box     *Box           // draw optional box around the table
+	Keys    TableKeys      // optional custom key bindings
+	ID      string         // optional identifier for emitted events (see TableInvoked)
+	Style   TableStyle
+}
+
+// TableStyle describes styling options for a Table.
+type TableStyle struct {
+	Header  gruid.Style // header line style
+	Sorted  gruid.Style // style for the header of the sort column (no change if default)
+	Active  gruid.Style // specific styling for active row (no change if default)
+	Row     gruid.Style // style used to clear row lines before drawing
+	LineNum gruid.Style // line num display style (for boxed table)
+}
+
+// TableKeys contains key bindings configuration for the table.
+type TableKeys struct {
+	Down     []gruid.Key // move down active row (default: ArrowDown, j)
+	Up       []gruid.Key // move up active row (default: ArrowUp, k)
+	PageDown []gruid.Key // go one page down (default: PageDown, f)
+	PageUp   []gruid.Key // go one page up (default: PageUp, b)
+	Top      []gruid.Key // go to the first row (default: Home, g)
+	Bottom   []gruid.Key // go to the last row (default: End, G)
+	Invoke   []gruid.Key // invoke active row (default: Enter)
+	Sort     []gruid.Key // cycle sort column and order (default: s)
+	Quit     []gruid.Key // requist table quit (default: Escape, q, Q)
+}
+
+// Table is a widget that displays rows of cells in aligned columns with
+// headers. It allows to select and invoke a row, as well as sorting rows by a
+// column, either programmatically with SortBy, by cycling with the Sort keys,
+// or by clicking on a column header. Only visible rows are drawn, so tables
+// with many rows are handled efficiently.
+//
+// Table implements gruid.Model, but is not suitable for use as main model of
+// an application.
+type Table struct {
+	grid    gruid.Grid
+	columns []TableColumn
+	rows    [][]StyledText
+	box     *Box
+	keys    TableKeys
+	style   TableStyle
+	list    *List
+	order   []int // sorted row indices
+	col     int   // sort column (-1 if unsorted)
+	reverse bool  // descending order
+	widths  []int // computed column widths
+	action  TableAction
+	dirty   bool
+	drawn   gruid.Grid
+	id      string // identifier for emitted events
+}
+
+// TableAction represents an user action with the table.
+type TableAction int
+
+// These constants represent the available actions in a table.
+const (
+	// TablePass reports that the table state did not change.
+	TablePass TableAction = iota
+
+	// TableMove reports that the user moved the active row, or scrolled
+	// the table.
+	TableMove
+
+	// TableInvoke reports that the user clicked or pressed enter to
+	// invoke the active row.
+	TableInvoke
+
+	// TableSort reports that the user changed the sort column or order.
+	TableSort
+
+	// TableQuit reports that the user requested to quit the table, either
+	// by clicking outside the table, or by using a key shortcut.
+	TableQuit
+)
+
+// NewTable returns a new table with the given configuration.
+func NewTable(cfg TableConfig) *Table {
+	t := &Table{
+		grid:    cfg.Grid,
+		columns: cfg.Columns,
+		box:     cfg.Box,
+		keys:    cfg.Keys,
+		style:   cfg.Style,
+		col:     -1,
+		id:      cfg.ID,
+	}
+	if t.keys.Invoke == nil {
+		t.keys.Invoke = []gruid.Key{gruid.KeyEnter}
+	}
+	if t.keys.Sort == nil {
+		t.keys.Sort = []gruid.Key{"s"}
+	}
+	if t.keys.Quit == nil {
+		t.keys.Quit = []gruid.Key{gruid.KeyEscape, "q", "Q"}
+	}
+	t.list = NewList(ListConfig{
+		Grid: t.rowsGrid(),
+		Keys: ListKeys{
+			Down:     t.keys.Down,
+			Up:       t.keys.Up,
+			PageDown: t.keys.PageDown,
+			PageUp:   t.keys.PageUp,
+			Top:      t.keys.Top,
+			Bottom:   t.keys.Bottom,
+			Invoke:   t.keys.Invoke,
+			Quit:     t.keys.Quit,
+		},
+		Style:    ListStyle{Item: t.style.Row},
+		DrawItem: t.drawRow,
+	})
+	t.SetRows(cfg.Rows)
+	return t
+}
+
+// content returns the grid slice inside the box, if any.
+func (t *Table) content() gruid.Grid {
+	return t.grid.Slice(t.box.inner(t.grid.Range()))
+}
+
+// rowsGrid returns the grid slice where rows are drawn.
+func (t *Table) rowsGrid() gruid.Grid {
+	content := t.content()
+	return content.Slice(content.Range().Shift(0, 1, 0, 0))
+}
+
+// SetRows updates the table rows. The current sort order is applied to the
+// new rows, and the active row is reset to the first one.
+func (t *Table) SetRows(rows [][]StyledText) {
+	t.rows = rows
+	t.order = t.order[:0]
+	for i := range t.rows {
+		t.order = append(t.order, i)
+	}
+	t.sort()
+	t.list.SetLen(len(t.rows))
+	t.list.SetActive(0)
+	t.dirty = true
+}
+
+// Rows returns the table rows, in their original order.
+func (t *Table) Rows() [][]StyledText {
+	return t.rows
+}
+
+// SetBox updates the table surrounding box.
+func (t *Table) SetBox(b *Box) {
+	t.box = b
+	t.list.grid = t.rowsGrid()
+	t.list.SetActive(t.list.active)
+	t.dirty = true
+}
+
+// Active returns the index, in the table rows, of the active row. It returns
+// -1 if there are no rows.
+func (t *Table) Active() int {
+	i := t.list.Active()
+	if i < 0 {
+		return -1
+	}
+	return t.order[i]
+}
+
+// SetActive updates the active row, given its index in the table rows, and
+// scrolls the view if necessary.
+func (t *Table) SetActive(row int) {
+	for i, r := range t.order {
+		if r == row {
+			t.list.SetActive(i)
+			t.dirty = true
+			return
+		}
+	}
+}
+
+// SortBy sorts the rows by the given column, in ascending order, or in
+// descending order if reverse is true. A negative column restores the
+// original order. The active row is preserved.
+func (t *Table) SortBy(col int, reverse bool) {
+	if col >= len(t.columns) {
+		return
+	}
+	active := t.Active()
+	if col < 0 {
+		col, reverse = -1, false
+	}
+	t.col, t.reverse = col, reverse
+	for i := range t.order {
+		t.order[i] = i
+	}
+	t.sort()
+	if active >= 0 {
+		t.SetActive(active)
+	}
+	t.dirty = true
+}
+
+// SortColumn returns the current sort column, or -1 if rows are in their
+// original order, and whether the order is descending.
+func (t *Table) SortColumn() (col int, reverse bool) {
+	return t.col, t.reverse
+}
+
+func (t *Table) sort() {
+	if t.col < 0 {
+		return
+	}
+	less := t.columns[t.col].Less
+	if less == nil {
+		col := t.col
+		less = func(a, b int) bool {
+			return t.cell(a, col).Text() < t.cell(b, col).Text()
+		}
+	}
+	sort.SliceStable(t.order, func(i, j int) bool {
+		if t.reverse {
+			return less(t.order[j], t.order[i])
+		}
+		return less(t.order[i], t.order[j])
+	})
+}
+
+// cell returns the cell of the given row and column, or an empty text if the
+// row has not enough cells.
+func (t *Table) cell(row, col int) StyledText {
+	if col < len(t.rows[row]) {
+		return t.rows[row][col]
+	}
+	return StyledText{}
+}
+
+// nextSort cycles through columns in ascending then descending order.
+func (t *Table) nextSort() {
+	if len(t.columns) == 0 {
+		return
+	}
+	switch {
+	case t.col < 0:
+		t.SortBy(0, false)
+	case !t.reverse:
+		t.SortBy(t.col, true)
+	default:
+		t.SortBy((t.col+1)%len(t.columns), false)
+	}
+	t.action = TableSort
+}
+
+// Action returns the last action performed with the table.
+func (t *Table) Action() TableAction {
+	return t.action
+}
+
+// Update implements gruid.Model.Update and updates the table state in
+// response to user input messages. It considers mouse message coordinates to
+// be absolute in its grid.
+func (t *Table) Update(msg gruid.Msg) gruid.Effect {
+	t.action = TablePass
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		if msg.Key.In(t.keys.Sort) {
+			t.nextSort()
+			break
+		}
+		t.updateList(msg)
+	case gruid.MsgMouse:
+		t.updateMouse(msg)
+	}
+	if t.action == TablePass {
+		return nil
+	}
+	t.dirty = true
+	switch t.action {
+	case TableInvoke:
+		return emit(t.id, TableInvoked{ID: t.id, Index: t.Active()})
+	case TableQuit:
+		return emit(t.id, WidgetClosed{ID: t.id})
+	}
+	return nil
+}
+
+func (t *Table) updateMouse(msg gruid.MsgMouse) {
+	content := t.content().Bounds()
+	header := content.Lines(0, 1)
+	switch {
+	case msg.P.In(header):
+		if msg.Action != gruid.MouseMain {
+			break
+		}
+		col := t.columnAt(msg.P.X - header.Min.X)
+		if col < 0 {
+			break
+		}
+		t.SortBy(col, col == t.col && !t.reverse)
+		t.action = TableSort
+	case msg.P.In(t.grid.Bounds()) && !msg.P.In(t.list.grid.Bounds()):
+		// box borders
+	default:
+		t.updateList(msg)
+	}
+}
+
+// updateList delegates a message to the rows list.
+func (t *Table) updateList(msg gruid.Msg) {
+	t.list.Update(msg)
+	switch t.list.Action() {
+	case ListMove:
+		t.action = TableMove
+	case ListInvoke:
+		t.action = TableInvoke
+	case ListQuit:
+		t.action = TableQuit
+	}
+}
+
+// computeWidths computes the column widths for a given total width.
+func (t *Table) computeWidths(w int) {
+	t.widths = t.widths[:0]
+	fixed, shared := 0, 0
+	for _, c := range t.columns {
+		t.widths = append(t.widths, c.Width)
+		if c.Width > 0 {
+			fixed += c.Width
+		} else {
+			shared++
+		}
+	}
+	if len(t.columns) > 1 {
+		fixed += len(t.columns) - 1 // separators
+	}
+	if shared == 0 {
+		return
+	}
+	rem := w - fixed
+	if rem < 0 {
+		rem = 0
+	}
+	for i, c := range t.columns {
+		if c.Width > 0 {
+			continue
+		}
+		t.widths[i] = rem / shared
+		if shared == 1 {
+			t.widths[i] = rem
+		}
+		rem -= t.widths[i]
+		shared--
+	}
+}
+
+// columnAt returns the column at a given relative x position, or -1.
+func (t *Table) columnAt(x int) int {
+	from := 0
+	for i, w := range t.widths {
+		if x >= from && x < from+w {
+			return i
+		}
+		from += w + 1
+	}
+	return -1
+}
+
+// drawLine draws the given cells aligned in columns.
+func (t *Table) drawLine(gd gruid.Grid, cells func(col int) StyledText, st gruid.Style, override bool) {
+	h := gd.Size().Y
+	from := 0
+	for i, w := range t.widths {
+		stt := cells(i)
+		if override {
+			stt = stt.WithStyle(st)
+		}
+		stt.drawTextLine(gd.Slice(gruid.NewRange(from, 0, from+w, h)), t.columns[i].Align)
+		from += w + 1
+	}
+}
+
+func (t *Table) drawRow(gd gruid.Grid, i int, active bool) {
+	row := t.order[i]
+	st := t.style.Row
+	override := false
+	if active {
+		if t.style.Active.Fg != gruid.ColorDefault {
+			st.Fg = t.style.Active.Fg
+			override = true
+		}
+		if t.style.Active.Bg != gruid.ColorDefault {
+			st.Bg = t.style.Active.Bg
+			override = true
+		}
+		if t.style.Active.Attrs != gruid.AttrsDefault {
+			st.Attrs = t.style.Active.Attrs
+			override = true
+		}
+		gd.Fill(gruid.Cell{Rune: ' ', Style: st})
+	}
+	t.drawLine(gd, func(col int) StyledText { return t.cell(row, col) }, st, override)
+}
+
+// Draw implements gruid.Model.Draw. It returns the grid slice that was drawn.
+func (t *Table) Draw() gruid.Grid {
+	if !t.dirty && !t.list.dirty {
+		return t.drawn
+	}
+	if t.box != nil {
+		foot := t.box.Footer
+		if foot.Text() == "" && len(t.rows) > t.list.nlines() {
+			t.box.Footer = NewStyledText(fmt.Sprintf("%d/%d", t.list.active+1, len(t.rows)), t.style.LineNum)
+		}
+		t.box.Draw(t.grid)
+		t.box.Footer = foot
+	}
+	content := t.content()
+	t.computeWidths(content.Size().X)
+	header := content.Slice(content.Range().Line(0))
+	header.Fill(gruid.Cell{Rune: ' ', Style: t.style.Header})
+	t.drawLine(header, func(col int) StyledText {
+		stt := t.columns[col].Header.WithStyle(t.style.Header)
+		if col == t.col && t.style.Sorted != (gruid.Style{}) {
+			stt = stt.WithStyle(t.style.Sorted)
+		}
+		return stt
+	}, t.style.Header, false)
+	t.list.dirty = true
+	t.list.Draw()
+	t.dirty = false
+	t.drawn = t.grid
+	return t.drawn
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestTable(t *testing.T) {
+	gd := gruid.NewGrid(20, 6)
+	scores := []int{120, 5, 42, 300, 7}
+	rows := [][]StyledText{}
+	for i, sc := range scores {
+		rows = append(rows, []StyledText{Text(fmt.Sprintf("p%d", i)), Text(strconv.Itoa(sc))})
+	}
+	tb := NewTable(TableConfig{
+		Grid: gd,
+		Columns: []TableColumn{
+			{Header: Text("Name"), Width: 6, Align: AlignLeft},
+			{Header: Text("Score"), Align: AlignRight, Less: func(a, b int) bool { return scores[a] < scores[b] }},
+		},
+		Rows: rows,
+	})
+	tb.Draw()
+	line := func(y int) string {
+		s := ""
+		for x := 0; x < 20; x++ {
+			s += string(gd.At(gruid.Point{x, y}).Rune)
+		}
+		return s
+	}
+	if l := line(0); l != "Name           Score" {
+		t.Errorf("bad header: %q", l)
+	}
+	if l := line(1); l != "p0               120" {
+		t.Errorf("bad first row: %q", l)
+	}
+	tb.SortBy(1, true)
+	tb.Draw()
+	if l := line(1); l != "p3               300" {
+		t.Errorf("bad sorted row: %q", l)
+	}
+	if tb.Active() != 0 {
+		t.Errorf("active row not preserved: %d", tb.Active())
+	}
+	tb.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if tb.Action() != TableMove || tb.Active() != 2 {
+		t.Errorf("bad move: %v %d", tb.Action(), tb.Active())
+	}
+	tb.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 0}})
+	if col, rev := tb.SortColumn(); tb.Action() != TableSort || col != 0 || rev {
+		t.Errorf("bad header click sort: %v %d %v", tb.Action(), col, rev)
+	}
+	tb.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 0}})
+	if col, rev := tb.SortColumn(); col != 0 || !rev {
+		t.Errorf("bad header click reverse: %d %v", col, rev)
+	}
+	tb.Update(gruid.MsgKeyDown{Key: "s"})
+	if col, rev := tb.SortColumn(); tb.Action() != TableSort || col != 1 || rev {
+		t.Errorf("bad sort key: %d %v", col, rev)
+	}
+	tb.Draw()
+	if l := line(1); l != "p1                 5" {
+		t.Errorf("bad ascending sorted row: %q", l)
+	}
+	tb.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{2, 3}})
+	if tb.Action() != TableInvoke || tb.Active() != 2 {
+		t.Errorf("bad row click: %v %d", tb.Action(), tb.Active())
+	}
+	tb.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape})
+	if tb.Action() != TableQuit {
+		t.Errorf("bad quit: %v", tb.Action())
+	}
+	tb.SortBy(-1, false)
+	tb.Draw()
+	if l := line(4); l != "p3               300" {
+		t.Errorf("bad unsorted row: %q", l)
+	}
+}
@@ -0,0 +1,507 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/anaseto/gruid"
+)
+
+// TextAreaConfig describes configuration options for creating a text area.
+type TextAreaConfig struct {
+	Grid  gruid.Grid   // grid slice where the text area is drawn
+	Text  StyledText   // styled text with initial text area content
+	Box   *Box         // draw optional box around the text area
+	Keys  TextAreaKeys // optional custom key bindings for the text area
+	ID    string       // optional identifier for emitted events (see WidgetClosed)
+	Style TextAreaStyle
+}
+
+// TextAreaStyle describes styling options for a TextArea.
+type TextAreaStyle struct {
+	Cursor    gruid.Style // cursor style
+	Selection gruid.Style // selected text style (default: cursor style)
+}
+
+// TextAreaKeys contains key bindings configuration for the text area.
+type TextAreaKeys struct {
+	Quit []gruid.Key // quit text area (default: Escape)
+}
+
+// TextArea represents a multi-line text entry supplied by the user. Long lines
+// are not wrapped: the view scrolls horizontally and vertically so that the
+// cursor is always visible.
+//
+// The cursor can be moved with arrows, Home, End, PageUp and PageDown, or by
+// clicking. Text can be selected by moving the cursor with the Shift modifier
+// or by dragging with the mouse. Typed text replaces the selection, and
+// Backspace or Delete remove it. Keys corresponding to control characters are
+// ignored. Text from MsgPaste messages is inserted at cursor position.
+//
+// TextArea implements gruid.Model, but is not suitable for use as main model
+// of an application.
+type TextArea struct {
+	grid     gruid.Grid
+	stt      StyledText
+	box      *Box
+	lines    [][]rune
+	cursor   gruid.Point // cursor position: column and line
+	anchor   gruid.Point // selection anchor
+	selected bool        // whether there is an active selection
+	dragging bool        // whether the main mouse button is held
+	offset   gruid.Point // first visible column and line
+	style    TextAreaStyle
+	keys     TextAreaKeys
+	action   TextAreaAction
+	dirty    bool       // state changed in Update and Draw was still not called
+	drawn    gruid.Grid // the last grid slice that was drawn
+	id       string     // identifier for emitted events
+}
+
+// TextAreaAction represents last user action with the text area.
+type TextAreaAction int
+
+// These constants represent possible actions raising from interaction with the
+// text area.
+const (
+	TextAreaPass   TextAreaAction = iota // no change in state
+	TextAreaChange                       // changed content
+	TextAreaMove                         // moved cursor or changed selection
+	TextAreaQuit                         // quit text area
+)
+
+// NewTextArea returns a new text area with given configuration options.
+func NewTextArea(cfg TextAreaConfig) *TextArea {
+	ta := &TextArea{
+		grid:  cfg.Grid,
+		stt:   cfg.Text.WithMarkups(nil),
+		box:   cfg.Box,
+		style: cfg.Style,
+		keys:  cfg.Keys,
+		id:    cfg.ID,
+	}
+	stdefault := gruid.Style{}
+	if ta.style.Cursor == stdefault {
+		ta.style.Cursor = cfg.Text.Style()
+		ta.style.Cursor.Bg, ta.style.Cursor.Fg = ta.style.Cursor.Fg, ta.style.Cursor.Bg
+	}
+	if ta.style.Selection == stdefault {
+		ta.style.Selection = ta.style.Cursor
+	}
+	if ta.keys.Quit == nil {
+		ta.keys.Quit = []gruid.Key{gruid.KeyEscape}
+	}
+	ta.setContent(ta.stt.Text())
+	ta.dirty = true
+	return ta
+}
+
+func (ta *TextArea) setContent(s string) {
+	ta.lines = ta.lines[:0]
+	for _, l := range strings.Split(s, "\n") {
+		ta.lines = append(ta.lines, []rune(l))
+	}
+	ta.cursor = gruid.Point{}
+	ta.selected = false
+}
+
+// Content returns the current content of the text area. Lines are separated
+// by newlines.
+func (ta *TextArea) Content() string {
+	var sb strings.Builder
+	for i, l := range ta.lines {
+		if i > 0 {
+			sb.WriteRune('\n')
+		}
+		sb.WriteString(string(l))
+	}
+	return sb.String()
+}
+
+// SetContent replaces the content of the text area. The cursor is moved to
+// the start and selection is cleared.
+func (ta *TextArea) SetContent(s string) {
+	ta.setContent(s)
+	ta.offset = gruid.Point{}
+	ta.dirty = true
+}
+
+// Cursor returns the cursor position, as column and line numbers.
+func (ta *TextArea) Cursor() gruid.Point {
+	return ta.cursor
+}
+
+// SetCursor updates the cursor position, given as column and line numbers. It
+// clears any selection.
+func (ta *TextArea) SetCursor(p gruid.Point) {
+	ta.cursor = ta.clamp(p)
+	ta.selected = false
+	ta.scroll()
+	ta.dirty = true
+}
+
+// Selection returns the currently selected text, if any.
+func (ta *TextArea) Selection() string {
+	if !ta.selected {
+		return ""
+	}
+	from, to := ta.selection()
+	var sb strings.Builder
+	for y := from.Y; y <= to.Y; y++ {
+		l := ta.lines[y]
+		x0, x1 := 0, len(l)
+		if y == from.Y {
+			x0 = from.X
+		}
+		if y == to.Y {
+			x1 = to.X
+		}
+		if y > from.Y {
+			sb.WriteRune('\n')
+		}
+		sb.WriteString(string(l[x0:x1]))
+	}
+	return sb.String()
+}
+
+// SetBox updates the text area surrounding box.
+func (ta *TextArea) SetBox(b *Box) {
+	ta.box = b
+	ta.scroll()
+	ta.dirty = true
+}
+
+// Action returns the action performed with the TextArea in the last call to
+// Update.
+func (ta *TextArea) Action() TextAreaAction {
+	return ta.action
+}
+
+// content returns the grid slice where the text is drawn.
+func (ta *TextArea) content() gruid.Grid {
+	return ta.grid.Slice(ta.box.inner(ta.grid.Range()))
+}
+
+// clamp returns the closest valid cursor position.
+func (ta *TextArea) clamp(p gruid.Point) gruid.Point {
+	if p.Y >= len(ta.lines) {
+		p.Y = len(ta.lines) - 1
+	}
+	if p.Y < 0 {
+		p.Y = 0
+	}
+	if p.X > len(ta.lines[p.Y]) {
+		p.X = len(ta.lines[p.Y])
+	}
+	if p.X < 0 {
+		p.X = 0
+	}
+	return p
+}
+
+// textBefore reports whether position p comes before q in the text.
+func textBefore(p, q gruid.Point) bool {
+	return p.Y < q.Y || p.Y == q.Y && p.X < q.X
+}
+
+// selection returns the start and end of the selection.
+func (ta *TextArea) selection() (from, to gruid.Point) {
+	if textBefore(ta.cursor, ta.anchor) {
+		return ta.cursor, ta.anchor
+	}
+	return ta.anchor, ta.cursor
+}
+
+func (ta *TextArea) isSelected(p gruid.Point) bool {
+	if !ta.selected {
+		return false
+	}
+	from, to := ta.selection()
+	return !textBefore(p, from) && textBefore(p, to)
+}
+
+// scroll updates the view offset so that the cursor is visible.
+func (ta *TextArea) scroll() {
+	size := ta.content().Size()
+	if ta.cursor.Y < ta.offset.Y {
+		ta.offset.Y = ta.cursor.Y
+	}
+	if size.Y > 0 && ta.cursor.Y >= ta.offset.Y+size.Y {
+		ta.offset.Y = ta.cursor.Y - size.Y + 1
+	}
+	if ta.cursor.X < ta.offset.X {
+		ta.offset.X = ta.cursor.X
+	}
+	if size.X > 0 && ta.cursor.X >= ta.offset.X+size.X {
+		ta.offset.X = ta.cursor.X - size.X + 1
+	}
+}
+
+// moveTo moves the cursor, extending the selection if extend is true, and
+// clearing it otherwise.
+func (ta *TextArea) moveTo(p gruid.Point, extend bool) {
+	p = ta.clamp(p)
+	if extend && !ta.selected {
+		ta.anchor = ta.cursor
+		ta.selected = true
+	}
+	if !extend && ta.selected {
+		ta.selected = false
+		ta.action = TextAreaMove
+	}
+	if p != ta.cursor {
+		ta.cursor = p
+		ta.action = TextAreaMove
+	}
+	if ta.selected && ta.anchor == ta.cursor {
+		ta.selected = false
+	}
+	ta.scroll()
+}
+
+// deleteSelection removes the selected text, if any, and reports whether
+// something was removed.
+func (ta *TextArea) deleteSelection() bool {
+	if !ta.selected {
+		return false
+	}
+	from, to := ta.selection()
+	ta.deleteRange(from, to)
+	ta.selected = false
+	return true
+}
+
+// deleteRange removes text between from and to, and puts the cursor at from.
+func (ta *TextArea) deleteRange(from, to gruid.Point) {
+	l := append([]rune{}, ta.lines[from.Y][:from.X]...)
+	l = append(l, ta.lines[to.Y][to.X:]...)
+	lines := append(ta.lines[:from.Y], l)
+	ta.lines = append(lines, ta.lines[to.Y+1:]...)
+	ta.cursor = from
+	ta.action = TextAreaChange
+	ta.scroll()
+}
+
+// insert inserts a rune at cursor position. A newline splits the current
+// line.
+func (ta *TextArea) insert(r rune) {
+	ta.deleteSelection()
+	y := ta.cursor.Y
+	l := ta.lines[y]
+	if r == '\n' {
+		tail := append([]rune{}, l[ta.cursor.X:]...)
+		ta.lines[y] = l[:ta.cursor.X]
+		ta.lines = append(ta.lines, nil)
+		copy(ta.lines[y+2:], ta.lines[y+1:])
+		ta.lines[y+1] = tail
+		ta.cursor = gruid.Point{0, y + 1}
+	} else {
+		var c []rune
+		c = append(c, l[:ta.cursor.X]...)
+		c = append(c, r)
+		c = append(c, l[ta.cursor.X:]...)
+		ta.lines[y] = c
+		ta.cursor.X++
+	}
+	ta.action = TextAreaChange
+	ta.scroll()
+}
+
+// paste inserts pasted text at cursor position, replacing the selection, if
+// any. Tabs are replaced by spaces, and control characters other than
+// newlines are ignored.
+func (ta *TextArea) paste(s string) {
+	parts := [][]rune{{}}
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			parts = append(parts, []rune{})
+		case r == '\t':
+			parts[len(parts)-1] = append(parts[len(parts)-1], ' ')
+		case !unicode.IsControl(r):
+			parts[len(parts)-1] = append(parts[len(parts)-1], r)
+		}
+	}
+	if len(parts) == 1 && len(parts[0]) == 0 {
+		return
+	}
+	ta.deleteSelection()
+	x, y := ta.cursor.X, ta.cursor.Y
+	l := ta.lines[y]
+	last := len(parts) - 1
+	ta.cursor = gruid.Point{len(parts[last]), y + last}
+	if last == 0 {
+		ta.cursor.X += x
+	}
+	parts[0] = append(append([]rune{}, l[:x]...), parts[0]...)
+	parts[last] = append(parts[last], l[x:]...)
+	lines := make([][]rune, 0, len(ta.lines)+last)
+	lines = append(lines, ta.lines[:y]...)
+	lines = append(lines, parts...)
+	ta.lines = append(lines, ta.lines[y+1:]...)
+	ta.action = TextAreaChange
+	ta.scroll()
+}
+
+// prev returns the position before the cursor, moving to the end of the
+// previous line if necessary.
+func (ta *TextArea) prev() gruid.Point {
+	p := ta.cursor
+	if p.X > 0 {
+		return gruid.Point{p.X - 1, p.Y}
+	}
+	if p.Y > 0 {
+		return gruid.Point{len(ta.lines[p.Y-1]), p.Y - 1}
+	}
+	return p
+}
+
+// next returns the position after the cursor, moving to the start of the
+// next line if necessary.
+func (ta *TextArea) next() gruid.Point {
+	p := ta.cursor
+	if p.X < len(ta.lines[p.Y]) {
+		return gruid.Point{p.X + 1, p.Y}
+	}
+	if p.Y < len(ta.lines)-1 {
+		return gruid.Point{0, p.Y + 1}
+	}
+	return p
+}
+
+// Update implements gruid.Model.Update for TextArea. It considers mouse
+// message coordinates to be absolute in its grid.
+func (ta *TextArea) Update(msg gruid.Msg) gruid.Effect {
+	ta.action = TextAreaPass
+	switch msg := msg.(type) {
+	case gruid.MsgKeyDown:
+		ta.updateMsgKeyDown(msg)
+	case gruid.MsgMouse:
+		ta.updateMsgMouse(msg)
+	case gruid.MsgPaste:
+		ta.paste(msg.Text)
+	}
+	if ta.action == TextAreaPass {
+		return nil
+	}
+	ta.dirty = true
+	if ta.action == TextAreaQuit {
+		return emit(ta.id, WidgetClosed{ID: ta.id})
+	}
+	return nil
+}
+
+func (ta *TextArea) updateMsgKeyDown(msg gruid.MsgKeyDown) {
+	if msg.Key.In(ta.keys.Quit) {
+		ta.action = TextAreaQuit
+		return
+	}
+	extend := msg.Mod&gruid.ModShift != 0
+	page := ta.content().Size().Y - 1
+	if page < 1 {
+		page = 1
+	}
+	switch msg.Key {
+	case gruid.KeyArrowLeft:
+		ta.moveTo(ta.prev(), extend)
+	case gruid.KeyArrowRight:
+		ta.moveTo(ta.next(), extend)
+	case gruid.KeyArrowUp:
+		ta.moveTo(ta.cursor.Shift(0, -1), extend)
+	case gruid.KeyArrowDown:
+		ta.moveTo(ta.cursor.Shift(0, 1), extend)
+	case gruid.KeyPageUp:
+		ta.moveTo(ta.cursor.Shift(0, -page), extend)
+	case gruid.KeyPageDown:
+		ta.moveTo(ta.cursor.Shift(0, page), extend)
+	case gruid.KeyHome:
+		ta.moveTo(gruid.Point{0, ta.cursor.Y}, extend)
+	case gruid.KeyEnd:
+		ta.moveTo(gruid.Point{len(ta.lines[ta.cursor.Y]), ta.cursor.Y}, extend)
+	case gruid.KeyBackspace:
+		if !ta.deleteSelection() && ta.prev() != ta.cursor {
+			ta.deleteRange(ta.prev(), ta.cursor)
+		}
+	case gruid.KeyDelete:
+		if !ta.deleteSelection() && ta.next() != ta.cursor {
+			ta.deleteRange(ta.cursor, ta.next())
+		}
+	case gruid.KeyEnter:
+		ta.insert('\n')
+	default:
+		if !msg.Key.IsRune() {
+			return
+		}
+		r, _ := utf8.DecodeRuneInString(string(msg.Key))
+		if unicode.IsControl(r) {
+			return
+		}
+		ta.insert(r)
+	}
+}
+
+func (ta *TextArea) updateMsgMouse(msg gruid.MsgMouse) {
+	crg := ta.content().Bounds()
+	switch msg.Action {
+	case gruid.MouseMain:
+		if !msg.P.In(ta.grid.Bounds()) {
+			ta.action = TextAreaQuit
+			return
+		}
+		if !msg.P.In(crg) {
+			return
+		}
+		ta.moveTo(msg.P.Sub(crg.Min).Add(ta.offset), false)
+		ta.dragging = true
+	case gruid.MouseMove:
+		if ta.dragging {
+			ta.moveTo(msg.P.Sub(crg.Min).Add(ta.offset), true)
+		}
+	case gruid.MouseRelease:
+		ta.dragging = false
+	case gruid.MouseWheelUp, gruid.MouseWheelDown:
+		if !msg.P.In(crg) {
+			return
+		}
+		delta := 1
+		if msg.Action == gruid.MouseWheelUp {
+			delta = -1
+		}
+		ta.moveTo(ta.cursor.Shift(0, delta), false)
+	}
+}
+
+// Draw implements gruid.Model.Draw for TextArea.
+func (ta *TextArea) Draw() gruid.Grid {
+	if !ta.dirty {
+		return ta.drawn
+	}
+	if ta.box != nil {
+		ta.box.Draw(ta.grid)
+	}
+	cgrid := ta.content()
+	st := ta.stt.Style()
+	cgrid.Fill(gruid.Cell{Rune: ' ', Style: st})
+	size := cgrid.Size()
+	for y := 0; y < size.Y && y+ta.offset.Y < len(ta.lines); y++ {
+		l := ta.lines[y+ta.offset.Y]
+		for x := 0; x < size.X && x+ta.offset.X <= len(l); x++ {
+			p := gruid.Point{x + ta.offset.X, y + ta.offset.Y}
+			c := gruid.Cell{Rune: ' ', Style: st}
+			if p.X < len(l) {
+				c.Rune = l[p.X]
+			}
+			switch {
+			case p == ta.cursor:
+				c.Style = ta.style.Cursor
+			case ta.isSelected(p):
+				c.Style = ta.style.Selection
+			}
+			cgrid.Set(gruid.Point{x, y}, c)
+		}
+	}
+	ta.dirty = false
+	ta.drawn = ta.grid
+	return ta.drawn
+}
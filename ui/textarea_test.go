@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestTextArea(t *testing.T) {
+	gd := gruid.NewGrid(5, 2)
+	ta := NewTextArea(TextAreaConfig{
+		Grid: gd,
+		Text: Text("hello\nworld"),
+	})
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyEnd})
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter})
+	ta.Update(gruid.MsgKeyDown{Key: "!"})
+	if ta.Content() != "hello\n!\nworld" || ta.Action() != TextAreaChange {
+		t.Errorf("bad content after insertion: %q", ta.Content())
+	}
+	if ta.Cursor() != (gruid.Point{1, 1}) {
+		t.Errorf("bad cursor: %v", ta.Cursor())
+	}
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyEnd})
+	ta.Draw()
+	// view scrolled to show cursor at end of third line
+	if c := gd.At(gruid.Point{0, 1}); c.Rune != 'o' {
+		t.Errorf("bad scrolled view: %v", c)
+	}
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowLeft, Mod: gruid.ModShift})
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowUp, Mod: gruid.ModShift})
+	if s := ta.Selection(); s != "\nworld" || ta.Action() != TextAreaMove {
+		t.Errorf("bad selection: %q", s)
+	}
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyBackspace})
+	if ta.Content() != "hello\n!" || ta.Selection() != "" {
+		t.Errorf("bad content after selection deletion: %q", ta.Content())
+	}
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyHome})
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyBackspace})
+	if ta.Content() != "hello!" || ta.Cursor() != (gruid.Point{5, 0}) {
+		t.Errorf("bad line join: %q %v", ta.Content(), ta.Cursor())
+	}
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyHome})
+	ta.Update(gruid.MsgMouse{Action: gruid.MouseMain, P: gruid.Point{1, 0}})
+	ta.Update(gruid.MsgMouse{Action: gruid.MouseMove, P: gruid.Point{2, 0}})
+	ta.Update(gruid.MsgMouse{Action: gruid.MouseRelease, P: gruid.Point{2, 0}})
+	if s := ta.Selection(); s != "e" {
+		t.Errorf("bad mouse selection: %q", s)
+	}
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape})
+	if ta.Action() != TextAreaQuit {
+		t.Errorf("bad quit: %v", ta.Action())
+	}
+}
+
+func TestTextAreaPaste(t *testing.T) {
+	gd := gruid.NewGrid(10, 3)
+	ta := NewTextArea(TextAreaConfig{
+		Grid: gd,
+		Text: Text("ab\ncd"),
+	})
+	ta.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowRight})
+	ta.Update(gruid.MsgPaste{Text: "x\ny\tz"})
+	if ta.Content() != "ax\ny zb\ncd" || ta.Action() != TextAreaChange {
+		t.Errorf("bad content after paste: %q", ta.Content())
+	}
+	if ta.Cursor() != (gruid.Point{3, 1}) {
+		t.Errorf("bad cursor after paste: %v", ta.Cursor())
+	}
+	ta.Update(gruid.MsgPaste{Text: "!"})
+	if ta.Content() != "ax\ny z!b\ncd" || ta.Cursor() != (gruid.Point{4, 1}) {
+		t.Errorf("bad single line paste: %q %v", ta.Content(), ta.Cursor())
+	}
+}
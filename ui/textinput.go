@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/anaseto/gruid"
@@ -13,21 +14,53 @@ type TextInputConfig struct {
 	Prompt StyledText    // optional prompt text
 	Box    *Box          // draw optional box around the text input
 	Keys   TextInputKeys // optional custom key bindings for the text input
+	ID     string        // optional identifier for emitted events (see InputAccepted)
 	Style  TextInputStyle
+
+	// History is an optional initial input history, from oldest to newest
+	// entry. Validated inputs are appended to it.
+	History []string
+
+	// Complete is an optional completion function. It receives the text
+	// before the cursor, and returns candidates that may replace it.
+	Complete func(prefix string) []string
 }
 
 // TextInputStyle describes styling options for a TextInput.
 type TextInputStyle struct {
-	Cursor gruid.Style // cursor style
+	Cursor           gruid.Style // cursor style
+	Completion       gruid.Style // completion candidate style (default: text style)
+	CompletionActive gruid.Style // active completion candidate style (default: cursor style)
 }
 
 // TextInputKeys contains key bindings configuration for the text input.
 type TextInputKeys struct {
-	Quit []gruid.Key // quit text input (default: Escape, Tab)
+	Quit     []gruid.Key // quit text input (default: Escape, and Tab without completion)
+	Complete []gruid.Key // complete text before cursor (default: Tab)
 }
 
 // TextInput represents a line entry with text supplied from the user that can
-// be validated. It offers only basic editing shortcuts.
+// be validated. Keys corresponding to unhandled control characters are
+// ignored. Text from MsgPaste messages is inserted at cursor position.
+//
+// In addition to arrows, Home, End, Backspace and Delete, the following
+// Emacs-style editing shortcuts are available, either with the Ctrl modifier,
+// or as raw control characters as reported by some terminals:
+//
+//	Ctrl-A, Ctrl-E: go to start or end of line
+//	Ctrl-B, Ctrl-F: move one character backward or forward
+//	Alt-B, Alt-F, Ctrl-Left, Ctrl-Right: move one word backward or forward
+//	Ctrl-D: delete character under cursor
+//	Ctrl-K, Ctrl-U: kill text to end or start of line
+//	Ctrl-W: kill previous word
+//	Ctrl-Y: yank last killed text
+//	Ctrl-P, Ctrl-N: same as Up and Down
+//
+// Up and Down browse the input history. If a completion function is
+// configured, the Complete key inserts the longest common prefix of the
+// candidates, and shows them in the remaining lines of the text input grid
+// slice, if any. Further uses of the Complete key, as well as Up and Down,
+// then cycle through the candidates.
 //
 // TextInput implements gruid.Model, but is not suitable for use as main model
 // of an application.
@@ -42,8 +75,17 @@ type TextInput struct {
 	cursor    int
 	action    TextInputAction
 	keys      TextInputKeys
+	history   []string
+	hindex    int    // current history entry (len(history) for new input)
+	draft     []rune // new input content saved while browsing history
+	kill      []rune // last killed text
+	complete  func(string) []string
+	cands     []string   // completion candidates currently shown
+	cindex    int        // active candidate index (-1 if none)
+	csuffix   []rune     // text after cursor when completion started
 	dirty     bool       // state changed in Update and Draw was still not called
 	drawn     gruid.Grid // the last grid slice that was drawn
+	id        string     // identifier for emitted events
 }
 
 // TextInputAction represents last user action with the text input.
@@ -67,18 +109,35 @@ func NewTextInput(cfg TextInputConfig) *TextInput {
 		prompt: cfg.Prompt,
 		style:  cfg.Style,
 		keys:   cfg.Keys,
+		id:     cfg.ID,
 	}
+	ti.history = append(ti.history, cfg.History...)
+	ti.hindex = len(ti.history)
+	ti.complete = cfg.Complete
 	stdefault := gruid.Style{}
 	if ti.style.Cursor == stdefault {
 		// not true reverse with terminal driver, but good enough as a default
 		ti.style.Cursor = cfg.Text.Style()
 		ti.style.Cursor.Bg, ti.style.Cursor.Fg = ti.style.Cursor.Fg, ti.style.Cursor.Bg
 	}
+	if ti.style.Completion == stdefault {
+		ti.style.Completion = cfg.Text.Style()
+	}
+	if ti.style.CompletionActive == stdefault {
+		ti.style.CompletionActive = ti.style.Cursor
+	}
 	ti.cursorMin = ti.prompt.Size().X
 	ti.content = []rune(ti.stt.Text())
 	ti.cursor = len(ti.content)
 	if ti.keys.Quit == nil {
-		ti.keys.Quit = []gruid.Key{gruid.KeyEscape, gruid.KeyTab}
+		if ti.complete != nil {
+			ti.keys.Quit = []gruid.Key{gruid.KeyEscape}
+		} else {
+			ti.keys.Quit = []gruid.Key{gruid.KeyEscape, gruid.KeyTab}
+		}
+	}
+	if ti.keys.Complete == nil {
+		ti.keys.Complete = []gruid.Key{gruid.KeyTab}
 	}
 	ti.dirty = true
 	return ti
@@ -102,6 +161,12 @@ func (ti *TextInput) SetBox(b *Box) {
 	ti.dirty = true
 }
 
+// History returns the input history, from oldest to newest entry. The
+// returned slice should not be modified.
+func (ti *TextInput) History() []string {
+	return ti.history
+}
+
 func (ti *TextInput) cursorMax() int {
 	return len(ti.content)
 }
@@ -115,67 +180,375 @@ func (ti *TextInput) Update(msg gruid.Msg) gruid.Effect {
 		ti.updateMsgKeyDown(msg)
 	case gruid.MsgMouse:
 		ti.updateMsgMouse(msg)
+	case gruid.MsgPaste:
+		ti.cands = nil
+		ti.paste(msg.Text)
 	}
-	if ti.action != TextInputPass {
-		ti.dirty = true
+	if ti.action == TextInputPass {
+		return nil
+	}
+	ti.dirty = true
+	switch ti.action {
+	case TextInputInvoke:
+		return emit(ti.id, InputAccepted{ID: ti.id, Text: ti.Content()})
+	case TextInputQuit:
+		return emit(ti.id, WidgetClosed{ID: ti.id})
 	}
 	return nil
 }
 
-func (ti *TextInput) updateMsgKeyDown(msg gruid.MsgKeyDown) {
-	if msg.Key.In(ti.keys.Quit) {
-		ti.action = TextInputQuit
-		return
-	}
+// textInputOp represents an editing operation.
+type textInputOp int
+
+const (
+	opNone textInputOp = iota
+	opLineStart
+	opLineEnd
+	opCharLeft
+	opCharRight
+	opWordLeft
+	opWordRight
+	opBackspace
+	opDelete
+	opKillEnd
+	opKillStart
+	opKillWord
+	opYank
+	opHistoryPrev
+	opHistoryNext
+)
+
+// editOp returns the editing operation corresponding to a key press, if any.
+func editOp(msg gruid.MsgKeyDown) textInputOp {
 	switch msg.Key {
 	case gruid.KeyHome:
-		if ti.cursor > 0 {
-			ti.action = TextInputChange
-			ti.cursor = 0
-		}
+		return opLineStart
 	case gruid.KeyEnd:
-		if ti.cursor < ti.cursorMax() {
+		return opLineEnd
+	case gruid.KeyArrowLeft:
+		if msg.Mod&gruid.ModCtrl != 0 {
+			return opWordLeft
+		}
+		return opCharLeft
+	case gruid.KeyArrowRight:
+		if msg.Mod&gruid.ModCtrl != 0 {
+			return opWordRight
+		}
+		return opCharRight
+	case gruid.KeyArrowUp:
+		return opHistoryPrev
+	case gruid.KeyArrowDown:
+		return opHistoryNext
+	case gruid.KeyBackspace:
+		return opBackspace
+	case gruid.KeyDelete:
+		return opDelete
+	}
+	if !msg.Key.IsRune() {
+		return opNone
+	}
+	r, _ := utf8.DecodeRuneInString(string(msg.Key))
+	if msg.Mod&gruid.ModAlt != 0 {
+		switch r {
+		case 'b':
+			return opWordLeft
+		case 'f':
+			return opWordRight
+		}
+		return opNone
+	}
+	if msg.Mod&gruid.ModCtrl != 0 && r >= 'a' && r <= 'z' {
+		// same as the raw control character
+		r = r - 'a' + 1
+	}
+	switch r {
+	case 0x01: // Ctrl-A
+		return opLineStart
+	case 0x02: // Ctrl-B
+		return opCharLeft
+	case 0x04: // Ctrl-D
+		return opDelete
+	case 0x05: // Ctrl-E
+		return opLineEnd
+	case 0x06: // Ctrl-F
+		return opCharRight
+	case 0x0b: // Ctrl-K
+		return opKillEnd
+	case 0x0e: // Ctrl-N
+		return opHistoryNext
+	case 0x10: // Ctrl-P
+		return opHistoryPrev
+	case 0x15: // Ctrl-U
+		return opKillStart
+	case 0x17: // Ctrl-W
+		return opKillWord
+	case 0x19: // Ctrl-Y
+		return opYank
+	}
+	return opNone
+}
+
+func (ti *TextInput) updateMsgKeyDown(msg gruid.MsgKeyDown) {
+	if ti.cands != nil {
+		switch {
+		case msg.Key.In(ti.keys.Complete) || msg.Key == gruid.KeyArrowDown:
+			ti.cycleCompletion(1)
+			return
+		case msg.Key == gruid.KeyArrowUp:
+			ti.cycleCompletion(-1)
+			return
+		case msg.Key == gruid.KeyEscape || msg.Key == gruid.KeyEnter:
+			ti.cands = nil
 			ti.action = TextInputChange
-			ti.cursor = ti.cursorMax()
+			return
 		}
-	case gruid.KeyArrowLeft:
+		ti.cands = nil
+		ti.dirty = true
+	}
+	if ti.complete != nil && msg.Key.In(ti.keys.Complete) {
+		ti.startCompletion()
+		return
+	}
+	if msg.Key.In(ti.keys.Quit) {
+		ti.action = TextInputQuit
+		return
+	}
+	if msg.Key == gruid.KeyEnter {
+		ti.action = TextInputInvoke
+		ti.addHistory()
+		return
+	}
+	if op := editOp(msg); op != opNone {
+		ti.edit(op)
+		return
+	}
+	if !msg.Key.IsRune() {
+		return
+	}
+	r, _ := utf8.DecodeRuneInString(string(msg.Key))
+	if unicode.IsControl(r) {
+		// raw control characters may be reported by some
+		// terminals, for example when pasting text.
+		return
+	}
+	ti.insert([]rune{r})
+}
+
+func (ti *TextInput) edit(op textInputOp) {
+	ocursor := ti.cursor
+	switch op {
+	case opLineStart:
+		ti.cursor = 0
+	case opLineEnd:
+		ti.cursor = ti.cursorMax()
+	case opCharLeft:
 		if ti.cursor > 0 {
-			ti.action = TextInputChange
 			ti.cursor--
 		}
-	case gruid.KeyArrowRight:
+	case opCharRight:
 		if ti.cursor < ti.cursorMax() {
-			ti.action = TextInputChange
 			ti.cursor++
 		}
-	case gruid.KeyBackspace:
+	case opWordLeft:
+		ti.cursor = ti.wordStart()
+	case opWordRight:
+		ti.cursor = ti.wordEnd()
+	case opBackspace:
 		if ti.cursor > 0 {
-			ti.content = append(ti.content[:ti.cursor-1], ti.content[ti.cursor:]...)
-			ti.cursor--
-			ti.action = TextInputChange
+			ti.remove(ti.cursor-1, ti.cursor)
 		}
-	case gruid.KeyEnter:
-		ti.action = TextInputInvoke
-	default:
-		if !msg.Key.IsRune() {
-			return
+	case opDelete:
+		if ti.cursor < ti.cursorMax() {
+			ti.remove(ti.cursor, ti.cursor+1)
 		}
-		r, _ := utf8.DecodeRuneInString(string(msg.Key))
-		var c []rune
-		c = append(c, ti.content[:ti.cursor]...)
-		c = append(c, r)
-		c = append(c, ti.content[ti.cursor:]...)
-		ti.content = c
-		ti.cursor++
+	case opKillEnd:
+		ti.killRange(ti.cursor, ti.cursorMax())
+	case opKillStart:
+		ti.killRange(0, ti.cursor)
+	case opKillWord:
+		ti.killRange(ti.wordStart(), ti.cursor)
+	case opYank:
+		ti.insert(ti.kill)
+	case opHistoryPrev:
+		ti.browseHistory(-1)
+	case opHistoryNext:
+		ti.browseHistory(1)
+	}
+	if ti.cursor != ocursor {
 		ti.action = TextInputChange
 	}
 }
 
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordStart returns the start of the word before the cursor.
+func (ti *TextInput) wordStart() int {
+	i := ti.cursor
+	for i > 0 && !isWordRune(ti.content[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(ti.content[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor.
+func (ti *TextInput) wordEnd() int {
+	i := ti.cursor
+	for i < len(ti.content) && !isWordRune(ti.content[i]) {
+		i++
+	}
+	for i < len(ti.content) && isWordRune(ti.content[i]) {
+		i++
+	}
+	return i
+}
+
+// insert inserts text at cursor position.
+func (ti *TextInput) insert(rs []rune) {
+	if len(rs) == 0 {
+		return
+	}
+	var c []rune
+	c = append(c, ti.content[:ti.cursor]...)
+	c = append(c, rs...)
+	c = append(c, ti.content[ti.cursor:]...)
+	ti.content = c
+	ti.cursor += len(rs)
+	ti.action = TextInputChange
+}
+
+// paste inserts pasted text at cursor position. Newlines and tabs are
+// replaced by spaces, and other control characters are ignored.
+func (ti *TextInput) paste(s string) {
+	rs := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\n' || r == '\t':
+			rs = append(rs, ' ')
+		case !unicode.IsControl(r):
+			rs = append(rs, r)
+		}
+	}
+	ti.insert(rs)
+}
+
+// remove removes text between indices i and j, moving the cursor
+// accordingly.
+func (ti *TextInput) remove(i, j int) {
+	if i >= j {
+		return
+	}
+	ti.content = append(ti.content[:i], ti.content[j:]...)
+	if ti.cursor >= j {
+		ti.cursor -= j - i
+	} else if ti.cursor > i {
+		ti.cursor = i
+	}
+	ti.action = TextInputChange
+}
+
+// killRange removes text between indices i and j, saving it for yanking.
+func (ti *TextInput) killRange(i, j int) {
+	if i >= j {
+		return
+	}
+	ti.kill = append(ti.kill[:0], ti.content[i:j]...)
+	ti.remove(i, j)
+}
+
+func (ti *TextInput) setContent(rs []rune) {
+	ti.content = append([]rune(nil), rs...)
+	ti.cursor = len(ti.content)
+	ti.action = TextInputChange
+}
+
+// browseHistory moves delta entries in the input history. The new input
+// being edited is saved when leaving it, and restored when coming back.
+func (ti *TextInput) browseHistory(delta int) {
+	i := ti.hindex + delta
+	if i < 0 || i > len(ti.history) {
+		return
+	}
+	if ti.hindex == len(ti.history) {
+		ti.draft = append(ti.draft[:0], ti.content...)
+	}
+	ti.hindex = i
+	if i == len(ti.history) {
+		ti.setContent(ti.draft)
+		return
+	}
+	ti.setContent([]rune(ti.history[i]))
+}
+
+// addHistory appends the current content to the history, unless it is empty
+// or the same as the last entry.
+func (ti *TextInput) addHistory() {
+	s := string(ti.content)
+	if s != "" && (len(ti.history) == 0 || ti.history[len(ti.history)-1] != s) {
+		ti.history = append(ti.history, s)
+	}
+	ti.hindex = len(ti.history)
+	ti.draft = ti.draft[:0]
+}
+
+// startCompletion computes completion candidates for the text before the
+// cursor.
+func (ti *TextInput) startCompletion() {
+	cands := ti.complete(string(ti.content[:ti.cursor]))
+	if len(cands) == 0 {
+		return
+	}
+	ti.csuffix = append(ti.csuffix[:0], ti.content[ti.cursor:]...)
+	if len(cands) == 1 {
+		ti.applyCompletion(cands[0])
+		return
+	}
+	prefix := []rune(cands[0])
+	for _, c := range cands[1:] {
+		prefix = commonPrefix(prefix, []rune(c))
+	}
+	if len(prefix) > ti.cursor {
+		ti.applyCompletion(string(prefix))
+	}
+	ti.cands = cands
+	ti.cindex = -1
+	ti.action = TextInputChange
+}
+
+func commonPrefix(a, b []rune) []rune {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// cycleCompletion makes the next or previous candidate active, and uses it
+// as completion.
+func (ti *TextInput) cycleCompletion(delta int) {
+	n := len(ti.cands)
+	ti.cindex = (ti.cindex + delta + n) % n
+	ti.applyCompletion(ti.cands[ti.cindex])
+	ti.action = TextInputChange
+}
+
+// applyCompletion replaces text before cursor with the given candidate.
+func (ti *TextInput) applyCompletion(cand string) {
+	c := []rune(cand)
+	ti.cursor = len(c)
+	ti.content = append(c, ti.csuffix...)
+	ti.action = TextInputChange
+}
+
 func (ti *TextInput) updateMsgMouse(msg gruid.MsgMouse) {
 	cgrid := ti.grid
 	if ti.box != nil {
 		rg := ti.grid.Range()
-		cgrid = ti.grid.Slice(rg.Shift(1, 1, -1, -1))
+		cgrid = ti.grid.Slice(ti.box.inner(rg))
 	}
 	start := ti.start()
 	p := msg.P.Sub(cgrid.Bounds().Min)
@@ -188,8 +561,21 @@ func (ti *TextInput) updateMsgMouse(msg gruid.MsgMouse) {
 		if !cgrid.Contains(p) {
 			return
 		}
+		if ti.cands != nil {
+			if p.Y > 0 {
+				i := ti.candOffset(cgrid.Size().Y-1) + p.Y - 1
+				if i < len(ti.cands) {
+					ti.applyCompletion(ti.cands[i])
+				}
+			}
+			ti.cands = nil
+			ti.action = TextInputChange
+			if p.Y > 0 {
+				return
+			}
+		}
 		ocursor := ti.cursor
-		ti.cursor = msg.P.X + start - ti.cursorMin - 1
+		ti.cursor = p.X + start - ti.cursorMin
 		if ti.cursor > ti.cursorMax() {
 			ti.cursor = ti.cursorMax()
 		}
@@ -213,6 +599,16 @@ func (ti *TextInput) Action() TextInputAction {
 	return ti.action
 }
 
+// CursorPos returns the screen position of the cell where the cursor is
+// drawn. It can be used with the gruid.ShowCursor command, so that the
+// hardware cursor follows the text input's cursor.
+func (ti *TextInput) CursorPos() gruid.Point {
+	p := ti.grid.Bounds().Min
+	min, _ := ti.box.margins()
+	p = p.Add(min)
+	return p.Shift(ti.cursorMin+ti.cursor-ti.start(), 0)
+}
+
 func (ti *TextInput) cursorRune() rune {
 	if ti.cursor < len(ti.content) {
 		return ti.content[ti.cursor]
@@ -224,7 +620,7 @@ func (ti *TextInput) start() int {
 	cgrid := ti.grid
 	if ti.box != nil {
 		rg := ti.grid.Range()
-		cgrid = ti.grid.Slice(rg.Shift(1, 1, -1, -1))
+		cgrid = ti.grid.Slice(ti.box.inner(rg))
 	}
 	crg := cgrid.Range()
 	start := 0
@@ -249,7 +645,7 @@ func (ti *TextInput) Draw() gruid.Grid {
 	if ti.box != nil {
 		ti.box.Draw(ti.grid)
 		rg := ti.grid.Range()
-		cgrid = ti.grid.Slice(rg.Shift(1, 1, -1, -1))
+		cgrid = ti.grid.Slice(ti.box.inner(rg))
 	}
 	cgrid.Fill(gruid.Cell{Rune: ' ', Style: ti.stt.Style()})
 	ti.prompt.Draw(cgrid)
@@ -257,7 +653,37 @@ func (ti *TextInput) Draw() gruid.Grid {
 	start := ti.start()
 	ti.stt.WithText(string(ti.content[start:])).Draw(cgrid.Slice(crg.Shift(ti.cursorMin, 0, 0, 0)))
 	ti.stt.With(string(ti.cursorRune()), ti.style.Cursor).Draw(cgrid.Slice(crg.Shift(ti.cursorMin+ti.cursor-start, 0, 0, 0)))
+	ti.drawCompletion(cgrid)
 	ti.dirty = false
 	ti.drawn = ti.grid
 	return ti.drawn
 }
+
+// candOffset returns the index of the first visible completion candidate, so
+// that the active one is visible in the given number of lines.
+func (ti *TextInput) candOffset(lines int) int {
+	if lines <= 0 || ti.cindex < lines {
+		return 0
+	}
+	return ti.cindex - lines + 1
+}
+
+// drawCompletion draws completion candidates below the input line.
+func (ti *TextInput) drawCompletion(cgrid gruid.Grid) {
+	crg := cgrid.Range()
+	lines := crg.Size().Y - 1
+	if ti.cands == nil || lines <= 0 {
+		return
+	}
+	offset := ti.candOffset(lines)
+	for y := 1; y <= lines && offset+y-1 < len(ti.cands); y++ {
+		i := offset + y - 1
+		st := ti.style.Completion
+		if i == ti.cindex {
+			st = ti.style.CompletionActive
+		}
+		line := cgrid.Slice(crg.Line(y))
+		line.Fill(gruid.Cell{Rune: ' ', Style: st})
+		ti.stt.With(ti.cands[i], st).Draw(line)
+	}
+}
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestTextInput(t *testing.T) {
+	gd := gruid.NewGrid(10, 1)
+	ti := NewTextInput(TextInputConfig{
+		Grid: gd,
+	})
+	for _, k := range []gruid.Key{"a", "\x1b", "\t", "b", "\x00", "\u009b"} {
+		ti.Update(gruid.MsgKeyDown{Key: k})
+	}
+	if ti.Content() != "ab" {
+		t.Errorf("bad content: %q", ti.Content())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyBackspace})
+	if ti.Content() != "a" || ti.Action() != TextInputChange {
+		t.Errorf("bad content after backspace: %q", ti.Content())
+	}
+}
+
+func TestTextInputEditing(t *testing.T) {
+	gd := gruid.NewGrid(20, 1)
+	ti := NewTextInput(TextInputConfig{
+		Grid: gd,
+		Text: Text("foo bar-baz"),
+	})
+	ti.Update(gruid.MsgKeyDown{Key: "b", Mod: gruid.ModAlt})
+	ti.Update(gruid.MsgKeyDown{Key: "b", Mod: gruid.ModAlt})
+	ti.Update(gruid.MsgKeyDown{Key: "k", Mod: gruid.ModCtrl})
+	if ti.Content() != "foo " || ti.Action() != TextInputChange {
+		t.Errorf("bad content after kill: %q", ti.Content())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: "\x01"})
+	ti.Update(gruid.MsgKeyDown{Key: "\x19"})
+	if ti.Content() != "bar-bazfoo " {
+		t.Errorf("bad content after yank: %q", ti.Content())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: "f", Mod: gruid.ModAlt})
+	ti.Update(gruid.MsgKeyDown{Key: "w", Mod: gruid.ModCtrl})
+	if ti.Content() != "bar- " {
+		t.Errorf("bad content after word kill: %q", ti.Content())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyDelete})
+	if ti.Content() != "bar-" {
+		t.Errorf("bad content after delete: %q", ti.Content())
+	}
+}
+
+func TestTextInputHistory(t *testing.T) {
+	gd := gruid.NewGrid(20, 1)
+	ti := NewTextInput(TextInputConfig{
+		Grid:    gd,
+		History: []string{"first"},
+	})
+	for _, k := range []gruid.Key{"a", gruid.KeyEnter} {
+		ti.Update(gruid.MsgKeyDown{Key: k})
+	}
+	if h := ti.History(); len(h) != 2 || h[1] != "a" {
+		t.Errorf("bad history: %v", h)
+	}
+	ti.Update(gruid.MsgKeyDown{Key: "b"})
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowUp})
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowUp})
+	if ti.Content() != "first" {
+		t.Errorf("bad history entry: %q", ti.Content())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowUp})
+	if ti.Action() != TextInputPass {
+		t.Errorf("bad action at history start: %v", ti.Action())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowDown})
+	if ti.Content() != "ab" {
+		t.Errorf("draft not restored: %q", ti.Content())
+	}
+}
+
+func TestTextInputCompletion(t *testing.T) {
+	gd := gruid.NewGrid(20, 3)
+	cmds := []string{"help", "hello", "quit"}
+	ti := NewTextInput(TextInputConfig{
+		Grid: gd,
+		Text: Text("h"),
+		Complete: func(prefix string) []string {
+			var cands []string
+			for _, c := range cmds {
+				if len(c) >= len(prefix) && c[:len(prefix)] == prefix {
+					cands = append(cands, c)
+				}
+			}
+			return cands
+		},
+	})
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	if ti.Content() != "hel" || ti.Action() != TextInputChange {
+		t.Errorf("bad common prefix completion: %q", ti.Content())
+	}
+	ti.Draw()
+	if c := gd.At(gruid.Point{0, 2}); c.Rune != 'h' {
+		t.Errorf("candidates not drawn: %v", c)
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyTab})
+	if ti.Content() != "hello" {
+		t.Errorf("bad cycled completion: %q", ti.Content())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape})
+	if ti.Action() != TextInputChange {
+		t.Errorf("escape with candidates should close them: %v", ti.Action())
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape})
+	if ti.Action() != TextInputQuit {
+		t.Errorf("bad quit: %v", ti.Action())
+	}
+}
+
+func TestTextInputPaste(t *testing.T) {
+	gd := gruid.NewGrid(20, 1)
+	ti := NewTextInput(TextInputConfig{
+		Grid: gd,
+		Text: Text("ad"),
+	})
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyArrowLeft})
+	ti.Update(gruid.MsgPaste{Text: "b\nc\x00"})
+	if ti.Content() != "ab cd" || ti.Action() != TextInputChange {
+		t.Errorf("bad content after paste: %q", ti.Content())
+	}
+}
+
+func TestTextInputEvents(t *testing.T) {
+	gd := gruid.NewGrid(10, 1)
+	ti := NewTextInput(TextInputConfig{Grid: gd, ID: "name"})
+	if eff := ti.Update(gruid.MsgKeyDown{Key: "a"}); eff != nil {
+		t.Errorf("event on change")
+	}
+	cmd, ok := ti.Update(gruid.MsgKeyDown{Key: gruid.KeyEnter}).(gruid.Cmd)
+	if !ok || cmd() != (InputAccepted{ID: "name", Text: "a"}) {
+		t.Errorf("bad accept event")
+	}
+	cmd, ok = ti.Update(gruid.MsgKeyDown{Key: gruid.KeyEscape}).(gruid.Cmd)
+	if !ok || cmd() != (WidgetClosed{ID: "name"}) {
+		t.Errorf("bad quit event")
+	}
+}
+
+func TestTextInputCursorPos(t *testing.T) {
+	gd := gruid.NewGrid(20, 5)
+	ti := NewTextInput(TextInputConfig{
+		Grid:   gd.Slice(gruid.NewRange(2, 1, 12, 4)),
+		Prompt: Text("> "),
+		Box:    &Box{},
+		Text:   Text("abc"),
+	})
+	if p := ti.CursorPos(); p != (gruid.Point{8, 2}) {
+		t.Errorf("bad cursor position: %v", p)
+	}
+	ti.Update(gruid.MsgKeyDown{Key: gruid.KeyHome})
+	if p := ti.CursorPos(); p != (gruid.Point{5, 2}) {
+		t.Errorf("bad cursor position after Home: %v", p)
+	}
+	for _, k := range []gruid.Key{"d", "e", "f", "g", "h", "i"} {
+		ti.Update(gruid.MsgKeyDown{Key: k})
+	}
+	// the input scrolls, so that the cursor stays within the box
+	if p := ti.CursorPos(); p.X > 10 {
+		t.Errorf("bad cursor position after scroll: %v", p)
+	}
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"github.com/anaseto/gruid"
+)
+
+// ViewportConfig contains configuration options for creating a viewport.
+type ViewportConfig struct {
+	Grid gruid.Grid  // screen grid slice where the map is displayed
+	Map  gruid.Range // range of valid map positions
+}
+
+// Viewport represents a movable camera that displays a part of a map bigger
+// than the screen into a smaller grid slice. The camera position is clamped
+// so that no position outside the map is shown, unless the map is smaller
+// than the viewport grid.
+type Viewport struct {
+	grid gruid.Grid  // screen grid slice
+	rg   gruid.Range // map range
+	pos  gruid.Point // map position displayed at the top-left corner
+}
+
+// NewViewport returns a new viewport with the given configuration. The camera
+// initially shows the top-left part of the map.
+func NewViewport(cfg ViewportConfig) *Viewport {
+	vp := &Viewport{
+		grid: cfg.Grid,
+		rg:   cfg.Map,
+	}
+	vp.SetPosition(cfg.Map.Min)
+	return vp
+}
+
+// Grid returns the viewport's screen grid slice.
+func (vp *Viewport) Grid() gruid.Grid {
+	return vp.grid
+}
+
+// SetGrid updates the viewport's screen grid slice, for example after a
+// screen resize. The camera position is clamped again if necessary.
+func (vp *Viewport) SetGrid(gd gruid.Grid) {
+	vp.grid = gd
+	vp.SetPosition(vp.pos)
+}
+
+// SetMap updates the range of valid map positions.
+func (vp *Viewport) SetMap(rg gruid.Range) {
+	vp.rg = rg
+	vp.SetPosition(vp.pos)
+}
+
+// Position returns the map position displayed at the top-left corner of the
+// viewport.
+func (vp *Viewport) Position() gruid.Point {
+	return vp.pos
+}
+
+// SetPosition moves the camera so that the given map position is displayed at
+// the top-left corner of the viewport, clamping it to the map edges.
+func (vp *Viewport) SetPosition(p gruid.Point) {
+	max := vp.grid.Size()
+	vp.pos = gruid.Point{
+		X: clamp(p.X, vp.rg.Min.X, vp.rg.Max.X-max.X),
+		Y: clamp(p.Y, vp.rg.Min.Y, vp.rg.Max.Y-max.Y),
+	}
+}
+
+func clamp(x, min, max int) int {
+	if x > max {
+		x = max
+	}
+	if x < min {
+		x = min
+	}
+	return x
+}
+
+// Move moves the camera by the given delta, clamping it to the map edges.
+func (vp *Viewport) Move(delta gruid.Point) {
+	vp.SetPosition(vp.pos.Add(delta))
+}
+
+// CenterOn moves the camera so that the given map position is displayed at
+// the center of the viewport, or as close as possible to it.
+func (vp *Viewport) CenterOn(p gruid.Point) {
+	max := vp.grid.Size()
+	vp.SetPosition(p.Sub(gruid.Point{max.X / 2, max.Y / 2}))
+}
+
+// Follow moves the camera only as much as necessary so that the given map
+// position stays at least at margin cells from the viewport edges. It provides
+// smoother scrolling than CenterOn when following a moving player. The margin
+// is reduced if the viewport is too small for it.
+func (vp *Viewport) Follow(p gruid.Point, margin int) {
+	max := vp.grid.Size()
+	mx, my := margin, margin
+	if 2*mx >= max.X {
+		mx = (max.X - 1) / 2
+	}
+	if 2*my >= max.Y {
+		my = (max.Y - 1) / 2
+	}
+	pos := vp.pos
+	switch {
+	case p.X < pos.X+mx:
+		pos.X = p.X - mx
+	case p.X >= pos.X+max.X-mx:
+		pos.X = p.X - max.X + mx + 1
+	}
+	switch {
+	case p.Y < pos.Y+my:
+		pos.Y = p.Y - my
+	case p.Y >= pos.Y+max.Y-my:
+		pos.Y = p.Y - max.Y + my + 1
+	}
+	vp.SetPosition(pos)
+}
+
+// View returns the range of map positions currently displayed.
+func (vp *Viewport) View() gruid.Range {
+	return vp.grid.Range().Add(vp.pos).Intersect(vp.rg)
+}
+
+// ToScreen returns the position relative to the viewport's grid where the
+// given map position is displayed. It returns false if the map position is
+// not visible.
+func (vp *Viewport) ToScreen(p gruid.Point) (gruid.Point, bool) {
+	return p.Sub(vp.pos), p.In(vp.View())
+}
+
+// ToMap returns the map position displayed at the given position relative to
+// the viewport's grid.
+func (vp *Viewport) ToMap(p gruid.Point) gruid.Point {
+	return p.Add(vp.pos)
+}
+
+// MouseMap translates the coordinates of a mouse message, considered to be
+// absolute in the screen grid, into map coordinates. It returns false if the
+// mouse position is outside the viewport or the map.
+func (vp *Viewport) MouseMap(msg gruid.MsgMouse) (gruid.Point, bool) {
+	bounds := vp.grid.Bounds()
+	if !msg.P.In(bounds) {
+		return gruid.Point{}, false
+	}
+	p := vp.ToMap(msg.P.Sub(bounds.Min))
+	return p, p.In(vp.rg)
+}
+
+// Draw draws the visible part of the map in the viewport's grid, using the
+// given function to get the cell at each map position, and returns the grid.
+// Screen positions outside the map are not modified. It can be used with any
+// kind of map representation, such as an rl.Grid.
+func (vp *Viewport) Draw(fn func(p gruid.Point) gruid.Cell) gruid.Grid {
+	it := vp.grid.Iterator()
+	for it.Next() {
+		p := it.P().Add(vp.pos)
+		if p.In(vp.rg) {
+			it.SetCell(fn(p))
+		}
+	}
+	return vp.grid
+}
+
+// DrawGrid draws the visible part of a map represented by a grid whose range
+// of positions is the viewport's map range, and returns the viewport's grid.
+func (vp *Viewport) DrawGrid(gd gruid.Grid) gruid.Grid {
+	vp.grid.Copy(gd.Slice(vp.View().Sub(vp.rg.Min)))
+	return vp.grid
+}
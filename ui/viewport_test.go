@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/anaseto/gruid"
+)
+
+func TestViewport(t *testing.T) {
+	screen := gruid.NewGrid(30, 12)
+	gd := screen.Slice(gruid.NewRange(2, 1, 12, 6)) // 10x5 viewport
+	mapgd := gruid.NewGrid(100, 50)
+	mapgd.Iter(func(p gruid.Point, c gruid.Cell) {
+		mapgd.Set(p, gruid.Cell{Rune: rune('a' + (p.X+p.Y)%26)})
+	})
+	vp := NewViewport(ViewportConfig{Grid: gd, Map: mapgd.Range()})
+	if vp.Position() != (gruid.Point{}) {
+		t.Errorf("bad initial position: %v", vp.Position())
+	}
+	vp.Move(gruid.Point{-5, -5})
+	if vp.Position() != (gruid.Point{}) {
+		t.Errorf("bad clamped position: %v", vp.Position())
+	}
+	vp.CenterOn(gruid.Point{50, 25})
+	if vp.Position() != (gruid.Point{45, 23}) {
+		t.Errorf("bad centered position: %v", vp.Position())
+	}
+	vp.CenterOn(gruid.Point{99, 49})
+	if vp.Position() != (gruid.Point{90, 45}) {
+		t.Errorf("bad clamped centered position: %v", vp.Position())
+	}
+	vp.SetPosition(gruid.Point{20, 20})
+	vp.Follow(gruid.Point{25, 22}, 2)
+	if vp.Position() != (gruid.Point{20, 20}) {
+		t.Errorf("bad follow position: %v", vp.Position())
+	}
+	vp.Follow(gruid.Point{29, 23}, 2)
+	if vp.Position() != (gruid.Point{22, 21}) {
+		t.Errorf("bad follow position: %v", vp.Position())
+	}
+	p, ok := vp.MouseMap(gruid.MsgMouse{P: gruid.Point{3, 2}})
+	if !ok || p != (gruid.Point{23, 22}) {
+		t.Errorf("bad mouse map position: %v", p)
+	}
+	if _, ok := vp.MouseMap(gruid.MsgMouse{P: gruid.Point{0, 0}}); ok {
+		t.Errorf("mouse outside viewport")
+	}
+	if q, ok := vp.ToScreen(p); !ok || q != (gruid.Point{1, 1}) {
+		t.Errorf("bad screen position: %v", q)
+	}
+	vp.DrawGrid(mapgd)
+	gd.Iter(func(q gruid.Point, c gruid.Cell) {
+		if mc := mapgd.At(vp.ToMap(q)); c != mc {
+			t.Errorf("bad cell at %v: %c vs %c", q, c.Rune, mc.Rune)
+		}
+	})
+	gd.Fill(gruid.Cell{})
+	vp.Draw(mapgd.At)
+	gd.Iter(func(q gruid.Point, c gruid.Cell) {
+		if mc := mapgd.At(vp.ToMap(q)); c != mc {
+			t.Errorf("bad cell at %v: %c vs %c", q, c.Rune, mc.Rune)
+		}
+	})
+}
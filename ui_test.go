@@ -2,8 +2,12 @@ package gruid
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/gob"
+	"errors"
 	"testing"
+	"time"
 )
 
 type testModel struct {
@@ -147,6 +151,150 @@ func TestApp(t *testing.T) {
 	}
 }
 
+func TestRecordInputs(t *testing.T) {
+	m := &testModel{gd: NewGrid(8, 4)}
+	framebuf := &bytes.Buffer{}
+	app := NewApp(AppConfig{
+		Driver:       &testDriver{t: t},
+		Model:        m,
+		FrameWriter:  framebuf,
+		RecordInputs: true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	dec, err := NewFrameDecoder(framebuf)
+	if err != nil {
+		t.Fatalf("frame decoding %v", err)
+	}
+	keys := 0
+	frame := Frame{}
+	for dec.Decode(&frame) == nil {
+		for _, in := range frame.Inputs {
+			if in.Key == nil || in.Mouse != nil {
+				t.Fatalf("bad input: %+v", in)
+			}
+			if in.Key.Key == KeyEnter {
+				keys++
+			}
+		}
+	}
+	// the test driver keeps sending keys until the application ends
+	if keys < niter {
+		t.Errorf("bad recorded key count: %d", keys)
+	}
+}
+
+func TestFrameIndex(t *testing.T) {
+	framebuf := &bytes.Buffer{}
+	idxbuf := &bytes.Buffer{}
+	enc := newFrameEncoder(framebuf, idxbuf, FrameHeader{})
+	t0 := time.Unix(0, 0)
+	const nframes = 300
+	for i := 0; i < nframes; i++ {
+		fr := Frame{Time: t0.Add(time.Duration(i) * time.Second), Width: 8, Height: 4}
+		fr.Cells = []FrameCell{{P: Point{i % 8, i % 4}, Cell: Cell{Rune: 'a'}}}
+		if err := enc.encode(fr); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	enc.close()
+	dec, err := NewFrameDecoder(bytes.NewReader(framebuf.Bytes()))
+	if err != nil {
+		t.Fatalf("frame decoding %v", err)
+	}
+	if err := dec.LoadIndex(idxbuf); err != nil {
+		t.Fatalf("index decoding %v", err)
+	}
+	count := func() int {
+		n := 0
+		frame := Frame{}
+		for dec.Decode(&frame) == nil {
+			n++
+		}
+		return n
+	}
+	if n := count(); n != nframes {
+		t.Errorf("bad frame count: %d", n)
+	}
+	n, err := dec.SeekFrame(200)
+	if err != nil || n != frameIndexInterval {
+		t.Errorf("bad seek frame: %d (%v)", n, err)
+	}
+	frame := Frame{}
+	if err := dec.Decode(&frame); err != nil || !frame.Time.Equal(t0.Add(frameIndexInterval*time.Second)) {
+		t.Errorf("bad frame after seek: %v (%v)", frame.Time, err)
+	}
+	n, err = dec.SeekFrame(200)
+	if err != nil || n != frameIndexInterval+1 {
+		t.Errorf("bad forward seek frame: %d (%v)", n, err)
+	}
+	n, err = dec.SeekTo(t0.Add(260 * time.Second))
+	if err != nil || n != 2*frameIndexInterval {
+		t.Errorf("bad seek time: %d (%v)", n, err)
+	}
+	if n := count(); n != nframes-2*frameIndexInterval {
+		t.Errorf("bad frame count after seek: %d", n)
+	}
+	n, err = dec.SeekFrame(5)
+	if err != nil || n != 0 {
+		t.Errorf("bad seek frame: %d (%v)", n, err)
+	}
+	if n := count(); n != nframes {
+		t.Errorf("bad frame count after seek: %d", n)
+	}
+}
+
+func TestFrameHeader(t *testing.T) {
+	framebuf := &bytes.Buffer{}
+	enc := newFrameEncoder(framebuf, nil, FrameHeader{Name: "test", Metadata: map[string]string{"seed": "42"}})
+	t0 := time.Unix(100, 0)
+	for i := 0; i < 3; i++ {
+		if err := enc.encode(Frame{Time: t0, Width: 8, Height: 4}); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	enc.close()
+	dec, err := NewFrameDecoder(bytes.NewReader(framebuf.Bytes()))
+	if err != nil {
+		t.Fatalf("frame decoding %v", err)
+	}
+	hdr := dec.Header()
+	if hdr.Version != FrameFormatVersion || hdr.Name != "test" || hdr.Metadata["seed"] != "42" {
+		t.Errorf("bad header: %+v", hdr)
+	}
+	if hdr.Width != 8 || hdr.Height != 4 || !hdr.Time.Equal(t0) {
+		t.Errorf("bad header size or time: %+v", hdr)
+	}
+	n := 0
+	frame := Frame{}
+	for dec.Decode(&frame) == nil {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("bad frame count: %d", n)
+	}
+	if n, err := dec.SeekFrame(0); err != nil || n != 0 || dec.Decode(&frame) != nil {
+		t.Errorf("bad seek to start: %d (%v)", n, err)
+	}
+
+	// recording without header
+	framebuf.Reset()
+	gzw := gzip.NewWriter(framebuf)
+	gob.NewEncoder(gzw).Encode(Frame{Width: 8, Height: 4})
+	gzw.Close()
+	dec, err = NewFrameDecoder(bytes.NewReader(framebuf.Bytes()))
+	if err != nil {
+		t.Fatalf("frame decoding %v", err)
+	}
+	if hdr := dec.Header(); hdr.Version != 0 || hdr.Name != "" {
+		t.Errorf("bad header: %+v", hdr)
+	}
+	if err := dec.Decode(&frame); err != nil || frame.Width != 8 {
+		t.Errorf("bad frame: %+v (%v)", frame, err)
+	}
+}
+
 func TestApp2(t *testing.T) {
 	gd := NewGrid(8, 4)
 	m := &testModel{gd: gd}
@@ -167,3 +315,620 @@ func TestApp2(t *testing.T) {
 		}
 	})
 }
+
+type testPollDriver struct {
+	testDriver
+	count int
+}
+
+func (td *testPollDriver) PollMsg() (Msg, error) {
+	var msg Msg
+	msg = MsgKeyDown{Key: KeyEnter}
+	if td.count == niter {
+		msg = MsgScreen{}
+	}
+	if td.count == niter+1 {
+		msg = MsgKeyDown{Key: KeyEscape}
+	}
+	if td.count > niter+1 {
+		msg = nil
+	}
+	td.count++
+	return msg, nil
+}
+
+func TestAppSingleThread(t *testing.T) {
+	gd := NewGrid(8, 4)
+	m := &testModel{gd: gd}
+	td := &testDriver{t: t}
+	app := NewApp(AppConfig{
+		Driver:       td,
+		Model:        m,
+		SingleThread: true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if m.count != niter {
+		t.Errorf("bad count: %d", m.count)
+	}
+	if !td.closed || !td.init {
+		t.Errorf("not closed or not init")
+	}
+	m = &testModel{gd: gd}
+	tpd := &testPollDriver{testDriver: testDriver{t: t}}
+	app = NewApp(AppConfig{
+		Driver:       tpd,
+		Model:        m,
+		SingleThread: true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if m.count != niter {
+		t.Errorf("bad count: %d", m.count)
+	}
+	if tpd.testDriver.count != 1+1+2*niter/3 {
+		t.Errorf("bad driver count: %d", tpd.testDriver.count)
+	}
+}
+
+type testInfoDriver struct {
+	testDriver
+}
+
+func (td *testInfoDriver) Capabilities() DriverCapabilities {
+	return DriverCapabilities{Colors: 256, Mouse: true}
+}
+
+func TestDriverCapabilities(t *testing.T) {
+	app := NewApp(AppConfig{Driver: &testInfoDriver{}, Model: &testModel{}})
+	if caps, ok := app.DriverCapabilities(); !ok || caps.Colors != 256 || !caps.Mouse || caps.Tiles {
+		t.Errorf("bad capabilities: %+v (%v)", caps, ok)
+	}
+	app = NewApp(AppConfig{Driver: &testDriver{}, Model: &testModel{}})
+	if _, ok := app.DriverCapabilities(); ok {
+		t.Errorf("unexpected capabilities")
+	}
+}
+
+type testDropModel struct {
+	gd Grid
+}
+
+func (m *testDropModel) Update(msg Msg) Effect {
+	if _, ok := msg.(MsgInit); ok {
+		ret := func(msg Msg) Cmd { return func() Msg { return msg } }
+		return Batch(End(), ret(testMsg(1)), ret(testMsg(2)), ret(MsgScreen{}))
+	}
+	return nil
+}
+
+func (m *testDropModel) Draw() Grid {
+	return m.gd
+}
+
+func TestDroppedMsgs(t *testing.T) {
+	tpd := &testPollDriver{testDriver: testDriver{t: t}, count: niter + 2}
+	app := NewApp(AppConfig{
+		Driver:       tpd,
+		Model:        &testDropModel{gd: NewGrid(8, 4)},
+		SingleThread: true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	dm := app.DroppedMsgs()
+	if dm.Count != 3 || dm.Types["gruid.testMsg"] != 2 || dm.Types["gruid.MsgScreen"] != 1 {
+		t.Errorf("bad dropped messages: %+v", dm)
+	}
+	if s := dm.String(); s != "3 dropped messages: gruid.MsgScreen (1), gruid.testMsg (2)" {
+		t.Errorf("bad summary: %s", s)
+	}
+}
+
+type testClipboardDriver struct {
+	testDriver
+	clip    string
+	pending []Msg
+}
+
+func (td *testClipboardDriver) PollMsg() (Msg, error) {
+	if len(td.pending) == 0 {
+		return nil, nil
+	}
+	msg := td.pending[0]
+	td.pending = td.pending[1:]
+	return msg, nil
+}
+
+func (td *testClipboardDriver) SetClipboard(s string) error {
+	td.clip = s
+	return nil
+}
+
+func (td *testClipboardDriver) RequestClipboard() error {
+	td.pending = append(td.pending, MsgPaste{Text: td.clip})
+	return nil
+}
+
+type testClipboardModel struct {
+	gd    Grid
+	paste string
+}
+
+func (m *testClipboardModel) Update(msg Msg) Effect {
+	switch msg := msg.(type) {
+	case MsgInit:
+		return Batch(SetClipboard("hello"), RequestClipboard())
+	case MsgPaste:
+		m.paste = msg.Text
+		return End()
+	}
+	return nil
+}
+
+func (m *testClipboardModel) Draw() Grid {
+	return m.gd
+}
+
+func TestClipboard(t *testing.T) {
+	td := &testClipboardDriver{testDriver: testDriver{t: t}}
+	m := &testClipboardModel{gd: NewGrid(8, 4)}
+	app := NewApp(AppConfig{
+		Driver:       td,
+		Model:        m,
+		SingleThread: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.Start(ctx); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if m.paste != "hello" {
+		t.Errorf("bad pasted text: %q", m.paste)
+	}
+}
+
+type testBatchModel struct {
+	testModel
+	updates int
+	draws   int
+}
+
+func (m *testBatchModel) Update(msg Msg) Effect {
+	m.updates++
+	return m.testModel.Update(msg)
+}
+
+func (m *testBatchModel) Draw() Grid {
+	m.draws++
+	return m.testModel.Draw()
+}
+
+func TestBatchMsgs(t *testing.T) {
+	m := &testBatchModel{testModel: testModel{gd: NewGrid(8, 4)}}
+	tpd := &testPollDriver{testDriver: testDriver{t: t}}
+	app := NewApp(AppConfig{
+		Driver:       tpd,
+		Model:        m,
+		SingleThread: true,
+		BatchMsgs:    true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if m.count != niter {
+		t.Errorf("bad count: %d", m.count)
+	}
+	if m.draws >= m.updates/2 {
+		t.Errorf("messages not batched: %d draws for %d updates", m.draws, m.updates)
+	}
+	m = &testBatchModel{testModel: testModel{gd: NewGrid(8, 4)}}
+	td := &testDriver{t: t}
+	app = NewApp(AppConfig{
+		Driver:    td,
+		Model:     m,
+		BatchMsgs: true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if m.count != niter {
+		t.Errorf("bad count: %d", m.count)
+	}
+	if m.draws > m.updates {
+		t.Errorf("too many draws: %d draws for %d updates", m.draws, m.updates)
+	}
+}
+
+type testHeldKeysDriver struct {
+	testDriver
+	count int
+}
+
+func (td *testHeldKeysDriver) PollMsg() (Msg, error) {
+	if td.count > niter {
+		return nil, nil
+	}
+	msg := MsgKeyDown{Key: Key(rune('!' + td.count))}
+	if td.count == niter {
+		msg.Key = KeyEscape
+	}
+	td.count++
+	return msg, nil
+}
+
+type testHeldKeysModel struct {
+	gd   Grid
+	keys []Key
+}
+
+func (m *testHeldKeysModel) Update(msg Msg) Effect {
+	if msg, ok := msg.(MsgKeyDown); ok {
+		if msg.Key == KeyEscape {
+			return End()
+		}
+		m.keys = append(m.keys, msg.Key)
+	}
+	return nil
+}
+
+func (m *testHeldKeysModel) Draw() Grid {
+	return m.gd
+}
+
+func TestBatchMsgsOrder(t *testing.T) {
+	for _, single := range []bool{false, true} {
+		m := &testHeldKeysModel{gd: NewGrid(8, 4)}
+		app := NewApp(AppConfig{
+			Driver:       &testHeldKeysDriver{testDriver: testDriver{t: t}},
+			Model:        m,
+			SingleThread: single,
+			BatchMsgs:    true,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := app.Start(ctx); err != nil {
+			t.Errorf("Start returns error: %v", err)
+		}
+		cancel()
+		if len(m.keys) != niter {
+			t.Errorf("bad key count (single thread %v): %d", single, len(m.keys))
+		}
+		for i, k := range m.keys {
+			if k != Key(rune('!'+i)) {
+				t.Errorf("bad key order (single thread %v): %d: %q", single, i, k)
+				break
+			}
+		}
+	}
+	// input buffered before polling the driver again
+	app := NewApp(AppConfig{
+		Driver: &testHeldKeysDriver{testDriver: testDriver{t: t}},
+		Model:  &testHeldKeysModel{gd: NewGrid(8, 4)},
+	})
+	app.msgs = make(chan Msg, 1)
+	app.inputs = make(chan Msg, 4)
+	for i := 0; i < 2; i++ {
+		if err := app.pollMsg(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < niter; i++ {
+		msg, err := app.queuedMsg(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg != (MsgKeyDown{Key: Key(rune('!' + i))}) {
+			t.Errorf("bad buffered key order: %d: %v", i, msg)
+			break
+		}
+	}
+}
+
+type testMirrorDriver struct {
+	t      *testing.T
+	init   bool
+	closed bool
+	count  int
+	cells  int
+}
+
+func (td *testMirrorDriver) Init() error {
+	td.init = true
+	return nil
+}
+
+func (td *testMirrorDriver) PollMsgs(ctx context.Context, msgs chan<- Msg) error {
+	td.t.Errorf("PollMsgs called on mirror")
+	return nil
+}
+
+func (td *testMirrorDriver) Flush(fr Frame) {
+	td.count++
+	td.cells += len(fr.Cells)
+}
+
+func (td *testMirrorDriver) Close() {
+	td.closed = true
+}
+
+type testMirrorModel struct {
+	testModel
+	md *testMirrorDriver
+}
+
+func (m *testMirrorModel) Update(msg Msg) Effect {
+	eff := m.testModel.Update(msg)
+	if m.count == niter/2 && !m.md.init {
+		return AttachMirror(m.md)
+	}
+	return eff
+}
+
+func TestMirrors(t *testing.T) {
+	md := &testMirrorDriver{t: t}
+	td := &testDriver{t: t}
+	m := &testMirrorModel{testModel: testModel{gd: NewGrid(8, 4)}, md: &testMirrorDriver{t: t}}
+	app := NewApp(AppConfig{
+		Driver:       td,
+		Model:        m,
+		Mirrors:      []Driver{md},
+		SingleThread: true,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if !md.init || !md.closed || md.count != td.count {
+		t.Errorf("bad mirror: %+v (driver count: %d)", md, td.count)
+	}
+	amd := m.md
+	if !amd.init || !amd.closed || amd.count == 0 || amd.count >= td.count {
+		t.Errorf("bad attached mirror: %+v (driver count: %d)", amd, td.count)
+	}
+	if amd.cells < 8*4 {
+		t.Errorf("no full frame for attached mirror: %d cells", amd.cells)
+	}
+}
+
+type testFailingDriver struct {
+	testMirrorDriver
+}
+
+func (td *testFailingDriver) Init() error {
+	return errors.New("init failed")
+}
+
+func TestMirrorInitError(t *testing.T) {
+	md := &testMirrorDriver{t: t}
+	td := &testDriver{t: t}
+	app := NewApp(AppConfig{
+		Driver:  td,
+		Model:   &testModel{gd: NewGrid(8, 4)},
+		Mirrors: []Driver{md, &testFailingDriver{testMirrorDriver{t: t}}},
+	})
+	if err := app.Start(context.Background()); err == nil {
+		t.Errorf("no error for failed mirror initialization")
+	}
+	if !md.closed || !td.closed {
+		t.Errorf("drivers not closed: mirror %v, driver %v", md.closed, td.closed)
+	}
+	if len(app.mirrors) != 2 {
+		t.Errorf("mirrors dropped: %d", len(app.mirrors))
+	}
+}
+
+type testQuietDriver struct {
+	testMirrorDriver
+}
+
+func (td *testQuietDriver) PollMsgs(ctx context.Context, msgs chan<- Msg) error {
+	<-ctx.Done()
+	return nil
+}
+
+type testBackpressureModel struct {
+	gd       Grid
+	received []int
+}
+
+const nbackpressure = 50
+
+func (m *testBackpressureModel) Update(msg Msg) Effect {
+	switch msg := msg.(type) {
+	case MsgInit:
+		return Sub(func(ctx context.Context, msgs chan<- Msg) {
+			for i := 0; i < nbackpressure; i++ {
+				select {
+				case msgs <- testMsg(i):
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	case testMsg:
+		time.Sleep(time.Millisecond)
+		m.received = append(m.received, int(msg))
+	}
+	return nil
+}
+
+func (m *testBackpressureModel) Draw() Grid {
+	return m.gd
+}
+
+func TestBackpressure(t *testing.T) {
+	for _, bp := range []BackpressurePolicy{BackpressureBlock, BackpressureDropOldest, BackpressureDropNewest} {
+		m := &testBackpressureModel{gd: NewGrid(8, 4)}
+		app := NewApp(AppConfig{
+			Driver:       &testQuietDriver{testMirrorDriver{t: t}},
+			Model:        m,
+			MsgQueueSize: 2,
+			Backpressure: bp,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		if err := app.Start(ctx); err != nil {
+			t.Errorf("Start returns error: %v", err)
+		}
+		cancel()
+		dropped := app.DroppedMsgs().Types["gruid.testMsg"]
+		if len(m.received)+dropped != nbackpressure {
+			t.Errorf("bad message count (policy %d): %d received, %d dropped", bp, len(m.received), dropped)
+		}
+		switch bp {
+		case BackpressureBlock:
+			if dropped > 0 {
+				t.Errorf("dropped messages with blocking policy: %d", dropped)
+			}
+		case BackpressureDropOldest:
+			if dropped == 0 || m.received[len(m.received)-1] != nbackpressure-1 {
+				t.Errorf("bad drop oldest: %d dropped, received %v", dropped, m.received)
+			}
+		case BackpressureDropNewest:
+			if dropped == 0 || m.received[0] != 0 {
+				t.Errorf("bad drop newest: %d dropped, received %v", dropped, m.received)
+			}
+		}
+	}
+}
+
+func TestBackpressureOrder(t *testing.T) {
+	app := NewApp(AppConfig{
+		Driver:       &testQuietDriver{testMirrorDriver{t: t}},
+		Model:        &testBackpressureModel{gd: NewGrid(8, 4)},
+		MsgQueueSize: 4,
+		Backpressure: BackpressureDropOldest,
+	})
+	app.msgs = make(chan Msg, app.msgsSize)
+	app.lossy = make(chan Msg, app.msgsSize)
+	for i := 0; i < app.msgsSize; i++ {
+		app.msgs <- MsgKeyDown{Key: Key(rune('a' + i))}
+	}
+	ctx := context.Background()
+	for i := 0; i < 2*app.msgsSize; i++ {
+		app.sendMsg(ctx, testMsg(i))
+	}
+	keys := []Msg{}
+	cmds := []Msg{}
+	for {
+		msg, _ := app.queuedMsg(false)
+		if msg == nil {
+			break
+		}
+		if special(msg) {
+			keys = append(keys, msg)
+		} else {
+			cmds = append(cmds, msg)
+		}
+	}
+	if len(keys) != app.msgsSize || len(cmds) != app.msgsSize {
+		t.Fatalf("bad message counts: %d keys, %d commands", len(keys), len(cmds))
+	}
+	for i, msg := range keys {
+		if msg != (MsgKeyDown{Key: Key(rune('a' + i))}) {
+			t.Errorf("bad key order: %d: %v", i, msg)
+		}
+	}
+	for i, msg := range cmds {
+		if msg != testMsg(app.msgsSize+i) {
+			t.Errorf("bad command message: %d: %v", i, msg)
+		}
+	}
+	if dm := app.DroppedMsgs(); dm.Count != app.msgsSize {
+		t.Errorf("bad dropped count: %d", dm.Count)
+	}
+}
+
+type testEndModel struct {
+	gd    Grid
+	draws int
+	td    *testQuietDriver
+	fn    bool
+}
+
+func (m *testEndModel) Update(msg Msg) Effect {
+	switch msg.(type) {
+	case MsgInit:
+		return EndAfterDraw(func() {
+			m.fn = true
+			if m.td.closed || m.td.count != 2 {
+				m.td.t.Errorf("bad state before end: closed %v, %d flushes", m.td.closed, m.td.count)
+			}
+		})
+	}
+	return nil
+}
+
+func (m *testEndModel) Draw() Grid {
+	m.draws++
+	m.gd.Set(Point{}, Cell{Rune: rune('a' + m.draws)})
+	return m.gd
+}
+
+func TestEndAfterDraw(t *testing.T) {
+	td := &testQuietDriver{testMirrorDriver{t: t}}
+	m := &testEndModel{gd: NewGrid(8, 4), td: td}
+	app := NewApp(AppConfig{
+		Driver: td,
+		Model:  m,
+	})
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if !m.fn || m.draws != 2 || !td.closed {
+		t.Errorf("bad end: fn %v, %d draws, closed %v", m.fn, m.draws, td.closed)
+	}
+}
+
+type testCursorDriver struct {
+	testDriver
+	p       Point
+	visible bool
+}
+
+func (td *testCursorDriver) ShowCursor(p Point) {
+	td.p = p
+	td.visible = true
+}
+
+func (td *testCursorDriver) HideCursor() {
+	td.visible = false
+}
+
+type testCursorModel struct {
+	gd Grid
+}
+
+func (m *testCursorModel) Update(msg Msg) Effect {
+	switch msg := msg.(type) {
+	case MsgInit:
+		return ShowCursor(Point{3, 2})
+	case MsgKeyDown:
+		if msg.Key == KeyEscape {
+			return End()
+		}
+	}
+	return nil
+}
+
+func (m *testCursorModel) Draw() Grid {
+	return m.gd
+}
+
+func TestCursor(t *testing.T) {
+	td := &testCursorDriver{testDriver: testDriver{t: t}}
+	m := &testCursorModel{gd: NewGrid(8, 4)}
+	app := NewApp(AppConfig{
+		Driver:       td,
+		Model:        m,
+		SingleThread: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.Start(ctx); err != nil {
+		t.Errorf("Start returns error: %v", err)
+	}
+	if !td.visible || td.p != (Point{3, 2}) {
+		t.Errorf("bad cursor state: %v %v", td.visible, td.p)
+	}
+}